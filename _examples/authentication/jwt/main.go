@@ -0,0 +1,64 @@
+package main
+
+import (
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+
+	"github.com/get-ion/ion/middleware/jwt"
+)
+
+const issuer = "ion-example"
+
+// keep the secret out of the source code on production.
+var secret = []byte("my-secret")
+
+func newApp() *ion.Application {
+	app := ion.New()
+
+	// issue a token.
+	app.Post("/login", func(ctx context.Context) {
+		username := ctx.FormValue("username")
+		if username == "" {
+			ctx.StatusCode(ion.StatusBadRequest)
+			return
+		}
+
+		claims := jwt.NewClaims(username, 15*time.Minute)
+		claims["iss"] = issuer
+		token, err := jwt.Sign(jwt.HS256, secret, claims)
+		if err != nil {
+			ctx.StatusCode(ion.StatusInternalServerError)
+			return
+		}
+
+		ctx.JSON(map[string]string{"token": token})
+	})
+
+	// verify the tokens of the api requests,
+	// use the jwt.LoadJWKS and the Config.KeyResolver to verify tokens with rotated keys.
+	api := app.Party("/api", jwt.New(jwt.Config{
+		Key:        secret,
+		Algorithms: []string{jwt.HS256},
+		Extractors: []jwt.TokenExtractor{jwt.FromAuthHeader, jwt.FromCookie("token"), jwt.FromQuery("token")},
+		Issuer:     issuer,
+		ClockSkew:  30 * time.Second,
+	}))
+	{
+		api.Get("/profile", func(ctx context.Context) {
+			claims := jwt.Get(ctx)
+			ctx.Writef("hello %s", claims.Subject())
+		})
+	}
+
+	return app
+}
+
+func main() {
+	app := newApp()
+
+	// $ curl -d "username=gerasimos" http://localhost:8080/login
+	// $ curl -H "Authorization: Bearer $TOKEN" http://localhost:8080/api/profile
+	app.Run(ion.Addr(":8080"))
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/httptest"
+	"github.com/get-ion/ion/middleware/jwt"
+	"golang.org/x/crypto/ed25519"
+)
+
+func TestJWT(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	e.GET("/api/profile").Expect().Status(httptest.StatusUnauthorized).
+		Header("WWW-Authenticate").Equal("Bearer")
+
+	token := e.POST("/login").WithFormField("username", "gerasimos").Expect().
+		Status(httptest.StatusOK).JSON().Object().Value("token").String().Raw()
+
+	e.GET("/api/profile").WithHeader("Authorization", "Bearer "+token).Expect().
+		Status(httptest.StatusOK).Body().Equal("hello gerasimos")
+	e.GET("/api/profile").WithCookie("token", token).Expect().
+		Status(httptest.StatusOK).Body().Equal("hello gerasimos")
+	e.GET("/api/profile").WithQuery("token", token).Expect().
+		Status(httptest.StatusOK).Body().Equal("hello gerasimos")
+
+	// tampered.
+	e.GET("/api/profile").WithHeader("Authorization", "Bearer "+token+"x").Expect().
+		Status(httptest.StatusUnauthorized).Header("WWW-Authenticate").Equal(`Bearer error="invalid_token"`)
+
+	invalid := map[string]jwt.Claims{
+		"expired":       {"sub": "gerasimos", "iss": issuer, "exp": time.Now().Add(-time.Minute).Unix()},
+		"not valid yet": {"sub": "gerasimos", "iss": issuer, "nbf": time.Now().Add(time.Minute).Unix()},
+		"issuer":        {"sub": "gerasimos", "iss": "other"},
+	}
+	for name, claims := range invalid {
+		token, err := jwt.Sign(jwt.HS256, secret, claims)
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		e.GET("/api/profile").WithHeader("Authorization", "Bearer "+token).Expect().
+			Status(httptest.StatusUnauthorized)
+	}
+
+	// inside the clock skew.
+	token, _ = jwt.Sign(jwt.HS256, secret, jwt.Claims{
+		"sub": "gerasimos",
+		"iss": issuer,
+		"exp": time.Now().Add(-10 * time.Second).Unix(),
+	})
+	e.GET("/api/profile").WithHeader("Authorization", "Bearer "+token).Expect().
+		Status(httptest.StatusOK)
+
+	// an algorithm which is not accepted.
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	token, _ = jwt.Sign(jwt.EdDSA, edKey, jwt.Claims{"sub": "gerasimos", "iss": issuer})
+	e.GET("/api/profile").WithHeader("Authorization", "Bearer "+token).Expect().
+		Status(httptest.StatusUnauthorized)
+}
+
+func TestJWKS(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPublic, edKey, _ := ed25519.GenerateKey(rand.Reader)
+
+	dir, err := ioutil.TempDir("", "jwks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "jwks.json")
+
+	keys := []map[string]string{
+		{"kty": "RSA", "kid": "rsa", "alg": jwt.RS256, "n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes())},
+		{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.Bytes()), "y": b64(ecKey.Y.Bytes())},
+	}
+	writeJWKS(t, filename, keys, time.Now())
+
+	jwks, err := jwt.LoadJWKS(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := ion.New()
+	app.Get("/", jwt.New(jwt.Config{KeyResolver: jwks.Key, Audience: "api"}), func(ctx context.Context) {
+		ctx.Writef("hello %s", jwt.Get(ctx).Subject())
+	})
+	e := httptest.New(t, app)
+
+	claims := jwt.Claims{"sub": "gerasimos", "aud": []string{"web", "api"}}
+	expect := func(alg, kid string, key interface{}, claims jwt.Claims, status int) {
+		token, err := jwt.SignWithKeyID(alg, kid, key, claims)
+		if err != nil {
+			t.Fatalf("%s %s: %v", alg, kid, err)
+		}
+		e.GET("/").WithHeader("Authorization", "Bearer "+token).Expect().Status(status)
+	}
+
+	expect(jwt.RS256, "rsa", rsaKey, claims, httptest.StatusOK)
+	expect(jwt.ES256, "ec", ecKey, claims, httptest.StatusOK)
+	// wrong audience.
+	expect(jwt.RS256, "rsa", rsaKey, jwt.Claims{"sub": "gerasimos", "aud": "web"}, httptest.StatusUnauthorized)
+	// the key's algorithm.
+	expect(jwt.HS256, "rsa", []byte("secret"), claims, httptest.StatusUnauthorized)
+	// signed with an other key.
+	expect(jwt.ES256, "ec", mustECKey(t), claims, httptest.StatusUnauthorized)
+	// unknown key.
+	expect(jwt.EdDSA, "ed", edKey, claims, httptest.StatusUnauthorized)
+
+	// rotate, add the new key.
+	keys = append(keys, map[string]string{"kty": "OKP", "kid": "ed", "crv": "Ed25519", "x": b64(edPublic)})
+	writeJWKS(t, filename, keys, time.Now().Add(time.Minute))
+
+	expect(jwt.EdDSA, "ed", edKey, claims, httptest.StatusOK)
+	expect(jwt.RS256, "rsa", rsaKey, claims, httptest.StatusOK)
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func mustECKey(t *testing.T) *ecdsa.PrivateKey {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func writeJWKS(t *testing.T, filename string, keys []map[string]string, modTime time.Time) {
+	b, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(filename, b, os.FileMode(0644)); err != nil {
+		t.Fatal(err)
+	}
+	// the modification time is compared on reload.
+	if err = os.Chtimes(filename, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
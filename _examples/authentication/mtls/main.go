@@ -0,0 +1,60 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/host"
+	"github.com/get-ion/ion/middleware/mtls"
+)
+
+// The certificates of the "certs" directory are signed by the "certs/ca.crt",
+// the "billing" and the "web" clients have the SPIFFE ids
+// "spiffe://example.org/ns/prod/sa/billing" and "spiffe://example.org/ns/prod/sa/web".
+//
+// $ curl --cacert certs/ca.crt --cert certs/billing.crt --key certs/billing.key https://127.0.0.1:8443/internal/invoices
+func newApp() *ion.Application {
+	app := ion.New()
+
+	app.Get("/", func(ctx context.Context) {
+		id := ctx.PeerIdentity()
+		if id == nil {
+			ctx.Writef("Hello anonymous")
+			return
+		}
+		ctx.Writef("Hello %s", id.SPIFFEID)
+	})
+
+	// any client of the example.org trust domain.
+	services := app.Party("/services", mtls.New(mtls.Config{
+		TrustDomains: []string{"example.org"},
+	}))
+	services.Get("/", func(ctx context.Context) {
+		ctx.Writef("Hello %s", ctx.PeerIdentity().CommonName)
+	})
+
+	// only the billing service.
+	internal := app.Party("/internal", mtls.New(mtls.Config{
+		SPIFFEIDs: []string{"spiffe://example.org/ns/prod/sa/billing"},
+	}))
+	internal.Get("/invoices", func(ctx context.Context) {
+		ctx.JSON([]string{"invoice-1", "invoice-2"})
+	})
+
+	return app
+}
+
+func main() {
+	app := newApp()
+
+	m := host.NewCertManager()
+	if err := m.Add("certs/server.crt", "certs/server.key"); err != nil {
+		panic(err)
+	}
+
+	// the clients without a certificate are accepted, the "/" is public,
+	// use the host.ClientCertRequired to reject them on the handshake.
+	app.Run(ion.MutualTLS("127.0.0.1:8443", m, host.ClientAuth{
+		CAFiles: []string{"certs/ca.crt"},
+		Mode:    host.ClientCertIfGiven,
+	}))
+}
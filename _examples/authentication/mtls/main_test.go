@@ -0,0 +1,84 @@
+package main
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+
+	"github.com/get-ion/ion/core/host"
+)
+
+func newClient(t *testing.T, name string) *http.Client {
+	roots, err := host.LoadClientCAs("certs/ca.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &tls.Config{RootCAs: roots}
+	if name != "" {
+		cert, err := tls.LoadX509KeyPair("certs/"+name+".crt", "certs/"+name+".key")
+		if err != nil {
+			t.Fatal(err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: cfg}}
+}
+
+func TestMutualTLS(t *testing.T) {
+	app := newApp()
+	app.Build()
+
+	clientCAs, err := host.LoadClientCAs("certs/ca.crt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := tls.LoadX509KeyPair("certs/server.crt", "certs/server.key")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := stdhttptest.NewUnstartedServer(app.Router)
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    clientCAs,
+		ClientAuth:   tls.VerifyClientCertIfGiven,
+	}
+	srv.StartTLS()
+	defer srv.Close()
+
+	tests := []struct {
+		client     string
+		path       string
+		statusCode int
+		body       string
+	}{
+		{"", "/", http.StatusOK, "Hello anonymous"},
+		{"billing", "/", http.StatusOK, "Hello spiffe://example.org/ns/prod/sa/billing"},
+		{"", "/services", http.StatusUnauthorized, ""},
+		{"web", "/services", http.StatusOK, "Hello web"},
+		{"billing", "/services", http.StatusOK, "Hello billing"},
+		{"", "/internal/invoices", http.StatusUnauthorized, ""},
+		{"web", "/internal/invoices", http.StatusForbidden, ""},
+		{"billing", "/internal/invoices", http.StatusOK, `["invoice-1","invoice-2"]`},
+	}
+
+	for _, tt := range tests {
+		resp, err := newClient(t, tt.client).Get(srv.URL + tt.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != tt.statusCode {
+			t.Fatalf("%s %s: expected status %d but got %d", tt.client, tt.path, tt.statusCode, resp.StatusCode)
+		}
+		if tt.body != "" && string(b) != tt.body {
+			t.Fatalf("%s %s: expected body %s but got %s", tt.client, tt.path, tt.body, b)
+		}
+	}
+}
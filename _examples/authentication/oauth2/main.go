@@ -27,7 +27,7 @@ import (
 	"github.com/get-ion/ion"
 	"github.com/get-ion/ion/context"
 
-	"github.com/get-ion/sessions"
+	"github.com/get-ion/ion/sessions"
 
 	"github.com/gorilla/securecookie" // optionally, used for session's encoder/decoder
 
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+)
+
+// CreateUserRequest is the request body, decoded by its content type (json, xml or form)
+// and validated by its "validate" tags, see context.Validate.
+type CreateUserRequest struct {
+	Username string `json:"username" xml:"username" form:"username" validate:"required,min=3"`
+}
+
+// User is the response, rendered as json or as xml based on the "Accept" header.
+type User struct {
+	ID       int    `json:"id" xml:"id"`
+	Username string `json:"username" xml:"username"`
+}
+
+// userError sends its status code to the client, see handlerconv.StatusCoder.
+type userError struct {
+	code    int
+	message string
+}
+
+func (e userError) Error() string   { return e.message }
+func (e userError) StatusCode() int { return e.code }
+
+func main() {
+	app := ion.New()
+
+	users := map[int]User{1: {ID: 1, Username: "ion"}}
+
+	// the path parameters are bound to the int and string input arguments, by their order,
+	// the func is validated on registration, i.e a string "id" input argument
+	// for the {id:int} is valid but an int input argument for a {name:string} is not.
+	//
+	// http://localhost:8080/users/1
+	app.HandleFunc("GET", "/users/{id:int min(1)}", func(id int) (User, error) {
+		user, ok := users[id]
+		if !ok {
+			return User{}, userError{code: ion.StatusNotFound, message: "user not found"}
+		}
+		return user, nil
+	})
+
+	// curl -X POST -d '{"username":"gopher"}' -H "Content-Type: application/json" http://localhost:8080/users
+	// an invalid username, i.e "go", is sent back as a list of field errors with the 422 status code.
+	app.HandleFunc("POST", "/users", func(ctx context.Context, req CreateUserRequest) (User, error) {
+		user := User{ID: len(users) + 1, Username: req.Username}
+		users[user.ID] = user
+
+		ctx.StatusCode(ion.StatusCreated)
+		return user, nil
+	})
+
+	// a string is sent as text.
+	//
+	// http://localhost:8080/hello/ion
+	app.HandleFunc("GET", "/hello/{name:string}", func(name string) string {
+		return "Hello " + name
+	})
+
+	app.Run(ion.Addr(":8080"))
+}
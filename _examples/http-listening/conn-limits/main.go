@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/netutil"
+
+	"github.com/get-ion/ion/middleware/metrics"
+)
+
+func newApp(registry *metrics.Registry) *ion.Application {
+	app := ion.New()
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("hello")
+	})
+
+	// the open connections per state (new, active and idle), the hijacked, the rejected ones
+	// and the drained or forcibly closed ones on shutdown.
+	app.Get("/metrics", metrics.Handler(registry))
+
+	return app
+}
+
+// http://localhost:8080/metrics
+func main() {
+	app := newApp(metrics.DefaultRegistry)
+	app.Scheduler.Schedule(metrics.DefaultRegistry.HostTask())
+
+	// up to 1000 concurrent connections, the extra ones wait in the listener's backlog,
+	// and up to 20 concurrent connections per client, the extra ones are rejected.
+	//
+	// On CTRL/CMD+C the connections are drained for 5 seconds and the rest are closed forcibly,
+	// the log reports how many of them.
+	app.Run(ion.ConnLimits(ion.Addr(":8080"), netutil.ConnLimits{
+		MaxConnections:      1000,
+		Queue:               true,
+		MaxConnectionsPerIP: 20,
+	}))
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/core/netutil"
+	"github.com/get-ion/ion/middleware/metrics"
+)
+
+func TestConnLimits(t *testing.T) {
+	registry := metrics.NewRegistry("ion", nil)
+	app := newApp(registry)
+	app.Scheduler.Schedule(registry.HostTask())
+
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Run(ion.ConnLimits(ion.Listener(l), netutil.ConnLimits{MaxConnectionsPerIP: 1}),
+		ion.WithoutBanner, ion.WithoutInterruptHandler)
+
+	held, err := net.Dial("tcp4", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a second connection of the same client is rejected.
+	rejected, err := net.Dial("tcp4", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rejected.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := rejected.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the second connection to be rejected but got: %v", err)
+	}
+	rejected.Close()
+
+	// the held connection is served.
+	defer held.Close()
+	io.WriteString(held, "GET /metrics HTTP/1.1\r\nHost: localhost\r\nConnection: close\r\n\r\n")
+	resp, err := http.ReadResponse(bufio.NewReader(held), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	// the host of a Listener has the default address.
+	for _, expected := range []string{
+		`ion_host_connections_rejected_total{addr=":8080"} 1`,
+		// the held one, it serves the metrics.
+		`ion_host_connections{addr=":8080",state="active"} 1`,
+	} {
+		if !strings.Contains(string(b), expected+"\n") {
+			t.Fatalf("expected the metrics to contain '%s' but got:\n%s", expected, b)
+		}
+	}
+
+	app.Shutdown(context.Background())
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/host"
+)
+
+// Graceful restart starts a new process of the same executable which
+// inherits the listening sockets, when it's ready to serve the old process is shut down gracefully,
+// so no connection is refused or dropped, i.e on deploys.
+//
+// $ go build -o app && ./app
+// $ kill -HUP <pid> # or -USR2, after replacing the ./app binary with a new version.
+//
+// The same binary can be started by the systemd socket activation as well,
+// the listener of the `.socket` unit, with `ListenStream=8080`, is used instead of a new one.
+func main() {
+	app := ion.New()
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("hello from the process %d", os.Getpid())
+	})
+
+	app.Get("/slow", func(ctx context.Context) {
+		// the old process finishes this request before it exits.
+		time.Sleep(5 * time.Second)
+		ctx.Writef("slow response from the process %d", os.Getpid())
+	})
+
+	// restart on SIGHUP or SIGUSR2, the old process waits up to 10 seconds
+	// for the new one to be ready and then for its active connections to finish.
+	app.Scheduler.Schedule(host.RestartOnSignalTask(10 * time.Second))
+
+	app.Run(ion.Addr(":8080"))
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/host"
+)
+
+func newApp() *ion.Application {
+	app := ion.New()
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("Hello from %s", ctx.Request().Proto)
+	})
+
+	return app
+}
+
+// $ curl --http2-prior-knowledge http://localhost:8080
+// $ curl --http2 http://localhost:8080
+func main() {
+	app := newApp()
+
+	// the HTTP/1.1 and the HTTP/2 without tls, i.e behind a tls-terminating service mesh.
+	app.Run(ion.H2C(":8080", host.HTTP2{
+		MaxConcurrentStreams: 500,
+		IdleTimeout:          2 * time.Minute,
+	}))
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/core/host"
+	"golang.org/x/net/http2"
+)
+
+func TestH2C(t *testing.T) {
+	app := newApp()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Run(ion.HTTP2(ion.Listener(l), host.HTTP2{H2C: true}), ion.WithoutBanner, ion.WithoutInterruptHandler)
+
+	// the prior knowledge.
+	h2c := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	for _, tt := range []struct {
+		client   *http.Client
+		expected string
+	}{
+		{h2c, "Hello from HTTP/2.0"},
+		{http.DefaultClient, "Hello from HTTP/1.1"},
+	} {
+		resp, err := tt.client.Get("http://" + l.Addr().String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if string(b) != tt.expected {
+			t.Fatalf("expected %q but got %q", tt.expected, b)
+		}
+	}
+
+	app.Shutdown(context.Background())
+}
+
+func TestH2CConfigureError(t *testing.T) {
+	app := newApp()
+
+	// the HTTP/2 requires the TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256 cipher suite.
+	srv := &http.Server{Addr: "127.0.0.1:0", TLSConfig: &tls.Config{
+		CipherSuites: []uint16{tls.TLS_RSA_WITH_AES_128_CBC_SHA},
+	}}
+
+	err := app.Run(ion.HTTP2(ion.Server(srv), host.HTTP2{H2C: true}), ion.WithoutBanner, ion.WithoutInterruptHandler)
+	if err == nil || !strings.Contains(err.Error(), "CipherSuites") {
+		t.Fatalf("expected the HTTP/2 configuration error but got: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+
+	"github.com/get-ion/ion/core/host"
+)
+
+func main() {
+	app := ion.New()
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("Hello from the SECURE server of %s", ctx.Request().TLS.ServerName)
+	})
+
+	// the certificate is chosen by the server name of the client,
+	// the first one is the default.
+	m := host.NewCertManager()
+	if err := m.Add("../listen-tls/mycert.cert", "../listen-tls/mykey.key"); err != nil {
+		panic(err)
+	}
+	// m.Add("example.org.crt", "example.org.key")
+
+	// the files are checked every 10 seconds, replace them
+	// with the renewed certificates, without a restart.
+	m.CheckInterval = 10 * time.Second
+
+	// start the server (HTTPS) on port 443, this is a blocking func
+	app.Run(ion.Certs("127.0.0.1:443", m))
+}
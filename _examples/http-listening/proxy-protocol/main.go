@@ -0,0 +1,41 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/netutil"
+	"github.com/get-ion/ion/middleware/logger"
+)
+
+func newApp() *ion.Application {
+	app := ion.New()
+	// the request logger logs the address of the client too.
+	app.Use(logger.New())
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("Hello %s", ctx.RemoteAddr())
+
+		// the header itself, its TLVs too.
+		if h := netutil.ProxyHeaderOf(ctx.Request()); h != nil && h.AWSVPCEndpointID() != "" {
+			ctx.Writef(" from %s", h.AWSVPCEndpointID())
+		}
+	})
+
+	return app
+}
+
+// HAProxy's "server ion1 10.0.0.2:8080 send-proxy-v2" of a backend
+// or an AWS Network Load Balancer with the proxy protocol v2 enabled on its target group.
+func main() {
+	app := newApp()
+
+	// only the connections of the load balancers can send the PROXY protocol header, and they must send it.
+	app.Run(ion.ProxyProtocol(ion.Addr(":8080"), "10.0.0.0/8"))
+
+	// the health checks of some load balancers don't send the header:
+	// app.Run(ion.ProxyProtocolOptional(ion.Addr(":8080"), "10.0.0.0/8"))
+
+	// the UNIX sockets are trusted, i.e HAProxy's "server ion1 /var/run/ion.sock send-proxy":
+	// l, _ := netutil.UNIX("/var/run/ion.sock", 0666)
+	// app.Run(ion.ProxyProtocol(ion.Listener(l), "127.0.0.1"))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion"
+)
+
+func TestProxyProtocol(t *testing.T) {
+	app := newApp()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Run(ion.ProxyProtocol(ion.Listener(l), "127.0.0.1"), ion.WithoutBanner, ion.WithoutInterruptHandler)
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	conn.Write([]byte("PROXY TCP4 192.0.2.1 10.0.0.2 56324 8080\r\nGET / HTTP/1.0\r\n\r\n"))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if b, _ := ioutil.ReadAll(resp.Body); string(b) != "Hello 192.0.2.1" {
+		t.Fatalf("expected the address of the client but got %q", b)
+	}
+
+	app.Shutdown(context.Background())
+}
+
+func TestProxyProtocolTrustedRequired(t *testing.T) {
+	app := newApp()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	// any client could spoof its address without trusted proxies.
+	if err := app.Run(ion.ProxyProtocol(ion.Listener(l)), ion.WithoutBanner, ion.WithoutInterruptHandler); err == nil {
+		t.Fatalf("expected an error without trusted proxies")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"io"
+	"os"
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+
+	"github.com/get-ion/ion/middleware/accesslog"
+)
+
+func newApp(w io.Writer) *ion.Application {
+	app := ion.New()
+
+	// the Combined Log Format to the "w".
+	// Other formats: accesslog.CommonFormat, accesslog.JSONFormat, accesslog.LogfmtFormat
+	// or a custom template of fields, i.e "{time} {method} {uri} {status} {latency} {header:X-Forwarded-For}".
+	app.UseGlobal(accesslog.New(accesslog.Config{
+		Format: accesslog.CombinedFormat,
+		Output: w,
+		// the health checks and the static assets stay out of the log.
+		Skip: accesslog.SkipPaths("/health", "/assets/*"),
+	}))
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("hello")
+	}).Name = "home"
+
+	app.Get("/users/{id:int}", func(ctx context.Context) {
+		ctx.JSON(context.Map{"id": ctx.Params().Get("id")})
+	}).Name = "user"
+
+	app.Get("/health", func(ctx context.Context) {
+		ctx.Writef("ok")
+	})
+
+	app.Get("/assets/{file:path}", func(ctx context.Context) {
+		ctx.Writef("asset")
+	})
+
+	return app
+}
+
+func main() {
+	// the access.log is moved to a backup file when it reaches 10MB and every day at midnight UTC.
+	file, err := accesslog.NewRotatingFile("./access.log", 10<<20, 24*time.Hour)
+	if err != nil {
+		panic(err)
+	}
+
+	// write the entries from a different goroutine,
+	// the requests don't wait for the writes to the file.
+	w := accesslog.NewAsyncWriter(io.MultiWriter(file, os.Stdout), accesslog.DefaultBufferSize)
+	// write the queued entries and close the file on shutdown.
+	defer w.Close()
+	defer file.Close()
+
+	app := newApp(w)
+
+	// http://localhost:8080
+	// http://localhost:8080/users/42
+	// http://localhost:8080/health
+	// see the ./access.log file and the output on the console.
+	app.Run(ion.Addr(":8080"))
+}
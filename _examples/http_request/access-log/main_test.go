@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/httptest"
+	"github.com/get-ion/ion/middleware/accesslog"
+)
+
+func TestAccessLogCombined(t *testing.T) {
+	var buf bytes.Buffer
+	app := newApp(&buf)
+	e := httptest.New(t, app)
+
+	e.GET("/").WithHeader("Referer", "http://example.com").WithHeader("User-Agent", "test-agent").
+		WithBasicAuth("makis", "pass").
+		Expect().Status(httptest.StatusOK)
+	e.GET("/health").Expect().Status(httptest.StatusOK)
+	e.GET("/assets/css/main.css").Expect().Status(httptest.StatusOK)
+
+	expected := regexp.MustCompile(`^192\.0\.2\.1 - makis \[\d{2}/\w{3}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4}\] ` +
+		`"GET / HTTP/1\.1" 200 5 "http://example.com" "test-agent"\n$`)
+	if got := buf.String(); !expected.MatchString(got) {
+		t.Fatalf("unexpected log, got: %q", got)
+	}
+}
+
+func TestAccessLogEscape(t *testing.T) {
+	var buf bytes.Buffer
+	app := newApp(&buf)
+	e := httptest.New(t, app)
+
+	// the client's values can't forge the fields of the entry.
+	e.GET("/").WithQueryString(`q=x" 200 1 "-" "fake`).
+		WithHeader("Referer", `http://example.com/\`).WithHeader("User-Agent", "agent\" \"é").
+		Expect().Status(httptest.StatusOK)
+
+	expected := `"GET /?q=x\" 200 1 \"-\" \"fake HTTP/1.1" 200 5 "http://example.com/\\" "agent\" \"\xc3\xa9"` + "\n"
+	if got := buf.String(); !strings.HasSuffix(got, expected) {
+		t.Fatalf("expected the escaped values %q but got %q", expected, got)
+	}
+}
+
+func TestAccessLogJSON(t *testing.T) {
+	var buf bytes.Buffer
+	app := newApp(ioutil.Discard)
+	app.UseGlobal(accesslog.New(accesslog.Config{
+		Format: accesslog.JSONFormat,
+		Fields: []string{"method", "path", "status", "bytes", "route", "header:X-Tenant", "query:page"},
+		Output: &buf,
+	}))
+	e := httptest.New(t, app)
+
+	e.GET("/users/42").WithHeader("X-Tenant", "acme").WithQuery("page", 2).
+		Expect().Status(httptest.StatusOK)
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("invalid json entry: %q: %v", buf.String(), err)
+	}
+
+	expected := map[string]interface{}{
+		"method":   "GET",
+		"path":     "/users/42",
+		"status":   float64(200),
+		"bytes":    float64(len(`{"id":"42"}`)),
+		"route":    "user",
+		"X-Tenant": "acme",
+		"page":     "2",
+	}
+	for k, v := range expected {
+		if entry[k] != v {
+			t.Fatalf("expected %s to be %v but got %v", k, v, entry[k])
+		}
+	}
+}
+
+func TestAccessLogTemplate(t *testing.T) {
+	var buf bytes.Buffer
+	app := newApp(ioutil.Discard)
+	app.UseGlobal(accesslog.New(accesslog.Config{
+		Format: "{method} {path} {status} {route} {response_header:Content-Type} {header:X-Missing}",
+		Output: &buf,
+	}))
+	app.Post("/messages", func(ctx context.Context) {
+		ctx.StatusCode(httptest.StatusCreated)
+		ctx.Text("created")
+	}).Name = "messages"
+	e := httptest.New(t, app)
+
+	e.POST("/messages").Expect().Status(httptest.StatusCreated)
+	if got, expected := buf.String(), "POST /messages 201 messages text/plain; charset=UTF-8 -\n"; got != expected {
+		t.Fatalf("expected %q but got %q", expected, got)
+	}
+
+	buf.Reset()
+	app = newApp(ioutil.Discard)
+	app.UseGlobal(accesslog.New(accesslog.Config{
+		Format: accesslog.LogfmtFormat,
+		Fields: []string{"method", "path", "user_agent"},
+		Output: &buf,
+	}))
+	httptest.New(t, app).GET("/").WithHeader("User-Agent", "a b").Expect().Status(httptest.StatusOK)
+	if got, expected := strings.TrimSpace(buf.String()), `method=GET path=/ user_agent="a b"`; got != expected {
+		t.Fatalf("expected %q but got %q", expected, got)
+	}
+}
+
+func TestAccessLogRotatingFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access-log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	filename := filepath.Join(dir, "access.log")
+	// a line of the combined format is larger than 50 bytes, so each write rotates the file.
+	file, err := accesslog.NewRotatingFile(filename, 50, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	e := httptest.New(t, newApp(file))
+	for i := 0; i < 3; i++ {
+		e.GET("/").Expect().Status(httptest.StatusOK)
+	}
+	file.Close()
+
+	backups, _ := filepath.Glob(filepath.Join(dir, "access-*.log"))
+	contents, _ := ioutil.ReadFile(filename)
+	if lines := strings.Count(string(contents), "\n"); len(backups) != 2 || lines != 1 {
+		t.Fatalf("expected 2 backups and 1 line but got %d backups and %d lines", len(backups), lines)
+	}
+}
+
+func TestAccessLogAsyncWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := accesslog.NewAsyncWriter(&buf, 0)
+
+	e := httptest.New(t, newApp(w))
+	for i := 0; i < 10; i++ {
+		e.GET("/").Expect().Status(httptest.StatusOK)
+	}
+
+	// writes the queued entries.
+	w.Close()
+	if lines := strings.Count(buf.String(), "\n"); lines != 10 {
+		t.Fatalf("expected 10 lines but got %d", lines)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+)
+
+// Address is validated as part of the User.
+type Address struct {
+	City string `json:"city" xml:"city" yaml:"city" form:"city" validate:"required"`
+}
+
+// User is decoded from json, xml, yaml, form or the url query,
+// based on the request, and it's validated by its "validate" tags.
+// The rules are checked on the zero values too, the Role is optional so it's a pointer.
+type User struct {
+	Username string  `json:"username" xml:"username" yaml:"username" form:"username" validate:"required,min=3,max=20,alphanum"`
+	Email    string  `json:"email" xml:"email" yaml:"email" form:"email" validate:"required,email"`
+	Age      int     `json:"age" xml:"age" yaml:"age" form:"age" validate:"min=18"`
+	Role     *string `json:"role,omitempty" xml:"role,omitempty" yaml:"role,omitempty" form:"role" validate:"oneof=admin member"`
+	Address  Address `json:"address" xml:"address" yaml:"address" form:"address"`
+}
+
+func readUser(ctx context.Context) {
+	var user User
+	if err := ctx.ReadBody(&user); err != nil {
+		if errs, ok := err.(context.ValidationErrors); ok {
+			// [{"field":"email","rule":"email","message":"email must be a valid email address"}]
+			ctx.StatusCode(errs.StatusCode())
+			ctx.JSON(errs)
+			return
+		}
+
+		ctx.StatusCode(ion.StatusBadRequest)
+		ctx.WriteString(err.Error())
+		return
+	}
+
+	ctx.JSON(user)
+}
+
+func newApp() *ion.Application {
+	app := ion.New()
+
+	// curl -X POST -H "Content-Type: application/json" \
+	// -d '{"username":"gopher","email":"gopher@ion.dev","age":20,"address":{"city":"Athens"}}' \
+	// http://localhost:8080/users
+	//
+	// curl -X POST -H "Content-Type: application/x-yaml" \
+	// --data-binary $'username: gopher\nemail: gopher@ion.dev\nage: 20\naddress:\n  city: Athens' \
+	// http://localhost:8080/users
+	app.Post("/users", readUser)
+
+	// curl "http://localhost:8080/users?username=gopher&email=gopher@ion.dev&age=20&address.city=Athens"
+	app.Get("/users", readUser)
+
+	return app
+}
+
+func main() {
+	app := newApp()
+	app.Run(ion.Addr(":8080"))
+}
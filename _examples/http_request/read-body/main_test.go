@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/httptest"
+)
+
+func TestReadBody(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	expected := User{Username: "gopher", Email: "gopher@ion.dev", Age: 20, Address: Address{City: "Athens"}}
+
+	e.POST("/users").WithJSON(expected).Expect().Status(ion.StatusOK).
+		JSON().Object().Equal(expected)
+	e.POST("/users").WithHeader("Content-Type", "application/xml").
+		WithText("<User><username>gopher</username><email>gopher@ion.dev</email><age>20</age><address><city>Athens</city></address></User>").
+		Expect().Status(ion.StatusOK).JSON().Object().Equal(expected)
+	e.POST("/users").WithHeader("Content-Type", "application/x-yaml").
+		WithText("username: gopher\nemail: gopher@ion.dev\nage: 20\naddress:\n  city: Athens\n").
+		Expect().Status(ion.StatusOK).JSON().Object().Equal(expected)
+	e.POST("/users").WithFormField("username", "gopher").WithFormField("email", "gopher@ion.dev").
+		WithFormField("age", "20").WithFormField("address.city", "Athens").
+		Expect().Status(ion.StatusOK).JSON().Object().Equal(expected)
+	e.GET("/users").WithQueryString("username=gopher&email=gopher@ion.dev&age=20&address.city=Athens").
+		Expect().Status(ion.StatusOK).JSON().Object().Equal(expected)
+
+	e.POST("/users").WithJSON(map[string]interface{}{"username": "go", "email": "gopher", "age": 20, "role": "root"}).
+		Expect().Status(ion.StatusUnprocessableEntity).JSON().Array().Equal([]map[string]string{
+		{"field": "username", "rule": "min", "param": "3", "message": "username must be at least 3 characters"},
+		{"field": "email", "rule": "email", "message": "email must be a valid email address"},
+		{"field": "role", "rule": "oneof", "param": "admin member", "message": "role must be one of: admin member"},
+		{"field": "address.city", "rule": "required", "message": "address.city is required"},
+	})
+
+	// the rules are checked on the zero values too.
+	e.POST("/users").WithJSON(map[string]interface{}{"username": "", "email": "gopher@ion.dev", "address": map[string]string{"city": "Athens"}}).
+		Expect().Status(ion.StatusUnprocessableEntity).JSON().Array().Equal([]map[string]string{
+		{"field": "username", "rule": "required", "message": "username is required"},
+		{"field": "age", "rule": "min", "param": "18", "message": "age must be at least 18"},
+	})
+	e.POST("/users").WithJSON(map[string]interface{}{"username": "gopher", "email": "gopher@ion.dev", "age": 20, "role": "", "address": map[string]string{"city": "Athens"}}).
+		Expect().Status(ion.StatusUnprocessableEntity).JSON().Array().Equal([]map[string]string{
+		{"field": "role", "rule": "oneof", "param": "admin member", "message": "role must be one of: admin member"},
+	})
+
+	// the optional role is checked only if it's set.
+	role := "admin"
+	expected.Role = &role
+	e.POST("/users").WithJSON(expected).Expect().Status(ion.StatusOK).
+		JSON().Object().Equal(expected)
+	e.GET("/users").WithQueryString("username=gopher&email=gopher@ion.dev&age=20&role=admin&address.city=Athens").
+		Expect().Status(ion.StatusOK).JSON().Object().Equal(expected)
+
+	e.POST("/users").WithHeader("Content-Type", "application/msgpack").WithText("").
+		Expect().Status(ion.StatusBadRequest)
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+)
+
+func newApp() *ion.Application {
+	app := ion.New()
+
+	// The forwarding headers are honoured only when the request comes from a trusted proxy,
+	// i.e an nginx on the same machine or a load balancer of the private network,
+	// otherwise any client could spoof its ip address.
+	app.Configure(
+		ion.WithTrustedProxies("127.0.0.1", "10.0.0.0/8"),
+		ion.WithRemoteAddrHeaders("X-Forwarded-For", "Forwarded", "X-Real-Ip"),
+	)
+
+	// nginx:
+	// proxy_set_header X-Forwarded-For $proxy_add_x_forwarded_for;
+	// proxy_set_header X-Forwarded-Proto $scheme;
+	// proxy_set_header X-Forwarded-Host $host;
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("%s %s://%s", ctx.RemoteAddr(), ctx.Scheme(), ctx.Host())
+	})
+
+	return app
+}
+
+func main() {
+	app := newApp()
+	app.Run(ion.Addr("127.0.0.1:8080"))
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestTrustedProxies(t *testing.T) {
+	app := newApp()
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		remoteAddr string
+		headers    map[string]string
+		expected   string
+	}{
+		// not a trusted proxy, the headers are ignored.
+		{"203.0.113.9:4000", map[string]string{"X-Forwarded-For": "1.1.1.1", "X-Forwarded-Proto": "https"},
+			"203.0.113.9 http://example.com"},
+		// the chain is walked from right to left, the spoofed leftmost address is ignored.
+		{"127.0.0.1:4000", map[string]string{"X-Forwarded-For": "6.6.6.6, 198.51.100.7, 10.0.0.2",
+			"X-Forwarded-Proto": "https", "X-Forwarded-Host": "ion.dev"},
+			"198.51.100.7 https://ion.dev"},
+		// each proxy appends its host and proto, the forged leftmost ones of the client are ignored.
+		{"127.0.0.1:4000", map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.2",
+			"X-Forwarded-Proto": "https, http, http", "X-Forwarded-Host": "evil.com, ion.dev, ion.dev"},
+			"198.51.100.7 http://ion.dev"},
+		{"127.0.0.1:4000", map[string]string{"Forwarded": "for=6.6.6.6;proto=https;host=evil.com, " +
+			"for=198.51.100.9;proto=http;host=ion.dev, for=10.0.0.2;proto=http;host=ion.dev"},
+			"198.51.100.9 http://ion.dev"},
+		// a forged host of an untrusted address is ignored.
+		{"203.0.113.9:4000", map[string]string{"Forwarded": "for=6.6.6.6;host=evil.com", "X-Forwarded-Host": "evil.com"},
+			"203.0.113.9 http://example.com"},
+		// all the addresses are trusted, the leftmost is the client.
+		{"127.0.0.1:4000", map[string]string{"X-Forwarded-For": "10.0.0.3, 10.0.0.2"},
+			"10.0.0.3 http://example.com"},
+		{"10.1.2.3:4000", map[string]string{"Forwarded": `for="[2001:db8::17]:4711";proto=https;host=ion.dev, for=10.0.0.2`},
+			"2001:db8::17 https://ion.dev"},
+		// an invalid address, the next header is checked.
+		{"127.0.0.1:4000", map[string]string{"X-Forwarded-For": "unknown", "X-Real-Ip": "198.51.100.8"},
+			"198.51.100.8 http://example.com"},
+		{"127.0.0.1:4000", nil, "127.0.0.1 http://example.com"},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest("GET", "http://example.com/", nil)
+		req.RemoteAddr = tt.remoteAddr
+		for k, v := range tt.headers {
+			req.Header.Set(k, v)
+		}
+
+		rec := httptest.NewRecorder()
+		app.ServeHTTP(rec, req)
+
+		if got := rec.Body.String(); got != tt.expected {
+			t.Fatalf("[%d] expected %q but got %q", i, tt.expected, got)
+		}
+	}
+}
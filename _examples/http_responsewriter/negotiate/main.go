@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+)
+
+// User is rendered as the content type which the client prefers.
+type User struct {
+	Username string `json:"username" xml:"username" yaml:"username"`
+	City     string `json:"city" xml:"city" yaml:"city"`
+}
+
+const readme = `# Negotiation
+
+The same endpoint serves **browsers** and **machines**.`
+
+func newApp() *ion.Application {
+	app := ion.New()
+	app.RegisterView(ion.HTML("./templates", ".html"))
+
+	// curl -H "Accept: application/json" http://localhost:8080/user
+	// curl -H "Accept: application/xml" http://localhost:8080/user
+	// curl -H "Accept: application/x-yaml" http://localhost:8080/user
+	// or open it with a browser to render the ./templates/user.html.
+	app.Get("/user", func(ctx context.Context) {
+		user := User{Username: "ion", City: "Athens"}
+		ctx.Negotiate(user, "application/json", "application/xml", "application/x-yaml",
+			"text/html; template=user.html")
+	})
+
+	// The markdown is rendered as html for the browsers
+	// and it's sent as it's to the clients that accept plain text only.
+	app.Get("/readme", func(ctx context.Context) {
+		ctx.Negotiate(readme, "text/markdown", "text/plain")
+	})
+
+	// Without offers the json, xml and yaml are negotiated,
+	// a client which accepts none of them receives the 406 Not Acceptable.
+	app.Get("/default", func(ctx context.Context) {
+		ctx.Negotiate(User{Username: "default"})
+	})
+
+	// The response is gzip compressed only if the gzip is enabled,
+	// then the "Accept-Encoding" decides if it's compressed.
+	app.Get("/gzip", func(ctx context.Context) {
+		ctx.Gzip(true)
+		ctx.Negotiate(User{Username: "gzip"})
+	})
+
+	return app
+}
+
+func main() {
+	app := newApp()
+	app.Run(ion.Addr(":8080"))
+}
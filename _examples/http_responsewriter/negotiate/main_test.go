@@ -0,0 +1,58 @@
+package main
+
+import (
+	"compress/gzip"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/httptest"
+)
+
+func TestNegotiate(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	e.GET("/user").Expect().Status(ion.StatusOK).
+		Header("Vary").Equal("Accept")
+	e.GET("/user").WithHeader("Accept", "application/json").Expect().Status(ion.StatusOK).
+		JSON().Object().Equal(User{Username: "ion", City: "Athens"})
+	e.GET("/user").WithHeader("Accept", "application/json;q=0.5, application/xml").Expect().Status(ion.StatusOK).
+		Body().Equal("<User><username>ion</username><city>Athens</city></User>")
+	e.GET("/user").WithHeader("Accept", "application/x-yaml").Expect().Status(ion.StatusOK).
+		Body().Equal("username: ion\ncity: Athens\n")
+	e.GET("/user").WithHeader("Accept", "text/html,application/xhtml+xml,*/*;q=0.8").Expect().Status(ion.StatusOK).
+		Body().Contains("<h1>ion</h1>")
+	e.GET("/user").WithHeader("Accept", "image/png").Expect().Status(ion.StatusNotAcceptable)
+	e.GET("/user").WithHeader("Accept-Charset", "iso-8859-1").Expect().Status(ion.StatusNotAcceptable)
+
+	e.GET("/readme").WithHeader("Accept", "text/html").Expect().Status(ion.StatusOK).
+		Body().Contains("<h1>Negotiation</h1>")
+	e.GET("/readme").WithHeader("Accept", "text/plain").Expect().Status(ion.StatusOK).
+		Body().Equal(readme)
+
+	e.GET("/default").WithHeader("Accept", "text/*").Expect().Status(ion.StatusNotAcceptable)
+	e.GET("/default").WithHeader("Accept", "application/*").Expect().Status(ion.StatusOK).
+		ContentType("application/json", "utf-8")
+
+	// the gzip is not enabled, the response is sent as it's.
+	r := e.GET("/default").WithHeader("Accept-Encoding", "gzip").Expect().Status(ion.StatusOK)
+	r.Header("Content-Encoding").Empty()
+	r.JSON().Object().Equal(User{Username: "default"})
+	e.GET("/default").WithHeader("Accept-Encoding", "gzip, identity;q=0").Expect().Status(ion.StatusNotAcceptable)
+
+	// the gzip is enabled, the "Accept-Encoding" decides.
+	r = e.GET("/gzip").WithHeader("Accept-Encoding", "gzip").Expect().Status(ion.StatusOK)
+	r.Header("Content-Encoding").Equal("gzip")
+	gr, err := gzip.NewReader(strings.NewReader(r.Body().Raw()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadAll(gr); string(body) != `{"username":"gzip","city":""}` {
+		t.Fatalf("unexpected decompressed body: %s", body)
+	}
+	r = e.GET("/gzip").WithHeader("Accept-Encoding", "gzip;q=0").Expect().Status(ion.StatusOK)
+	r.Header("Content-Encoding").Empty()
+	r.JSON().Object().Equal(User{Username: "gzip"})
+}
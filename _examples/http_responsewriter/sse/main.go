@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+)
+
+func newApp(broker *context.SSEBroker) *ion.Application {
+	app := ion.New()
+
+	// http://localhost:8080/events
+	app.Get("/events", broker.Handler())
+
+	// http://localhost:8080/countdown
+	app.Get("/countdown", func(ctx context.Context) {
+		w := ctx.SSE()
+		stop := w.Heartbeat(10 * time.Second)
+		defer stop()
+
+		for i := 10; i > 0; i-- {
+			select {
+			case <-ctx.Done(): // the client is disconnected.
+				return
+			case <-time.After(time.Second):
+				w.Send(context.SSEEvent{ID: fmt.Sprintf("%d", i), Data: []byte(fmt.Sprintf("%d", i))})
+			}
+		}
+	})
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.HTML(`<pre id="time"></pre>
+<script>
+	var source = new EventSource("/events");
+	source.addEventListener("time", function(e) {
+		document.getElementById("time").innerHTML = e.data;
+	});
+</script>`)
+	})
+
+	return app
+}
+
+func main() {
+	// the broker fans out the published events to all connected clients,
+	// a client that reconnects receives the events that has lost, based on its "Last-Event-ID" header.
+	broker := context.NewSSEBroker()
+	broker.Retry = 3 * time.Second
+
+	go func() {
+		for now := range time.Tick(time.Second) {
+			broker.Publish(context.SSEEvent{Event: "time", Data: []byte(now.Format(time.RFC1123))})
+		}
+	}()
+
+	app := newApp(broker)
+	app.Run(ion.Addr(":8080"))
+}
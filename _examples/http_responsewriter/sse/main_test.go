@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion/context"
+)
+
+// readEvent reads the lines of the next event, or comment, of the stream.
+func readEvent(t *testing.T, r *bufio.Reader) string {
+	var lines []string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("expected an event but got: %v", err)
+		}
+		if line == "\n" {
+			return strings.Join(lines, "")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestSSE(t *testing.T) {
+	broker := context.NewSSEBroker()
+	broker.Retry = 3 * time.Second
+	broker.HeartbeatInterval = 0
+	defer broker.Close()
+
+	app := newApp(broker)
+	app.Get("/invalid", func(ctx context.Context) {
+		w := ctx.SSE()
+		if err := w.Send(context.SSEEvent{ID: "1\ndata: forged", Data: []byte("a")}); err == nil {
+			t.Errorf("expected an error for the new line of the id")
+		}
+		if err := w.Send(context.SSEEvent{Event: "update\rid: 2", Data: []byte("a")}); err == nil {
+			t.Errorf("expected an error for the new line of the event")
+		}
+		w.Comment("done\ndata: forged")
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := stdhttptest.NewServer(app.Router)
+	defer srv.Close()
+	client := &http.Client{Timeout: 5 * time.Second}
+
+	resp, err := client.Get(srv.URL + "/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if expected, got := "text/event-stream", resp.Header.Get("Content-Type"); !strings.HasPrefix(got, expected) {
+		t.Fatalf("expected the content type %s but got %s", expected, got)
+	}
+	if expected, got := "no-cache", resp.Header.Get("Cache-Control"); expected != got {
+		t.Fatalf("expected the cache control %s but got %s", expected, got)
+	}
+
+	// the retry hint is flushed before any event, the client is subscribed already.
+	r := bufio.NewReader(resp.Body)
+	if expected, got := "retry: 3000\n", readEvent(t, r); expected != got {
+		t.Fatalf("expected %q but got %q", expected, got)
+	}
+
+	broker.Publish(context.SSEEvent{Event: "update", Data: []byte("line1\nline2\r\nline3\rline4")})
+	if expected, got := "id: 1\nevent: update\ndata: line1\ndata: line2\ndata: line3\ndata: line4\n", readEvent(t, r); expected != got {
+		t.Fatalf("expected %q but got %q", expected, got)
+	}
+
+	// the events with a new line in their id or event are dropped.
+	broker.Publish(context.SSEEvent{ID: "forged\nid", Data: []byte("dropped")})
+	broker.Publish(context.SSEEvent{Data: []byte("next")})
+	if expected, got := "id: 2\ndata: next\n", readEvent(t, r); expected != got {
+		t.Fatalf("expected %q but got %q", expected, got)
+	}
+
+	resp, err = client.Get(srv.URL + "/invalid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if expected, got := ": done\n: data: forged\n", readEvent(t, bufio.NewReader(resp.Body)); expected != got {
+		t.Fatalf("expected %q but got %q", expected, got)
+	}
+}
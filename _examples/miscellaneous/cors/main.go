@@ -0,0 +1,52 @@
+package main
+
+import (
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+
+	"github.com/get-ion/ion/middleware/cors"
+)
+
+func newApp() *ion.Application {
+	app := ion.New()
+
+	crs := cors.New(cors.Config{
+		// exact origins, wildcard subdomains,
+		// or AllowedOriginRegexps and AllowOriginFunc for anything more complex.
+		AllowedOrigins:   []string{"https://example.com", "https://*.example.com"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
+		AllowedHeaders:   []string{"Content-Type", "Authorization"},
+		ExposedHeaders:   []string{"X-Total-Count"},
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	})
+
+	// the preflight (OPTIONS) requests are answered by the router automatically,
+	// it runs the party's middleware of the requested path, the cors middleware here,
+	// not the routes' own handlers.
+	api := app.Party("/api", crs)
+	{
+		api.Get("/users", func(ctx context.Context) {
+			ctx.Header("X-Total-Count", "1")
+			ctx.JSON([]string{"gerasimos"})
+		})
+
+		api.Put("/users/{id:int}", func(ctx context.Context) {
+			ctx.Writef("updated user %s", ctx.Params().Get("id"))
+		})
+	}
+
+	return app
+}
+
+func main() {
+	app := newApp()
+
+	// $ curl -i -X OPTIONS http://localhost:8080/api/users/42 \
+	//   -H "Origin: https://app.example.com" \
+	//   -H "Access-Control-Request-Method: PUT" \
+	//   -H "Access-Control-Request-Headers: Content-Type"
+	app.Run(ion.Addr(":8080"))
+}
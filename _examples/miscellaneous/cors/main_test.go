@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/get-ion/ion/httptest"
+)
+
+func TestCORS(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	// preflight of an allowed wildcard subdomain origin.
+	r := e.OPTIONS("/api/users/42").
+		WithHeader("Origin", "https://app.example.com").
+		WithHeader("Access-Control-Request-Method", "PUT").
+		WithHeader("Access-Control-Request-Headers", "content-type").
+		Expect().Status(httptest.StatusNoContent)
+	r.Header("Access-Control-Allow-Origin").Equal("https://app.example.com")
+	r.Header("Access-Control-Allow-Methods").Equal("GET, POST, PUT, DELETE")
+	r.Header("Access-Control-Allow-Headers").Equal("Content-Type, Authorization")
+	r.Header("Access-Control-Allow-Credentials").Equal("true")
+	r.Header("Access-Control-Max-Age").Equal("600")
+	r.Body().Empty()
+
+	// preflight of a forbidden origin, method or header.
+	e.OPTIONS("/api/users").
+		WithHeader("Origin", "https://evil.com").
+		WithHeader("Access-Control-Request-Method", "GET").
+		Expect().Status(httptest.StatusForbidden)
+	e.OPTIONS("/api/users").
+		WithHeader("Origin", "https://example.com").
+		WithHeader("Access-Control-Request-Method", "PATCH").
+		Expect().Status(httptest.StatusForbidden)
+	e.OPTIONS("/api/users").
+		WithHeader("Origin", "https://example.com").
+		WithHeader("Access-Control-Request-Method", "GET").
+		WithHeader("Access-Control-Request-Headers", "X-Custom").
+		Expect().Status(httptest.StatusForbidden)
+
+	// preflight of a path without routes.
+	e.OPTIONS("/api/products").
+		WithHeader("Origin", "https://example.com").
+		WithHeader("Access-Control-Request-Method", "GET").
+		Expect().Status(httptest.StatusNotFound)
+
+	// the path parameters are validated on preflight too.
+	e.OPTIONS("/api/users/abc").
+		WithHeader("Origin", "https://example.com").
+		WithHeader("Access-Control-Request-Method", "PUT").
+		Expect().Status(httptest.StatusNotFound)
+
+	// actual request of an allowed origin.
+	r = e.GET("/api/users").WithHeader("Origin", "https://example.com").
+		Expect().Status(httptest.StatusOK)
+	r.Header("Access-Control-Allow-Origin").Equal("https://example.com")
+	r.Header("Access-Control-Expose-Headers").Equal("X-Total-Count")
+	r.Header("Vary").Equal("Origin")
+
+	// actual request of a forbidden origin, the client blocks the response.
+	e.GET("/api/users").WithHeader("Origin", "https://example.com.evil.com").
+		Expect().Status(httptest.StatusOK).
+		Header("Access-Control-Allow-Origin").Empty()
+
+	// same-origin request.
+	e.GET("/api/users").Expect().Status(httptest.StatusOK).
+		Header("Access-Control-Allow-Origin").Empty()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+
+	"github.com/get-ion/ion/middleware/csrf"
+)
+
+func newApp() *ion.Application {
+	app := ion.New()
+	app.RegisterView(ion.HTML("./templates", ".html"))
+
+	// register the "csrf_token" and "csrf_field" template functions
+	// to all the view engines, html: {{ csrf_field . }}, handlebars: {{{csrf_field this}}}.
+	csrf.RegisterViewFuncs(app)
+
+	// the double-submit cookie pattern, use the
+	// csrf.Config{Secret: "..."} for the golang.org/x/net/xsrftoken tokens instead.
+	protect := csrf.New()
+	app.Use(protect)
+
+	app.OnErrorCode(ion.StatusForbidden, func(ctx context.Context) {
+		ctx.WriteString("invalid csrf token")
+	})
+
+	app.Get("/signup", signupForm)
+	app.Post("/signup", signup)
+
+	// the ajax requests send the token by the "X-CSRF-Token" header.
+	app.Post("/api/messages", func(ctx context.Context) {
+		ctx.Writef("message sent")
+	})
+
+	// the webhooks are not protected, they're called by other services.
+	webhooks := app.Party("/webhooks", csrf.Exempt)
+	{
+		webhooks.Post("/payment", func(ctx context.Context) {
+			ctx.Writef("payment received")
+		})
+	}
+	// or per route.
+	app.Post("/ping", csrf.Exempt, func(ctx context.Context) {
+		ctx.Writef("pong")
+	})
+
+	return app
+}
+
+func signupForm(ctx context.Context) {
+	// the token can be retrieved by the csrf.Token(ctx) too.
+	ctx.View("signup.html")
+}
+
+func signup(ctx context.Context) {
+	ctx.Writef("welcome %s", ctx.FormValue("username"))
+}
+
+func main() {
+	app := newApp()
+	// http://localhost:8080/signup
+	app.Run(ion.Addr(":8080"))
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/httptest"
+	"github.com/get-ion/ion/middleware/csrf"
+)
+
+var tokenRegexp = regexp.MustCompile(`<input type="hidden" name="csrf_token" value="([^"]+)">`)
+
+func TestCSRF(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	// no token, the cookie is not set yet.
+	e.POST("/signup").WithFormField("username", "makis").
+		Expect().Status(httptest.StatusForbidden).Body().Equal("invalid csrf token")
+
+	body := e.GET("/signup").Expect().Status(httptest.StatusOK).Body().Raw()
+	m := tokenRegexp.FindStringSubmatch(body)
+	if len(m) != 2 {
+		t.Fatalf("expected the hidden input of the token but got: %s", body)
+	}
+	token := m[1]
+	if !regexp.MustCompile(`<meta name="csrf-token" content="` + regexp.QuoteMeta(token) + `">`).MatchString(body) {
+		t.Fatalf("expected the token of the csrf_token func but got: %s", body)
+	}
+
+	// the form field.
+	e.POST("/signup").WithFormField("csrf_token", token).WithFormField("username", "makis").
+		Expect().Status(httptest.StatusOK).Body().Equal("welcome makis")
+	// the header.
+	e.POST("/api/messages").WithHeader("X-CSRF-Token", token).
+		Expect().Status(httptest.StatusOK).Body().Equal("message sent")
+
+	// missing and invalid tokens.
+	e.POST("/api/messages").Expect().Status(httptest.StatusForbidden)
+	e.POST("/api/messages").WithHeader("X-CSRF-Token", "invalid").
+		Expect().Status(httptest.StatusForbidden)
+
+	// a token of a different client.
+	other := httptest.New(t, app)
+	other.POST("/api/messages").WithHeader("X-CSRF-Token", token).
+		Expect().Status(httptest.StatusForbidden)
+
+	// exempt party and route.
+	e.POST("/webhooks/payment").Expect().Status(httptest.StatusOK).Body().Equal("payment received")
+	e.POST("/ping").Expect().Status(httptest.StatusOK).Body().Equal("pong")
+}
+
+const testSecret = "my-secret"
+
+func newSecretApp(userID func(ctx context.Context) string) *ion.Application {
+	app := ion.New()
+	app.Use(csrf.New(csrf.Config{Secret: testSecret, UserID: userID}))
+
+	app.Get("/token", func(ctx context.Context) {
+		ctx.WriteString(csrf.Token(ctx))
+	})
+	app.Post("/messages", func(ctx context.Context) {
+		ctx.Writef("message sent")
+	})
+
+	return app
+}
+
+// xsrfToken returns a token of the golang.org/x/net/xsrftoken which is issued at the "issued" time.
+func xsrfToken(userID string, issued time.Time) string {
+	millis := issued.UnixNano() / 1e6
+	h := hmac.New(sha1.New, []byte(testSecret))
+	fmt.Fprintf(h, "%s::%d", userID, millis)
+	return strings.TrimRight(base64.URLEncoding.EncodeToString(h.Sum(nil)), "=") + ":" + strconv.FormatInt(millis, 10)
+}
+
+func TestCSRFSecret(t *testing.T) {
+	app := newSecretApp(func(ctx context.Context) string {
+		return ctx.GetHeader("X-User")
+	})
+	e := httptest.New(t, app)
+
+	token := e.GET("/token").WithHeader("X-User", "makis").Expect().Status(httptest.StatusOK).Body().Raw()
+	e.POST("/messages").WithHeader("X-User", "makis").WithHeader("X-CSRF-Token", token).
+		Expect().Status(httptest.StatusOK).Body().Equal("message sent")
+
+	// the token is issued for a different user.
+	e.POST("/messages").WithHeader("X-User", "gerasimos").WithHeader("X-CSRF-Token", token).
+		Expect().Status(httptest.StatusForbidden)
+
+	// a token of the same secret and user is valid for 24 hours.
+	e.POST("/messages").WithHeader("X-User", "makis").WithHeader("X-CSRF-Token", xsrfToken("makis", time.Now().Add(-time.Hour))).
+		Expect().Status(httptest.StatusOK)
+	e.POST("/messages").WithHeader("X-User", "makis").WithHeader("X-CSRF-Token", xsrfToken("makis", time.Now().Add(-25*time.Hour))).
+		Expect().Status(httptest.StatusForbidden)
+
+	// tampered tokens, the mac and the issue time.
+	sep := strings.LastIndex(token, ":")
+	mac, issued := token[:sep], token[sep+1:]
+	tamperedMAC := mac[:len(mac)-1] + "A"
+	if mac[len(mac)-1] == 'A' {
+		tamperedMAC = mac[:len(mac)-1] + "B"
+	}
+	millis, _ := strconv.ParseInt(issued, 10, 64)
+
+	for _, tampered := range []string{
+		tamperedMAC + ":" + issued,
+		mac + ":" + strconv.FormatInt(millis+1, 10),
+		mac,
+	} {
+		e.POST("/messages").WithHeader("X-User", "makis").WithHeader("X-CSRF-Token", tampered).
+			Expect().Status(httptest.StatusForbidden)
+	}
+}
+
+func TestCSRFSecretCookieUserID(t *testing.T) {
+	// the user id is the random id of the client's cookie.
+	app := newSecretApp(nil)
+	e := httptest.New(t, app)
+
+	token := e.GET("/token").Expect().Status(httptest.StatusOK).Body().Raw()
+	e.POST("/messages").WithHeader("X-CSRF-Token", token).
+		Expect().Status(httptest.StatusOK).Body().Equal("message sent")
+
+	other := httptest.New(t, app)
+	other.POST("/messages").WithHeader("X-CSRF-Token", token).
+		Expect().Status(httptest.StatusForbidden)
+}
+
+func TestRegisterViewFuncsAfterBuild(t *testing.T) {
+	app := newApp()
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("expected the RegisterViewFuncs to panic after the Build")
+		}
+	}()
+	csrf.RegisterViewFuncs(app)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/handlerconv"
+	"github.com/get-ion/ion/core/host"
+)
+
+func newLoadBalancer(targets ...*url.URL) *host.LoadBalancer {
+	lb := host.NewLoadBalancer(targets...)
+	// host.RoundRobin() is the default,
+	// host.Weighted() uses the weights of the lb.Add(target, weight) upstreams
+	// and host.ConsistentHash("X-Session") sends the same session to the same upstream.
+	lb.Strategy = host.LeastConnections()
+	// requests the /health of each upstream every 5 seconds,
+	// the upstreams that respond with an error don't receive requests until they're healthy again.
+	lb.HealthCheck = host.HealthCheck{Path: "/health", Interval: 5 * time.Second}
+	// 3 failed requests in 10 seconds eject an upstream for 10 seconds.
+	lb.MaxFails = 3
+	lb.FailTimeout = 10 * time.Second
+	// the GET, HEAD, OPTIONS, PUT and DELETE requests are sent to another upstream
+	// if their upstream fails to connect or responds with 502, 503 or 504.
+	lb.Retries = 1
+	// 5 consecutive failures open the circuit breaker of an upstream for 30 seconds.
+	lb.BreakerThreshold = 5
+
+	return lb
+}
+
+func newApp(lb *host.LoadBalancer) *ion.Application {
+	app := ion.New()
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("the /api/* requests are served by the upstreams")
+	})
+
+	app.Get("/upstreams", func(ctx context.Context) {
+		var upstreams []context.Map
+		for _, u := range lb.Upstreams() {
+			upstreams = append(upstreams, context.Map{
+				"url":     u.URL.String(),
+				"healthy": u.Healthy(),
+				"active":  u.ActiveRequests(),
+				"breaker": u.BreakerState().String(),
+			})
+		}
+		ctx.JSON(upstreams)
+	})
+
+	// the load balancer is an http.Handler.
+	app.Any("/api/{p:path}", handlerconv.FromStd(lb))
+
+	return app
+}
+
+func newUpstream(name string) *ion.Application {
+	app := ion.New()
+	app.Get("/health", func(ctx context.Context) {
+		ctx.WriteString("ok")
+	})
+	app.Get("/api/{p:path}", func(ctx context.Context) {
+		ctx.Writef("%s: %s", name, ctx.Path())
+	})
+	return app
+}
+
+func main() {
+	go newUpstream("upstream1").Run(ion.Addr(":9091"), ion.WithoutBanner)
+	go newUpstream("upstream2").Run(ion.Addr(":9092"), ion.WithoutBanner)
+
+	u1, _ := url.Parse("http://localhost:9091")
+	u2, _ := url.Parse("http://localhost:9092")
+	lb := newLoadBalancer(u1, u2)
+
+	// http://localhost:8080/api/users
+	// http://localhost:8080/upstreams
+	app := newApp(lb)
+	app.Run(ion.Addr(":8080"))
+
+	// or as a standalone proxy server:
+	// host.NewLoadBalancerProxy(":8080", lb).ListenAndServe()
+}
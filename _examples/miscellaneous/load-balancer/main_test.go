@@ -0,0 +1,45 @@
+package main
+
+import (
+	stdhttptest "net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/get-ion/ion/httptest"
+)
+
+func newUpstreamServer(name string) *stdhttptest.Server {
+	app := newUpstream(name)
+	app.Build()
+	return stdhttptest.NewServer(app.Router)
+}
+
+func TestLoadBalancer(t *testing.T) {
+	srv1 := newUpstreamServer("upstream1")
+	defer srv1.Close()
+	srv2 := newUpstreamServer("upstream2")
+	u1, _ := url.Parse(srv1.URL)
+	u2, _ := url.Parse(srv2.URL)
+
+	lb := newLoadBalancer(u1, u2)
+	defer lb.Close()
+	e := httptest.New(t, newApp(lb))
+
+	served := make(map[string]bool)
+	for i := 0; i < 4; i++ {
+		body := e.GET("/api/users").Expect().Status(httptest.StatusOK).Body().Raw()
+		served[body] = true
+	}
+
+	if !served["upstream1: /api/users"] || !served["upstream2: /api/users"] {
+		t.Fatalf("expected both upstreams to serve the requests but got %v", served)
+	}
+
+	// the requests to the closed upstream are retried to the other one.
+	srv2.Close()
+	for i := 0; i < 4; i++ {
+		e.GET("/api/users").Expect().Status(httptest.StatusOK).Body().Equal("upstream1: /api/users")
+	}
+
+	e.GET("/upstreams").Expect().Status(httptest.StatusOK).JSON().Array().Length().Equal(2)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+
+	"github.com/get-ion/ion/middleware/metrics"
+)
+
+func newApp(registry *metrics.Registry) *ion.Application {
+	app := ion.New()
+
+	// records the requests per route name, method and status code.
+	app.UseGlobal(metrics.New(metrics.Config{Registry: registry}))
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("hello")
+	}).Name = "home"
+
+	// all the users share the same "user" route label.
+	app.Get("/users/{id:int}", func(ctx context.Context) {
+		time.Sleep(10 * time.Millisecond)
+		ctx.JSON(context.Map{"id": ctx.Params().Get("id")})
+	}).Name = "user"
+
+	// the metrics can be mounted on any party, i.e an internal one.
+	internal := app.Party("/internal")
+	{
+		internal.Get("/metrics", metrics.Handler(registry))
+	}
+
+	return app
+}
+
+func main() {
+	app := newApp(metrics.DefaultRegistry)
+
+	// report the connections, the tls handshakes and the tasks of the hosts too.
+	app.Scheduler.Schedule(metrics.DefaultRegistry.HostTask())
+
+	// http://localhost:8080/users/42
+	// http://localhost:8080/internal/metrics
+	app.Run(ion.Addr(":8080"))
+}
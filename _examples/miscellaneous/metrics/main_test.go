@@ -0,0 +1,107 @@
+package main
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/host"
+	"github.com/get-ion/ion/httptest"
+	"github.com/get-ion/ion/middleware/metrics"
+	"github.com/get-ion/ion/middleware/recover"
+)
+
+func TestMetrics(t *testing.T) {
+	registry := metrics.NewRegistry("ion", []float64{0.005, 1})
+	e := httptest.New(t, newApp(registry))
+
+	e.GET("/").Expect().Status(httptest.StatusOK)
+	e.GET("/users/1").Expect().Status(httptest.StatusOK)
+	e.GET("/users/2").Expect().Status(httptest.StatusOK)
+
+	body := e.GET("/internal/metrics").Expect().Status(httptest.StatusOK).
+		ContentType("text/plain", "utf-8").Body()
+
+	body.Contains("# TYPE ion_http_requests_total counter\n")
+	body.Contains(`ion_http_requests_total{route="home",method="GET",code="200"} 1` + "\n")
+	body.Contains(`ion_http_requests_total{route="user",method="GET",code="200"} 2` + "\n")
+	body.NotContains(`/users/1`)
+
+	body.Contains("# TYPE ion_http_request_duration_seconds histogram\n")
+	// the users sleep for 10ms.
+	body.Contains(`ion_http_request_duration_seconds_bucket{route="user",method="GET",code="200",le="0.005"} 0` + "\n")
+	body.Contains(`ion_http_request_duration_seconds_bucket{route="user",method="GET",code="200",le="1"} 2` + "\n")
+	body.Contains(`ion_http_request_duration_seconds_bucket{route="user",method="GET",code="200",le="+Inf"} 2` + "\n")
+	body.Contains(`ion_http_request_duration_seconds_count{route="user",method="GET",code="200"} 2` + "\n")
+
+	// the metrics request itself is in flight.
+	body.Contains(`ion_http_requests_in_flight{route="GET/internal/metrics",method="GET"} 1` + "\n")
+	body.Contains(`ion_http_requests_in_flight{route="user",method="GET"} 0` + "\n")
+}
+
+func TestMetricsPanic(t *testing.T) {
+	registry := metrics.NewRegistry("ion", nil)
+	app := newApp(registry)
+	// the recover runs before the metrics, the global middleware are prepended.
+	app.UseGlobal(recover.New())
+	app.Get("/panic", func(ctx context.Context) {
+		panic("handler panic")
+	}).Name = "panic"
+
+	e := httptest.New(t, app)
+	e.GET("/panic").Expect().Status(httptest.StatusInternalServerError)
+
+	// the request is not in flight after the panic.
+	e.GET("/internal/metrics").Expect().Status(httptest.StatusOK).
+		Body().Contains(`ion_http_requests_in_flight{route="panic",method="GET"} 0` + "\n")
+}
+
+func TestMetricsHost(t *testing.T) {
+	registry := metrics.NewRegistry("ion", nil)
+	app := newApp(registry)
+	app.Build()
+
+	srv := stdhttptest.NewUnstartedServer(app.Router)
+	su := host.New(srv.Config)
+	registry.RegisterHost(su)
+	srv.StartTLS()
+	defer srv.Close()
+
+	// a failed handshake, the client doesn't trust the test certificate.
+	insecure := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{}}}
+	if _, err := insecure.Get(srv.URL); err == nil {
+		t.Fatalf("expected a tls error")
+	}
+
+	client := srv.Client()
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get(srv.URL + "/users/42")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// read the body, so the connection is reused.
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+	}
+
+	// the connection states are tracked asynchronously by the server.
+	time.Sleep(50 * time.Millisecond)
+
+	e := httptest.New(t, app)
+	body := e.GET("/internal/metrics").Expect().Status(httptest.StatusOK).Body()
+	body.Contains(`ion_host_connections_open{addr=""} 1` + "\n")
+	body.Contains(`ion_host_connections_accepted_total{addr=""} 2` + "\n")
+	body.Contains(`ion_host_connections{addr="",state="idle"} 1` + "\n")
+	body.Contains(`ion_host_tls_handshakes_total{addr=""} 1` + "\n")
+	body.Contains(`ion_host_tls_handshake_errors_total{addr=""} 1` + "\n")
+	body.Contains(`ion_host_tasks{addr="",state="running"} 0` + "\n")
+
+	if !strings.Contains(body.Raw(), "# TYPE ion_host_tasks gauge\n") {
+		t.Fatalf("expected the tasks gauge")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+
+	"github.com/get-ion/ion/middleware/basicauth"
+	"github.com/get-ion/ion/middleware/ratelimit"
+)
+
+func newApp() *ion.Application {
+	app := ion.New()
+
+	// the 429 Too Many Requests is fired through the error code handlers.
+	app.OnErrorCode(ion.StatusTooManyRequests, func(ctx context.Context) {
+		ctx.Writef("slow down, retry after %s seconds", ctx.ResponseWriter().Header().Get("Retry-After"))
+	})
+
+	// the public api allows bursts of 2 requests per client ip,
+	// refilled at 2 requests per minute.
+	public := app.Party("/public", ratelimit.New(ratelimit.Config{
+		Algorithm: ratelimit.TokenBucket,
+		Limit:     2,
+		Period:    time.Minute,
+	}))
+	{
+		public.Get("/", func(ctx context.Context) {
+			ctx.Writef("public")
+		})
+	}
+
+	// the internal api allows 3 requests in any minute per user.
+	auth := basicauth.DefaultConfig()
+	auth.Users = map[string]string{"alice": "alice_pass", "bob": "bob_pass"}
+
+	// the limit runs before the authentication in order to limit the guesses of the passwords too,
+	// the requests with wrong credentials are limited by the client's ip address.
+	internal := app.Party("/internal", ratelimit.New(ratelimit.Config{
+		Algorithm: ratelimit.SlidingWindow,
+		Limit:     3,
+		Period:    time.Minute,
+		KeyFunc:   ratelimit.ByUser(auth),
+	}), basicauth.New(auth))
+	{
+		internal.Get("/", func(ctx context.Context) {
+			username, _, _ := auth.User(ctx)
+			ctx.Writef("internal %s", username)
+		})
+	}
+
+	return app
+}
+
+func main() {
+	app := newApp()
+
+	// $ curl -i http://localhost:8080/public
+	// $ curl -i -u alice:alice_pass http://localhost:8080/internal
+	app.Run(ion.Addr(":8080"))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/get-ion/ion/httptest"
+)
+
+func TestRateLimit(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	r := e.GET("/public").Expect().Status(httptest.StatusOK)
+	r.Header("RateLimit-Limit").Equal("2")
+	r.Header("RateLimit-Remaining").Equal("1")
+	r.Header("RateLimit-Reset").Equal("30")
+
+	e.GET("/public").Expect().Status(httptest.StatusOK).
+		Header("RateLimit-Remaining").Equal("0")
+
+	r = e.GET("/public").Expect().Status(httptest.StatusTooManyRequests)
+	r.Header("RateLimit-Remaining").Equal("0")
+	r.Header("Retry-After").Equal("30")
+	r.Body().Equal("slow down, retry after 30 seconds")
+}
+
+func TestRateLimitByUser(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	for i := 0; i < 3; i++ {
+		e.GET("/internal").WithBasicAuth("alice", "alice_pass").Expect().
+			Status(httptest.StatusOK).Body().Equal("internal alice")
+	}
+
+	e.GET("/internal").WithBasicAuth("alice", "alice_pass").Expect().
+		Status(httptest.StatusTooManyRequests).Header("Retry-After").NotEmpty()
+
+	// each user has its own limit.
+	e.GET("/internal").WithBasicAuth("bob", "bob_pass").Expect().
+		Status(httptest.StatusOK).Header("RateLimit-Remaining").Equal("2")
+
+	// the wrong credentials are limited by the ip address, not by the user.
+	for i := 0; i < 3; i++ {
+		e.GET("/internal").WithBasicAuth("bob", "wrong").Expect().Status(httptest.StatusUnauthorized)
+	}
+	e.GET("/internal").WithBasicAuth("bob", "wrong").Expect().Status(httptest.StatusTooManyRequests)
+	e.GET("/internal").WithBasicAuth("bob", "bob_pass").Expect().
+		Status(httptest.StatusOK).Header("RateLimit-Remaining").Equal("1")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/handlerconv"
+	"github.com/get-ion/ion/core/host"
+
+	"github.com/get-ion/ion/middleware/accesslog"
+	"github.com/get-ion/ion/middleware/trace"
+)
+
+func newApp(exporter trace.Exporter, backend *url.URL) *ion.Application {
+	app := ion.New()
+
+	// accepts or generates the X-Request-Id and continues or starts
+	// the W3C trace of the "traceparent" and "tracestate" headers.
+	app.UseGlobal(trace.New(trace.Config{
+		// records the spans, nil to propagate the trace context only.
+		Exporter: exporter,
+	}))
+	// the request id and the trace id are logged automatically,
+	// by the logger, the accesslog and the recover middlewares.
+	app.UseGlobal(accesslog.New(accesslog.Config{
+		Format: "{time} {request_id} {trace_id} {method} {uri} {status} {latency}",
+	}))
+
+	// trace.Segment records a span of the rest of the handlers.
+	app.Get("/users/{id:int}", trace.Segment("authenticate"), authenticate, trace.Segment("load user"), loadUser).
+		Name = "user"
+
+	// the forwarded requests carry the request id and the trace context of the current span.
+	app.Any("/backend/{p:path}", handlerconv.FromStd(host.ProxyHandler(backend)))
+
+	return app
+}
+
+func authenticate(ctx context.Context) {
+	time.Sleep(time.Millisecond)
+	ctx.Next()
+}
+
+func loadUser(ctx context.Context) {
+	// or a span of a part of a handler.
+	span := trace.StartSpan(ctx, "query")
+	span.SetAttribute("db.statement", "SELECT * FROM users WHERE id = ?")
+	time.Sleep(time.Millisecond)
+	span.End()
+
+	// the ids of the current span, the "load user" segment.
+	traceID, spanID := ctx.TraceIDs()
+	ctx.JSON(context.Map{
+		"id":         ctx.Params().Get("id"),
+		"request_id": ctx.RequestID(),
+		"trace_id":   traceID,
+		"span_id":    spanID,
+	})
+}
+
+func main() {
+	// the spans are written as OTLP/JSON lines,
+	// they can be imported by the OpenTelemetry collector's file receiver.
+	f, err := os.OpenFile("./traces.json", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		panic(err)
+	}
+	defer f.Close()
+
+	backend, _ := url.Parse("http://localhost:9090")
+	app := newApp(trace.NewOTLPExporter(f, "users-service"), backend)
+
+	// http://localhost:8080/users/42
+	// curl -H "traceparent: 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" http://localhost:8080/users/42
+	// see the ./traces.json file.
+	app.Run(ion.Addr(":8080"))
+}
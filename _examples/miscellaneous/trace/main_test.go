@@ -0,0 +1,156 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	stdhttptest "net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/get-ion/ion/httptest"
+	"github.com/get-ion/ion/middleware/trace"
+)
+
+type otlpSpan struct {
+	TraceID      string `json:"traceId"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	TraceState   string `json:"traceState"`
+	Name         string `json:"name"`
+	Kind         int    `json:"kind"`
+}
+
+// readSpans returns the spans of the exported lines by their names.
+func readSpans(t *testing.T, buf *bytes.Buffer) map[string]otlpSpan {
+	spans := make(map[string]otlpSpan)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var req struct {
+			ResourceSpans []struct {
+				ScopeSpans []struct {
+					Spans []otlpSpan `json:"spans"`
+				} `json:"scopeSpans"`
+			} `json:"resourceSpans"`
+		}
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			t.Fatalf("invalid otlp line %q: %v", line, err)
+		}
+		s := req.ResourceSpans[0].ScopeSpans[0].Spans[0]
+		spans[s.Name] = s
+	}
+	buf.Reset()
+	return spans
+}
+
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	backend, _ := url.Parse("http://localhost")
+	e := httptest.New(t, newApp(trace.NewOTLPExporter(&buf, "test"), backend))
+
+	// a new trace, the request id is the trace id.
+	r := e.GET("/users/42").Expect().Status(httptest.StatusOK)
+	requestID := r.Header("X-Request-Id").Raw()
+	body := r.JSON().Object()
+	body.Value("request_id").String().Equal(requestID)
+	body.Value("trace_id").String().Equal(requestID)
+
+	spans := readSpans(t, &buf)
+	server, authenticate, loadUser, query := spans["user"], spans["authenticate"], spans["load user"], spans["query"]
+	// the context has the ids of the current span, for the logs.
+	body.Value("span_id").String().Equal(loadUser.SpanID)
+	if server.TraceID != requestID || server.ParentSpanID != "" || server.Kind != int(trace.SpanKindServer) {
+		t.Fatalf("unexpected server span: %#v", server)
+	}
+	if authenticate.TraceID != requestID || authenticate.ParentSpanID != server.SpanID {
+		t.Fatalf("expected the authenticate span to be a child of the server span: %#v", authenticate)
+	}
+	if loadUser.ParentSpanID != authenticate.SpanID || query.ParentSpanID != loadUser.SpanID {
+		t.Fatalf("expected nested segments: %#v %#v", loadUser, query)
+	}
+
+	// a continued trace and an accepted request id.
+	traceparent := "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	r = e.GET("/users/42").WithHeader("traceparent", traceparent).WithHeader("tracestate", "vendor=value").
+		WithHeader("X-Request-Id", "my-request").Expect().Status(httptest.StatusOK)
+	r.Header("X-Request-Id").Equal("my-request")
+
+	server = readSpans(t, &buf)["user"]
+	if server.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || server.ParentSpanID != "00f067aa0ba902b7" ||
+		server.TraceState != "vendor=value" {
+		t.Fatalf("expected the server span to continue the trace: %#v", server)
+	}
+
+	// an invalid traceparent starts a new trace.
+	e.GET("/users/42").WithHeader("traceparent", "00-00000000000000000000000000000000-00f067aa0ba902b7-01").
+		Expect().Status(httptest.StatusOK)
+	if server = readSpans(t, &buf)["user"]; server.TraceID == "00000000000000000000000000000000" || server.ParentSpanID != "" {
+		t.Fatalf("expected a new trace: %#v", server)
+	}
+
+	// a not sampled trace is not recorded.
+	e.GET("/users/42").WithHeader("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00").
+		Expect().Status(httptest.StatusOK)
+	if buf.Len() != 0 {
+		t.Fatalf("expected no spans but got: %s", buf.String())
+	}
+}
+
+func TestTraceProxy(t *testing.T) {
+	var received http.Header
+	srv := stdhttptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r.Header
+		w.Write([]byte(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	var buf bytes.Buffer
+	backend, _ := url.Parse(srv.URL)
+	e := httptest.New(t, newApp(trace.NewOTLPExporter(&buf, "test"), backend))
+
+	e.GET("/backend/ping").WithHeader("X-Request-Id", "my-request").
+		WithHeader("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01").
+		Expect().Status(httptest.StatusOK).Body().Equal("/backend/ping")
+
+	var server otlpSpan
+	for _, s := range readSpans(t, &buf) {
+		if s.Kind == int(trace.SpanKindServer) {
+			server = s
+		}
+	}
+	expected := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + server.SpanID + "-01"
+	if got := received.Get("traceparent"); got != expected {
+		t.Fatalf("expected the forwarded traceparent %q but got %q", expected, got)
+	}
+	if got := received.Get("X-Request-Id"); got != "my-request" {
+		t.Fatalf("expected the forwarded request id but got %q", got)
+	}
+}
+
+func TestParseTraceparent(t *testing.T) {
+	valid := []string{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		// a future version with extra fields.
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	}
+	for _, s := range valid {
+		sc, err := trace.ParseTraceparent(s)
+		if err != nil || sc.TraceID.String() != "4bf92f3577b34da6a3ce929d0e0e4736" || !sc.IsSampled() {
+			t.Fatalf("expected %q to be valid: %v", s, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01extra",
+	}
+	for _, s := range invalid {
+		if _, err := trace.ParseTraceparent(s); err == nil {
+			t.Fatalf("expected %q to be invalid", s)
+		}
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/mvc"
+)
+
+// User is the model of this example.
+type User struct {
+	ID       int    `json:"id"`
+	Username string `json:"username"`
+}
+
+// UserRepository is a shared dependency, it's bind to the controllers.
+type UserRepository interface {
+	Get(id int) (User, bool)
+	Save(user User) User
+	Delete(id int) bool
+}
+
+type memoryUserRepository struct {
+	mu     sync.RWMutex
+	lastID int
+	users  map[int]User
+}
+
+func (r *memoryUserRepository) Get(id int) (User, bool) {
+	r.mu.RLock()
+	user, ok := r.users[id]
+	r.mu.RUnlock()
+	return user, ok
+}
+
+func (r *memoryUserRepository) Save(user User) User {
+	r.mu.Lock()
+	if user.ID == 0 {
+		r.lastID++
+		user.ID = r.lastID
+	}
+	r.users[user.ID] = user
+	r.mu.Unlock()
+	return user
+}
+
+func (r *memoryUserRepository) Delete(id int) bool {
+	r.mu.Lock()
+	_, ok := r.users[id]
+	delete(r.users, id)
+	r.mu.Unlock()
+	return ok
+}
+
+// UserController serves the /users routes,
+// a new UserController is created per request.
+type UserController struct {
+	mvc.Controller
+
+	// Repo is set by the bind values of the app.Controller.
+	Repo UserRepository
+}
+
+// BeginRequest runs before each method of the controller.
+func (c *UserController) BeginRequest(ctx context.Context) {
+	c.Controller.BeginRequest(ctx)
+
+	if ctx.Method() != ion.MethodGet && ctx.GetHeader("X-Token") != "secret" {
+		ctx.StatusCode(ion.StatusUnauthorized)
+		ctx.StopExecution()
+	}
+}
+
+// Get serves
+// GET /users
+func (c *UserController) Get() {
+	c.Ctx.Writef("list of users")
+}
+
+// GetBy serves
+// GET /users/{paramA:int}
+func (c *UserController) GetBy(id int) {
+	user, ok := c.Repo.Get(id)
+	if !ok {
+		c.Ctx.NotFound()
+		return
+	}
+
+	c.Ctx.JSON(user)
+}
+
+// Post serves
+// POST /users
+func (c *UserController) Post() {
+	var user User
+	if err := c.Ctx.ReadJSON(&user); err != nil {
+		c.Ctx.StatusCode(ion.StatusBadRequest)
+		return
+	}
+
+	c.Ctx.StatusCode(ion.StatusCreated)
+	c.Ctx.JSON(c.Repo.Save(user))
+}
+
+// PutBy serves
+// PUT /users/{paramA:int}
+func (c *UserController) PutBy(id int) {
+	if _, ok := c.Repo.Get(id); !ok {
+		c.Ctx.NotFound()
+		return
+	}
+
+	var user User
+	if err := c.Ctx.ReadJSON(&user); err != nil {
+		c.Ctx.StatusCode(ion.StatusBadRequest)
+		return
+	}
+
+	user.ID = id
+	c.Ctx.JSON(c.Repo.Save(user))
+}
+
+// DeleteBy serves
+// DELETE /users/{paramA:int}
+func (c *UserController) DeleteBy(id int) {
+	if !c.Repo.Delete(id) {
+		c.Ctx.NotFound()
+	}
+}
+
+// GetProfileBy serves
+// GET /users/profile/{paramA:string}
+func (c *UserController) GetProfileBy(username string) {
+	c.Ctx.Writef("profile of %s", username)
+}
+
+// FilesController doesn't embed the mvc.Controller,
+// its exported fields of type context.Context are set to the request's context.
+type FilesController struct {
+	Ctx context.Context
+}
+
+// GetBy serves
+// GET /files/{paramA:path}
+func (c *FilesController) GetBy(file mvc.Path) {
+	c.Ctx.Writef("file: %s", file)
+}
+
+func newApp() *ion.Application {
+	app := ion.New()
+
+	repo := &memoryUserRepository{users: make(map[int]User)}
+	app.Controller("/users", new(UserController), repo)
+	app.Controller("/files", new(FilesController))
+
+	return app
+}
+
+func main() {
+	app := newApp()
+	app.Run(ion.Addr(":8080"))
+}
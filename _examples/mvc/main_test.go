@@ -0,0 +1,32 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/get-ion/ion/httptest"
+)
+
+func TestControllers(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	e.GET("/users").Expect().Status(httptest.StatusOK).Body().Equal("list of users")
+	e.GET("/users/1").Expect().Status(httptest.StatusNotFound)
+
+	e.POST("/users").WithJSON(map[string]interface{}{"username": "ion"}).
+		Expect().Status(httptest.StatusUnauthorized)
+
+	e.POST("/users").WithHeader("X-Token", "secret").WithJSON(map[string]interface{}{"username": "ion"}).
+		Expect().Status(httptest.StatusCreated).JSON().Object().Equal(map[string]interface{}{"id": 1, "username": "ion"})
+
+	e.GET("/users/1").Expect().Status(httptest.StatusOK).JSON().Object().ValueEqual("username", "ion")
+
+	e.PUT("/users/1").WithHeader("X-Token", "secret").WithJSON(map[string]interface{}{"username": "gopher"}).
+		Expect().Status(httptest.StatusOK).JSON().Object().ValueEqual("username", "gopher")
+
+	e.DELETE("/users/1").WithHeader("X-Token", "secret").Expect().Status(httptest.StatusOK)
+	e.DELETE("/users/1").WithHeader("X-Token", "secret").Expect().Status(httptest.StatusNotFound)
+
+	e.GET("/users/profile/gopher").Expect().Status(httptest.StatusOK).Body().Equal("profile of gopher")
+	e.GET("/files/css/style.css").Expect().Status(httptest.StatusOK).Body().Equal("file: css/style.css")
+}
@@ -5,7 +5,7 @@ import (
 
 	"github.com/get-ion/ion"
 	"github.com/get-ion/ion/context"
-	"github.com/get-ion/sessions"
+	"github.com/get-ion/ion/sessions"
 )
 
 // Owner is our application structure, it contains the methods or fields we need,
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+)
+
+func newApp() *ion.Application {
+	app := ion.New()
+
+	app.Get("/users/{id:int}", func(ctx context.Context) {
+		ctx.Writef("user %s", ctx.Params().Get("id"))
+	})
+
+	app.Delete("/users/{id:int}", func(ctx context.Context) {
+		ctx.StatusCode(ion.StatusNoContent)
+	})
+
+	app.Post("/users", func(ctx context.Context) {
+		ctx.StatusCode(ion.StatusCreated)
+	})
+
+	return app
+}
+
+func main() {
+	app := newApp()
+
+	// The router answers the requests of an existing path on a not registered method
+	// with 405 Method Not Allowed and the "Allow: DELETE, GET, HEAD, OPTIONS" header,
+	// instead of 404 Not Found:
+	// $ curl -i -X PUT http://localhost:8080/users/42
+	//
+	// The OPTIONS requests are answered automatically with the "Allow" header:
+	// $ curl -i -X OPTIONS http://localhost:8080/users/42
+	//
+	// and the HEAD requests are served by the GET routes, without the body:
+	// $ curl -I http://localhost:8080/users/42
+	app.Run(ion.Addr(":8080"), ion.WithFireMethodNotAllowed)
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/httptest"
+)
+
+func TestMethodNotAllowed(t *testing.T) {
+	app := newApp()
+	app.Configure(ion.WithFireMethodNotAllowed)
+	e := httptest.New(t, app)
+
+	e.PUT("/users/42").Expect().Status(httptest.StatusMethodNotAllowed).
+		Header("Allow").Equal("DELETE, GET, HEAD, OPTIONS")
+	e.GET("/users").Expect().Status(httptest.StatusMethodNotAllowed).
+		Header("Allow").Equal("OPTIONS, POST")
+	// not existing paths.
+	e.PUT("/products").Expect().Status(httptest.StatusNotFound).
+		Header("Allow").Empty()
+
+	// automatic OPTIONS.
+	e.OPTIONS("/users/42").Expect().Status(httptest.StatusNoContent).
+		Header("Allow").Equal("DELETE, GET, HEAD, OPTIONS")
+	e.OPTIONS("/products").Expect().Status(httptest.StatusNotFound)
+
+	// HEAD is served by the GET route, the body is dropped by the net/http server,
+	// the test's client calls the app directly.
+	e.HEAD("/users/42").Expect().Status(httptest.StatusOK)
+	e.GET("/users/42").Expect().Status(httptest.StatusOK).Body().Equal("user 42")
+}
+
+func TestMethodNotAllowedDisabled(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app)
+
+	e.PUT("/users/42").Expect().Status(httptest.StatusNotFound)
+	e.OPTIONS("/users/42").Expect().Status(httptest.StatusNoContent).
+		Header("Allow").Equal("DELETE, GET, HEAD, OPTIONS")
+	e.HEAD("/users/42").Expect().Status(httptest.StatusOK)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/openapi"
+)
+
+// User is described as a schema of the document's components.
+type User struct {
+	ID        int       `json:"id"`
+	Username  string    `json:"username" description:"The unique name of the user."`
+	Email     string    `json:"email,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// APIError is the body of the error responses.
+type APIError struct {
+	Message string `json:"message"`
+}
+
+func main() {
+	app := ion.New()
+
+	spec := openapi.New(openapi.Config{
+		Title:       "Users API",
+		Description: "An example of an OpenAPI document generated from the registered routes.",
+		Version:     "1.0.0",
+		Servers:     []string{"http://localhost:8080"},
+	})
+
+	users := app.Party("/users")
+	{
+		spec.Describe(users.Get("/", listUsers)).
+			Summary("List the users").
+			Tags("users").
+			Response(200, []User{})
+
+		// the "id" path parameter is described as an integer with a minimum of 1.
+		spec.Describe(users.Get("/{id:int min(1)}", getUser)).
+			Summary("Get a user by id").
+			Tags("users").
+			Response(200, User{})
+
+		spec.Describe(users.Post("/", createUser)).
+			Summary("Create a user").
+			Tags("users").
+			Request(User{}).
+			Response(201, User{}).
+			Response(400, APIError{})
+	}
+
+	// the routes which serve the document are excluded from it.
+	//
+	// http://localhost:8080/swagger.json
+	// http://localhost:8080/swagger.yaml
+	spec.Exclude(
+		app.Get("/swagger.json", spec.JSONHandler(app)),
+		app.Get("/swagger.yaml", spec.YAMLHandler(app)),
+	)
+
+	app.Run(ion.Addr(":8080"))
+}
+
+func listUsers(ctx context.Context) {
+	ctx.JSON([]User{{ID: 1, Username: "ion", CreatedAt: time.Now()}})
+}
+
+func getUser(ctx context.Context) {
+	id, _ := ctx.Params().GetInt("id")
+	ctx.JSON(User{ID: id, Username: "ion", CreatedAt: time.Now()})
+}
+
+func createUser(ctx context.Context) {
+	var user User
+	if err := ctx.ReadJSON(&user); err != nil {
+		ctx.StatusCode(400)
+		ctx.JSON(APIError{Message: err.Error()})
+		return
+	}
+
+	ctx.StatusCode(201)
+	ctx.JSON(user)
+}
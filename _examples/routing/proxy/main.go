@@ -0,0 +1,71 @@
+package main
+
+import (
+	"net/url"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+)
+
+func newApp(usersService *url.URL) *ion.Application {
+	app := ion.New()
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("the /api/* requests are served by the users service")
+	})
+
+	// forwards the /api and the /api/* requests to the "usersService" target,
+	// without the /api prefix, i.e the /api/users/42 to the http://localhost:9090/users/42.
+	// The redirects and the cookies of the users service are rewritten to the /api prefix,
+	// the websockets are tunnelled too.
+	//
+	// The handlers before the target are executed before the proxy, i.e authentication.
+	app.Proxy("/api", usersService, func(ctx context.Context) {
+		ctx.Request().Header.Set("X-Gateway", "ion")
+		ctx.Next()
+	})
+
+	return app
+}
+
+func newUsersService() *ion.Application {
+	app := ion.New()
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("users service")
+	})
+
+	app.Get("/users", func(ctx context.Context) {
+		ctx.JSON(context.Map{
+			"path":    ctx.Path(),
+			"gateway": ctx.GetHeader("X-Gateway"),
+		})
+	})
+
+	app.Get("/users/{id:int}", func(ctx context.Context) {
+		ctx.JSON(context.Map{
+			"id":      ctx.Params().Get("id"),
+			"gateway": ctx.GetHeader("X-Gateway"),
+			"prefix":  ctx.GetHeader("X-Forwarded-Prefix"),
+		})
+	})
+
+	app.Get("/users/me", func(ctx context.Context) {
+		ctx.Redirect("/users/42")
+	})
+
+	return app
+}
+
+func main() {
+	// http://localhost:9090/users/42
+	go newUsersService().Run(ion.Addr(":9090"), ion.WithoutBanner)
+
+	target, _ := url.Parse("http://localhost:9090")
+	app := newApp(target)
+
+	// http://localhost:8080/api/users
+	// http://localhost:8080/api/users/42
+	// http://localhost:8080/api/users/me
+	app.Run(ion.Addr(":8080"))
+}
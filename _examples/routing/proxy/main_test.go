@@ -0,0 +1,38 @@
+package main
+
+import (
+	stdhttptest "net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/get-ion/ion/httptest"
+)
+
+func TestProxy(t *testing.T) {
+	usersService := newUsersService()
+	usersService.Build()
+	srv := stdhttptest.NewServer(usersService.Router)
+	defer srv.Close()
+
+	target, _ := url.Parse(srv.URL)
+	e := httptest.New(t, newApp(target))
+
+	// the bare path of the proxy.
+	e.GET("/api").Expect().Status(httptest.StatusOK).Body().Equal("users service")
+
+	// the /api prefix is stripped, the /api/users reaches the target as /users.
+	users := e.GET("/api/users").Expect().Status(httptest.StatusOK).JSON().Object()
+	users.Value("path").Equal("/users")
+	users.Value("gateway").Equal("ion")
+
+	user := e.GET("/api/users/42").Expect().Status(httptest.StatusOK).JSON().Object()
+	user.Value("id").Equal("42")
+	user.Value("gateway").Equal("ion")
+	user.Value("prefix").Equal("/api")
+
+	// the redirect of the users service to the /users/42 is rewritten to the /api/users/42.
+	e.GET("/api/users/me").Expect().Status(httptest.StatusOK).
+		JSON().Object().Value("id").Equal("42")
+
+	e.GET("/api/posts").Expect().Status(httptest.StatusNotFound)
+}
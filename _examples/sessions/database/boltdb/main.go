@@ -0,0 +1,77 @@
+package main
+
+import (
+	"time"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/sessions"
+	"github.com/get-ion/ion/sessions/sessiondb/boltdb"
+)
+
+func main() {
+	// the sessions are stored inside the "./sessions.db" BoltDB file,
+	// they are loaded back even after a server restart.
+	db, err := boltdb.New("./sessions.db", 0600, "users")
+	if err != nil {
+		panic(err)
+	}
+
+	app := ion.New()
+
+	sess := sessions.New(sessions.Config{
+		Cookie:  "sessionscookieid",
+		Expires: 45 * time.Minute, // <=0 means unlimited life
+		// the database errors are reported to the app's logger.
+		Logger: app.Logger(),
+	})
+
+	//
+	// IMPORTANT:
+	//
+	sess.UseDatabase(db)
+
+	// close and unlock the database when control+C/cmd+C pressed
+	app.Scheduler.OnInterrupt(func() { db.Close() })
+
+	app.Get("/", func(ctx context.Context) {
+		ctx.Writef("You should navigate to the /set, /get, /delete, /clear,/destroy instead")
+	})
+	app.Get("/set", func(ctx context.Context) {
+		s := sess.Start(ctx)
+		//set session values
+		s.Set("name", "ion")
+
+		//test if setted here
+		ctx.Writef("All ok session setted to: %s", s.GetString("name"))
+	})
+
+	app.Get("/get", func(ctx context.Context) {
+		// get a specific key, as string, if no found returns just an empty string
+		name := sess.Start(ctx).GetString("name")
+
+		ctx.Writef("The name on the /set was: %s", name)
+	})
+
+	app.Get("/delete", func(ctx context.Context) {
+		// delete a specific key
+		sess.Start(ctx).Delete("name")
+	})
+
+	app.Get("/clear", func(ctx context.Context) {
+		// removes all entries
+		sess.Start(ctx).Clear()
+	})
+
+	app.Get("/update", func(ctx context.Context) {
+		// updates expire date with a new date
+		sess.ShiftExpiration(ctx)
+	})
+
+	app.Get("/destroy", func(ctx context.Context) {
+		//destroy, removes the entire session data and cookie
+		sess.Destroy(ctx)
+	})
+
+	app.Run(ion.Addr(":8080"))
+}
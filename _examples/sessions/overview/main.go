@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/sessions"
+)
+
+var (
+	cookieNameForSessionID = "mycookiesessionnameid"
+	sess                   = sessions.New(sessions.Config{Cookie: cookieNameForSessionID})
+)
+
+func secret(ctx context.Context) {
+	// Check if user is authenticated
+	if auth, _ := sess.Start(ctx).GetBoolean("authenticated"); !auth {
+		ctx.StatusCode(ion.StatusForbidden)
+		return
+	}
+
+	// Print secret message
+	ctx.WriteString("The cake is a lie!")
+}
+
+func login(ctx context.Context) {
+	// Authentication goes here
+	// ...
+
+	// the privilege level of the client changes,
+	// give a new id to its session.
+	session := sess.Rotate(ctx)
+
+	// Set user as authenticated
+	session.Set("authenticated", true)
+}
+
+func logout(ctx context.Context) {
+	session := sess.Start(ctx)
+
+	// Revoke users authentication
+	session.Set("authenticated", false)
+}
+
+func newApp() *ion.Application {
+	app := ion.New()
+
+	app.Get("/secret", secret)
+	app.Get("/login", login)
+	app.Get("/logout", logout)
+
+	app.Get("/destroy", func(ctx context.Context) {
+		// destroy, removes the entire session data and cookie
+		sess.Destroy(ctx)
+	})
+
+	return app
+}
+
+func main() {
+	app := newApp()
+
+	app.Run(ion.Addr(":8080"))
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/httptest"
+	"github.com/get-ion/ion/sessions"
+)
+
+func TestSessions(t *testing.T) {
+	app := newApp()
+	e := httptest.New(t, app, httptest.URL("http://example.com"))
+
+	e.GET("/secret").Expect().Status(httptest.StatusForbidden)
+	e.GET("/login").Expect().Status(httptest.StatusOK)
+	e.GET("/secret").Expect().Status(httptest.StatusOK).Body().Equal("The cake is a lie!")
+	e.GET("/logout").Expect().Status(httptest.StatusOK)
+	e.GET("/secret").Expect().Status(httptest.StatusForbidden)
+
+	e.GET("/login").Expect().Status(httptest.StatusOK)
+	e.GET("/destroy").Expect().Status(httptest.StatusOK)
+	e.GET("/secret").Expect().Status(httptest.StatusForbidden)
+}
+
+func TestSessionsWithoutValues(t *testing.T) {
+	manager := sessions.New(sessions.Config{Cookie: "visits"})
+
+	app := ion.New()
+	app.Get("/", func(ctx context.Context) {
+		manager.Start(ctx)
+	})
+	app.Get("/visit", func(ctx context.Context) {
+		visits, err := manager.Start(ctx).GetInt("visits")
+		if err != nil {
+			visits = 0
+		}
+		// the same new session of the request.
+		manager.Start(ctx).Set("visits", visits+1)
+		ctx.Writef("%d", visits+1)
+	})
+
+	// the clients without a cookie, i.e the bots, which never set a value are not kept in memory.
+	for i := 0; i < 10; i++ {
+		httptest.New(t, app).GET("/").Expect().Status(httptest.StatusOK).Cookie("visits").Value().NotEmpty()
+	}
+	if n := manager.Len(); n != 0 {
+		t.Fatalf("expected no sessions in memory but got %d", n)
+	}
+
+	e := httptest.New(t, app)
+	e.GET("/visit").Expect().Status(httptest.StatusOK).Body().Equal("1")
+	e.GET("/visit").Expect().Status(httptest.StatusOK).Body().Equal("2")
+	if n := manager.Len(); n != 1 {
+		t.Fatalf("expected one session in memory but got %d", n)
+	}
+}
@@ -5,11 +5,10 @@ import (
 
 	"github.com/get-ion/ion"
 	"github.com/get-ion/ion/context"
-
-	"github.com/get-ion/websocket"
+	"github.com/get-ion/ion/websocket"
 )
 
-func main() {
+func newApp(ws *websocket.Server) *ion.Application {
 	// init the web application instance
 	// app := ion.New()
 	app := ion.Default()
@@ -17,8 +16,9 @@ func main() {
 	// load templaes
 	app.RegisterView(ion.HTML("./templates", ".html").Reload(true))
 	// setup the websocket server
-	ws := websocket.New(websocket.Config{})
 	ws.OnConnection(HandleWebsocketConnection)
+	// close the websocket connections when the server is shutdown.
+	app.Scheduler.Schedule(ws)
 
 	app.Get("/my_endpoint", ws.Handler())
 	app.Any("/ion-ws.js", websocket.ClientHandler())
@@ -42,6 +42,13 @@ func main() {
 	// Each page has its own online-visitors counter.
 	app.Get("/", h)
 	app.Get("/other", h2)
+
+	return app
+}
+
+func main() {
+	ws := websocket.New(websocket.Config{})
+	app := newApp(ws)
 	app.Run(ion.Addr(":8080"))
 }
 
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	stdhttptest "net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion/websocket"
+
+	xwebsocket "golang.org/x/net/websocket"
+)
+
+type testMessage struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data"`
+}
+
+func dial(srvURL string, origin string) (*xwebsocket.Conn, error) {
+	cfg, err := xwebsocket.NewConfig("ws"+strings.TrimPrefix(srvURL, "http")+"/my_endpoint", origin)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := xwebsocket.DialConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+	return c, nil
+}
+
+func expectMessage(t *testing.T, c *xwebsocket.Conn, event string, data string) {
+	var msg testMessage
+	if err := xwebsocket.JSON.Receive(c, &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Event != event || string(msg.Data) != data {
+		t.Fatalf("expected the message %s %s but got %s %s", event, data, msg.Event, msg.Data)
+	}
+}
+
+func TestOnlineVisitors(t *testing.T) {
+	ws := websocket.New(websocket.Config{})
+	disconnected := make(chan struct{}, 1)
+	ws.OnConnection(func(c websocket.Connection) {
+		c.OnDisconnect(func() { disconnected <- struct{}{} })
+	})
+
+	app := newApp(ws)
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+	srv := stdhttptest.NewServer(app.Router)
+	defer srv.Close()
+
+	// the handshake.
+	c1, err := dial(srv.URL, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c1.Close()
+
+	// the message round-trip.
+	if err = xwebsocket.Message.Send(c1, `{"event":"watch","data":"page-a"}`); err != nil {
+		t.Fatal(err)
+	}
+	expectMessage(t, c1, "watch", "1")
+
+	c2, err := dial(srv.URL, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c2.Close()
+
+	xwebsocket.Message.Send(c2, `{"event":"watch","data":"page-a"}`)
+	// the room of the page receives the new count.
+	expectMessage(t, c1, "watch", "2")
+	expectMessage(t, c2, "watch", "2")
+
+	// the close of the client leaves its rooms, the rest of the room receives the new count.
+	c2.Close()
+	select {
+	case <-disconnected:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the server to disconnect the closed client")
+	}
+	expectMessage(t, c1, "watch", "1")
+
+	// the origin check.
+	if _, err = dial(srv.URL, "http://example.com"); err == nil {
+		t.Fatalf("expected the handshake of an other origin to fail")
+	}
+	resp, err := http.Get(srv.URL + "/my_endpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected the bad request status of a non websocket request but got %d", resp.StatusCode)
+	}
+
+	// the close of the server closes the connections.
+	ws.Close()
+	var msg testMessage
+	if err = xwebsocket.JSON.Receive(c1, &msg); err == nil {
+		t.Fatalf("expected the connection to be closed by the server but got %#v", msg)
+	}
+	if ws.Len() != 0 {
+		t.Fatalf("expected no connections but got %d", ws.Len())
+	}
+}
@@ -1,6 +1,6 @@
 // Package main shows how you can create a simple URL SHortener.
 //
-// $ go get github.com/boltdb/bolt/...
+// $ go get go.etcd.io/bbolt/...
 // $ go run main.go
 // $ start http://localhost:8080
 package main
@@ -14,7 +14,7 @@ import (
 	"github.com/get-ion/ion/context"
 	"github.com/get-ion/ion/view"
 
-	"github.com/boltdb/bolt"
+	bolt "go.etcd.io/bbolt"
 
 	"github.com/satori/go.uuid"
 )
@@ -2,13 +2,16 @@ package ion
 
 import (
 	"io/ioutil"
+	"net"
 	"path/filepath"
 
 	"github.com/BurntSushi/toml"
+	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 
 	"github.com/get-ion/ion/context"
 	"github.com/get-ion/ion/core/errors"
+	"github.com/get-ion/ion/core/netutil"
 )
 
 var errConfigurationDecode = errors.New("error while trying to decode configuration")
@@ -159,6 +162,25 @@ func WithCharset(charset string) Configurator {
 	}
 }
 
+// WithTrustedProxies sets the TrustedProxies setting,
+// the invalid ip addresses and CIDRs are logged and skipped.
+//
+// See` Configuration`.
+func WithTrustedProxies(proxies ...string) Configurator {
+	return func(app *Application) {
+		app.config.setTrustedProxies(proxies, app.Logger())
+	}
+}
+
+// WithRemoteAddrHeaders sets the RemoteAddrHeaders setting.
+//
+// See` Configuration`.
+func WithRemoteAddrHeaders(headers ...string) Configurator {
+	return func(app *Application) {
+		app.config.RemoteAddrHeaders = headers
+	}
+}
+
 // WithOtherValue adds a value based on a key to the Other setting.
 //
 // See` Configuration`.
@@ -212,7 +234,8 @@ type Configuration struct {
 	EnablePathEscape bool `yaml:"EnablePathEscape" toml:"EnablePathEscape"`
 
 	// FireMethodNotAllowed if it's true router checks for StatusMethodNotAllowed(405) and
-	//  fires the 405 error instead of 404
+	//  fires the 405 error instead of 404, when the path exists on other methods,
+	// with the "Allow" header set to the sorted list of the path's methods.
 	// Defaults to false.
 	FireMethodNotAllowed bool `yaml:"FireMethodNotAllowed" toml:"FireMethodNotAllowed"`
 
@@ -248,6 +271,32 @@ type Configuration struct {
 	// Defaults to "UTF-8".
 	Charset string `yaml:"Charset" toml:"Charset"`
 
+	//  +----------------------------------------------------+
+	//  | Proxies                                            |
+	//  +----------------------------------------------------+
+
+	// TrustedProxies are the ip addresses or the CIDRs, i.e "127.0.0.1" or "10.0.0.0/8",
+	// of the reverse proxies in front of the server, i.e nginx or a load balancer.
+	//
+	// The `RemoteAddrHeaders` and the "X-Forwarded-Proto", "X-Forwarded-Host" and "Forwarded" headers
+	// are honoured only when the request comes from a trusted proxy,
+	// otherwise any client could spoof them.
+	// See `context#RemoteAddr`, `context#Scheme` and `context#Host`.
+	//
+	// Defaults to empty, no proxy is trusted.
+	TrustedProxies []string `yaml:"TrustedProxies" toml:"TrustedProxies"`
+	// trustedProxies are the parsed TrustedProxies.
+	trustedProxies []*net.IPNet
+
+	// RemoteAddrHeaders are the request headers which contain the client's ip address,
+	// in order of priority, they're honoured only when the request comes from one of the `TrustedProxies`.
+	// The supported headers are the "X-Forwarded-For", "Forwarded" (RFC 7239) and any header
+	// with a comma separated list of ip addresses, i.e "X-Real-Ip" or "CF-Connecting-IP",
+	// the lists are walked from right to left and the first ip that is not a trusted proxy is the client's one.
+	//
+	// Defaults to "X-Forwarded-For", "X-Real-Ip".
+	RemoteAddrHeaders []string `yaml:"RemoteAddrHeaders" toml:"RemoteAddrHeaders"`
+
 	//  +----------------------------------------------------+
 	//  | Context's keys for values used on various featuers |
 	//  +----------------------------------------------------+
@@ -375,6 +424,36 @@ func (c Configuration) GetViewDataContextKey() string {
 	return c.ViewDataContextKey
 }
 
+// GetTrustedProxies returns the parsed configuration.TrustedProxies,
+// the networks of the reverse proxies whose forwarding headers are honoured.
+func (c Configuration) GetTrustedProxies() []*net.IPNet {
+	return c.trustedProxies
+}
+
+// GetRemoteAddrHeaders returns the configuration.RemoteAddrHeaders,
+// the request headers which contain the client's ip address.
+func (c Configuration) GetRemoteAddrHeaders() []string {
+	return c.RemoteAddrHeaders
+}
+
+var errInvalidTrustedProxy = errors.New("configuration: invalid trusted proxy '%s', expected an ip address or a CIDR")
+
+// setTrustedProxies sets the TrustedProxies and parses them,
+// the invalid ones are logged and skipped.
+func (c *Configuration) setTrustedProxies(proxies []string, logger *logrus.Logger) {
+	c.TrustedProxies = proxies
+	c.trustedProxies = nil
+
+	for _, proxy := range proxies {
+		ipNet, err := netutil.ParseIPNet(proxy)
+		if err != nil {
+			logger.Warnln(errInvalidTrustedProxy.Format(proxy))
+			continue
+		}
+		c.trustedProxies = append(c.trustedProxies, ipNet)
+	}
+}
+
 // GetOther returns the configuration.Other map.
 func (c Configuration) GetOther() map[string]interface{} {
 	return c.Other
@@ -428,6 +507,14 @@ func WithConfiguration(c Configuration) Configurator {
 			main.Charset = v
 		}
 
+		if v := c.TrustedProxies; len(v) > 0 {
+			main.setTrustedProxies(v, app.Logger())
+		}
+
+		if v := c.RemoteAddrHeaders; len(v) > 0 {
+			main.RemoteAddrHeaders = v
+		}
+
 		if v := c.TranslateFunctionContextKey; v != "" {
 			main.TranslateFunctionContextKey = v
 		}
@@ -467,6 +554,7 @@ func DefaultConfiguration() Configuration {
 		DisableAutoFireStatusCode:         false,
 		TimeFormat:                        "Mon, Jan 02 2006 15:04:05 GMT",
 		Charset:                           "UTF-8",
+		RemoteAddrHeaders:                 []string{"X-Forwarded-For", "X-Real-Ip"},
 		TranslateFunctionContextKey:       "ion.translate",
 		TranslateLanguageContextKey:       "ion.language",
 		ViewLayoutContextKey:              "ion.viewLayout",
@@ -1,5 +1,7 @@
 package context
 
+import "net"
+
 // ConfigurationReadOnly can be implemented
 // by Configuration, it's being used inside the Context.
 // All methods that it contains should be "safe" to be called by the context
@@ -68,6 +70,13 @@ type ConfigurationReadOnly interface {
 	// binding data from a middleware or the main handler.
 	GetViewDataContextKey() string
 
+	// GetTrustedProxies returns the parsed configuration.TrustedProxies,
+	// the networks of the reverse proxies whose forwarding headers are honoured.
+	GetTrustedProxies() []*net.IPNet
+	// GetRemoteAddrHeaders returns the configuration.RemoteAddrHeaders,
+	// the request headers which contain the client's ip address.
+	GetRemoteAddrHeaders() []string
+
 	// GetOther returns the configuration.Other map.
 	GetOther() map[string]interface{}
 }
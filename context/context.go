@@ -2,6 +2,7 @@ package context
 
 import (
 	"bytes"
+	"crypto/x509"
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
@@ -20,11 +21,13 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/microcosm-cc/bluemonday"
 	"github.com/monoculum/formam"
 	"github.com/russross/blackfriday"
+	"gopkg.in/yaml.v2"
 
 	"github.com/get-ion/ion/core/errors"
 	"github.com/get-ion/ion/core/memstore"
@@ -69,6 +72,39 @@ func (u UnmarshalerFunc) Unmarshal(data []byte, v interface{}) error {
 	return u(data, v)
 }
 
+var (
+	unmarshalersMu sync.RWMutex
+	// the unmarshalers of the `ReadBody` by media type.
+	unmarshalers = make(map[string]Unmarshaler)
+)
+
+// RegisterUnmarshaler registers an unmarshaler which the `Context#ReadBody`
+// uses to decode the request bodies of a content type,
+// i.e context.RegisterUnmarshaler("application/msgpack", UnmarshalerFunc(msgpack.Unmarshal)).
+//
+// It overrides the built'n decoders of the content type, if any.
+func RegisterUnmarshaler(contentType string, unmarshaler Unmarshaler) {
+	unmarshalersMu.Lock()
+	unmarshalers[mediaTypeOf(contentType)] = unmarshaler
+	unmarshalersMu.Unlock()
+}
+
+func lookupUnmarshaler(mediaType string) (Unmarshaler, bool) {
+	unmarshalersMu.RLock()
+	u, ok := unmarshalers[mediaType]
+	unmarshalersMu.RUnlock()
+	return u, ok
+}
+
+// mediaTypeOf returns the media type of a content type, without its parameters,
+// i.e "application/json; charset=UTF-8" -> "application/json".
+func mediaTypeOf(contentType string) string {
+	if idx := strings.IndexByte(contentType, ';'); idx != -1 {
+		contentType = contentType[:idx]
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
 // RequestParams is a key string - value string storage which context's request params should implement.
 // RequestValues is for communication between middleware, RequestParams cannot be changed, are setted at the routing
 // time, stores the dynamic named parameters, can be empty if the route is static.
@@ -205,6 +241,12 @@ type Context interface {
 	HandlerIndex(n int) (currentIndex int)
 	// HandlerName returns the current handler's name, helpful for debugging.
 	HandlerName() string
+	// SetCurrentRouteName sets the name of the route which serves the request,
+	// it's called by the router before the execution of the route's handlers.
+	SetCurrentRouteName(name string)
+	// GetCurrentRouteName returns the name of the route which serves the request,
+	// it's empty if no route matched the request.
+	GetCurrentRouteName() string
 	// Next calls all the next handler from the handlers chain,
 	// it should be used inside a middleware.
 	//
@@ -261,12 +303,51 @@ type Context interface {
 	RequestPath(escape bool) string
 
 	// Host returns the host part of the current url.
+	//
+	// The "Forwarded" host or the "X-Forwarded-Host" are honoured
+	// if the request comes from one of the `Configuration#TrustedProxies`,
+	// their values are walked from right to left, the one of the first proxy
+	// after the client is used, a value set by the client itself is ignored.
 	Host() string
+	// Scheme returns the scheme of the current url, "http" or "https".
+	//
+	// The "Forwarded" proto or the "X-Forwarded-Proto" are honoured
+	// if the request comes from one of the `Configuration#TrustedProxies`,
+	// see `Host` for the way their values are walked.
+	Scheme() string
 	// Subdomain returns the subdomain of this request, if any.
 	// Note that this is a fast method which does not cover all cases.
 	Subdomain() (subdomain string)
-	// RemoteAddr tries to return the real client's request IP.
+	// RemoteAddr returns the client's ip address.
+	//
+	// If the request comes from one of the `Configuration#TrustedProxies`
+	// then the `Configuration#RemoteAddrHeaders` are checked, in order,
+	// their addresses are walked from right to left and the first address
+	// which is not a trusted proxy is the client's one.
+	// Otherwise, or if none of the headers is valid, it's the address of the connection.
 	RemoteAddr() string
+	// ClientCertificate returns the verified certificate of the client,
+	// when the server authenticates the clients by their certificates, the mutual tls,
+	// see `ion#MutualTLS`.
+	// It returns nil if the client sent no certificate or if it's not verified.
+	ClientCertificate() *x509.Certificate
+	// PeerIdentity returns the identity of the verified certificate of the client,
+	// its subject, its subject alternative names and its SPIFFE id,
+	// or nil if there is no verified certificate, see `ClientCertificate`.
+	PeerIdentity() *PeerIdentity
+	// SetRequestID sets the id of the request, i.e by the trace middleware,
+	// the id is used by the request logger and the recover middlewares to correlate the logs.
+	SetRequestID(id string)
+	// RequestID returns the id of the request, see `SetRequestID`,
+	// it's empty if no id was set.
+	RequestID() string
+	// SetTraceIDs sets the trace id and the span id of the request's current span,
+	// i.e by the trace middleware, the ids are used by the access log and the recover middlewares
+	// to correlate the logs with the traces.
+	SetTraceIDs(traceID, spanID string)
+	// TraceIDs returns the trace id and the span id of the request's current span, see `SetTraceIDs`,
+	// they're empty if no ids were set.
+	TraceIDs() (traceID, spanID string)
 	// GetHeader returns the request header's value based on its name.
 	GetHeader(name string) string
 	// IsAjax returns true if this request is an 'ajax request'( XMLHttpRequest)
@@ -361,8 +442,28 @@ type Context interface {
 	// ReadXML reads XML from request's body and binds it to a value of any xml-valid type.
 	ReadXML(xmlObject interface{}) error
 	// ReadForm binds the formObject  with the form data
-	// it supports any kind of struct.
+	// it supports any kind of struct, the fields are matched by their name or by their "form" tag.
 	ReadForm(formObject interface{}) error
+	// ReadYAML reads YAML from request's body and binds it to a value of any yaml-valid type.
+	ReadYAML(yamlObject interface{}) error
+	// ReadQuery binds the queryObject with the url query parameters,
+	// it supports any kind of struct, the fields are matched by their name or by their "form" tag.
+	ReadQuery(queryObject interface{}) error
+	// ReadBody binds the "v" with the request's data based on the request's content type
+	// and validates it, see `Validate`.
+	//
+	// The GET and HEAD requests are read from the url query parameters, see `ReadQuery`,
+	// the rest are decoded based on the "Content-Type" header:
+	// - "application/json" or any "+json" type, the default if the "Content-Type" is empty, see `ReadJSON`
+	// - "application/xml", "text/xml" or any "+xml" type, see `ReadXML`
+	// - "application/x-yaml", "application/yaml" or "text/yaml", see `ReadYAML`
+	// - "application/x-www-form-urlencoded" and "multipart/form-data", see `ReadForm`
+	// - any content type which is registered with the `RegisterUnmarshaler`.
+	//
+	// It returns `ValidationErrors` if the decoded value is not valid.
+	//
+	// Example: https://github.com/get-ion/ion/tree/master/_examples/http_request/read-body
+	ReadBody(v interface{}) error
 
 	//  +------------------------------------------------------------+
 	//  | Body (raw) Writers                                         |
@@ -414,6 +515,15 @@ type Context interface {
 	// receives a function which receives the response writer
 	// and returns false when it should stop writing, otherwise true in order to continue
 	StreamWriter(writer func(w io.Writer) bool)
+	// SSE sends the headers of a "text/event-stream" response
+	// and returns a writer of server-sent events.
+	//
+	// The writer can resume a stream based on the "Last-Event-ID" request header,
+	// send retry hints and heartbeats.
+	// It's the caller's responsibility to stop writing when the `Done` channel is closed.
+	//
+	// See `SSEBroker` too.
+	SSE() *SSEWriter
 
 	//  +------------------------------------------------------------+
 	//  | Body Writers with compression                              |
@@ -501,6 +611,36 @@ type Context interface {
 	XML(v interface{}, options ...XML) (int, error)
 	// Markdown parses the markdown to html and renders to client.
 	Markdown(markdownB []byte, options ...Markdown) (int, error)
+	// YAML marshals the given interface object and writes the YAML response.
+	YAML(v interface{}) (int, error)
+	// Negotiate writes the "v" as the content type which the client prefers,
+	// based on the "Accept" request header's media ranges and their quality values,
+	// the "offers" are the content types that the server can send, in order of preference,
+	// defaults to "application/json", "application/xml" and "application/x-yaml".
+	//
+	// The supported offers are:
+	// - "application/json" or any "+json" type
+	// - "application/xml", "text/xml" or any "+xml" type
+	// - "application/x-yaml", "application/yaml" or "text/yaml"
+	// - "text/html", a string is written as it's, otherwise the "template" parameter is required,
+	// i.e "text/html; template=users/index.html", the "v" is the view's binding data, see `View`
+	// - "text/markdown", the markdown string is rendered as "text/html", see `Markdown`
+	// - "text/plain", the text form of the "v"
+	// - any other content type, a string or []byte "v" is written as it's.
+	//
+	// The "Accept-Charset" is negotiated against the `Configuration#Charset`.
+	// The response is gzip compressed only if the gzip is enabled by the caller, see `Gzip`,
+	// then the "Accept-Encoding" decides if it's compressed or sent as it's.
+	// The "Vary: Accept" response header is set.
+	//
+	// If the client accepts none of the offers then it sends the 406 Not Acceptable status code
+	// and it returns an error.
+	//
+	// Usage:
+	// ctx.Negotiate(user, "application/json", "text/xml", "text/html; template=user.html")
+	//
+	// Example: https://github.com/get-ion/ion/tree/master/_examples/http_responsewriter/negotiate
+	Negotiate(v interface{}, offers ...string) (int, error)
 
 	//  +------------------------------------------------------------+
 	//  | Serve files                                                |
@@ -657,6 +797,9 @@ type Context interface {
 	//
 	// See http://blog.golang.org/pipelines for more examples of how to use
 	// a Done channel for cancelation.
+	//
+	// The Done channel is closed when the client's connection closes
+	// or when the request is canceled, see `http.Request#Context`.
 	Done() <-chan struct{}
 
 	// Err returns a non-nil error value after Done is closed.  Err returns
@@ -756,6 +899,12 @@ type context struct {
 	handlers Handlers
 	// the current position of the handler's chain
 	currentHandlerIndex int
+	// the name of the route which serves the request.
+	currentRouteName string
+	// the id of the request, see `SetRequestID`.
+	requestID string
+	// the ids of the request's current span, see `SetTraceIDs`.
+	traceID, spanID string
 }
 
 // NewContext returns the default, internal, context implementation.
@@ -783,6 +932,9 @@ func (ctx *context) BeginRequest(w http.ResponseWriter, r *http.Request) {
 	ctx.params.store = ctx.params.store[0:0]
 	ctx.request = r
 	ctx.currentHandlerIndex = 0
+	ctx.currentRouteName = ""
+	ctx.requestID = ""
+	ctx.traceID, ctx.spanID = "", ""
 	ctx.writer = AcquireResponseWriter()
 	ctx.writer.BeginResponse(w)
 }
@@ -873,6 +1025,18 @@ func (ctx *context) HandlerName() string {
 	return runtime.FuncForPC(reflect.ValueOf(ctx.handlers[ctx.currentHandlerIndex]).Pointer()).Name()
 }
 
+// SetCurrentRouteName sets the name of the route which serves the request,
+// it's called by the router before the execution of the route's handlers.
+func (ctx *context) SetCurrentRouteName(name string) {
+	ctx.currentRouteName = name
+}
+
+// GetCurrentRouteName returns the name of the route which serves the request,
+// it's empty if no route matched the request.
+func (ctx *context) GetCurrentRouteName() string {
+	return ctx.currentRouteName
+}
+
 // Do sets the handler index to zero, executes the first handler
 // and the rest of the Handlers if ctx.Next() was called.
 // func (ctx *context) Do() {
@@ -1027,7 +1191,16 @@ func (ctx *context) RequestPath(escape bool) string {
 // } no, it will not work because map is a random peek data structure.
 
 // Host returns the host part of the current url.
+//
+// The "Forwarded" host or the "X-Forwarded-Host" are honoured
+// if the request comes from one of the `Configuration#TrustedProxies`,
+// their values are walked from right to left, the one of the first proxy
+// after the client is used, a value set by the client itself is ignored.
 func (ctx *context) Host() string {
+	if h := ctx.forwardedParam("host", xForwardedHostHeaderKey); h != "" {
+		return h
+	}
+
 	h := ctx.request.URL.Host
 	if h == "" {
 		h = ctx.request.Host
@@ -1035,6 +1208,53 @@ func (ctx *context) Host() string {
 	return h
 }
 
+// Scheme returns the scheme of the current url, "http" or "https".
+//
+// The "Forwarded" proto or the "X-Forwarded-Proto" are honoured
+// if the request comes from one of the `Configuration#TrustedProxies`,
+// see `Host` for the way their values are walked.
+func (ctx *context) Scheme() string {
+	if proto := ctx.forwardedParam("proto", xForwardedProtoHeaderKey); proto != "" {
+		return strings.ToLower(proto)
+	}
+
+	if ctx.request.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// forwardedParam returns the "param" of the "Forwarded" header, or the value of the "xHeader",
+// which the first trusted proxy after the client set, if the request comes from a trusted proxy.
+func (ctx *context) forwardedParam(param, xHeader string) string {
+	if !ctx.fromTrustedProxy() {
+		return ""
+	}
+	proxies := ctx.Application().ConfigurationReadOnly().GetTrustedProxies()
+
+	if elements := parseForwarded(ctx.request.Header[forwardedHeaderKey]); len(elements) > 0 {
+		if v := forwardedElement(elements, proxies)[param]; v != "" {
+			return v
+		}
+	}
+
+	values := splitHeaderValues(ctx.request.Header[xHeader])
+	if len(values) == 0 {
+		return ""
+	}
+	hops := trustedHops(splitHeaderValues(ctx.request.Header[xForwardedForHeaderKey]), proxies)
+	return forwardedValue(values, hops)
+}
+
+// fromTrustedProxy reports whether the request comes from one of the `Configuration#TrustedProxies`.
+func (ctx *context) fromTrustedProxy() bool {
+	proxies := ctx.Application().ConfigurationReadOnly().GetTrustedProxies()
+	if len(proxies) == 0 {
+		return false
+	}
+	return isTrustedProxy(net.ParseIP(hostIP(ctx.request.RemoteAddr)), proxies)
+}
+
 // Subdomain returns the subdomain of this request, if any.
 // Note that this is a fast method which does not cover all cases.
 func (ctx *context) Subdomain() (subdomain string) {
@@ -1053,35 +1273,27 @@ func (ctx *context) Subdomain() (subdomain string) {
 	return
 }
 
-// RemoteAddr tries to return the real client's request IP.
+// RemoteAddr returns the client's ip address.
+//
+// If the request comes from one of the `Configuration#TrustedProxies`
+// then the `Configuration#RemoteAddrHeaders` are checked, in order,
+// their addresses are walked from right to left and the first address
+// which is not a trusted proxy is the client's one.
+// Otherwise, or if none of the headers is valid, it's the address of the connection.
 func (ctx *context) RemoteAddr() string {
-	header := ctx.GetHeader("X-Real-Ip")
-	realIP := strings.TrimSpace(header)
-	if realIP != "" {
-		return realIP
+	addr := hostIP(ctx.request.RemoteAddr)
+	if !ctx.fromTrustedProxy() {
+		return addr
 	}
 
-	header = ctx.GetHeader("X-Forwarded-For")
-	idx := strings.IndexByte(header, ',')
-	if idx >= 0 {
-		header = header[0:idx]
-	}
-	realIP = strings.TrimSpace(header)
-	if realIP != "" {
-		return realIP
-	}
-
-	// cloudflare
-	header = ctx.GetHeader("HTTP_CF_CONNECTING_IP")
-	realIP = strings.TrimSpace(header)
-	if realIP != "" {
-		return realIP
-	}
+	cfg := ctx.Application().ConfigurationReadOnly()
+	for _, header := range cfg.GetRemoteAddrHeaders() {
+		lines := ctx.request.Header[http.CanonicalHeaderKey(header)]
+		if len(lines) == 0 {
+			continue
+		}
 
-	addr := strings.TrimSpace(ctx.request.RemoteAddr)
-	if addr != "" {
-		// if addr has port use the net.SplitHostPort otherwise(error occurs) take as it is
-		if ip, _, err := net.SplitHostPort(addr); err == nil {
+		if ip := clientIP(forwardedChain(header, lines), cfg.GetTrustedProxies()); ip != "" {
 			return ip
 		}
 	}
@@ -1089,6 +1301,55 @@ func (ctx *context) RemoteAddr() string {
 	return addr
 }
 
+// ClientCertificate returns the verified certificate of the client,
+// when the server authenticates the clients by their certificates, the mutual tls,
+// see `ion#MutualTLS`.
+// It returns nil if the client sent no certificate or if it's not verified.
+func (ctx *context) ClientCertificate() *x509.Certificate {
+	state := ctx.request.TLS
+	// the peer certificates of a server which doesn't verify them are not trusted.
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return nil
+	}
+	return state.VerifiedChains[0][0]
+}
+
+// PeerIdentity returns the identity of the verified certificate of the client,
+// its subject, its subject alternative names and its SPIFFE id,
+// or nil if there is no verified certificate, see `ClientCertificate`.
+func (ctx *context) PeerIdentity() *PeerIdentity {
+	cert := ctx.ClientCertificate()
+	if cert == nil {
+		return nil
+	}
+	return NewPeerIdentity(cert)
+}
+
+// SetRequestID sets the id of the request, i.e by the trace middleware,
+// the id is used by the request logger and the recover middlewares to correlate the logs.
+func (ctx *context) SetRequestID(id string) {
+	ctx.requestID = id
+}
+
+// RequestID returns the id of the request, see `SetRequestID`,
+// it's empty if no id was set.
+func (ctx *context) RequestID() string {
+	return ctx.requestID
+}
+
+// SetTraceIDs sets the trace id and the span id of the request's current span,
+// i.e by the trace middleware, the ids are used by the access log and the recover middlewares
+// to correlate the logs with the traces.
+func (ctx *context) SetTraceIDs(traceID, spanID string) {
+	ctx.traceID, ctx.spanID = traceID, spanID
+}
+
+// TraceIDs returns the trace id and the span id of the request's current span, see `SetTraceIDs`,
+// they're empty if no ids were set.
+func (ctx *context) TraceIDs() (traceID, spanID string) {
+	return ctx.traceID, ctx.spanID
+}
+
 // GetHeader returns the request header's value based on its name.
 func (ctx *context) GetHeader(name string) string {
 	return ctx.request.Header.Get(name)
@@ -1326,17 +1587,97 @@ var (
 )
 
 // ReadForm binds the formObject  with the form data
-// it supports any kind of struct.
+// it supports any kind of struct, the fields are matched by their name or by their "form" tag.
 func (ctx *context) ReadForm(formObject interface{}) error {
 	values := ctx.FormValues()
 	if values == nil {
 		return errors.New("An empty form passed on ReadForm")
 	}
 
-	// or dec := formam.NewDecoder(&formam.DecoderOptions{TagName: "form"})
-	// somewhere at the app level. I did change the tagName to "form"
-	// inside its source code, so it's not needed for now.
-	return errReadBody.With(formam.Decode(values, formObject))
+	if err := decodeForm(values, formObject); err != nil {
+		return errReadBody.Format("form", err)
+	}
+	return nil
+}
+
+// formTagName is the struct tag of the `ReadForm` and `ReadQuery`.
+const formTagName = "form"
+
+// decodeForm binds the "v" with the form "values", the fields are matched
+// by their name or by their "form" tag.
+func decodeForm(values url.Values, v interface{}) error {
+	return formam.NewDecoder(&formam.DecoderOptions{TagName: formTagName}).Decode(values, v)
+}
+
+// ReadYAML reads YAML from request's body and binds it to a value of any yaml-valid type.
+func (ctx *context) ReadYAML(yamlObject interface{}) error {
+	return ctx.UnmarshalBody(yamlObject, UnmarshalerFunc(yaml.Unmarshal))
+}
+
+// ReadQuery binds the queryObject with the url query parameters,
+// it supports any kind of struct, the fields are matched by their name or by their "form" tag.
+func (ctx *context) ReadQuery(queryObject interface{}) error {
+	return errReadQuery.With(decodeForm(ctx.request.URL.Query(), queryObject))
+}
+
+// defaultMultipartMemory is the maximum memory of the multipart form values and files,
+// the rest of the files are stored on disk, same as the net/http.
+const defaultMultipartMemory = 32 << 20 // 32 MB
+
+var (
+	errReadQuery           = errors.New("while trying to read the url query. Trace %s")
+	errReadBodyContentType = errors.New("unsupported content type '%s' of the request body")
+)
+
+// ReadBody binds the "v" with the request's data based on the request's content type
+// and validates it, see `Validate`.
+//
+// The GET and HEAD requests are read from the url query parameters, see `ReadQuery`,
+// the rest are decoded based on the "Content-Type" header:
+// - "application/json" or any "+json" type, the default if the "Content-Type" is empty, see `ReadJSON`
+// - "application/xml", "text/xml" or any "+xml" type, see `ReadXML`
+// - "application/x-yaml", "application/yaml" or "text/yaml", see `ReadYAML`
+// - "application/x-www-form-urlencoded" and "multipart/form-data", see `ReadForm`
+// - any content type which is registered with the `RegisterUnmarshaler`.
+//
+// It returns `ValidationErrors` if the decoded value is not valid.
+//
+// Example: https://github.com/get-ion/ion/tree/master/_examples/http_request/read-body
+func (ctx *context) ReadBody(v interface{}) error {
+	if err := ctx.decodeBody(v); err != nil {
+		return err
+	}
+
+	return Validate(v)
+}
+
+func (ctx *context) decodeBody(v interface{}) error {
+	if method := ctx.Method(); method == http.MethodGet || method == http.MethodHead {
+		return ctx.ReadQuery(v)
+	}
+
+	mediaType := mediaTypeOf(ctx.GetHeader(contentTypeHeaderKey))
+	if unmarshaler, ok := lookupUnmarshaler(mediaType); ok {
+		return ctx.UnmarshalBody(v, unmarshaler)
+	}
+
+	switch {
+	case mediaType == "" || isJSONMediaType(mediaType):
+		return ctx.ReadJSON(v)
+	case isXMLMediaType(mediaType):
+		return ctx.ReadXML(v)
+	case isYAMLMediaType(mediaType):
+		return ctx.ReadYAML(v)
+	case mediaType == "multipart/form-data":
+		if err := ctx.request.ParseMultipartForm(defaultMultipartMemory); err != nil {
+			return errReadBody.Format("multipart form", err)
+		}
+		return ctx.ReadForm(v)
+	case mediaType == "application/x-www-form-urlencoded":
+		return ctx.ReadForm(v)
+	default:
+		return errReadBodyContentType.Format(mediaType)
+	}
 }
 
 //  +------------------------------------------------------------+
@@ -1452,6 +1793,18 @@ func (ctx *context) StreamWriter(writer func(w io.Writer) bool) {
 	}
 }
 
+// SSE sends the headers of a "text/event-stream" response
+// and returns a writer of server-sent events.
+//
+// The writer can resume a stream based on the "Last-Event-ID" request header,
+// send retry hints and heartbeats.
+// It's the caller's responsibility to stop writing when the `Done` channel is closed.
+//
+// See `SSEBroker` too.
+func (ctx *context) SSE() *SSEWriter {
+	return newSSEWriter(ctx)
+}
+
 //  +------------------------------------------------------------+
 //  | Body Writers with compression                              |
 //  +------------------------------------------------------------+
@@ -1643,6 +1996,8 @@ const (
 
 	// contentMarkdownHeaderValue custom key/content type, the real is the text/html.
 	contentMarkdownHeaderValue = "text/markdown"
+	// contentYAMLHeaderValue header value for YAML data.
+	contentYAMLHeaderValue = "application/x-yaml"
 )
 
 // Binary writes out the raw bytes as binary data.
@@ -1890,6 +2245,131 @@ func (ctx *context) Markdown(markdownB []byte, opts ...Markdown) (int, error) {
 	return n, err
 }
 
+// WriteYAML marshals the given interface object and writes the YAML response to the writer.
+func WriteYAML(writer io.Writer, v interface{}) (int, error) {
+	result, err := yaml.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return writer.Write(result)
+}
+
+// YAML marshals the given interface object and writes the YAML response to the client.
+func (ctx *context) YAML(v interface{}) (int, error) {
+	ctx.ContentType(contentYAMLHeaderValue)
+
+	n, err := WriteYAML(ctx.writer, v)
+	if err != nil {
+		ctx.StatusCode(http.StatusInternalServerError)
+		return 0, err
+	}
+
+	return n, err
+}
+
+const (
+	acceptHeaderKey        = "Accept"
+	acceptCharsetHeaderKey = "Accept-Charset"
+)
+
+var defaultNegotiateOffers = []string{contentJSONHeaderValue, "application/xml", contentYAMLHeaderValue}
+
+var (
+	errNegotiateOffer         = errors.New("invalid offer '%s', expected a content type, i.e application/json")
+	errNegotiateNotAcceptable = errors.New("not acceptable, the client accepts none of: %s")
+	errNegotiateCharset       = errors.New("not acceptable, the client doesn't accept the '%s' charset")
+	errNegotiateEncoding      = errors.New("not acceptable, the client accepts neither the gzip nor the identity encoding")
+	errNegotiateIdentity      = errors.New("not acceptable, the client doesn't accept the identity encoding")
+	errNegotiateTemplate      = errors.New("the text/html offer requires a template parameter in order to render a %T")
+	errNegotiateUnsupported   = errors.New("can't render a %T as %s")
+)
+
+// Negotiate writes the "v" as the content type which the client prefers,
+// based on the "Accept" request header's media ranges and their quality values,
+// the "offers" are the content types that the server can send, in order of preference,
+// defaults to "application/json", "application/xml" and "application/x-yaml".
+//
+// The supported offers are:
+// - "application/json" or any "+json" type
+// - "application/xml", "text/xml" or any "+xml" type
+// - "application/x-yaml", "application/yaml" or "text/yaml"
+// - "text/html", a string is written as it's, otherwise the "template" parameter is required,
+// i.e "text/html; template=users/index.html", the "v" is the view's binding data, see `View`
+// - "text/markdown", the markdown string is rendered as "text/html", see `Markdown`
+// - "text/plain", the text form of the "v"
+// - any other content type, a string or []byte "v" is written as it's.
+//
+// The "Accept-Charset" is negotiated against the `Configuration#Charset`.
+// The response is gzip compressed only if the gzip is enabled by the caller, see `Gzip`,
+// then the "Accept-Encoding" decides if it's compressed or sent as it's.
+// The "Vary: Accept" response header is set.
+//
+// If the client accepts none of the offers then it sends the 406 Not Acceptable status code
+// and it returns an error.
+//
+// Usage:
+// ctx.Negotiate(user, "application/json", "text/xml", "text/html; template=user.html")
+//
+// Example: https://github.com/get-ion/ion/tree/master/_examples/http_responsewriter/negotiate
+func (ctx *context) Negotiate(v interface{}, offers ...string) (int, error) {
+	if len(offers) == 0 {
+		offers = defaultNegotiateOffers
+	}
+
+	parsed := make([]negotiateOffer, 0, len(offers))
+	for _, offer := range offers {
+		o, err := parseOffer(offer)
+		if err != nil {
+			ctx.StatusCode(http.StatusInternalServerError)
+			return 0, err
+		}
+		parsed = append(parsed, o)
+	}
+
+	addVary(ctx, acceptHeaderKey)
+
+	idx := bestOffer(ctx.GetHeader(acceptHeaderKey), parsed)
+	if idx == -1 {
+		ctx.StatusCode(http.StatusNotAcceptable)
+		return 0, errNegotiateNotAcceptable.Format(offersString(parsed))
+	}
+
+	if acceptCharset := ctx.GetHeader(acceptCharsetHeaderKey); acceptCharset != "" {
+		addVary(ctx, acceptCharsetHeaderKey)
+		charset := ctx.Application().ConfigurationReadOnly().GetCharset()
+		if !acceptsCharset(acceptCharset, charset) {
+			ctx.StatusCode(http.StatusNotAcceptable)
+			return 0, errNegotiateCharset.Format(charset)
+		}
+	}
+
+	// the gzip is offered only if it's enabled by the caller, see `Gzip`.
+	_, gzipEnabled := ctx.writer.(*GzipResponseWriter)
+	encoding, ok := negotiateEncoding(ctx.GetHeader(acceptEncodingHeaderKey), gzipEnabled)
+	if !ok {
+		addVary(ctx, acceptEncodingHeaderKey)
+		ctx.StatusCode(http.StatusNotAcceptable)
+		if gzipEnabled {
+			return 0, errNegotiateEncoding
+		}
+		return 0, errNegotiateIdentity
+	}
+
+	if gzipEnabled && encoding != "gzip" {
+		// i.e "gzip;q=0", the response is sent uncompressed.
+		addVary(ctx, acceptEncodingHeaderKey)
+		ctx.Gzip(false)
+	}
+
+	n, err := ctx.renderOffer(v, parsed[idx])
+	if err != nil {
+		ctx.StatusCode(http.StatusInternalServerError)
+		return 0, err
+	}
+
+	return n, err
+}
+
 //  +------------------------------------------------------------+
 //  | Serve files                                                |
 //  +------------------------------------------------------------+
@@ -2236,7 +2716,7 @@ func (ctx *context) Application() Application {
 // should be canceled.  Deadline returns ok==false when no deadline is
 // set.  Successive calls to Deadline return the same results.
 func (ctx *context) Deadline() (deadline time.Time, ok bool) {
-	return
+	return ctx.request.Context().Deadline()
 }
 
 // Done returns a channel that's closed when work done on behalf of this
@@ -2268,8 +2748,11 @@ func (ctx *context) Deadline() (deadline time.Time, ok bool) {
 //
 // See http://blog.golang.org/pipelines for more examples of how to use
 // a Done channel for cancelation.
+//
+// The Done channel is closed when the client's connection closes
+// or when the request is canceled, see `http.Request#Context`.
 func (ctx *context) Done() <-chan struct{} {
-	return nil
+	return ctx.request.Context().Done()
 }
 
 // Err returns a non-nil error value after Done is closed.  Err returns
@@ -2277,7 +2760,7 @@ func (ctx *context) Done() <-chan struct{} {
 // context's deadline passed.  No other values for Err are defined.
 // After Done is closed, successive calls to Err return the same value.
 func (ctx *context) Err() error {
-	return nil
+	return ctx.request.Context().Err()
 }
 
 // Value returns the value associated with this context for key, or nil
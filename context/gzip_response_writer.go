@@ -2,6 +2,7 @@ package context
 
 import (
 	"io"
+	"io/ioutil"
 	"sync"
 
 	"github.com/klauspost/compress/gzip"
@@ -64,6 +65,10 @@ func AcquireGzipResponseWriter() *GzipResponseWriter {
 }
 
 func releaseGzipResponseWriter(w *GzipResponseWriter) {
+	if w.disabled {
+		// the response is written in plain form, the close should not write the gzip header and footer.
+		w.gzipWriter.Reset(ioutil.Discard)
+	}
 	releaseGzipWriter(w.gzipWriter)
 	gzpool.Put(w)
 }
@@ -0,0 +1,77 @@
+package context
+
+import (
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net"
+	"net/url"
+	"strings"
+)
+
+// spiffeScheme is the scheme of the SPIFFE ids, i.e "spiffe://example.org/ns/default/sa/billing".
+//
+// See https://github.com/spiffe/spiffe/blob/master/standards/SPIFFE-ID.md.
+const spiffeScheme = "spiffe"
+
+// PeerIdentity is the identity of a client which is authenticated by its certificate, the mutual tls,
+// see `Context#PeerIdentity`.
+type PeerIdentity struct {
+	// Certificate is the verified certificate of the client.
+	Certificate *x509.Certificate
+	// Subject is the subject of the certificate.
+	Subject pkix.Name
+	// CommonName is the common name of the subject.
+	CommonName string
+	// DNSNames, EmailAddresses, IPAddresses and URIs are the subject alternative names, the SANs.
+	DNSNames       []string
+	EmailAddresses []string
+	IPAddresses    []net.IP
+	URIs           []*url.URL
+	// SPIFFEID is the SPIFFE id of the URIs, if any,
+	// i.e "spiffe://example.org/ns/default/sa/billing".
+	SPIFFEID string
+	// TrustDomain is the trust domain of the SPIFFE id, if any, i.e "example.org".
+	TrustDomain string
+}
+
+// NewPeerIdentity returns the identity of the certificate "cert".
+func NewPeerIdentity(cert *x509.Certificate) *PeerIdentity {
+	id := &PeerIdentity{
+		Certificate:    cert,
+		Subject:        cert.Subject,
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+		IPAddresses:    cert.IPAddresses,
+		URIs:           cert.URIs,
+	}
+
+	// a SPIFFE certificate has exactly one SPIFFE id.
+	for _, u := range cert.URIs {
+		if strings.EqualFold(u.Scheme, spiffeScheme) && u.Host != "" {
+			id.SPIFFEID = u.String()
+			id.TrustDomain = strings.ToLower(u.Host)
+			break
+		}
+	}
+
+	return id
+}
+
+// Names returns the common name and the DNS names, the email addresses, the ip addresses and the URIs
+// of the identity, in that order.
+func (id *PeerIdentity) Names() []string {
+	var names []string
+	if id.CommonName != "" {
+		names = append(names, id.CommonName)
+	}
+	names = append(names, id.DNSNames...)
+	names = append(names, id.EmailAddresses...)
+	for _, ip := range id.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, u := range id.URIs {
+		names = append(names, u.String())
+	}
+	return names
+}
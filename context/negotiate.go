@@ -0,0 +1,306 @@
+package context
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// acceptRange is a media range, a charset or an encoding
+// of an "Accept", "Accept-Charset" or "Accept-Encoding" request header
+// with its quality value.
+type acceptRange struct {
+	value string
+	q     float64
+}
+
+// parseAccept parses the value of an "Accept*" request header
+// to its ranges, the ranges without a valid quality value have the quality of 1,
+// the rest of the parameters are ignored.
+func parseAccept(header string) []acceptRange {
+	var ranges []acceptRange
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		value := strings.ToLower(strings.TrimSpace(fields[0]))
+		if value == "" {
+			continue
+		}
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if !strings.HasPrefix(param, "q=") && !strings.HasPrefix(param, "Q=") {
+				continue
+			}
+			if f, err := strconv.ParseFloat(param[2:], 64); err == nil && f >= 0 && f <= 1 {
+				q = f
+			}
+		}
+
+		ranges = append(ranges, acceptRange{value: value, q: q})
+	}
+	return ranges
+}
+
+// mediaTypeQuality returns the quality of the "mediaType" based on the most specific
+// media range which matches it, i.e "text/html" over "text/*" over "*/*",
+// returns -1 if no media range matches.
+func mediaTypeQuality(ranges []acceptRange, mediaType string) float64 {
+	q, specificity := -1.0, -1
+	for _, r := range ranges {
+		s := -1
+		switch {
+		case r.value == mediaType:
+			s = 2
+		case r.value == "*/*":
+			s = 0
+		case strings.HasSuffix(r.value, "/*") && strings.HasPrefix(mediaType, r.value[:len(r.value)-1]):
+			s = 1
+		}
+
+		if s > specificity {
+			q, specificity = r.q, s
+		}
+	}
+	return q
+}
+
+// valueQuality returns the quality of a charset or an encoding "value",
+// the exact value wins the "*", returns -1 if not listed.
+func valueQuality(ranges []acceptRange, value string) float64 {
+	q := -1.0
+	for _, r := range ranges {
+		if r.value == value {
+			return r.q
+		}
+		if r.value == "*" {
+			q = r.q
+		}
+	}
+	return q
+}
+
+// negotiateOffer is a content type that the server can send,
+// it's parsed from an offer of the `Context#Negotiate`.
+type negotiateOffer struct {
+	// the content type which is sent to the client.
+	contentType string
+	// the media type which is matched against the "Accept" header,
+	// it's the content type except the "text/markdown" which is matched as "text/html".
+	mediaType string
+	// the "template" parameter of a "text/html" offer.
+	template string
+	markdown bool
+}
+
+// parseOffer parses an offer, i.e "application/json" or "text/html; template=users/index.html".
+func parseOffer(offer string) (negotiateOffer, error) {
+	fields := strings.Split(offer, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(fields[0]))
+	if idx := strings.IndexByte(mediaType, '/'); idx <= 0 || idx == len(mediaType)-1 {
+		return negotiateOffer{}, errNegotiateOffer.Format(offer)
+	}
+
+	params := make(map[string]string, len(fields)-1)
+	for _, param := range fields[1:] {
+		kv := strings.SplitN(param, "=", 2)
+		if len(kv) != 2 {
+			return negotiateOffer{}, errNegotiateOffer.Format(offer)
+		}
+		params[strings.ToLower(strings.TrimSpace(kv[0]))] = strings.Trim(strings.TrimSpace(kv[1]), `"`)
+	}
+
+	o := negotiateOffer{
+		contentType: mediaType,
+		mediaType:   mediaType,
+		template:    params["template"],
+	}
+
+	if mediaType == contentMarkdownHeaderValue {
+		o.contentType = contentHTMLHeaderValue
+		o.mediaType = contentHTMLHeaderValue
+		o.markdown = true
+	}
+
+	return o, nil
+}
+
+// bestOffer returns the index of the offer with the highest quality
+// based on the "Accept" header's value, the offers that come first win on equal quality,
+// returns -1 if the client accepts none of them.
+func bestOffer(accept string, offers []negotiateOffer) int {
+	if strings.TrimSpace(accept) == "" {
+		// no "Accept" header means that the client accepts any media type.
+		return 0
+	}
+
+	ranges := parseAccept(accept)
+	best, bestQ := -1, 0.0
+	for i, o := range offers {
+		if q := mediaTypeQuality(ranges, o.mediaType); q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+	return best
+}
+
+// acceptsCharset reports whether the "charset" is acceptable
+// based on the "Accept-Charset" header's value.
+func acceptsCharset(acceptCharset string, charset string) bool {
+	if strings.TrimSpace(acceptCharset) == "" {
+		return true
+	}
+
+	return valueQuality(parseAccept(acceptCharset), strings.ToLower(charset)) > 0
+}
+
+// negotiateEncoding returns the content coding of the response,
+// "gzip", if it's offered by the server, or "identity", based on the "Accept-Encoding" header's value,
+// the gzip wins on equal quality, it returns false if none of them is acceptable.
+func negotiateEncoding(acceptEncoding string, offerGzip bool) (string, bool) {
+	if strings.TrimSpace(acceptEncoding) == "" {
+		return "identity", true
+	}
+
+	ranges := parseAccept(acceptEncoding)
+	gzipQ := -1.0
+	if offerGzip {
+		gzipQ = valueQuality(ranges, "gzip")
+	}
+	identityQ := valueQuality(ranges, "identity")
+	if identityQ == -1 {
+		// the identity is always acceptable, unless it's excluded explicitly or by the "*;q=0".
+		identityQ = 1
+		if q := valueQuality(ranges, "*"); q == 0 {
+			identityQ = 0
+		}
+	}
+
+	if gzipQ > 0 && gzipQ >= identityQ {
+		return "gzip", true
+	}
+
+	if identityQ > 0 {
+		return "identity", true
+	}
+
+	return "", false
+}
+
+// addVary adds a header name to the "Vary" response header, if it's not there already.
+func addVary(ctx Context, name string) {
+	h := ctx.ResponseWriter().Header()
+	for _, v := range h[varyHeaderKey] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s == "*" || strings.EqualFold(s, name) {
+				return
+			}
+		}
+	}
+	h.Add(varyHeaderKey, name)
+}
+
+// offersString returns the offers' media types for the error messages.
+func offersString(offers []negotiateOffer) string {
+	types := make([]string, len(offers))
+	for i, o := range offers {
+		types[i] = o.mediaType
+	}
+	return strings.Join(types, ", ")
+}
+
+// textOf returns the text form of a value which is sent as plain text.
+func textOf(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	case fmt.Stringer:
+		return value.String()
+	case error:
+		return value.Error()
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+func isJSONMediaType(mediaType string) bool {
+	return mediaType == contentJSONHeaderValue || strings.HasSuffix(mediaType, "+json")
+}
+
+func isXMLMediaType(mediaType string) bool {
+	return mediaType == contentXMLHeaderValue || mediaType == "application/xml" || strings.HasSuffix(mediaType, "+xml")
+}
+
+func isYAMLMediaType(mediaType string) bool {
+	switch mediaType {
+	case contentYAMLHeaderValue, "application/yaml", "text/yaml", "text/x-yaml":
+		return true
+	}
+	return false
+}
+
+// countWriter counts the bytes which are written to a writer.
+type countWriter struct {
+	io.Writer
+	n int
+}
+
+func (w *countWriter) Write(p []byte) (int, error) {
+	n, err := w.Writer.Write(p)
+	w.n += n
+	return n, err
+}
+
+// renderOffer writes the "v" as the content type of the "offer".
+func (ctx *context) renderOffer(v interface{}, offer negotiateOffer) (int, error) {
+	ctx.ContentType(offer.contentType)
+
+	switch mediaType := offer.contentType; {
+	case offer.markdown:
+		if !isText(v) {
+			return 0, errNegotiateUnsupported.Format(v, contentMarkdownHeaderValue)
+		}
+		return WriteMarkdown(ctx.writer, []byte(textOf(v)), defaultMarkdownOptions)
+	case mediaType == contentHTMLHeaderValue:
+		if offer.template == "" {
+			if !isText(v) {
+				return 0, errNegotiateTemplate.Format(v)
+			}
+			return ctx.writer.WriteString(textOf(v))
+		}
+
+		if v == nil {
+			// use the view data, see `ViewData`.
+			v = ctx.values.Get(ctx.Application().ConfigurationReadOnly().GetViewDataContextKey())
+		}
+		layout := ctx.values.GetString(ctx.Application().ConfigurationReadOnly().GetViewLayoutContextKey())
+		w := &countWriter{Writer: ctx.writer}
+		err := ctx.Application().View(w, offer.template, layout, v)
+		return w.n, err
+	case isJSONMediaType(mediaType):
+		return WriteJSON(ctx.writer, v, defaultJSONOptions)
+	case isXMLMediaType(mediaType):
+		return WriteXML(ctx.writer, v, defaultXMLOptions)
+	case isYAMLMediaType(mediaType):
+		return WriteYAML(ctx.writer, v)
+	case mediaType == contentTextHeaderValue:
+		return ctx.writer.WriteString(textOf(v))
+	default:
+		// i.e an image/png offer of a []byte.
+		if !isText(v) {
+			return 0, errNegotiateUnsupported.Format(v, mediaType)
+		}
+		return ctx.writer.WriteString(textOf(v))
+	}
+}
+
+func isText(v interface{}) bool {
+	switch v.(type) {
+	case string, []byte:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,145 @@
+package context
+
+import (
+	"net"
+	"strings"
+)
+
+const (
+	forwardedHeaderKey       = "Forwarded"
+	xForwardedForHeaderKey   = "X-Forwarded-For"
+	xForwardedProtoHeaderKey = "X-Forwarded-Proto"
+	xForwardedHostHeaderKey  = "X-Forwarded-Host"
+)
+
+// hostIP returns the ip address part of a "host:port" address,
+// or the address as it's if it has no port.
+func hostIP(addr string) string {
+	addr = strings.TrimSpace(addr)
+	if ip, _, err := net.SplitHostPort(addr); err == nil {
+		return ip
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+}
+
+// isTrustedProxy reports whether the "ip" belongs to one of the trusted proxies' networks.
+func isTrustedProxy(ip net.IP, proxies []*net.IPNet) bool {
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range proxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitHeaderValues returns the comma separated values of all the header's lines,
+// i.e ["a, b", "c"] -> ["a", "b", "c"].
+func splitHeaderValues(lines []string) []string {
+	var values []string
+	for _, line := range lines {
+		for _, v := range strings.Split(line, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				values = append(values, v)
+			}
+		}
+	}
+	return values
+}
+
+// parseForwarded parses the "Forwarded" header's lines to its elements,
+// each element is a map of its lowercase parameters, i.e
+// `for=192.0.2.60;proto=http, for="[2001:db8::1]:4711"` ->
+// [{"for": "192.0.2.60", "proto": "http"}, {"for": "[2001:db8::1]:4711"}].
+//
+// See https://tools.ietf.org/html/rfc7239.
+func parseForwarded(lines []string) []map[string]string {
+	var elements []map[string]string
+	for _, v := range splitHeaderValues(lines) {
+		element := make(map[string]string)
+		for _, pair := range strings.Split(v, ";") {
+			kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			element[strings.ToLower(kv[0])] = strings.Trim(kv[1], `"`)
+		}
+		elements = append(elements, element)
+	}
+	return elements
+}
+
+// forwardedChain returns the addresses of a forwarding header from the client to the last proxy.
+func forwardedChain(header string, lines []string) []string {
+	if !strings.EqualFold(header, forwardedHeaderKey) {
+		return splitHeaderValues(lines)
+	}
+
+	var chain []string
+	for _, element := range parseForwarded(lines) {
+		// the "unknown" and the obfuscated identifiers are kept,
+		// they stop the walk through the chain.
+		chain = append(chain, element["for"])
+	}
+	return chain
+}
+
+// clientIP walks the "chain" from right to left and returns the first address which
+// is not a trusted proxy, or the leftmost one if they're all trusted.
+// It returns an empty string if an address of the walk is not a valid ip address.
+func clientIP(chain []string, proxies []*net.IPNet) string {
+	for i := len(chain) - 1; i >= 0; i-- {
+		addr := hostIP(chain[i])
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return ""
+		}
+
+		if i == 0 || !isTrustedProxy(ip, proxies) {
+			return ip.String()
+		}
+	}
+	return ""
+}
+
+// forwardedElement walks the "Forwarded" elements from right to left and returns the first element
+// whose "for" is not a trusted proxy, the one which the first proxy after the client added,
+// or the leftmost one if they're all trusted.
+func forwardedElement(elements []map[string]string, proxies []*net.IPNet) map[string]string {
+	for i := len(elements) - 1; i > 0; i-- {
+		// the "unknown" and the obfuscated identifiers stop the walk.
+		if ip := net.ParseIP(hostIP(elements[i]["for"])); !isTrustedProxy(ip, proxies) {
+			return elements[i]
+		}
+	}
+	return elements[0]
+}
+
+// trustedHops returns the number of the trusted proxies which forwarded the request,
+// the one of the connection and the trusted addresses of the "X-Forwarded-For" "chain" from right to left,
+// before the client.
+func trustedHops(chain []string, proxies []*net.IPNet) int {
+	hops := 1
+	for i := len(chain) - 1; i > 0; i-- {
+		if ip := net.ParseIP(hostIP(chain[i])); !isTrustedProxy(ip, proxies) {
+			break
+		}
+		hops++
+	}
+	return hops
+}
+
+// forwardedValue returns the value of an "X-Forwarded-*" header's "values" which the first
+// of the "hops" trusted proxies set, each proxy appends its value to the right,
+// the values on the left of it are set by the client.
+// If there are less values than the hops, the proxies replace the header, the leftmost is used.
+func forwardedValue(values []string, hops int) string {
+	i := len(values) - hops
+	if i < 0 {
+		i = 0
+	}
+	return values[i]
+}
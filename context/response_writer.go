@@ -293,6 +293,8 @@ func (w *responseWriter) Flush() {
 	// the buffered data may not reach the client until the response
 	// completes.
 	if fl, isFlusher := w.ResponseWriter.(http.Flusher); isFlusher {
+		// flush the status code and the headers too.
+		w.tryWriteHeader()
 		fl.Flush()
 	}
 }
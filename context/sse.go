@@ -0,0 +1,398 @@
+package context
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+)
+
+const (
+	contentEventStreamHeaderValue = "text/event-stream"
+	lastEventIDHeaderKey          = "Last-Event-ID"
+)
+
+var errSSEInvalidField = errors.New("sse: the %s of the event contains a new line")
+
+// SSEEvent is a server-sent event.
+//
+// See https://html.spec.whatwg.org/multipage/server-sent-events.html
+type SSEEvent struct {
+	// ID is the id of the event, the client sends it back
+	// with the "Last-Event-ID" header on reconnection.
+	// It can't contain new lines, "\r" or "\n". Optional.
+	ID string
+	// Event is the name of the event,
+	// empty name means the default "message" event.
+	// It can't contain new lines, "\r" or "\n". Optional.
+	Event string
+	// Data is the payload of the event,
+	// its lines, separated by "\n", "\r\n" or "\r", are sent as multiple "data:" fields.
+	Data []byte
+	// Retry is the time that the client should wait before reconnect, if the connection is lost. Optional.
+	Retry time.Duration
+}
+
+// validate returns an error if the ID or the Event contains a new line.
+func (evt SSEEvent) validate() error {
+	if strings.ContainsAny(evt.ID, "\r\n") {
+		return errSSEInvalidField.Format("id")
+	}
+	if strings.ContainsAny(evt.Event, "\r\n") {
+		return errSSEInvalidField.Format("event")
+	}
+	return nil
+}
+
+// SSEWriter writes server-sent events to the client.
+// It's safe for concurrent use.
+//
+// Look `Context#SSE`.
+type SSEWriter struct {
+	ctx Context
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func newSSEWriter(ctx Context) *SSEWriter {
+	ctx.ContentType(contentEventStreamHeaderValue)
+	ctx.Header(cacheControlHeaderKey, "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	// disable the response buffering of nginx.
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.StatusCode(200)
+	ctx.ResponseWriter().Flush()
+
+	return &SSEWriter{ctx: ctx}
+}
+
+// LastEventID returns the "Last-Event-ID" header that the client sends on reconnection,
+// it's the id of the last event that the client has received.
+func (w *SSEWriter) LastEventID() string {
+	return w.ctx.GetHeader(lastEventIDHeaderKey)
+}
+
+// Send writes an event and flushes it to the client.
+// It returns an error, without sending the event, if its ID or its Event contains a new line,
+// they would be parsed as different fields by the client.
+func (w *SSEWriter) Send(evt SSEEvent) error {
+	if err := evt.validate(); err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Reset()
+	if evt.ID != "" {
+		writeSSEField(&w.buf, "id", []byte(evt.ID))
+	}
+
+	if evt.Event != "" {
+		writeSSEField(&w.buf, "event", []byte(evt.Event))
+	}
+
+	if evt.Retry > 0 {
+		writeSSEField(&w.buf, "retry", []byte(strconv.FormatInt(int64(evt.Retry/time.Millisecond), 10)))
+	}
+
+	forEachSSELine(evt.Data, func(line []byte) {
+		writeSSEField(&w.buf, "data", line)
+	})
+	w.buf.WriteByte('\n')
+
+	return w.flush()
+}
+
+// Retry sends the time that the client should wait before reconnect,
+// if the connection is lost.
+func (w *SSEWriter) Retry(d time.Duration) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Reset()
+	writeSSEField(&w.buf, "retry", []byte(strconv.FormatInt(int64(d/time.Millisecond), 10)))
+	w.buf.WriteByte('\n')
+	return w.flush()
+}
+
+// Comment sends a comment, comments are ignored by the clients,
+// they are used to keep the connection alive.
+// Each line of the "text" is sent as a comment line.
+func (w *SSEWriter) Comment(text string) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Reset()
+	forEachSSELine([]byte(text), func(line []byte) {
+		w.buf.WriteString(": ")
+		w.buf.Write(line)
+		w.buf.WriteByte('\n')
+	})
+	w.buf.WriteByte('\n')
+	return w.flush()
+}
+
+// Heartbeat sends a comment every "interval" in order to keep the connection alive,
+// through proxies that close the idle connections.
+// It stops when the client disconnects or when the returned function is called,
+// the handler should call the returned function before it returns.
+func (w *SSEWriter) Heartbeat(interval time.Duration) (stop func()) {
+	stopChan := make(chan struct{})
+	exited := make(chan struct{})
+	var once sync.Once
+
+	go func() {
+		defer close(exited)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-w.ctx.Done():
+				return
+			case <-stopChan:
+				return
+			case <-ticker.C:
+				if err := w.Comment("heartbeat"); err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	return func() {
+		once.Do(func() { close(stopChan) })
+		// wait for the last heartbeat, the context may be released after stop.
+		<-exited
+	}
+}
+
+func (w *SSEWriter) flush() error {
+	writer := w.ctx.ResponseWriter()
+	if _, err := writer.Write(w.buf.Bytes()); err != nil {
+		return err
+	}
+	writer.Flush()
+	return nil
+}
+
+// forEachSSELine calls the "fn" for each line of the "data",
+// the lines are separated by "\n", "\r\n" or "\r", same as the clients do.
+func forEachSSELine(data []byte, fn func(line []byte)) {
+	for {
+		idx := bytes.IndexAny(data, "\r\n")
+		if idx == -1 {
+			fn(data)
+			return
+		}
+		fn(data[:idx])
+		if data[idx] == '\r' && idx+1 < len(data) && data[idx+1] == '\n' {
+			idx++
+		}
+		data = data[idx+1:]
+	}
+}
+
+func writeSSEField(buf *bytes.Buffer, name string, value []byte) {
+	buf.WriteString(name)
+	buf.WriteString(": ")
+	buf.Write(value)
+	buf.WriteByte('\n')
+}
+
+// SSEBroker fans out the published events to its subscribers, the clients
+// that are connected to its `Handler`.
+//
+// It keeps a history of the latest events, so a client that reconnects with
+// a "Last-Event-ID" header receives the events that has lost.
+type SSEBroker struct {
+	// HistorySize is the number of the latest events that are kept for the reconnected clients.
+	// Defaults to 100.
+	HistorySize int
+	// HeartbeatInterval is the interval which a comment is sent to the clients, zero disables the heartbeats.
+	// Defaults to 15 seconds.
+	HeartbeatInterval time.Duration
+	// Retry is the retry hint that is sent to the clients on connect, zero means no hint.
+	// Defaults to zero.
+	Retry time.Duration
+	// BufferSize is the number of events that can be queued for a subscriber,
+	// a subscriber that can't keep up is disconnected, it will reconnect and resume
+	// from its last received event.
+	// Defaults to 64.
+	BufferSize int
+
+	mu          sync.RWMutex
+	subscribers map[chan SSEEvent]struct{}
+	history     []SSEEvent
+	lastID      uint64
+	closed      bool
+	closeChan   chan struct{}
+}
+
+// NewSSEBroker returns a new SSEBroker with the default settings.
+//
+// Usage:
+// broker := context.NewSSEBroker()
+// app.Get("/events", broker.Handler())
+// [...]
+// broker.Publish(context.SSEEvent{Event: "update", Data: []byte("...")})
+func NewSSEBroker() *SSEBroker {
+	return &SSEBroker{
+		HistorySize:       100,
+		HeartbeatInterval: 15 * time.Second,
+		BufferSize:        64,
+		subscribers:       make(map[chan SSEEvent]struct{}),
+		closeChan:         make(chan struct{}),
+	}
+}
+
+// Publish sends an event to all subscribers,
+// an event without ID takes the next incremental id of the broker.
+// An event whose ID or Event contains a new line is dropped, see `SSEWriter#Send`.
+func (b *SSEBroker) Publish(evt SSEEvent) {
+	if evt.validate() != nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		return
+	}
+
+	if evt.ID == "" {
+		b.lastID++
+		evt.ID = strconv.FormatUint(b.lastID, 10)
+	}
+
+	if b.HistorySize > 0 {
+		if len(b.history) >= b.HistorySize {
+			b.history = append(b.history[:0], b.history[len(b.history)-b.HistorySize+1:]...)
+		}
+		b.history = append(b.history, evt)
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- evt:
+		default:
+			// slow subscriber, disconnect it.
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribers returns the number of the connected clients.
+func (b *SSEBroker) Subscribers() int {
+	b.mu.RLock()
+	n := len(b.subscribers)
+	b.mu.RUnlock()
+	return n
+}
+
+// subscribe registers a new subscriber and returns the
+// events of the history which are published after the "lastEventID".
+func (b *SSEBroker) subscribe(lastEventID string) (chan SSEEvent, []SSEEvent) {
+	bufferSize := b.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = 64
+	}
+	ch := make(chan SSEEvent, bufferSize)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.closed {
+		close(ch)
+		return ch, nil
+	}
+
+	b.subscribers[ch] = struct{}{}
+
+	if lastEventID == "" {
+		return ch, nil
+	}
+
+	for i := len(b.history) - 1; i >= 0; i-- {
+		if b.history[i].ID == lastEventID {
+			missed := make([]SSEEvent, len(b.history)-i-1)
+			copy(missed, b.history[i+1:])
+			return ch, missed
+		}
+	}
+
+	return ch, nil
+}
+
+func (b *SSEBroker) unsubscribe(ch chan SSEEvent) {
+	b.mu.Lock()
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+	b.mu.Unlock()
+}
+
+// Handler returns the handler which subscribes the clients to the broker,
+// i.e app.Get("/events", broker.Handler()).
+func (b *SSEBroker) Handler() Handler {
+	return b.Serve
+}
+
+// Serve subscribes the client to the broker and sends
+// the published events until the client disconnects, see `Context#Done`.
+func (b *SSEBroker) Serve(ctx Context) {
+	w := ctx.SSE()
+	ch, missed := b.subscribe(w.LastEventID())
+	defer b.unsubscribe(ch)
+
+	if b.Retry > 0 {
+		if err := w.Retry(b.Retry); err != nil {
+			return
+		}
+	}
+
+	for _, evt := range missed {
+		if err := w.Send(evt); err != nil {
+			return
+		}
+	}
+
+	if b.HeartbeatInterval > 0 {
+		stop := w.Heartbeat(b.HeartbeatInterval)
+		defer stop()
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.closeChan:
+			return
+		case evt, ok := <-ch:
+			if !ok {
+				return
+			}
+			if err := w.Send(evt); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Close disconnects all subscribers, after Close
+// the published events are dropped and the new clients are disconnected immediately.
+func (b *SSEBroker) Close() {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.closeChan)
+	}
+	b.mu.Unlock()
+}
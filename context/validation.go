@@ -0,0 +1,402 @@
+package context
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/get-ion/ion/core/errors"
+)
+
+var (
+	errValidationRule  = errors.New("validate: unknown rule '%s' of the field %s.%s")
+	errValidationParam = errors.New("validate: invalid parameter '%s' of the rule '%s' of the field %s.%s")
+	errValidationKind  = errors.New("validate: the rule '%s' of the field %s.%s expects a string but it's a %s")
+)
+
+// FieldError is a validation failure of a struct field,
+// see `Validate`.
+type FieldError struct {
+	// Field is the path of the field, its json name
+	// or its struct field name if it has not a json name,
+	// i.e "username", "address.city" or "items[0].quantity".
+	Field string `json:"field"`
+	// Rule is the rule which failed, i.e "required" or "min".
+	Rule string `json:"rule"`
+	// Param is the parameter of the rule, if any, i.e "3" for "min=3".
+	Param string `json:"param,omitempty"`
+	// Message is a human readable description of the failure.
+	Message string `json:"message"`
+}
+
+// Error returns the message of the field error.
+func (e FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors is the list of the field errors that `Validate` and `ReadBody` return,
+// it can be rendered as it's, i.e ctx.StatusCode(errs.StatusCode()); ctx.JSON(errs).
+type ValidationErrors []FieldError
+
+// Error returns the messages of the field errors.
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.Message
+	}
+	return strings.Join(messages, "; ")
+}
+
+// StatusCode returns the 422 Unprocessable Entity status code.
+func (errs ValidationErrors) StatusCode() int {
+	return http.StatusUnprocessableEntity
+}
+
+// validationRule is a rule of a `validate` struct tag, i.e "min=3".
+type validationRule struct {
+	name  string
+	param string
+	// the parsed param of the min, max and len rules.
+	n float64
+}
+
+type validatedField struct {
+	index int
+	name  string
+	rules []validationRule
+}
+
+var (
+	validationCacheMu sync.RWMutex
+	// the fields of the validated struct types.
+	validationCache = make(map[reflect.Type][]validatedField)
+)
+
+// validationRules are the supported rules, the "required" is checked before them.
+var validationRules = map[string]func(v reflect.Value, r validationRule) bool{
+	"min":      func(v reflect.Value, r validationRule) bool { return sizeOf(v) >= r.n },
+	"max":      func(v reflect.Value, r validationRule) bool { return sizeOf(v) <= r.n },
+	"len":      func(v reflect.Value, r validationRule) bool { return sizeOf(v) == r.n },
+	"email":    func(v reflect.Value, r validationRule) bool { return emailRegexp.MatchString(v.String()) },
+	"url":      func(v reflect.Value, r validationRule) bool { return isURL(v.String()) },
+	"alpha":    func(v reflect.Value, r validationRule) bool { return isEvery(v.String(), unicode.IsLetter) },
+	"numeric":  func(v reflect.Value, r validationRule) bool { return isEvery(v.String(), unicode.IsDigit) },
+	"alphanum": func(v reflect.Value, r validationRule) bool { return isEvery(v.String(), isLetterOrDigit) },
+	"oneof": func(v reflect.Value, r validationRule) bool {
+		s := fmt.Sprintf("%v", v.Interface())
+		for _, option := range strings.Fields(r.param) {
+			if s == option {
+				return true
+			}
+		}
+		return false
+	},
+}
+
+var emailRegexp = regexp.MustCompile(`^[^@\s]+@[^@\s]+\.[^@\s]+$`)
+
+func isURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != ""
+}
+
+func isEvery(s string, f func(rune) bool) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !f(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func isLetterOrDigit(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// sizeOf returns the number of the characters of a string, the length of a slice or a map,
+// or the value of a number.
+func sizeOf(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(len([]rune(v.String())))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	}
+	return 0
+}
+
+// Validate validates the fields of a struct, or a pointer to a struct,
+// based on their `validate` tags, the nested structs and slices of structs are validated too.
+// It returns `ValidationErrors` if a field is not valid
+// and a different error if a tag is invalid.
+//
+// The rules of a tag are separated by comma, i.e `validate:"required,min=3,max=20"`:
+// - required, the field should not be the zero value or an empty slice or map
+// - min=n and max=n, the minimum and maximum number of characters of a string,
+// length of a slice or a map, or value of a number
+// - len=n, the exact number of characters of a string, length of a slice or a map, or value of a number
+// - email, a valid email address
+// - url, an absolute url
+// - alpha, numeric and alphanum, a string of letters, digits or both
+// - oneof=a b c, one of the space separated values.
+//
+// The rules are checked on the zero values too, i.e the "min=18" fails on 0 and the "min=1" on an empty string,
+// an optional field should be a pointer, a slice or a map, its rules are not checked when it's nil,
+// unless it's "required".
+//
+// Validate is called by the `Context#ReadBody`.
+func Validate(v interface{}) error {
+	var errs ValidationErrors
+	if err := validateValue(reflect.ValueOf(v), "", &errs); err != nil {
+		return err
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateValue(v reflect.Value, path string, errs *ValidationErrors) error {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		return validateStruct(v, path, errs)
+	case reflect.Slice, reflect.Array:
+		for i, n := 0, v.Len(); i < n; i++ {
+			if err := validateValue(v.Index(i), path+"["+strconv.Itoa(i)+"]", errs); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateStruct(v reflect.Value, path string, errs *ValidationErrors) error {
+	fields, err := validatedFields(v.Type())
+	if err != nil {
+		return err
+	}
+
+	for _, f := range fields {
+		fieldPath := f.name
+		if path != "" {
+			fieldPath = path + "." + f.name
+		}
+
+		fv := v.Field(f.index)
+		if err = validateField(fv, fieldPath, f.rules, errs); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func validateField(v reflect.Value, path string, rules []validationRule, errs *ValidationErrors) error {
+	for _, r := range rules {
+		if r.name == "required" && isZeroValue(v) {
+			errs.add(path, v, r)
+			return nil
+		}
+	}
+
+	if isAbsent(v) {
+		// an optional field which is not set, the rest of the rules are not checked.
+		return nil
+	}
+
+	elem := reflect.Indirect(v)
+	for _, r := range rules {
+		if r.name == "required" {
+			continue
+		}
+
+		if !validationRules[r.name](elem, r) {
+			errs.add(path, elem, r)
+			// one error per field.
+			return nil
+		}
+	}
+
+	return validateValue(v, path, errs)
+}
+
+// isAbsent reports whether the "v" is a nil pointer, interface, slice or map,
+// i.e a field which is missing from the request's data.
+func isAbsent(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Slice, reflect.Map:
+		return v.IsNil()
+	}
+	return false
+}
+
+// isZeroValue reports whether the "v" is the zero value of its type,
+// the empty slices and maps are zero values too.
+func isZeroValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+func (errs *ValidationErrors) add(field string, v reflect.Value, r validationRule) {
+	var unit string
+	switch v.Kind() {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		unit = " items"
+	}
+
+	var message string
+	switch r.name {
+	case "required":
+		message = field + " is required"
+	case "min":
+		message = field + " must be at least " + r.param + unit
+	case "max":
+		message = field + " must be at most " + r.param + unit
+	case "len":
+		message = field + " must be exactly " + r.param + unit
+	case "email":
+		message = field + " must be a valid email address"
+	case "url":
+		message = field + " must be a valid url"
+	case "oneof":
+		message = field + " must be one of: " + r.param
+	default:
+		// alpha, numeric, alphanum.
+		message = field + " must be " + r.name
+	}
+
+	*errs = append(*errs, FieldError{Field: field, Rule: r.name, Param: r.param, Message: message})
+}
+
+// validatedFields returns the fields of a struct type which are validated,
+// the fields with rules and the fields that may contain structs with rules.
+func validatedFields(typ reflect.Type) ([]validatedField, error) {
+	validationCacheMu.RLock()
+	fields, ok := validationCache[typ]
+	validationCacheMu.RUnlock()
+	if ok {
+		return fields, nil
+	}
+
+	for i, n := 0, typ.NumField(); i < n; i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			// unexported.
+			continue
+		}
+
+		tag := field.Tag.Get("validate")
+		if tag == "-" || (tag == "" && !mayContainStructs(field.Type)) {
+			continue
+		}
+
+		rules, err := parseValidationRules(typ, field, tag)
+		if err != nil {
+			return nil, err
+		}
+
+		fields = append(fields, validatedField{index: i, name: jsonFieldName(field), rules: rules})
+	}
+
+	validationCacheMu.Lock()
+	validationCache[typ] = fields
+	validationCacheMu.Unlock()
+	return fields, nil
+}
+
+func mayContainStructs(typ reflect.Type) bool {
+	for {
+		switch typ.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			typ = typ.Elem()
+		case reflect.Struct:
+			return true
+		default:
+			return false
+		}
+	}
+}
+
+func indirectType(typ reflect.Type) reflect.Type {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
+
+func jsonFieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+func parseValidationRules(typ reflect.Type, field reflect.StructField, tag string) ([]validationRule, error) {
+	if tag == "" {
+		return nil, nil
+	}
+
+	parts := strings.Split(tag, ",")
+	rules := make([]validationRule, 0, len(parts))
+	for _, part := range parts {
+		kv := strings.SplitN(strings.TrimSpace(part), "=", 2)
+		r := validationRule{name: kv[0]}
+		if len(kv) == 2 {
+			r.param = kv[1]
+		}
+
+		if _, ok := validationRules[r.name]; !ok && r.name != "required" {
+			return nil, errValidationRule.Format(r.name, typ.Name(), field.Name)
+		}
+
+		switch r.name {
+		case "email", "url", "alpha", "numeric", "alphanum":
+			if kind := indirectType(field.Type).Kind(); kind != reflect.String {
+				return nil, errValidationKind.Format(r.name, typ.Name(), field.Name, kind)
+			}
+		case "min", "max", "len":
+			n, err := strconv.ParseFloat(r.param, 64)
+			if err != nil {
+				return nil, errValidationParam.Format(r.param, r.name, typ.Name(), field.Name)
+			}
+			r.n = n
+		case "oneof":
+			if r.param == "" {
+				return nil, errValidationParam.Format(r.param, r.name, typ.Name(), field.Name)
+			}
+		}
+
+		rules = append(rules, r)
+	}
+
+	return rules, nil
+}
@@ -0,0 +1,328 @@
+package handlerconv
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/errors"
+	"github.com/get-ion/ion/core/router/macro"
+	"github.com/get-ion/ion/core/router/macro/interpreter/ast"
+)
+
+var (
+	errFuncNotFunc        = errors.New("expected a func but got a %T")
+	errFuncTooManyOutputs = errors.New("expected at most two return values, a value and an error, but got %d")
+	errFuncSecondOutput   = errors.New("expected an error as the second return value but got a %s")
+	errFuncContextTwice   = errors.New("the context.Context input argument is declared more than once")
+	errFuncBodyTwice      = errors.New("the request body input argument is declared more than once, found %s and %s")
+	errFuncParamsMismatch = errors.New("the path parameters input arguments are %d but the path %s has %d parameters")
+	errFuncParamType      = errors.New("the input argument of type %s can't receive the '%s' path parameter of the %s")
+	errFuncUnsupportedArg = errors.New("unsupported input argument of type %s")
+)
+
+var (
+	contextTyp = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorTyp   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// StatusCoder can be implemented by the errors which are returned by
+// the funcs of the `FromFunc` in order to send a specific http status code,
+// the default status code of an error is the 500 internal server error.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// funcArgKind is the kind of an input argument of a func.
+type funcArgKind uint8
+
+const (
+	funcArgContext funcArgKind = iota
+	funcArgParam
+	funcArgBody
+)
+
+type funcArg struct {
+	kind funcArgKind
+	typ  reflect.Type
+	// the name of the path parameter, if param.
+	paramName string
+}
+
+// FromFunc converts a func with any input arguments and return values to a context.Handler,
+// it's validated once, it returns an error if the "fn" is not a valid func.
+//
+// The input arguments can be:
+// - a context.Context, it receives the request's context
+// - int, uint or string values, they receive the path parameters of the "tmpl", by their order,
+// an int can receive only an int path parameter, i.e {id:int}
+// - one struct, map or slice value (or a pointer to it), which receives the request body,
+// it's decoded based on the request's content type and it's validated, see `Context#ReadBody`,
+// the validation errors are sent with the 422 status code.
+//
+// The return values can be:
+// - none
+// - a value, which is rendered based on the request's "Accept" header, as json or xml, defaults to json,
+// see `Context#Negotiate`, a string is rendered as text and a []byte as binary
+// - an error, a non-nil error sends a status code, see `StatusCoder`,
+// the error message is sent to the client on status codes lower than 500,
+// otherwise it's logged and the error code handler is fired.
+// - a value and an error.
+//
+// Usage:
+// h, err := FromFunc(func(ctx context.Context, id int, body UpdateUserRequest) (User, error) {...}, tmpl)
+//
+// The Party#HandleFunc is the easy way to register routes with a func.
+func FromFunc(fn interface{}, tmpl macro.Template) (context.Handler, error) {
+	fnValue := reflect.ValueOf(fn)
+	if fnValue.Kind() != reflect.Func {
+		return nil, errFuncNotFunc.Format(fn)
+	}
+
+	fnTyp := fnValue.Type()
+	args, err := funcArgs(fnTyp, tmpl)
+	if err != nil {
+		return nil, err
+	}
+
+	numOut := fnTyp.NumOut()
+	if numOut > 2 {
+		return nil, errFuncTooManyOutputs.Format(numOut)
+	}
+
+	if numOut == 2 && fnTyp.Out(1) != errorTyp {
+		return nil, errFuncSecondOutput.Format(fnTyp.Out(1))
+	}
+
+	return func(ctx context.Context) {
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			v, ok := arg.value(ctx)
+			if !ok {
+				// the response is already written.
+				return
+			}
+			in[i] = v
+		}
+
+		dispatchFuncResults(ctx, fnValue.Call(in))
+	}, nil
+}
+
+func funcArgs(fnTyp reflect.Type, tmpl macro.Template) ([]funcArg, error) {
+	var (
+		args      = make([]funcArg, 0, fnTyp.NumIn())
+		params    = tmpl.Params
+		hasCtx    bool
+		bodyTyp   reflect.Type
+		numParams int
+	)
+
+	for i, n := 0, fnTyp.NumIn(); i < n; i++ {
+		typ := fnTyp.In(i)
+
+		if typ == contextTyp {
+			if hasCtx {
+				return nil, errFuncContextTwice
+			}
+			hasCtx = true
+			args = append(args, funcArg{kind: funcArgContext, typ: typ})
+			continue
+		}
+
+		if isParamKind(typ.Kind()) {
+			if numParams < len(params) {
+				p := params[numParams]
+				if !paramTypeCompatible(typ, p.Type) {
+					return nil, errFuncParamType.Format(typ, p.Name, tmpl.Src)
+				}
+				args = append(args, funcArg{kind: funcArgParam, typ: typ, paramName: p.Name})
+			}
+			numParams++
+			continue
+		}
+
+		if isBodyKind(typ) {
+			if bodyTyp != nil {
+				return nil, errFuncBodyTwice.Format(bodyTyp, typ)
+			}
+			bodyTyp = typ
+			args = append(args, funcArg{kind: funcArgBody, typ: typ})
+			continue
+		}
+
+		return nil, errFuncUnsupportedArg.Format(typ)
+	}
+
+	if numParams != len(params) {
+		return nil, errFuncParamsMismatch.Format(numParams, tmpl.Src, len(params))
+	}
+
+	return args, nil
+}
+
+func isParamKind(kind reflect.Kind) bool {
+	return kind == reflect.String || isIntKind(kind) || isUintKind(kind)
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+func isUintKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return false
+}
+
+func isBodyKind(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Slice:
+		return true
+	}
+	return false
+}
+
+// paramTypeCompatible reports whether an input argument of type "typ"
+// can receive the values of a path parameter of the macro type "paramTyp".
+func paramTypeCompatible(typ reflect.Type, paramTyp ast.ParamType) bool {
+	if typ.Kind() == reflect.String {
+		// all values are strings.
+		return true
+	}
+	return paramTyp == ast.ParamTypeInt
+}
+
+func (arg funcArg) value(ctx context.Context) (reflect.Value, bool) {
+	switch arg.kind {
+	case funcArgContext:
+		return reflect.ValueOf(ctx), true
+	case funcArgParam:
+		return paramValue(ctx, arg.typ, arg.paramName)
+	default:
+		return bodyValue(ctx, arg.typ)
+	}
+}
+
+func paramValue(ctx context.Context, typ reflect.Type, name string) (reflect.Value, bool) {
+	v := reflect.New(typ).Elem()
+	s := ctx.Params().Get(name)
+
+	switch kind := typ.Kind(); {
+	case kind == reflect.String:
+		v.SetString(s)
+	case isIntKind(kind):
+		n, err := strconv.ParseInt(s, 10, typ.Bits())
+		if err != nil {
+			// the int macro accepts only digits, so it's an overflow.
+			ctx.NotFound()
+			return v, false
+		}
+		v.SetInt(n)
+	case isUintKind(kind):
+		n, err := strconv.ParseUint(s, 10, typ.Bits())
+		if err != nil {
+			ctx.NotFound()
+			return v, false
+		}
+		v.SetUint(n)
+	}
+
+	return v, true
+}
+
+func bodyValue(ctx context.Context, typ reflect.Type) (reflect.Value, bool) {
+	isPtr := typ.Kind() == reflect.Ptr
+	if isPtr {
+		typ = typ.Elem()
+	}
+
+	ptr := reflect.New(typ)
+	if err := ctx.ReadBody(ptr.Interface()); err != nil {
+		if errs, ok := err.(context.ValidationErrors); ok {
+			ctx.StatusCode(errs.StatusCode())
+			if _, err = ctx.Negotiate(errs, "application/json", "application/xml"); err != nil {
+				// i.e the client accepts only html, keep the status code of the validation.
+				ctx.StatusCode(errs.StatusCode())
+				ctx.JSON(errs)
+			}
+			return ptr, false
+		}
+
+		ctx.StatusCode(http.StatusBadRequest)
+		ctx.WriteString(err.Error())
+		return ptr, false
+	}
+
+	if isPtr {
+		return ptr, true
+	}
+	return ptr.Elem(), true
+}
+
+func dispatchFuncResults(ctx context.Context, out []reflect.Value) {
+	if len(out) == 0 {
+		return
+	}
+
+	last := out[len(out)-1]
+	if last.Type() == errorTyp {
+		if !last.IsNil() {
+			dispatchFuncError(ctx, last.Interface().(error))
+			return
+		}
+		out = out[:len(out)-1]
+	}
+
+	if len(out) == 0 {
+		return
+	}
+
+	dispatchFuncValue(ctx, out[0])
+}
+
+func dispatchFuncError(ctx context.Context, err error) {
+	statusCode := http.StatusInternalServerError
+	if coder, ok := err.(StatusCoder); ok {
+		statusCode = coder.StatusCode()
+	}
+
+	ctx.StatusCode(statusCode)
+	if statusCode < http.StatusInternalServerError {
+		ctx.WriteString(err.Error())
+		return
+	}
+
+	// don't send the internal errors to the client,
+	// the error code handler will be fired instead.
+	ctx.Application().Logger().Warnf("%s: %v", ctx.Path(), err)
+}
+
+func dispatchFuncValue(ctx context.Context, v reflect.Value) {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if v.IsNil() {
+			return
+		}
+	}
+
+	switch value := v.Interface().(type) {
+	case string:
+		ctx.Text(value)
+	case []byte:
+		ctx.Binary(value)
+	default:
+		ctx.Negotiate(value, "application/json", "application/xml")
+	}
+}
@@ -0,0 +1,105 @@
+// black-box testing
+package handlerconv_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/handlerconv"
+	"github.com/get-ion/ion/core/router/macro"
+	"github.com/get-ion/ion/httptest"
+)
+
+type testUser struct {
+	ID       int    `json:"id" xml:"id"`
+	Username string `json:"username" xml:"username"`
+}
+
+type testSignup struct {
+	Username string `json:"username" validate:"required,min=3"`
+}
+
+type testNotFoundError struct{}
+
+func (testNotFoundError) Error() string   { return "user not found" }
+func (testNotFoundError) StatusCode() int { return ion.StatusNotFound }
+
+func TestFromFunc(t *testing.T) {
+	app := ion.New()
+
+	app.HandleFunc("GET", "/users/{id:int}", func(id int) (testUser, error) {
+		if id != 42 {
+			return testUser{}, testNotFoundError{}
+		}
+		return testUser{ID: id, Username: "ion"}, nil
+	})
+
+	app.HandleFunc("PUT", "/users/{id:int}/{role:string}", func(ctx context.Context, id int64, role string, user *testUser) testUser {
+		user.ID = int(id)
+		user.Username += ":" + role + ":" + ctx.Method()
+		return *user
+	})
+
+	app.HandleFunc("GET", "/text/{name}", func(name string) string {
+		return "Hello " + name
+	})
+
+	app.HandleFunc("POST", "/signup", func(s testSignup) string {
+		return "Welcome " + s.Username
+	})
+
+	app.HandleFunc("GET", "/internal", func() error {
+		return errors.New("internal")
+	})
+
+	e := httptest.New(t, app)
+
+	e.GET("/users/42").Expect().Status(ion.StatusOK).
+		JSON().Object().Equal(testUser{ID: 42, Username: "ion"})
+	e.GET("/users/42").WithHeader("Accept", "application/xml").Expect().Status(ion.StatusOK).
+		Body().Contains("<username>ion</username>")
+	e.GET("/users/1").Expect().Status(ion.StatusNotFound).Body().Equal("user not found")
+
+	e.PUT("/users/7/admin").WithJSON(testUser{Username: "gopher"}).Expect().Status(ion.StatusOK).
+		JSON().Object().Equal(testUser{ID: 7, Username: "gopher:admin:PUT"})
+	e.PUT("/users/7/admin").WithBytes([]byte("{")).Expect().Status(ion.StatusBadRequest)
+
+	e.GET("/text/ion").Expect().Status(ion.StatusOK).Body().Equal("Hello ion")
+	e.POST("/signup").WithJSON(testSignup{Username: "gopher"}).Expect().Status(ion.StatusOK).
+		Body().Equal("Welcome gopher")
+	e.POST("/signup").WithJSON(testSignup{Username: "go"}).Expect().Status(ion.StatusUnprocessableEntity).
+		JSON().Array().Element(0).Object().ValueEqual("field", "username")
+	// the validation errors are sent as json if the client accepts neither json nor xml.
+	e.POST("/signup").WithHeader("Accept", "text/html").WithJSON(testSignup{Username: "go"}).
+		Expect().Status(ion.StatusUnprocessableEntity).
+		JSON().Array().Element(0).Object().ValueEqual("field", "username")
+	e.GET("/internal").Expect().Status(ion.StatusInternalServerError).Body().NotContains("internal")
+}
+
+func TestFromFuncInvalid(t *testing.T) {
+	tmpl, err := macro.Parse("/users/{id:int}/{name:string}", macro.NewMap())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []interface{}{
+		"not a func",
+		func(id int) {},                          // missing path parameter.
+		func(name int, id int) {},                // the "name" parameter is a string.
+		func(id int, name string, b bool) {},     // unsupported argument.
+		func(a, b testUser, id int, s string) {}, // two bodies.
+		func(id int, name string) (int, int) { return 0, 0 },
+	}
+
+	for i, fn := range tests {
+		if _, err := handlerconv.FromFunc(fn, *tmpl); err == nil {
+			t.Fatalf("[%d] expected an error for %T", i, fn)
+		}
+	}
+
+	if _, err := handlerconv.FromFunc(func(id int, name string) (testUser, error) { return testUser{}, nil }, *tmpl); err != nil {
+		t.Fatal(err)
+	}
+}
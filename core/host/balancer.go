@@ -0,0 +1,572 @@
+package host
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+	"github.com/get-ion/ion/core/netutil"
+)
+
+var errNoUpstream = errors.New("load balancer: no available upstream")
+
+// BreakerState is the state of the circuit breaker of an upstream, see `LoadBalancer#BreakerThreshold`.
+type BreakerState uint32
+
+const (
+	// BreakerClosed is the state of the circuit breaker which lets the requests pass.
+	BreakerClosed BreakerState = iota
+	// BreakerOpen is the state of the circuit breaker which rejects the requests,
+	// after too many consecutive failures.
+	BreakerOpen
+	// BreakerHalfOpen is the state of the circuit breaker which lets one trial request pass,
+	// after the breaker's timeout, its result closes or opens the breaker again.
+	BreakerHalfOpen
+)
+
+// String returns the name of the breaker state, i.e "open".
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Upstream is a target server of a `LoadBalancer`.
+type Upstream struct {
+	// URL is the target of the upstream, its scheme, host, and base path,
+	// same as the target of the `NewReverseProxy`.
+	URL *url.URL
+	// Weight is the relative number of the requests that the upstream receives,
+	// it's used by the Weighted, LeastConnections and ConsistentHash strategies.
+	Weight int
+
+	// the number of the active requests, atomic.
+	active int64
+
+	mu sync.Mutex
+	// the result of the last active health check.
+	unhealthy bool
+	// the passive ejection.
+	fails        int
+	failsStart   time.Time
+	ejectedUntil time.Time
+	// the circuit breaker.
+	breaker          BreakerState
+	consecutiveFails int
+	openedAt         time.Time
+}
+
+// ActiveRequests returns the number of the requests that the upstream serves right now.
+func (u *Upstream) ActiveRequests() int64 {
+	return atomic.LoadInt64(&u.active)
+}
+
+// Healthy reports whether the upstream passed its last active health check
+// and it's not ejected because of its failures.
+func (u *Upstream) Healthy() bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return !u.unhealthy && !time.Now().Before(u.ejectedUntil)
+}
+
+// BreakerState returns the state of the upstream's circuit breaker.
+func (u *Upstream) BreakerState() BreakerState {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.breaker
+}
+
+// available reports whether the upstream can receive a request.
+func (u *Upstream) available(lb *LoadBalancer, now time.Time) bool {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if u.unhealthy || now.Before(u.ejectedUntil) {
+		return false
+	}
+
+	switch u.breaker {
+	case BreakerOpen:
+		return now.Sub(u.openedAt) >= lb.BreakerTimeout
+	case BreakerHalfOpen:
+		// the trial request is not completed yet.
+		return false
+	}
+	return true
+}
+
+// acquire marks the upstream as selected for a request,
+// it returns false if it became unavailable after the selection.
+func (u *Upstream) acquire(lb *LoadBalancer, now time.Time) bool {
+	u.mu.Lock()
+	if u.breaker == BreakerOpen {
+		if now.Sub(u.openedAt) < lb.BreakerTimeout {
+			u.mu.Unlock()
+			return false
+		}
+		// this is the trial request.
+		u.breaker = BreakerHalfOpen
+	} else if u.breaker == BreakerHalfOpen {
+		u.mu.Unlock()
+		return false
+	}
+	u.mu.Unlock()
+
+	atomic.AddInt64(&u.active, 1)
+	return true
+}
+
+// report records the result of a request to the upstream,
+// for the passive ejection and the circuit breaker.
+func (u *Upstream) report(lb *LoadBalancer, failed bool, now time.Time) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	if !failed {
+		u.consecutiveFails = 0
+		u.breaker = BreakerClosed
+		return
+	}
+
+	if lb.MaxFails > 0 {
+		if now.Sub(u.failsStart) > lb.FailTimeout {
+			u.fails = 0
+			u.failsStart = now
+		}
+		u.fails++
+		if u.fails >= lb.MaxFails {
+			u.ejectedUntil = now.Add(lb.FailTimeout)
+			u.fails = 0
+		}
+	}
+
+	u.consecutiveFails++
+	if u.breaker == BreakerHalfOpen ||
+		(lb.BreakerThreshold > 0 && u.consecutiveFails >= lb.BreakerThreshold) {
+		u.breaker = BreakerOpen
+		u.openedAt = now
+	}
+}
+
+// cancel records that a request to the upstream is canceled by its client,
+// if it's the trial request of the circuit breaker the next request is the trial one.
+func (u *Upstream) cancel() {
+	u.mu.Lock()
+	if u.breaker == BreakerHalfOpen {
+		u.breaker = BreakerOpen
+	}
+	u.mu.Unlock()
+}
+
+func (u *Upstream) setHealthy(healthy bool) {
+	u.mu.Lock()
+	u.unhealthy = !healthy
+	u.mu.Unlock()
+}
+
+// HealthCheck is the active health check of the upstreams of a `LoadBalancer`.
+type HealthCheck struct {
+	// Path is the path of the upstreams which is requested, i.e "/health",
+	// the upstreams that respond with a status code lower than 400 are healthy.
+	// Empty Path disables the active health checks.
+	Path string
+	// Interval is the time between the checks.
+	// Defaults to 10 seconds.
+	Interval time.Duration
+	// Timeout is the timeout of a check's request.
+	// Defaults to 2 seconds.
+	Timeout time.Duration
+}
+
+// LoadBalancer is a reverse proxy which distributes the requests to its upstreams.
+// The upstreams that fail the active health checks or fail too many requests
+// don't receive requests, until they're healthy again.
+// The requests which fail because of an upstream's connection error or a 502, 503 or 504 status code
+// can be retried to another upstream.
+//
+// It's an http.Handler, it can be served by a `Supervisor`, see `NewLoadBalancerProxy`,
+// or by the routes of a Party, i.e:
+// app.Any("/api/{p:path}", handlerconv.FromStd(lb)).
+//
+// It should be created by the `NewLoadBalancer`
+// and its fields should be set before the first request.
+type LoadBalancer struct {
+	// Strategy selects the upstream of each request.
+	// Defaults to the RoundRobin.
+	Strategy BalancerStrategy
+	// HealthCheck is the active health check of the upstreams.
+	HealthCheck HealthCheck
+	// MaxFails is the number of the failed requests, connection errors or 5xx status codes,
+	// in the FailTimeout, which eject an upstream for the FailTimeout.
+	// Zero disables the passive ejection.
+	MaxFails int
+	// FailTimeout is the period of the MaxFails and the time that an upstream is ejected.
+	// Defaults to 10 seconds.
+	FailTimeout time.Duration
+	// Retries is the number of the times that a request with an idempotent method and no body
+	// is retried to another upstream, if its upstream fails.
+	// Defaults to 0.
+	Retries int
+	// BreakerThreshold is the number of the consecutive failed requests of an upstream
+	// which open its circuit breaker, the upstream doesn't receive requests for the BreakerTimeout,
+	// then one trial request closes or opens the breaker again.
+	// Zero disables the circuit breakers.
+	BreakerThreshold int
+	// BreakerTimeout is the time that a circuit breaker is open before a trial request.
+	// Defaults to 30 seconds.
+	BreakerTimeout time.Duration
+	// Transport is the transport of the requests to the upstreams.
+	// Defaults to the http.DefaultTransport,
+	// the loopback upstreams skip the tls verification, same as the `NewReverseProxy`.
+	Transport http.RoundTripper
+
+	mu        sync.RWMutex
+	upstreams []*Upstream
+
+	once      sync.Once
+	proxy     *httputil.ReverseProxy
+	closeOnce sync.Once
+	closeChan chan struct{}
+
+	// the clock of the ejections and the breakers, and the hook
+	// which is called after each round of the health checks, they're replaced by the tests.
+	now           func() time.Time
+	healthChecked func()
+}
+
+// NewLoadBalancer returns a new load balancer to the "targets", with equal weights.
+// More upstreams can be added by the `Add`.
+//
+// Usage:
+// lb := NewLoadBalancer(target1, target2)
+// lb.Strategy = LeastConnections()
+// lb.HealthCheck = HealthCheck{Path: "/health", Interval: 5 * time.Second}
+// lb.MaxFails = 3
+// lb.Retries = 1
+func NewLoadBalancer(targets ...*url.URL) *LoadBalancer {
+	lb := &LoadBalancer{closeChan: make(chan struct{}), now: time.Now}
+	for _, target := range targets {
+		lb.Add(target, 1)
+	}
+	return lb
+}
+
+// Add adds an upstream to the load balancer and returns it,
+// the "weight" should be positive, otherwise it's 1.
+func (lb *LoadBalancer) Add(target *url.URL, weight int) *Upstream {
+	if weight <= 0 {
+		weight = 1
+	}
+
+	u := &Upstream{URL: target, Weight: weight}
+	lb.mu.Lock()
+	lb.upstreams = append(lb.upstreams, u)
+	lb.mu.Unlock()
+	return u
+}
+
+// Upstreams returns the upstreams of the load balancer.
+func (lb *LoadBalancer) Upstreams() []*Upstream {
+	lb.mu.RLock()
+	upstreams := make([]*Upstream, len(lb.upstreams))
+	copy(upstreams, lb.upstreams)
+	lb.mu.RUnlock()
+	return upstreams
+}
+
+// Close stops the active health checks.
+func (lb *LoadBalancer) Close() {
+	lb.closeOnce.Do(func() {
+		close(lb.closeChan)
+	})
+}
+
+func (lb *LoadBalancer) start() {
+	if lb.Strategy == nil {
+		lb.Strategy = RoundRobin()
+	}
+	if lb.FailTimeout <= 0 {
+		lb.FailTimeout = 10 * time.Second
+	}
+	if lb.BreakerTimeout <= 0 {
+		lb.BreakerTimeout = 30 * time.Second
+	}
+	if lb.HealthCheck.Interval <= 0 {
+		lb.HealthCheck.Interval = 10 * time.Second
+	}
+	if lb.HealthCheck.Timeout <= 0 {
+		lb.HealthCheck.Timeout = 2 * time.Second
+	}
+
+	lb.proxy = &httputil.ReverseProxy{
+		Director: func(req *http.Request) {
+			setForwardedHeaders(req.Header, originOf(req))
+
+			if _, ok := req.Header["User-Agent"]; !ok {
+				// explicitly disable User-Agent so it's not set to default value
+				req.Header.Set("User-Agent", "")
+			}
+		},
+		Transport: balancerTransport{lb},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			if e, ok := err.(errors.Error); ok && e.Equal(errNoUpstream) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+
+	if lb.HealthCheck.Path != "" {
+		go lb.healthChecks()
+	}
+}
+
+// ServeHTTP proxies the request to one of the upstreams.
+func (lb *LoadBalancer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	lb.once.Do(lb.start)
+
+	if isUpgradeRequest(r) {
+		lb.serveUpgrade(w, r)
+		return
+	}
+
+	lb.proxy.ServeHTTP(w, r)
+}
+
+// serveUpgrade tunnels an upgrade request, i.e a websocket, to one of the upstreams,
+// it's not retried.
+func (lb *LoadBalancer) serveUpgrade(w http.ResponseWriter, r *http.Request) {
+	u := lb.next(r, nil)
+	if u == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	defer atomic.AddInt64(&u.active, -1)
+
+	p := NewReverseProxy(u.URL)
+	p.Transport = lb.transport(u)
+	err := p.serveUpgrade(w, r)
+	u.report(lb, err != nil, lb.now())
+}
+
+// transport returns the transport of the requests to the upstream "u".
+func (lb *LoadBalancer) transport(u *Upstream) http.RoundTripper {
+	if lb.Transport != nil {
+		return lb.Transport
+	}
+	if netutil.IsLoopbackHost(u.URL.Host) {
+		return loopbackTransport
+	}
+	return http.DefaultTransport
+}
+
+// next selects an available upstream which is not "tried" already,
+// it returns nil if there is no one.
+func (lb *LoadBalancer) next(r *http.Request, tried map[*Upstream]bool) *Upstream {
+	now := lb.now()
+
+	var candidates []*Upstream
+	lb.mu.RLock()
+	for _, u := range lb.upstreams {
+		if !tried[u] && u.available(lb, now) {
+			candidates = append(candidates, u)
+		}
+	}
+	lb.mu.RUnlock()
+
+	for len(candidates) > 0 {
+		u := lb.Strategy.Select(r, candidates)
+		if u == nil {
+			return nil
+		}
+
+		if u.acquire(lb, now) {
+			return u
+		}
+
+		// it became unavailable, i.e a concurrent request is the breaker's trial request.
+		for i, c := range candidates {
+			if c == u {
+				candidates = append(candidates[:i], candidates[i+1:]...)
+				break
+			}
+		}
+	}
+
+	return nil
+}
+
+// healthChecks runs the active health checks until the load balancer is closed.
+func (lb *LoadBalancer) healthChecks() {
+	client := &http.Client{
+		Timeout: lb.HealthCheck.Timeout,
+		// a redirect is a healthy response.
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+
+	ticker := time.NewTicker(lb.HealthCheck.Interval)
+	defer ticker.Stop()
+
+	for {
+		for _, u := range lb.Upstreams() {
+			client.Transport = lb.transport(u)
+			u.setHealthy(lb.check(client, u))
+		}
+		if lb.healthChecked != nil {
+			lb.healthChecked()
+		}
+
+		select {
+		case <-lb.closeChan:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (lb *LoadBalancer) check(client *http.Client, u *Upstream) bool {
+	target := *u.URL
+	target.Path = singleJoiningSlash(target.Path, lb.HealthCheck.Path)
+
+	resp, err := client.Get(target.String())
+	if err != nil {
+		return false
+	}
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode < http.StatusBadRequest
+}
+
+// balancerTransport sends the requests of the load balancer's reverse proxy to its upstreams.
+type balancerTransport struct {
+	lb *LoadBalancer
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func isRetryStatus(statusCode int) bool {
+	switch statusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func (t balancerTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	lb := t.lb
+
+	attempts := 1
+	if isIdempotent(req.Method) && (req.Body == nil || req.Body == http.NoBody) {
+		attempts += lb.Retries
+	}
+
+	var (
+		tried    = make(map[*Upstream]bool)
+		lastResp *http.Response
+		lastErr  error = errNoUpstream
+	)
+
+	for i := 0; i < attempts; i++ {
+		u := lb.next(req, tried)
+		if u == nil {
+			break
+		}
+		tried[u] = true
+
+		if lastResp != nil {
+			lastResp.Body.Close()
+			lastResp = nil
+		}
+
+		outreq := new(http.Request)
+		*outreq = *req
+		outURL := *req.URL
+		outreq.URL = &outURL
+		rewriteRequestURL(outreq, u.URL)
+
+		resp, err := lb.transport(u).RoundTrip(outreq)
+		if req.Context().Err() != nil {
+			// the client is gone, it's not a failure of the upstream.
+			atomic.AddInt64(&u.active, -1)
+			u.cancel()
+			if err == nil {
+				resp.Body.Close()
+			}
+			return nil, req.Context().Err()
+		}
+
+		failed := err != nil || resp.StatusCode >= http.StatusInternalServerError
+		u.report(lb, failed, lb.now())
+
+		if err != nil {
+			atomic.AddInt64(&u.active, -1)
+			lastErr = err
+			continue
+		}
+
+		// the upstream is active until the response body is read.
+		resp.Body = &upstreamBody{ReadCloser: resp.Body, u: u}
+		if !isRetryStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		lastResp = resp
+	}
+
+	if lastResp != nil {
+		return lastResp, nil
+	}
+	return nil, lastErr
+}
+
+// upstreamBody is the body of an upstream's response,
+// it decrements the upstream's active requests when it's closed.
+type upstreamBody struct {
+	io.ReadCloser
+	u      *Upstream
+	closed int32
+}
+
+func (b *upstreamBody) Close() error {
+	if atomic.CompareAndSwapInt32(&b.closed, 0, 1) {
+		atomic.AddInt64(&b.u.active, -1)
+	}
+	return b.ReadCloser.Close()
+}
+
+// NewLoadBalancerProxy returns a new host (server supervisor) which
+// distributes all requests to the upstreams of the load balancer "lb",
+// its active health checks are stopped on the host's shutdown.
+//
+// Usage:
+// lb := NewLoadBalancer(target1, target2)
+// proxy := NewLoadBalancerProxy("mydomain.com:80", lb)
+// proxy.ListenAndServe() // use of proxy.Shutdown to close the proxy server.
+func NewLoadBalancerProxy(hostAddr string, lb *LoadBalancer) *Supervisor {
+	proxy := New(&http.Server{
+		Addr:    hostAddr,
+		Handler: lb,
+	})
+	proxy.RegisterOnShutdown(lb.Close)
+
+	return proxy
+}
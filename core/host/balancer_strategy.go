@@ -0,0 +1,134 @@
+package host
+
+import (
+	"hash/fnv"
+	"math"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// BalancerStrategy selects the upstream of a request, see `LoadBalancer#Strategy`.
+type BalancerStrategy interface {
+	// Select returns one of the "upstreams", they are the available upstreams
+	// that are not tried for the request already, there is at least one.
+	Select(r *http.Request, upstreams []*Upstream) *Upstream
+}
+
+// BalancerStrategyFunc is an adapter which converts a func to a `BalancerStrategy`.
+type BalancerStrategyFunc func(r *http.Request, upstreams []*Upstream) *Upstream
+
+// Select calls the func.
+func (f BalancerStrategyFunc) Select(r *http.Request, upstreams []*Upstream) *Upstream {
+	return f(r, upstreams)
+}
+
+// RoundRobin returns a strategy which selects the upstreams in turn.
+func RoundRobin() BalancerStrategy {
+	var counter uint64
+	return BalancerStrategyFunc(func(r *http.Request, upstreams []*Upstream) *Upstream {
+		n := atomic.AddUint64(&counter, 1) - 1
+		return upstreams[n%uint64(len(upstreams))]
+	})
+}
+
+// LeastConnections returns a strategy which selects the upstream with the fewest active requests
+// in proportion to its weight, the ties are selected in turn.
+func LeastConnections() BalancerStrategy {
+	var counter uint64
+	return BalancerStrategyFunc(func(r *http.Request, upstreams []*Upstream) *Upstream {
+		start := int(atomic.AddUint64(&counter, 1) % uint64(len(upstreams)))
+
+		var (
+			best     *Upstream
+			bestLoad float64
+		)
+		for i := range upstreams {
+			u := upstreams[(start+i)%len(upstreams)]
+			load := float64(u.ActiveRequests()) / float64(u.Weight)
+			if best == nil || load < bestLoad {
+				best, bestLoad = u, load
+			}
+		}
+		return best
+	})
+}
+
+// Weighted returns a strategy which selects the upstreams in turn in proportion to their weights,
+// the selections of an upstream are spread, i.e the weights 5, 1, 1 select a, a, b, a, c, a, a.
+func Weighted() BalancerStrategy {
+	var (
+		mu sync.Mutex
+		// the current weights of the smooth weighted round-robin.
+		current = make(map[*Upstream]int)
+	)
+
+	return BalancerStrategyFunc(func(r *http.Request, upstreams []*Upstream) *Upstream {
+		mu.Lock()
+		defer mu.Unlock()
+
+		var (
+			best  *Upstream
+			total int
+		)
+		for _, u := range upstreams {
+			current[u] += u.Weight
+			total += u.Weight
+			if best == nil || current[u] > current[best] {
+				best = u
+			}
+		}
+		current[best] -= total
+		return best
+	})
+}
+
+// ConsistentHash returns a strategy which selects the same upstream for the same value
+// of the request "header", i.e a session or a user id, or for the same client ip if the header is missing.
+// When an upstream is added or removed only its share of the values move to another upstream.
+//
+// It uses the weighted rendezvous hashing.
+func ConsistentHash(header string) BalancerStrategy {
+	return BalancerStrategyFunc(func(r *http.Request, upstreams []*Upstream) *Upstream {
+		key := r.Header.Get(header)
+		if key == "" {
+			key = r.RemoteAddr
+			if host, _, err := net.SplitHostPort(key); err == nil {
+				key = host
+			}
+		}
+
+		var (
+			best      *Upstream
+			bestScore float64
+		)
+		for _, u := range upstreams {
+			if score := rendezvousScore(key, u); best == nil || score > bestScore {
+				best, bestScore = u, score
+			}
+		}
+		return best
+	})
+}
+
+// rendezvousScore returns the score of the upstream "u" for the "key",
+// the upstream with the highest score is selected.
+func rendezvousScore(key string, u *Upstream) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(u.URL.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+
+	// the fnv's bits are mixed, so the similar keys have different scores.
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+
+	// a uniform value in (0, 1).
+	f := (float64(x>>11) + 0.5) / (1 << 53)
+	return float64(u.Weight) / -math.Log(f)
+}
@@ -0,0 +1,280 @@
+// white-box testing
+
+package host
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestUpstreams(weights ...int) []*Upstream {
+	upstreams := make([]*Upstream, len(weights))
+	for i, w := range weights {
+		u, _ := url.Parse("http://upstream" + strconv.Itoa(i))
+		upstreams[i] = &Upstream{URL: u, Weight: w}
+	}
+	return upstreams
+}
+
+func selections(s BalancerStrategy, r *http.Request, upstreams []*Upstream, n int) string {
+	var names []string
+	for i := 0; i < n; i++ {
+		names = append(names, strings.TrimPrefix(s.Select(r, upstreams).URL.Host, "upstream"))
+	}
+	return strings.Join(names, ",")
+}
+
+func TestBalancerStrategies(t *testing.T) {
+	r := httptest.NewRequest("GET", "/", nil)
+
+	upstreams := newTestUpstreams(1, 1, 1)
+	if expected, got := "0,1,2,0,1,2", selections(RoundRobin(), r, upstreams, 6); expected != got {
+		t.Fatalf("round robin: expected %s but got %s", expected, got)
+	}
+
+	upstreams = newTestUpstreams(5, 1, 1)
+	if expected, got := "0,0,1,0,2,0,0", selections(Weighted(), r, upstreams, 7); expected != got {
+		t.Fatalf("weighted: expected %s but got %s", expected, got)
+	}
+
+	upstreams = newTestUpstreams(1, 1, 1)
+	upstreams[0].active = 3
+	upstreams[1].active = 1
+	upstreams[2].active = 2
+	if expected, got := "1,1,1", selections(LeastConnections(), r, upstreams, 3); expected != got {
+		t.Fatalf("least connections: expected %s but got %s", expected, got)
+	}
+
+	// the weight 2 doubles the capacity of the upstream 2.
+	upstreams = newTestUpstreams(1, 1, 2)
+	upstreams[0].active = 2
+	upstreams[1].active = 2
+	upstreams[2].active = 3
+	if expected, got := "2", selections(LeastConnections(), r, upstreams, 1); expected != got {
+		t.Fatalf("weighted least connections: expected %s but got %s", expected, got)
+	}
+
+	hash := ConsistentHash("X-Session")
+	upstreams = newTestUpstreams(1, 1, 1, 1)
+	moved, selected := 0, make(map[string]int)
+	for i := 0; i < 400; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-Session", "session"+strconv.Itoa(i))
+
+		first := selections(hash, r, upstreams, 1)
+		if again := selections(hash, r, upstreams, 3); again != strings.Repeat(first+",", 2)+first {
+			t.Fatalf("consistent hash: expected the same upstream %s for the same key but got %s", first, again)
+		}
+		selected[first]++
+
+		// remove the last upstream, only its keys should move.
+		if after := selections(hash, r, upstreams[:3], 1); after != first {
+			if first != "3" {
+				t.Fatalf("consistent hash: the key of the upstream %s moved to %s", first, after)
+			}
+			moved++
+		}
+	}
+
+	if moved != selected["3"] {
+		t.Fatalf("consistent hash: expected the %d keys of the removed upstream to move but %d moved", selected["3"], moved)
+	}
+	for name, n := range selected {
+		if n < 50 {
+			t.Fatalf("consistent hash: expected the keys to be spread but the upstream %s has %d of 400", name, n)
+		}
+	}
+}
+
+func newTestBackend(name string, statusCode *int32) (*httptest.Server, *url.URL) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(int(atomic.LoadInt32(statusCode)))
+		w.Write([]byte(name + " " + r.URL.Path))
+	}))
+	u, _ := url.Parse(srv.URL)
+	return srv, u
+}
+
+func getFrom(t *testing.T, h http.Handler, method string) (int, string) {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(method, "/users", nil))
+	b, _ := ioutil.ReadAll(rec.Body)
+	return rec.Code, string(b)
+}
+
+func TestLoadBalancerRetries(t *testing.T) {
+	okStatus, failStatus := int32(http.StatusOK), int32(http.StatusServiceUnavailable)
+	okSrv, okURL := newTestBackend("ok", &okStatus)
+	defer okSrv.Close()
+	failSrv, failURL := newTestBackend("fail", &failStatus)
+	defer failSrv.Close()
+
+	lb := NewLoadBalancer(failURL, okURL)
+	lb.Retries = 1
+	defer lb.Close()
+
+	for i := 0; i < 4; i++ {
+		if code, body := getFrom(t, lb, "GET"); code != http.StatusOK || body != "ok /users" {
+			t.Fatalf("expected the GET request to be retried but got %d %s", code, body)
+		}
+	}
+
+	// POST is not idempotent, the first upstream of the round robin fails.
+	if code, _ := getFrom(t, lb, "POST"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the POST request not to be retried but got %d", code)
+	}
+
+	for _, u := range lb.Upstreams() {
+		if n := u.ActiveRequests(); n != 0 {
+			t.Fatalf("expected no active requests of %s but got %d", u.URL, n)
+		}
+	}
+}
+
+func TestLoadBalancerPassiveEjection(t *testing.T) {
+	okStatus := int32(http.StatusOK)
+	okSrv, okURL := newTestBackend("ok", &okStatus)
+	defer okSrv.Close()
+	// a closed server, connection refused.
+	downSrv, downURL := newTestBackend("down", &okStatus)
+	downSrv.Close()
+
+	lb := NewLoadBalancer(downURL, okURL)
+	lb.MaxFails = 1
+	lb.FailTimeout = time.Minute
+	defer lb.Close()
+
+	if code, _ := getFrom(t, lb, "GET"); code != http.StatusBadGateway {
+		t.Fatalf("expected the connection error of the first upstream but got %d", code)
+	}
+
+	if lb.Upstreams()[0].Healthy() {
+		t.Fatalf("expected the failed upstream to be ejected")
+	}
+
+	for i := 0; i < 4; i++ {
+		if code, body := getFrom(t, lb, "GET"); code != http.StatusOK || body != "ok /users" {
+			t.Fatalf("expected the ejected upstream to be skipped but got %d %s", code, body)
+		}
+	}
+}
+
+// testClock is the clock of a load balancer, it's advanced by the tests.
+type testClock struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+func (c *testClock) now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t
+}
+
+func (c *testClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.t = c.t.Add(d)
+	c.mu.Unlock()
+}
+
+func TestLoadBalancerCircuitBreaker(t *testing.T) {
+	status := int32(http.StatusInternalServerError)
+	srv, u := newTestBackend("backend", &status)
+	defer srv.Close()
+
+	clock := &testClock{t: time.Now()}
+	lb := NewLoadBalancer(u)
+	lb.BreakerThreshold = 2
+	lb.BreakerTimeout = time.Minute
+	lb.now = clock.now
+	defer lb.Close()
+	upstream := lb.Upstreams()[0]
+
+	for i := 0; i < 2; i++ {
+		if code, _ := getFrom(t, lb, "GET"); code != http.StatusInternalServerError {
+			t.Fatalf("expected the response of the upstream but got %d", code)
+		}
+	}
+
+	if state := upstream.BreakerState(); state != BreakerOpen {
+		t.Fatalf("expected the breaker to be open but it's %s", state)
+	}
+
+	if code, _ := getFrom(t, lb, "GET"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected no available upstream while the breaker is open but got %d", code)
+	}
+
+	clock.advance(lb.BreakerTimeout)
+	// the trial request fails, the breaker opens again.
+	if code, _ := getFrom(t, lb, "GET"); code != http.StatusInternalServerError {
+		t.Fatalf("expected the trial request to reach the upstream but got %d", code)
+	}
+	if state := upstream.BreakerState(); state != BreakerOpen {
+		t.Fatalf("expected the breaker to open again but it's %s", state)
+	}
+
+	clock.advance(lb.BreakerTimeout)
+	atomic.StoreInt32(&status, http.StatusOK)
+	if code, _ := getFrom(t, lb, "GET"); code != http.StatusOK {
+		t.Fatalf("expected the trial request to succeed but got %d", code)
+	}
+	if state := upstream.BreakerState(); state != BreakerClosed {
+		t.Fatalf("expected the breaker to be closed but it's %s", state)
+	}
+}
+
+func TestLoadBalancerHealthCheck(t *testing.T) {
+	var healthy int32 = 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" && atomic.LoadInt32(&healthy) == 0 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("backend"))
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+
+	su := NewLoadBalancerProxy("127.0.0.1:0", NewLoadBalancer(u))
+	lb := su.server.Handler.(*LoadBalancer)
+	lb.HealthCheck = HealthCheck{Path: "/health", Interval: 20 * time.Millisecond}
+	defer lb.Close()
+
+	checked := make(chan struct{})
+	lb.healthChecked = func() {
+		select {
+		case checked <- struct{}{}:
+		case <-lb.closeChan:
+		}
+	}
+	// waitCheck waits for a round of the health checks which started after the call,
+	// the current one may have started before.
+	waitCheck := func() {
+		<-checked
+		<-checked
+	}
+
+	if code, body := getFrom(t, su.server.Handler, "GET"); code != http.StatusOK || body != "backend" {
+		t.Fatalf("expected the response of the healthy upstream but got %d %s", code, body)
+	}
+
+	atomic.StoreInt32(&healthy, 0)
+	waitCheck()
+	if code, _ := getFrom(t, su.server.Handler, "GET"); code != http.StatusServiceUnavailable {
+		t.Fatalf("expected no available upstream after the failed health check but got %d", code)
+	}
+
+	atomic.StoreInt32(&healthy, 1)
+	waitCheck()
+	if code, _ := getFrom(t, su.server.Handler, "GET"); code != http.StatusOK {
+		t.Fatalf("expected the upstream to be healthy again but got %d", code)
+	}
+}
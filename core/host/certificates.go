@@ -0,0 +1,349 @@
+package host
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+)
+
+var (
+	errCertNoPairs = errors.New("certificate manager: no certificates")
+	errCertLeaf    = errors.New("certificate manager: invalid certificate %s: %v")
+)
+
+// CertLogger logs the reloads, the errors and the expiry warnings of a `CertManager`,
+// i.e the app's logger.
+type CertLogger interface {
+	Infof(format string, args ...interface{})
+	Warnf(format string, args ...interface{})
+}
+
+// certPair is a certificate and key pair of files.
+type certPair struct {
+	certFile string
+	keyFile  string
+
+	// the modification times and sizes of the files of the loaded certificate.
+	certStat fileStamp
+	keyStat  fileStamp
+
+	cert       *tls.Certificate
+	lastWarned time.Time
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func (s fileStamp) equal(other fileStamp) bool {
+	return s.modTime.Equal(other.modTime) && s.size == other.size
+}
+
+func stampOf(filename string) (fileStamp, error) {
+	fi, err := os.Stat(filename)
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: fi.ModTime(), size: fi.Size()}, nil
+}
+
+// CertManager serves the tls certificates of several cert/key pairs of files,
+// a certificate is chosen by the server name of the client, the SNI,
+// based on its DNS names, including the wildcard ones, and its IP addresses.
+// The first certificate is the default one.
+//
+// It checks the files for changes periodically and reloads them without a restart,
+// so the renewed certificates are served to the new connections.
+// It logs the certificates that expire soon, see `ExpiryWarning`.
+//
+// Usage:
+// m := NewCertManager()
+// m.Add("example.com.crt", "example.com.key")
+// m.Add("example.org.crt", "example.org.key")
+// su.ListenAndServeCerts(m)
+// or
+// app.Run(ion.Certs(":443", m)).
+type CertManager struct {
+	// CheckInterval is the time between the checks of the files.
+	// Defaults to 1 minute.
+	CheckInterval time.Duration
+	// ExpiryWarning is the time before the expiration of a certificate that it's logged,
+	// once a day.
+	// Defaults to 14 days.
+	ExpiryWarning time.Duration
+	// Logger logs the reloads, the errors and the expiry warnings, if not nil.
+	Logger CertLogger
+
+	mu    sync.RWMutex
+	pairs []*certPair
+	// the certificates by their lowercase names.
+	names map[string]*tls.Certificate
+
+	watchOnce sync.Once
+	closeOnce sync.Once
+	closeChan chan struct{}
+}
+
+// NewCertManager returns a new, empty, certificate manager,
+// the certificates are added by the `Add`.
+func NewCertManager() *CertManager {
+	return &CertManager{
+		CheckInterval: time.Minute,
+		ExpiryWarning: 14 * 24 * time.Hour,
+		names:         make(map[string]*tls.Certificate),
+		closeChan:     make(chan struct{}),
+	}
+}
+
+// loadCert loads a certificate and key pair, its Leaf is parsed.
+func loadCert(certFile, keyFile string) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+		return nil, errCertLeaf.Format(certFile, err)
+	}
+	return &cert, nil
+}
+
+// Add loads and adds a certificate and key pair of files,
+// it returns an error if they're not a valid pair.
+func (m *CertManager) Add(certFile, keyFile string) error {
+	p := &certPair{certFile: certFile, keyFile: keyFile}
+	if err := m.load(p); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	m.pairs = append(m.pairs, p)
+	m.index()
+	m.mu.Unlock()
+
+	m.warnExpiry(p, time.Now())
+	return nil
+}
+
+// load loads the files of the pair "p", the manager should not be locked.
+func (m *CertManager) load(p *certPair) error {
+	certStat, err := stampOf(p.certFile)
+	if err != nil {
+		return err
+	}
+	keyStat, err := stampOf(p.keyFile)
+	if err != nil {
+		return err
+	}
+
+	cert, err := loadCert(p.certFile, p.keyFile)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	p.cert, p.certStat, p.keyStat = cert, certStat, keyStat
+	// warn about the new certificate, if it expires soon too.
+	p.lastWarned = time.Time{}
+	m.mu.Unlock()
+	return nil
+}
+
+// index maps the names of the certificates to them,
+// the first certificate of a name wins, the manager should be locked.
+func (m *CertManager) index() {
+	m.names = make(map[string]*tls.Certificate)
+	for _, p := range m.pairs {
+		leaf := p.cert.Leaf
+		names := leaf.DNSNames
+		if len(names) == 0 && leaf.Subject.CommonName != "" {
+			names = []string{leaf.Subject.CommonName}
+		}
+		for _, ip := range leaf.IPAddresses {
+			names = append(names, ip.String())
+		}
+
+		for _, name := range names {
+			name = strings.ToLower(name)
+			if _, exists := m.names[name]; !exists {
+				m.names[name] = p.cert
+			}
+		}
+	}
+}
+
+// GetCertificate returns the certificate of the client's server name,
+// the certificate of its wildcard name, i.e "*.example.com" of "www.example.com",
+// or the default, the first, certificate.
+//
+// It's the `tls.Config#GetCertificate`.
+func (m *CertManager) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.pairs) == 0 {
+		return nil, errCertNoPairs
+	}
+
+	name := strings.TrimSuffix(strings.ToLower(hello.ServerName), ".")
+	if name == "" && hello.Conn != nil {
+		// the clients that connect by ip address don't send a server name.
+		if host, _, err := net.SplitHostPort(hello.Conn.LocalAddr().String()); err == nil {
+			name = host
+		}
+	}
+
+	if name != "" {
+		if cert, ok := m.names[name]; ok {
+			return cert, nil
+		}
+		if idx := strings.IndexByte(name, '.'); idx > 0 {
+			if cert, ok := m.names["*"+name[idx:]]; ok {
+				return cert, nil
+			}
+		}
+	}
+
+	return m.pairs[0].cert, nil
+}
+
+// TLSConfig returns a new tls configuration which serves the certificates of the manager,
+// with the http/2 enabled.
+func (m *CertManager) TLSConfig() *tls.Config {
+	cfg := &tls.Config{GetCertificate: m.GetCertificate}
+	setupHTTP2(cfg)
+	return cfg
+}
+
+// Reload reloads the certificates whose files are changed since their last load,
+// the certificates which fail to load are logged and kept as they're,
+// i.e when a certificate is written but its key is not yet.
+// The certificates that expire soon are logged too.
+//
+// It's called periodically after a `Watch`.
+func (m *CertManager) Reload() {
+	m.mu.RLock()
+	pairs := make([]*certPair, len(m.pairs))
+	copy(pairs, m.pairs)
+	m.mu.RUnlock()
+
+	reloaded := false
+	for _, p := range pairs {
+		if !m.changed(p) {
+			continue
+		}
+
+		if err := m.load(p); err != nil {
+			m.logWarnf("certificate manager: failed to reload %s: %v", p.certFile, err)
+			continue
+		}
+
+		reloaded = true
+		m.mu.RLock()
+		leaf := p.cert.Leaf
+		m.mu.RUnlock()
+		m.logInfof("certificate manager: reloaded %s, %s, expires at %s", p.certFile, leaf.Subject.CommonName, leaf.NotAfter.Format(time.RFC3339))
+	}
+
+	if reloaded {
+		m.mu.Lock()
+		m.index()
+		m.mu.Unlock()
+	}
+
+	now := time.Now()
+	for _, p := range pairs {
+		m.warnExpiry(p, now)
+	}
+}
+
+// changed reports whether the files of the pair are changed since their last load.
+func (m *CertManager) changed(p *certPair) bool {
+	certStat, err := stampOf(p.certFile)
+	if err != nil {
+		m.logWarnf("certificate manager: %v", err)
+		return false
+	}
+	keyStat, err := stampOf(p.keyFile)
+	if err != nil {
+		m.logWarnf("certificate manager: %v", err)
+		return false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !certStat.equal(p.certStat) || !keyStat.equal(p.keyStat)
+}
+
+// warnExpiry logs the certificate of the pair, once a day, if it expires in the `ExpiryWarning`.
+func (m *CertManager) warnExpiry(p *certPair, now time.Time) {
+	if m.Logger == nil {
+		return
+	}
+
+	m.mu.Lock()
+	leaf := p.cert.Leaf
+	if now.Add(m.ExpiryWarning).Before(leaf.NotAfter) || now.Sub(p.lastWarned) < 24*time.Hour {
+		m.mu.Unlock()
+		return
+	}
+	p.lastWarned = now
+	m.mu.Unlock()
+
+	if now.After(leaf.NotAfter) {
+		m.logWarnf("certificate manager: the certificate %s, %s, expired at %s", p.certFile, leaf.Subject.CommonName, leaf.NotAfter.Format(time.RFC3339))
+		return
+	}
+	m.logWarnf("certificate manager: the certificate %s, %s, expires in %s, at %s", p.certFile, leaf.Subject.CommonName,
+		leaf.NotAfter.Sub(now).Truncate(time.Hour), leaf.NotAfter.Format(time.RFC3339))
+}
+
+// Watch starts, once, to check the files for changes every `CheckInterval`, until the `Close`.
+func (m *CertManager) Watch() {
+	m.watchOnce.Do(func() {
+		interval := m.CheckInterval
+		if interval <= 0 {
+			interval = time.Minute
+		}
+
+		go func() {
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+
+			for {
+				select {
+				case <-m.closeChan:
+					return
+				case <-ticker.C:
+					m.Reload()
+				}
+			}
+		}()
+	})
+}
+
+// Close stops the checks of the files.
+func (m *CertManager) Close() {
+	m.closeOnce.Do(func() {
+		close(m.closeChan)
+	})
+}
+
+func (m *CertManager) logInfof(format string, args ...interface{}) {
+	if m.Logger != nil {
+		m.Logger.Infof(format, args...)
+	}
+}
+
+func (m *CertManager) logWarnf(format string, args ...interface{}) {
+	if m.Logger != nil {
+		m.Logger.Warnf(format, args...)
+	}
+}
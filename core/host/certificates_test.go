@@ -0,0 +1,248 @@
+// white-box testing
+
+package host
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// writeTestCert writes a self-signed certificate of the "names" which expires at the "notAfter"
+// to the "name".crt and "name".key files of the "dir".
+func writeTestCert(t *testing.T, dir, name string, notAfter time.Time, names ...string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: names[0]},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	for _, n := range names {
+		if ip := net.ParseIP(n); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, n)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile, keyFile := filepath.Join(dir, name+".crt"), filepath.Join(dir, name+".key")
+	if err = ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// the modification time of a rewritten file may be the same on some file systems.
+	future := time.Now().Add(time.Duration(time.Now().UnixNano()%1000) * time.Second)
+	os.Chtimes(certFile, future, future)
+	os.Chtimes(keyFile, future, future)
+	return certFile, keyFile
+}
+
+type testCertLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *testCertLogger) Infof(format string, args ...interface{}) {
+	l.mu.Lock()
+	l.messages = append(l.messages, "info: "+fmt.Sprintf(format, args...))
+	l.mu.Unlock()
+}
+
+func (l *testCertLogger) Warnf(format string, args ...interface{}) {
+	l.mu.Lock()
+	l.messages = append(l.messages, "warn: "+fmt.Sprintf(format, args...))
+	l.mu.Unlock()
+}
+
+func (l *testCertLogger) take() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s := strings.Join(l.messages, "\n")
+	l.messages = nil
+	return s
+}
+
+func commonNameOf(t *testing.T, m *CertManager, serverName string) string {
+	cert, err := m.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert.Leaf.Subject.CommonName
+}
+
+func TestCertManager(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ion-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	logger := new(testCertLogger)
+	m := NewCertManager()
+	m.Logger = logger
+
+	if _, err = m.GetCertificate(&tls.ClientHelloInfo{ServerName: "example.com"}); err == nil {
+		t.Fatalf("expected an error without certificates")
+	}
+
+	year := time.Now().Add(365 * 24 * time.Hour)
+	if err = m.Add(writeTestCert(t, dir, "com", year, "example.com", "www.example.com")); err != nil {
+		t.Fatal(err)
+	}
+	orgCert, orgKey := writeTestCert(t, dir, "org", year, "*.example.org", "127.0.0.1")
+	if err = m.Add(orgCert, orgKey); err != nil {
+		t.Fatal(err)
+	}
+
+	if err = m.Add(filepath.Join(dir, "missing.crt"), orgKey); err == nil {
+		t.Fatalf("expected an error for a missing file")
+	}
+
+	tests := []struct {
+		serverName string
+		expected   string
+	}{
+		{"example.com", "example.com"},
+		{"WWW.Example.Com.", "example.com"},
+		{"api.example.org", "*.example.org"},
+		{"127.0.0.1", "*.example.org"},
+		// the default one.
+		{"example.net", "example.com"},
+		{"", "example.com"},
+	}
+	for _, tt := range tests {
+		if got := commonNameOf(t, m, tt.serverName); got != tt.expected {
+			t.Fatalf("%s: expected the certificate of %s but got %s", tt.serverName, tt.expected, got)
+		}
+	}
+
+	if logs := logger.take(); logs != "" {
+		t.Fatalf("expected no logs but got\n%s", logs)
+	}
+
+	// a renewed certificate of the "*.example.org" which expires soon.
+	soon := time.Now().Add(3 * 24 * time.Hour)
+	writeTestCert(t, dir, "org", soon, "*.example.org", "api.example.net")
+	m.Reload()
+
+	if got := commonNameOf(t, m, "api.example.net"); got != "*.example.org" {
+		t.Fatalf("expected the reloaded certificate but got %s", got)
+	}
+	if cert, _ := m.GetCertificate(&tls.ClientHelloInfo{ServerName: "api.example.org"}); cert.Leaf.NotAfter.Unix() != soon.Unix() {
+		t.Fatalf("expected the reloaded certificate to expire at %s but it expires at %s", soon, cert.Leaf.NotAfter)
+	}
+
+	logs := logger.take()
+	if !strings.Contains(logs, "info: certificate manager: reloaded "+orgCert) ||
+		!strings.Contains(logs, "warn: certificate manager: the certificate "+orgCert+", *.example.org, expires in 71h") {
+		t.Fatalf("expected the reload and the expiry warning logs but got\n%s", logs)
+	}
+
+	// the expiry warning is logged once a day.
+	m.Reload()
+	if logs := logger.take(); logs != "" {
+		t.Fatalf("expected no logs but got\n%s", logs)
+	}
+
+	// an invalid key keeps the loaded certificate.
+	if err = ioutil.WriteFile(orgKey, []byte("invalid"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	m.Reload()
+	if got := commonNameOf(t, m, "api.example.net"); got != "*.example.org" {
+		t.Fatalf("expected the loaded certificate to be kept but got %s", got)
+	}
+	if logs := logger.take(); !strings.HasPrefix(logs, "warn: certificate manager: failed to reload "+orgCert) {
+		t.Fatalf("expected the reload error log but got\n%s", logs)
+	}
+}
+
+func TestSupervisorListenAndServeCerts(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ion-certs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	year := time.Now().Add(365 * 24 * time.Hour)
+	m := NewCertManager()
+	m.CheckInterval = 20 * time.Millisecond
+	if err = m.Add(writeTestCert(t, dir, "first", year, "first.local")); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	su := New(&http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})})
+	go su.ListenAndServeCerts(m)
+	defer su.Shutdown(context.Background())
+
+	peerName := func() string {
+		var (
+			conn *tls.Conn
+			err  error
+		)
+		for i := 0; i < 50; i++ {
+			if conn, err = tls.Dial("tcp", addr, &tls.Config{ServerName: "first.local", InsecureSkipVerify: true}); err == nil {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+		return conn.ConnectionState().PeerCertificates[0].Subject.CommonName
+	}
+
+	if got := peerName(); got != "first.local" {
+		t.Fatalf("expected the certificate of first.local but got %s", got)
+	}
+
+	// the files are replaced, without a restart.
+	writeTestCert(t, dir, "first", year, "second.local", "first.local")
+	time.Sleep(100 * time.Millisecond)
+	if got := peerName(); got != "second.local" {
+		t.Fatalf("expected the reloaded certificate but got %s", got)
+	}
+}
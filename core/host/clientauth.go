@@ -0,0 +1,96 @@
+package host
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+
+	"github.com/get-ion/ion/core/errors"
+)
+
+var (
+	errClientCAsMissing = errors.New("client auth: no certificate authority files")
+	errClientCAsInvalid = errors.New("client auth: no certificates found in %s")
+)
+
+// ClientCertMode is the verification mode of the client certificates, see `ClientAuth`.
+type ClientCertMode uint8
+
+const (
+	// ClientCertRequired requires a client certificate which is signed by one of the certificate authorities,
+	// the handshake of a client without a valid certificate fails.
+	ClientCertRequired ClientCertMode = iota
+	// ClientCertIfGiven verifies the client certificate only if the client sends one,
+	// the clients without a certificate are accepted,
+	// i.e when only some of the routes are authorized by certificates.
+	ClientCertIfGiven
+)
+
+func (m ClientCertMode) tlsClientAuth() tls.ClientAuthType {
+	if m == ClientCertIfGiven {
+		return tls.VerifyClientCertIfGiven
+	}
+	return tls.RequireAndVerifyClientCert
+}
+
+// ClientAuth are the options of the mutual tls, the authentication of the clients by their certificates.
+type ClientAuth struct {
+	// CAFiles are the PEM files of the certificate authorities, the bundles,
+	// which sign the client certificates.
+	CAFiles []string
+	// Mode is the verification mode of the client certificates.
+	// Defaults to ClientCertRequired.
+	Mode ClientCertMode
+}
+
+// LoadClientCAs returns a certificate pool of the certificate authorities of the PEM "files",
+// it returns an error if a file has no certificates.
+func LoadClientCAs(files ...string) (*x509.CertPool, error) {
+	if len(files) == 0 {
+		return nil, errClientCAsMissing
+	}
+
+	pool := x509.NewCertPool()
+	for _, filename := range files {
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		if !pool.AppendCertsFromPEM(b) {
+			return nil, errClientCAsInvalid.Format(filename)
+		}
+	}
+
+	return pool, nil
+}
+
+// SetClientAuth loads the certificate authorities of the "auth"
+// and requires or verifies the client certificates of the secure server,
+// it should be called before the `ListenAndServeTLS` or the `ListenAndServeCerts`.
+//
+// The verified certificates are available to the handlers
+// by the request's `TLS#VerifiedChains`, see the `Context#PeerIdentity`.
+func (su *Supervisor) SetClientAuth(auth ClientAuth) error {
+	pool, err := LoadClientCAs(auth.CAFiles...)
+	if err != nil {
+		return err
+	}
+
+	su.mu.Lock()
+	su.clientCAs = pool
+	su.clientAuth = auth.Mode.tlsClientAuth()
+	su.mu.Unlock()
+	return nil
+}
+
+// configureClientAuth sets the client authentication, if any, to the tls configuration "cfg".
+func (su *Supervisor) configureClientAuth(cfg *tls.Config) {
+	su.mu.Lock()
+	defer su.mu.Unlock()
+
+	if su.clientCAs == nil {
+		return
+	}
+	cfg.ClientCAs = su.clientCAs
+	cfg.ClientAuth = su.clientAuth
+}
@@ -0,0 +1,95 @@
+// white-box testing
+
+package host
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSupervisorClientAuth(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ion-client-auth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	year := time.Now().Add(365 * 24 * time.Hour)
+	m := NewCertManager()
+	if err = m.Add(writeTestCert(t, dir, "server", year, "127.0.0.1")); err != nil {
+		t.Fatal(err)
+	}
+	// the self-signed certificate of the client is its certificate authority too.
+	clientCert, clientKey := writeTestCert(t, dir, "client", year, "client.local")
+	otherCert, otherKey := writeTestCert(t, dir, "other", year, "other.local")
+
+	if _, err = LoadClientCAs(); err == nil {
+		t.Fatalf("expected an error without files")
+	}
+	if _, err = LoadClientCAs(clientKey); err == nil {
+		t.Fatalf("expected an error for a file without certificates")
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	su := New(&http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.VerifiedChains[0][0].Subject.CommonName))
+	}), ErrorLog: log.New(ioutil.Discard, "", 0)})
+	if err = su.SetClientAuth(ClientAuth{CAFiles: []string{clientCert}}); err != nil {
+		t.Fatal(err)
+	}
+	go su.ListenAndServeCerts(m)
+	defer su.Shutdown(context.Background())
+
+	get := func(certFile, keyFile string) (string, error) {
+		cfg := &tls.Config{InsecureSkipVerify: true}
+		if certFile != "" {
+			cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg.Certificates = []tls.Certificate{cert}
+		}
+
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: cfg, DisableKeepAlives: true}}
+		resp, err := client.Get("https://" + addr)
+		if err != nil {
+			return "", err
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		return string(b), err
+	}
+
+	// wait for the server.
+	for i := 0; i < 50; i++ {
+		var conn net.Conn
+		if conn, err = net.Dial("tcp", addr); err == nil {
+			conn.Close()
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if got, err := get(clientCert, clientKey); err != nil || got != "client.local" {
+		t.Fatalf("expected the verified client certificate but got %q, %v", got, err)
+	}
+	if _, err := get("", ""); err == nil {
+		t.Fatalf("expected the handshake to fail without a client certificate")
+	}
+	if _, err := get(otherCert, otherKey); err == nil {
+		t.Fatalf("expected the handshake to fail with an unknown client certificate")
+	}
+}
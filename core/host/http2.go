@@ -0,0 +1,347 @@
+package host
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+var (
+	errH2CSettings = errors.New("h2c: invalid HTTP2-Settings header")
+	errH2CPreface  = errors.New("h2c: invalid client preface")
+	errH2CHijack   = errors.New("h2c: the response writer is not a http.Hijacker")
+)
+
+// HTTP2 are the HTTP/2 options of a server, see `Supervisor#ConfigureHTTP2`.
+// The zero values are the defaults of the golang.org/x/net/http2 package.
+type HTTP2 struct {
+	// H2C enables the HTTP/2 over cleartext TCP, without tls,
+	// i.e behind a tls-terminating proxy or a service mesh.
+	// The clients can start with the HTTP/2 connection preface, the prior knowledge,
+	// or upgrade an HTTP/1.1 request with the "Upgrade: h2c" header.
+	//
+	// The tls connections negotiate the HTTP/2 as always.
+	H2C bool
+	// MaxConcurrentStreams is the number of the concurrent streams that each client may have open at a time.
+	// Defaults to 250.
+	MaxConcurrentStreams uint32
+	// MaxReadFrameSize is the largest frame that the server is willing to read,
+	// between 16KB and 16MB.
+	// Defaults to 1MB.
+	MaxReadFrameSize uint32
+	// IdleTimeout is the time until the idle clients are closed with a GOAWAY frame.
+	// Defaults to the server's IdleTimeout or ReadTimeout.
+	IdleTimeout time.Duration
+	// MaxUploadBufferPerConnection is the initial flow control window of each connection.
+	// Defaults to 1MB.
+	MaxUploadBufferPerConnection int32
+	// MaxUploadBufferPerStream is the initial flow control window of each stream.
+	// Defaults to 1MB.
+	MaxUploadBufferPerStream int32
+}
+
+// ConfigureHTTP2 configures the HTTP/2 of the server by the options "opts",
+// its settings and the h2c, by the golang.org/x/net/http2 package.
+// It should be called before the server starts, after its handler is set.
+//
+// The HTTP/2 connections are closed gracefully with a GOAWAY frame on `Shutdown`.
+func (su *Supervisor) ConfigureHTTP2(opts HTTP2) error {
+	conf := &http2.Server{
+		MaxConcurrentStreams:         opts.MaxConcurrentStreams,
+		MaxReadFrameSize:             opts.MaxReadFrameSize,
+		IdleTimeout:                  opts.IdleTimeout,
+		MaxUploadBufferPerConnection: opts.MaxUploadBufferPerConnection,
+		MaxUploadBufferPerStream:     opts.MaxUploadBufferPerStream,
+	}
+
+	// ConfigureServer sets a tls config, keep the server's one in order to be detected as plain,
+	// the secure listeners set their own config with the "h2" protocol.
+	tlsConfig := su.server.TLSConfig
+	if err := http2.ConfigureServer(su.server, conf); err != nil {
+		return err
+	}
+	if tlsConfig == nil {
+		su.server.TLSConfig = nil
+	}
+
+	if opts.H2C {
+		handler := su.server.Handler
+		if handler == nil {
+			handler = http.DefaultServeMux
+		}
+		su.server.Handler = &h2cHandler{Handler: handler, server: conf, base: su.server}
+	}
+
+	return nil
+}
+
+// h2cHandler serves the HTTP/2 cleartext connections, the rest requests are served by the Handler.
+type h2cHandler struct {
+	http.Handler
+	server *http2.Server
+	base   *http.Server
+}
+
+func (h *h2cHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.TLS == nil {
+		// the prior knowledge, the "PRI * HTTP/2.0" of the preface is parsed as a request.
+		if r.Method == "PRI" && r.URL.Path == "*" && r.ProtoMajor == 2 && len(r.Header) == 0 {
+			if conn, err := h2cPriorKnowledge(w); err == nil {
+				h.serveConn(conn)
+			}
+			return
+		}
+
+		if isH2CUpgrade(r) {
+			if conn, err := h2cUpgrade(w, r); err == nil {
+				h.serveConn(conn)
+			}
+			return
+		}
+	}
+
+	h.Handler.ServeHTTP(w, r)
+}
+
+func (h *h2cHandler) serveConn(conn net.Conn) {
+	defer conn.Close()
+	h.server.ServeConn(conn, &http2.ServeConnOpts{Handler: h.Handler, BaseConfig: h.base})
+}
+
+// hijack takes over the connection of the "w", its deadlines are cleared,
+// the HTTP/2 server has its own timeouts.
+func hijack(w http.ResponseWriter) (net.Conn, *bufio.ReadWriter, error) {
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, nil, errH2CHijack
+	}
+
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, rw, nil
+}
+
+// h2cPriorKnowledge returns the connection of a client which starts with the HTTP/2 connection preface,
+// the preface is read again by the HTTP/2 server.
+func h2cPriorKnowledge(w http.ResponseWriter) (net.Conn, error) {
+	conn, rw, err := hijack(w)
+	if err != nil {
+		return nil, err
+	}
+
+	// the "SM\r\n\r\n" after the "PRI * HTTP/2.0\r\n\r\n" request.
+	const prefaceTail = "SM\r\n\r\n"
+	tail := make([]byte, len(prefaceTail))
+	if _, err = io.ReadFull(rw, tail); err != nil || string(tail) != prefaceTail {
+		conn.Close()
+		return nil, errH2CPreface
+	}
+
+	return &h2cConn{
+		Conn:   conn,
+		reader: io.MultiReader(strings.NewReader(http2.ClientPreface), rw),
+		writer: conn,
+	}, nil
+}
+
+// isH2CUpgrade reports whether the request asks for an upgrade to h2c,
+// the requests with a body are served by the HTTP/1.1, the upgrade is optional.
+//
+// See https://tools.ietf.org/html/rfc7540#section-3.2.
+func isH2CUpgrade(r *http.Request) bool {
+	if r.ProtoMajor != 1 || !strings.EqualFold(r.Header.Get("Upgrade"), "h2c") ||
+		len(r.Header[http.CanonicalHeaderKey("HTTP2-Settings")]) != 1 {
+		return false
+	}
+
+	if r.ContentLength != 0 || len(r.TransferEncoding) > 0 {
+		return false
+	}
+
+	var upgrade, settings bool
+	for _, v := range r.Header["Connection"] {
+		for _, token := range strings.Split(v, ",") {
+			token = strings.TrimSpace(token)
+			upgrade = upgrade || strings.EqualFold(token, "Upgrade")
+			settings = settings || strings.EqualFold(token, "HTTP2-Settings")
+		}
+	}
+	return upgrade && settings
+}
+
+// h2cUpgrade switches the connection of the "r" to the HTTP/2 and returns it,
+// the HTTP/2 server reads a connection preface, a SETTINGS frame of the HTTP2-Settings header
+// and the client's settings, and the request "r" as the stream 1 first, then the frames of the client.
+func h2cUpgrade(w http.ResponseWriter, r *http.Request) (net.Conn, error) {
+	settings, err := decodeH2CSettings(r.Header.Get("HTTP2-Settings"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return nil, err
+	}
+
+	conn, rw, err := hijack(w)
+	if err != nil {
+		return nil, err
+	}
+
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: h2c\r\n\r\n")
+	if err = rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// the client sends its connection preface, the magic and a SETTINGS frame, after the 101.
+	// The settings of the header are acknowledged by the 101, so they're merged with the client's ones
+	// to a single SETTINGS frame which is acknowledged once.
+	clientSettings, err := readH2CPreface(rw)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	initial, err := h2cInitialFrames(r, append(settings, clientSettings...))
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &h2cConn{
+		Conn:   conn,
+		reader: io.MultiReader(initial, rw),
+		writer: conn,
+	}, nil
+}
+
+// readH2CPreface reads the client's connection preface and returns the settings of its SETTINGS frame.
+func readH2CPreface(r io.Reader) ([]http2.Setting, error) {
+	preface := make([]byte, len(http2.ClientPreface))
+	if _, err := io.ReadFull(r, preface); err != nil || string(preface) != http2.ClientPreface {
+		return nil, errH2CPreface
+	}
+
+	f, err := http2.NewFramer(ioutil.Discard, r).ReadFrame()
+	if err != nil {
+		return nil, errH2CPreface
+	}
+	sf, ok := f.(*http2.SettingsFrame)
+	if !ok || sf.IsAck() {
+		return nil, errH2CPreface
+	}
+
+	var settings []http2.Setting
+	sf.ForeachSetting(func(s http2.Setting) error {
+		settings = append(settings, s)
+		return nil
+	})
+	return settings, nil
+}
+
+// decodeH2CSettings decodes the payload of a SETTINGS frame of the HTTP2-Settings header.
+func decodeH2CSettings(header string) ([]http2.Setting, error) {
+	b, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(header, "="))
+	if err != nil || len(b)%6 != 0 {
+		return nil, errH2CSettings
+	}
+
+	settings := make([]http2.Setting, 0, len(b)/6)
+	for ; len(b) > 0; b = b[6:] {
+		s := http2.Setting{ID: http2.SettingID(binary.BigEndian.Uint16(b)), Val: binary.BigEndian.Uint32(b[2:])}
+		if s.Valid() != nil {
+			return nil, errH2CSettings
+		}
+		settings = append(settings, s)
+	}
+	return settings, nil
+}
+
+// the connection-specific headers which are not allowed in HTTP/2.
+var h2cSkipHeaders = map[string]bool{
+	"Connection":        true,
+	"Upgrade":           true,
+	"Http2-Settings":    true,
+	"Keep-Alive":        true,
+	"Proxy-Connection":  true,
+	"Transfer-Encoding": true,
+	"Host":              true,
+}
+
+// h2cInitialFrames returns the client's connection preface, the SETTINGS frame of the "settings"
+// and the HEADERS and CONTINUATION frames of the request "r", the stream 1 which is half-closed by the client.
+func h2cInitialFrames(r *http.Request, settings []http2.Setting) (io.Reader, error) {
+	buf := bytes.NewBufferString(http2.ClientPreface)
+	framer := http2.NewFramer(buf, nil)
+	if err := framer.WriteSettings(settings...); err != nil {
+		return nil, err
+	}
+
+	var block bytes.Buffer
+	enc := hpack.NewEncoder(&block)
+	enc.WriteField(hpack.HeaderField{Name: ":method", Value: r.Method})
+	enc.WriteField(hpack.HeaderField{Name: ":scheme", Value: "http"})
+	enc.WriteField(hpack.HeaderField{Name: ":authority", Value: r.Host})
+	enc.WriteField(hpack.HeaderField{Name: ":path", Value: r.URL.RequestURI()})
+	for key, values := range r.Header {
+		if h2cSkipHeaders[textproto.CanonicalMIMEHeaderKey(key)] {
+			continue
+		}
+		for _, v := range values {
+			enc.WriteField(hpack.HeaderField{Name: strings.ToLower(key), Value: v})
+		}
+	}
+
+	// the frames should not be larger than the initial max frame size.
+	const maxFrameSize = 16384
+	fragment := block.Bytes()
+	first := fragment
+	if len(first) > maxFrameSize {
+		first = first[:maxFrameSize]
+	}
+	fragment = fragment[len(first):]
+
+	err := framer.WriteHeaders(http2.HeadersFrameParam{
+		StreamID:      1,
+		BlockFragment: first,
+		EndStream:     true,
+		EndHeaders:    len(fragment) == 0,
+	})
+	for err == nil && len(fragment) > 0 {
+		next := fragment
+		if len(next) > maxFrameSize {
+			next = next[:maxFrameSize]
+		}
+		fragment = fragment[len(next):]
+		err = framer.WriteContinuation(1, len(fragment) == 0, next)
+	}
+
+	return buf, err
+}
+
+// h2cConn is a hijacked connection which is read from the "reader" and written to the "writer".
+type h2cConn struct {
+	net.Conn
+	reader io.Reader
+	writer io.Writer
+}
+
+func (c *h2cConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
+
+func (c *h2cConn) Write(b []byte) (int, error) {
+	return c.writer.Write(b)
+}
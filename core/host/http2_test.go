@@ -0,0 +1,252 @@
+// white-box testing
+
+package host
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+func newTestH2CServer(t *testing.T, opts HTTP2) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	su := New(&http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto + " " + r.Method + " " + r.URL.Path))
+	})})
+	if err = su.ConfigureHTTP2(opts); err != nil {
+		t.Fatal(err)
+	}
+	if su.server.TLSConfig != nil {
+		t.Fatalf("expected the server without a tls config to stay plain")
+	}
+
+	go su.Serve(l)
+	return l.Addr().String(), func() { su.Shutdown(context.Background()) }
+}
+
+func TestH2CPriorKnowledge(t *testing.T) {
+	addr, shutdown := newTestH2CServer(t, HTTP2{H2C: true})
+	defer shutdown()
+
+	client := &http.Client{Transport: &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}}
+
+	for i := 0; i < 2; i++ {
+		resp, err := client.Get("http://" + addr + "/users")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if expected, got := "HTTP/2.0 GET /users", string(b); expected != got {
+			t.Fatalf("expected %q but got %q", expected, got)
+		}
+	}
+
+	// the HTTP/1.1 clients are served as always.
+	resp, err := http.Get("http://" + addr + "/users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if expected, got := "HTTP/1.1 GET /users", string(b); expected != got {
+		t.Fatalf("expected %q but got %q", expected, got)
+	}
+}
+
+func TestH2CUpgrade(t *testing.T) {
+	addr, shutdown := newTestH2CServer(t, HTTP2{H2C: true, MaxConcurrentStreams: 42, MaxReadFrameSize: 1 << 16})
+	defer shutdown()
+
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// SETTINGS_ENABLE_PUSH = 0.
+	settings := base64.RawURLEncoding.EncodeToString([]byte{0, 2, 0, 0, 0, 0})
+	io.WriteString(conn, "GET /users HTTP/1.1\r\nHost: "+addr+"\r\n"+
+		"Connection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: "+settings+"\r\n\r\n")
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("expected the switching protocols response but got %d", resp.StatusCode)
+	}
+
+	io.WriteString(conn, http2.ClientPreface)
+	framer := http2.NewFramer(conn, br)
+	framer.WriteSettings()
+	// the ping is acknowledged after the settings.
+	framer.WritePing(false, [8]byte{1})
+
+	var (
+		pong        bool
+		acks        int
+		headers     []hpack.HeaderField
+		body        string
+		maxStreams  uint32
+		maxReadSize uint32
+	)
+	dec := hpack.NewDecoder(4096, nil)
+	for body == "" || !pong {
+		f, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		switch f := f.(type) {
+		case *http2.SettingsFrame:
+			if f.IsAck() {
+				acks++
+				continue
+			}
+			f.ForeachSetting(func(s http2.Setting) error {
+				switch s.ID {
+				case http2.SettingMaxConcurrentStreams:
+					maxStreams = s.Val
+				case http2.SettingMaxFrameSize:
+					maxReadSize = s.Val
+				}
+				return nil
+			})
+			framer.WriteSettingsAck()
+		case *http2.HeadersFrame:
+			if f.StreamID != 1 {
+				t.Fatalf("expected the response of the stream 1 but got %d", f.StreamID)
+			}
+			if headers, err = dec.DecodeFull(f.HeaderBlockFragment()); err != nil {
+				t.Fatal(err)
+			}
+		case *http2.DataFrame:
+			body += string(f.Data())
+		case *http2.PingFrame:
+			pong = f.IsAck()
+		}
+	}
+
+	if len(headers) == 0 || headers[0].Name != ":status" || headers[0].Value != "200" {
+		t.Fatalf("expected the 200 status of the upgraded request but got %v", headers)
+	}
+	if expected := "HTTP/2.0 GET /users"; body != expected {
+		t.Fatalf("expected %q but got %q", expected, body)
+	}
+	// the settings of the HTTP2-Settings header are acknowledged by the 101.
+	if acks != 1 {
+		t.Fatalf("expected one settings acknowledgment but got %d", acks)
+	}
+	if maxStreams != 42 || maxReadSize != 1<<16 {
+		t.Fatalf("expected the settings of the options but got %d streams and %d frame size", maxStreams, maxReadSize)
+	}
+}
+
+func TestH2CDisabled(t *testing.T) {
+	addr, shutdown := newTestH2CServer(t, HTTP2{})
+	defer shutdown()
+
+	req, _ := http.NewRequest("GET", "http://"+addr+"/users", nil)
+	req.Header.Set("Connection", "Upgrade, HTTP2-Settings")
+	req.Header.Set("Upgrade", "h2c")
+	req.Header.Set("HTTP2-Settings", "")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK || !strings.HasPrefix(string(b), "HTTP/1.1") {
+		t.Fatalf("expected the upgrade to be ignored but got %d %s", resp.StatusCode, b)
+	}
+}
+
+func TestDecodeH2CSettings(t *testing.T) {
+	if _, err := decodeH2CSettings("AAMAAABkAAQAAP__"); err != nil {
+		t.Fatal(err)
+	}
+	// SETTINGS_MAX_FRAME_SIZE = 1.
+	if _, err := decodeH2CSettings(base64.RawURLEncoding.EncodeToString([]byte{0, 5, 0, 0, 0, 1})); err == nil {
+		t.Fatalf("expected an error for an invalid setting")
+	}
+	if _, err := decodeH2CSettings("AAMA"); err == nil {
+		t.Fatalf("expected an error for a truncated setting")
+	}
+}
+
+func TestHTTP2TLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ion-http2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	m := NewCertManager()
+	if err = m.Add(writeTestCert(t, dir, "server", time.Now().Add(time.Hour), "127.0.0.1")); err != nil {
+		t.Fatal(err)
+	}
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	l.Close()
+
+	su := New(&http.Server{Addr: addr, Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})})
+	if err = su.ConfigureHTTP2(HTTP2{H2C: true, MaxConcurrentStreams: 10}); err != nil {
+		t.Fatal(err)
+	}
+	go su.ListenAndServeCerts(m)
+	defer su.Shutdown(context.Background())
+
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+		ForceAttemptHTTP2: true,
+	}}
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("https://" + addr); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if string(b) != "HTTP/2.0" {
+		t.Fatalf("expected the HTTP/2 over tls but got %s", b)
+	}
+}
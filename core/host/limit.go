@@ -0,0 +1,45 @@
+package host
+
+import (
+	"net"
+
+	"github.com/get-ion/ion/core/netutil"
+)
+
+// SetConnLimits sets the limits of the concurrent connections of the server,
+// the max connections, which are queued or rejected, and the max connections per ip address,
+// see `netutil#ConnLimits`.
+// It should be called before the `ListenAndServe`, the `ListenAndServeTLS`, the `ListenAndServeCerts` or the `Serve`.
+//
+// The limits are applied before the PROXY protocol, if enabled,
+// so the ip address of a connection is the one of the proxy, see `SetProxyProtocol`.
+// The rejected connections are counted by the `Stats`.
+func (su *Supervisor) SetConnLimits(limits netutil.ConnLimits) {
+	su.mu.Lock()
+	su.connLimits = limits
+	su.mu.Unlock()
+}
+
+// limitListener returns a limit listener of "l" if there are limits, otherwise the "l".
+func (su *Supervisor) limitListener(l net.Listener) net.Listener {
+	su.mu.Lock()
+	defer su.mu.Unlock()
+
+	if su.connLimits.MaxConnections <= 0 && su.connLimits.MaxConnectionsPerIP <= 0 {
+		return l
+	}
+
+	ll := netutil.LimitListener(l, su.connLimits)
+	su.limiters = append(su.limiters, ll)
+	return ll
+}
+
+// rejectedConnections returns the total number of the rejected connections of the limit listeners.
+func (su *Supervisor) rejectedConnections() (n uint64) {
+	su.mu.Lock()
+	for _, l := range su.limiters {
+		n += l.Rejected()
+	}
+	su.mu.Unlock()
+	return
+}
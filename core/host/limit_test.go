@@ -0,0 +1,184 @@
+// white-box testing
+
+package host
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion/core/netutil"
+)
+
+// waitStats waits for the stats of the "su" to match the "ok", the connection states are tracked asynchronously.
+func waitStats(t *testing.T, su *Supervisor, ok func(Stats) bool) Stats {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		stats := su.Stats()
+		if ok(stats) {
+			return stats
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("unexpected stats: %#v", stats)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func dialRequest(t *testing.T, addr, path string) net.Conn {
+	c, err := net.Dial("tcp4", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if path != "" {
+		io.WriteString(c, "GET "+path+" HTTP/1.1\r\nHost: localhost\r\n\r\n")
+	}
+	return c
+}
+
+func TestSupervisorConnLimits(t *testing.T) {
+	release := make(chan struct{})
+	hijacked := make(chan net.Conn, 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte("done"))
+	})
+	mux.HandleFunc("/hijack", func(w http.ResponseWriter, r *http.Request) {
+		c, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		hijacked <- c
+	})
+
+	su := New(&http.Server{Handler: mux})
+	su.SetConnLimits(netutil.ConnLimits{MaxConnections: 3})
+
+	ln, err := net.Listen("tcp4", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go su.Serve(ln)
+	defer su.Shutdown(context.Background())
+	addr := ln.Addr().String()
+
+	active := dialRequest(t, addr, "/slow")
+	defer active.Close()
+	fresh := dialRequest(t, addr, "")
+	defer fresh.Close()
+	hijack := dialRequest(t, addr, "/hijack")
+	defer hijack.Close()
+
+	// the hijacked connection keeps its slot until it's closed.
+	defer (<-hijacked).Close()
+
+	waitStats(t, su, func(s Stats) bool {
+		return s.NewConnections == 1 && s.ActiveConnections == 1 && s.HijackedConnections == 1
+	})
+
+	rejected := dialRequest(t, addr, "")
+	defer rejected.Close()
+	rejected.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := rejected.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the fourth connection to be rejected but got: %v", err)
+	}
+
+	stats := su.Stats()
+	if stats.OpenConnections != 2 || stats.AcceptedConnections != 3 || stats.RejectedConnections != 1 {
+		t.Fatalf("unexpected stats: %#v", stats)
+	}
+
+	close(release)
+	resp, err := http.ReadResponse(bufio.NewReader(active), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	waitStats(t, su, func(s Stats) bool {
+		return s.NewConnections == 1 && s.ActiveConnections == 0 && s.IdleConnections == 1
+	})
+}
+
+func TestSupervisorShutdownReport(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("done"))
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+
+	logs := new(bytes.Buffer)
+	su := New(&http.Server{Handler: mux, ErrorLog: log.New(logs, "", 0)})
+
+	ln, err := net.Listen("tcp4", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go su.Serve(ln)
+	addr := ln.Addr().String()
+
+	idle := dialRequest(t, addr, "/")
+	defer idle.Close()
+	resp, err := http.ReadResponse(bufio.NewReader(idle), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	active := dialRequest(t, addr, "/slow")
+	defer active.Close()
+
+	waitStats(t, su, func(s Stats) bool {
+		return s.IdleConnections == 1 && s.ActiveConnections == 1
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	if err := su.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected the shutdown to be expired but got: %v", err)
+	}
+
+	// the active connection is still open after the expired shutdown.
+	active.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, err := active.Read(make([]byte, 1)); err == nil || err == io.EOF {
+		t.Fatalf("expected the active connection to be open but got: %v", err)
+	}
+
+	stats := su.Stats()
+	if stats.DrainedConnections != 1 || stats.ForciblyClosedConnections != 0 || stats.OpenConnections != 1 {
+		t.Fatalf("expected one drained and one open connection but got: %#v", stats)
+	}
+
+	// the close closes it forcibly.
+	su.Close()
+	active.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := active.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the active connection to be closed but got: %v", err)
+	}
+
+	stats = su.Stats()
+	if stats.DrainedConnections != 1 || stats.ForciblyClosedConnections != 1 || stats.OpenConnections != 0 {
+		t.Fatalf("expected one drained and one forcibly closed connection but got: %#v", stats)
+	}
+
+	for _, expected := range []string{"shutdown: 1 connection(s) drained, 1 still open", "close: 1 connection(s) forcibly closed"} {
+		if got := logs.String(); !strings.Contains(got, expected) {
+			t.Fatalf("expected the log to contain '%s' but got '%s'", expected, got)
+		}
+	}
+}
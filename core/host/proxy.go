@@ -1,15 +1,21 @@
 package host
 
 import (
+	"context"
 	"crypto/tls"
+	"io"
+	"net"
 	"net/http"
 	"net/http/httputil"
 	"net/url"
 	"strings"
 
+	"github.com/get-ion/ion/core/errors"
 	"github.com/get-ion/ion/core/netutil"
 )
 
+var errUpgradeNotSupported = errors.New("proxy: the transport doesn't support the protocol switch")
+
 func singleJoiningSlash(a, b string) string {
 	aslash := strings.HasSuffix(a, "/")
 	bslash := strings.HasPrefix(b, "/")
@@ -22,52 +28,472 @@ func singleJoiningSlash(a, b string) string {
 	return a + b
 }
 
+// ProxyOptions are the options of a `Proxy`, see `NewReverseProxy`.
+type ProxyOptions struct {
+	// StripPrefix is removed from the request path before it's joined with the target's path,
+	// i.e the "/api/users" request of the "/api" prefix to the "http://backend/v1" target
+	// is forwarded to the "http://backend/v1/users".
+	// The removed prefix is sent by the "X-Forwarded-Prefix" header.
+	StripPrefix string
+	// PreserveHost sends the "Host" header of the client to the target
+	// instead of the target's host.
+	PreserveHost bool
+	// RequestHeaders are set to the requests which are forwarded to the target,
+	// an empty value removes the header.
+	RequestHeaders map[string]string
+	// ResponseHeaders are set to the responses of the target,
+	// an empty value removes the header.
+	ResponseHeaders map[string]string
+	// RewriteLocation rewrites the "Location" and "Content-Location" response headers
+	// which point to the target, i.e on redirects, to the host, the scheme and the prefix of the proxy.
+	RewriteLocation bool
+	// RewriteCookies rewrites the "Domain" attribute of the "Set-Cookie" response headers
+	// to the host of the proxy and the "Path" attribute to the prefix of the proxy.
+	RewriteCookies bool
+	// Transport sends the requests to the target, the upgrade requests too,
+	// i.e a transport with the tls configuration of the target.
+	// Defaults to the http.DefaultTransport or, for the loopback targets,
+	// to a transport which accepts their self-signed certificates.
+	Transport http.RoundTripper
+}
+
+// Proxy is a reverse proxy to a single target, see `NewReverseProxy`.
+//
+// It embeds the httputil.ReverseProxy, so its Transport, ErrorLog and FlushInterval
+// can be customized, its ModifyResponse applies the response options.
+// The upgrade requests, i.e websockets, are sent by its Transport and
+// tunnelled through the hijacked connection of the client.
+type Proxy struct {
+	*httputil.ReverseProxy
+	// Target is the scheme, host, and base path of the forwarded requests.
+	Target *url.URL
+	// Options are the options of the proxy, see `ProxyOptions`.
+	Options ProxyOptions
+}
+
+// the context key of the client's view of a request, see `proxyOrigin`.
+type proxyOriginKey struct{}
+
+// proxyOrigin is the host, the scheme and the remote address of a request
+// which is sent by the client to the proxy.
+type proxyOrigin struct {
+	host       string
+	proto      string
+	remoteAddr string
+}
+
+func originOf(req *http.Request) proxyOrigin {
+	if o, ok := req.Context().Value(proxyOriginKey{}).(proxyOrigin); ok {
+		return o
+	}
+
+	proto := "http"
+	if req.TLS != nil {
+		proto = "https"
+	}
+	return proxyOrigin{host: req.Host, proto: proto, remoteAddr: req.RemoteAddr}
+}
+
 // ProxyHandler returns a new ReverseProxy that rewrites
 // URLs to the scheme, host, and base path provided in target. If the
 // target's path is "/base" and the incoming request was for "/dir",
 // the target request will be for /base/dir.
 //
+// The requests carry the "X-Forwarded-For", "X-Forwarded-Proto", "X-Forwarded-Host"
+// and the "Forwarded" headers.
+//
 // Relative to httputil.NewSingleHostReverseProxy with some additions.
+// See `NewReverseProxy` for the options.
 func ProxyHandler(target *url.URL) *httputil.ReverseProxy {
-	targetQuery := target.RawQuery
-	director := func(req *http.Request) {
-		req.URL.Scheme = target.Scheme
-		req.URL.Host = target.Host
-		req.Host = target.Host
-		req.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
-		if targetQuery == "" || req.URL.RawQuery == "" {
-			req.URL.RawQuery = targetQuery + req.URL.RawQuery
-		} else {
-			req.URL.RawQuery = targetQuery + "&" + req.URL.RawQuery
+	return NewReverseProxy(target).ReverseProxy
+}
+
+// NewReverseProxy returns a new reverse proxy to the "target", same as the `ProxyHandler`.
+// The optional "options" can strip a path prefix, preserve the client's host
+// and rewrite the headers of the requests and the responses, see `ProxyOptions`.
+// The upgrade requests, i.e websockets, are tunnelled too.
+//
+// Usage:
+// app.Any("/api/{p:path}", handlerconv.FromStd(NewReverseProxy(target, ProxyOptions{StripPrefix: "/api"})))
+// or see the `Party#Proxy`.
+func NewReverseProxy(target *url.URL, options ...ProxyOptions) *Proxy {
+	p := &Proxy{Target: target}
+	if len(options) > 0 {
+		p.Options = options[0]
+	}
+
+	p.ReverseProxy = &httputil.ReverseProxy{
+		Director:       p.director,
+		ModifyResponse: p.modifyResponse,
+	}
+
+	if p.Options.Transport != nil {
+		p.Transport = p.Options.Transport
+	} else if netutil.IsLoopbackHost(target.Host) {
+		p.Transport = loopbackTransport
+	}
+
+	return p
+}
+
+// loopbackTransport is the transport of the proxies to the loopback hosts,
+// which are usually served with self-signed certificates.
+var loopbackTransport http.RoundTripper = &http.Transport{
+	TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+}
+
+// rewriteRequestURL rewrites the url of the "req" to the scheme, host, and base path of the "target".
+func rewriteRequestURL(req *http.Request, target *url.URL) {
+	req.URL.Scheme = target.Scheme
+	req.URL.Host = target.Host
+	req.Host = target.Host
+	req.URL.Path = singleJoiningSlash(target.Path, req.URL.Path)
+	if target.RawQuery == "" || req.URL.RawQuery == "" {
+		req.URL.RawQuery = target.RawQuery + req.URL.RawQuery
+	} else {
+		req.URL.RawQuery = target.RawQuery + "&" + req.URL.RawQuery
+	}
+}
+
+// stripPathPrefix removes the "prefix" from the "p", if it's a prefix of its segments,
+// i.e "/api" of "/api/users" but not of "/apis".
+func stripPathPrefix(p string, prefix string) (string, bool) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	if prefix == "" || !strings.HasPrefix(p, prefix) || (len(p) > len(prefix) && p[len(prefix)] != '/') {
+		return p, false
+	}
+
+	if p = p[len(prefix):]; p == "" {
+		p = "/"
+	}
+	return p, true
+}
+
+// ServeHTTP forwards the request to the target.
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	r = r.WithContext(context.WithValue(r.Context(), proxyOriginKey{}, originOf(r)))
+
+	if isUpgradeRequest(r) {
+		p.serveUpgrade(w, r)
+		return
+	}
+
+	p.ReverseProxy.ServeHTTP(w, r)
+}
+
+func (p *Proxy) director(req *http.Request) {
+	origin := originOf(req)
+
+	if p.Options.StripPrefix != "" {
+		if stripped, ok := stripPathPrefix(req.URL.Path, p.Options.StripPrefix); ok {
+			req.URL.Path = stripped
+			req.URL.RawPath = ""
+			req.Header.Set("X-Forwarded-Prefix", strings.TrimSuffix(p.Options.StripPrefix, "/"))
+		}
+	}
+
+	rewriteRequestURL(req, p.Target)
+	if p.Options.PreserveHost {
+		req.Host = origin.host
+	}
+
+	setForwardedHeaders(req.Header, origin)
+	setHeaders(req.Header, p.Options.RequestHeaders)
+
+	if _, ok := req.Header["User-Agent"]; !ok {
+		// explicitly disable User-Agent so it's not set to default value
+		req.Header.Set("User-Agent", "")
+	}
+}
+
+// setForwardedHeaders sets the "X-Forwarded-Proto" and "X-Forwarded-Host" headers
+// and appends the proxy's element to the "Forwarded" header, see https://tools.ietf.org/html/rfc7239.
+// The "X-Forwarded-For" is appended by the httputil.ReverseProxy.
+func setForwardedHeaders(h http.Header, origin proxyOrigin) {
+	h.Set("X-Forwarded-Proto", origin.proto)
+	h.Set("X-Forwarded-Host", origin.host)
+
+	element := "proto=" + origin.proto + ";host=" + quoteForwarded(origin.host)
+	if ip, _, err := net.SplitHostPort(origin.remoteAddr); err == nil {
+		if strings.Contains(ip, ":") {
+			// ipv6.
+			ip = "[" + ip + "]"
 		}
+		element = "for=" + quoteForwarded(ip) + ";" + element
+	}
+
+	if prior := strings.Join(h["Forwarded"], ", "); prior != "" {
+		element = prior + ", " + element
+	}
+	h.Set("Forwarded", element)
+}
+
+// quoteForwarded quotes a value of the "Forwarded" header if it's not a token, i.e an ipv6 address or a host:port.
+func quoteForwarded(v string) string {
+	if strings.ContainsAny(v, ":[]") {
+		return `"` + v + `"`
+	}
+	return v
+}
 
-		if _, ok := req.Header["User-Agent"]; !ok {
-			// explicitly disable User-Agent so it's not set to default value
-			req.Header.Set("User-Agent", "")
+// setHeaders sets the "values" to the header "h", the empty values remove the headers.
+func setHeaders(h http.Header, values map[string]string) {
+	for k, v := range values {
+		if v == "" {
+			h.Del(k)
+			continue
 		}
+		h.Set(k, v)
 	}
-	p := &httputil.ReverseProxy{Director: director}
+}
+
+func (p *Proxy) modifyResponse(resp *http.Response) error {
+	origin := originOf(resp.Request)
 
-	if netutil.IsLoopbackHost(target.Host) {
-		transport := &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	if p.Options.RewriteLocation {
+		for _, key := range []string{"Location", "Content-Location"} {
+			if loc := resp.Header.Get(key); loc != "" {
+				resp.Header.Set(key, p.rewriteLocation(loc, origin))
+			}
 		}
-		p.Transport = transport
 	}
 
-	return p
+	if p.Options.RewriteCookies {
+		cookies := resp.Header["Set-Cookie"]
+		for i, c := range cookies {
+			cookies[i] = p.rewriteCookie(c, origin)
+		}
+	}
+
+	setHeaders(resp.Header, p.Options.ResponseHeaders)
+	return nil
+}
+
+// rewritePath returns the path of the proxy of a target's path, or false if it's not a path of the target.
+func (p *Proxy) rewritePath(targetPath string) (string, bool) {
+	if tp := strings.TrimSuffix(p.Target.Path, "/"); tp != "" {
+		stripped, ok := stripPathPrefix(targetPath, tp)
+		if !ok {
+			return targetPath, false
+		}
+		targetPath = stripped
+	}
+
+	if prefix := strings.TrimSuffix(p.Options.StripPrefix, "/"); prefix != "" {
+		if targetPath == "/" {
+			return prefix, true
+		}
+		return singleJoiningSlash(prefix, targetPath), true
+	}
+	return targetPath, true
+}
+
+// rewriteLocation rewrites a location of the target, absolute or relative, to the location of the proxy.
+func (p *Proxy) rewriteLocation(loc string, origin proxyOrigin) string {
+	u, err := url.Parse(loc)
+	if err != nil {
+		return loc
+	}
+
+	if u.Host != "" {
+		if !strings.EqualFold(u.Host, p.Target.Host) {
+			// i.e a redirect to an external site.
+			return loc
+		}
+		u.Scheme = origin.proto
+		u.Host = origin.host
+	} else if !strings.HasPrefix(u.Path, "/") {
+		// relative to the current path.
+		return loc
+	}
+
+	if rewritten, ok := p.rewritePath(u.Path); ok {
+		u.Path = rewritten
+		u.RawPath = ""
+	}
+	return u.String()
+}
+
+// rewriteCookie rewrites the "Domain" and the "Path" attributes of a "Set-Cookie" header's value.
+func (p *Proxy) rewriteCookie(cookie string, origin proxyOrigin) string {
+	host := origin.host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	attrs := strings.Split(cookie, ";")
+	// the first one is the name=value.
+	for i := 1; i < len(attrs); i++ {
+		kv := strings.SplitN(strings.TrimSpace(attrs[i]), "=", 2)
+		if len(kv) != 2 {
+			continue
+		}
+
+		switch strings.ToLower(kv[0]) {
+		case "domain":
+			attrs[i] = " " + kv[0] + "=" + host
+		case "path":
+			if rewritten, ok := p.rewritePath(kv[1]); ok {
+				attrs[i] = " " + kv[0] + "=" + rewritten
+			}
+		}
+	}
+	return strings.Join(attrs, ";")
+}
+
+// isUpgradeRequest reports whether the "Connection" header of the request contains the "upgrade" option,
+// i.e a websocket request.
+func isUpgradeRequest(r *http.Request) bool {
+	if r.Header.Get("Upgrade") == "" {
+		return false
+	}
+
+	for _, v := range r.Header["Connection"] {
+		for _, option := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(option), "upgrade") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the hop-by-hop headers which are removed from the upgrade requests,
+// the "Connection" and the "Upgrade" are set again.
+var hopHeaders = []string{
+	"Connection",
+	"Proxy-Connection",
+	"Keep-Alive",
+	"Proxy-Authenticate",
+	"Proxy-Authorization",
+	"Te",
+	"Trailer",
+	"Transfer-Encoding",
+}
+
+// serveUpgrade forwards an upgrade request to the target and,
+// if the target switches the protocol, it tunnels the connection of the client to the target's one.
+// It returns an error if the target can't be reached.
+func (p *Proxy) serveUpgrade(w http.ResponseWriter, r *http.Request) error {
+	outreq := new(http.Request)
+	*outreq = *r
+	outURL := *r.URL
+	outreq.URL = &outURL
+	outreq.Header = make(http.Header, len(r.Header))
+	for k, v := range r.Header {
+		outreq.Header[k] = append([]string(nil), v...)
+	}
+
+	upgrade := r.Header.Get("Upgrade")
+	for _, h := range hopHeaders {
+		outreq.Header.Del(h)
+	}
+	outreq.Header.Set("Connection", "Upgrade")
+	outreq.Header.Set("Upgrade", upgrade)
+
+	if ip, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		if prior := outreq.Header.Get("X-Forwarded-For"); prior != "" {
+			ip = prior + ", " + ip
+		}
+		outreq.Header.Set("X-Forwarded-For", ip)
+	}
+
+	p.Director(outreq)
+	// a client request, it's sent by the transport.
+	outreq.RequestURI = ""
+
+	transport := p.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+
+	// the transport dials the target, with its tls configuration and its proxy, if any.
+	resp, err := transport.RoundTrip(outreq)
+	if err != nil {
+		p.logf("http: proxy error: %v", err)
+		w.WriteHeader(http.StatusBadGateway)
+		return err
+	}
+	defer resp.Body.Close()
+
+	if p.ModifyResponse != nil {
+		if err = p.ModifyResponse(resp); err != nil {
+			p.logf("http: proxy error: %v", err)
+			w.WriteHeader(http.StatusBadGateway)
+			return nil
+		}
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		// the target refused to upgrade, send its response as it's.
+		for k, v := range resp.Header {
+			w.Header()[k] = v
+		}
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return nil
+	}
+
+	// the connection to the target, the http.Transport supports the protocol switch.
+	targetConn, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		p.logf("http: proxy error: the transport doesn't support the protocol switch of the upgrade requests")
+		w.WriteHeader(http.StatusBadGateway)
+		return errUpgradeNotSupported
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		p.logf("http: proxy error: the response writer of the upgrade request is not a http.Hijacker")
+		w.WriteHeader(http.StatusInternalServerError)
+		return nil
+	}
+
+	clientConn, clientBuf, err := hijacker.Hijack()
+	if err != nil {
+		p.logf("http: proxy error: %v", err)
+		return nil
+	}
+	defer clientConn.Close()
+
+	clientBuf.WriteString("HTTP/1.1 " + resp.Status + "\r\n")
+	resp.Header.Write(clientBuf)
+	clientBuf.WriteString("\r\n")
+	if err = clientBuf.Flush(); err != nil {
+		return nil
+	}
+
+	// the buffered data of both sides are copied too.
+	errc := make(chan error, 2)
+	go func() {
+		_, err := io.Copy(targetConn, clientBuf)
+		errc <- err
+	}()
+	go func() {
+		_, err := io.Copy(clientConn, targetConn)
+		errc <- err
+	}()
+	// when one side closes its connection, the other side is closed too.
+	<-errc
+	return nil
+}
+
+func (p *Proxy) logf(format string, args ...interface{}) {
+	if p.ErrorLog != nil {
+		p.ErrorLog.Printf(format, args...)
+	}
 }
 
 // NewProxy returns a new host (server supervisor) which
 // redirects all requests to the target.
-// It uses the httputil.NewSingleHostReverseProxy.
+// It uses the `NewReverseProxy` with the optional "options".
 //
 // Usage:
 // target, _ := url.Parse("https://mydomain.com")
 // proxy := NewProxy("mydomain.com:80", target)
 // proxy.ListenAndServe() // use of proxy.Shutdown to close the proxy server.
-func NewProxy(hostAddr string, target *url.URL) *Supervisor {
-	proxyHandler := ProxyHandler(target)
+func NewProxy(hostAddr string, target *url.URL, options ...ProxyOptions) *Supervisor {
+	proxyHandler := NewReverseProxy(target, options...)
 	proxy := New(&http.Server{
 		Addr:    hostAddr,
 		Handler: proxyHandler,
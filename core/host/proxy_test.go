@@ -2,9 +2,18 @@
 package host_test
 
 import (
+	"bufio"
+	stdcontext "context"
+	"fmt"
+	"io"
+	"io/ioutil"
 	"net"
+	"net/http"
+	stdhttptest "net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/get-ion/ion"
 	"github.com/get-ion/ion/context"
@@ -58,3 +67,184 @@ func TestProxy(t *testing.T) {
 	e.GET("/about").Expect().Status(ion.StatusOK).Body().Equal(expectedAbout)
 	e.GET("/notfound").Expect().Status(ion.StatusNotFound).Body().Equal(unexpectedRoute)
 }
+
+func TestProxyOptions(t *testing.T) {
+	backend := stdhttptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/login" {
+			w.Header().Set("Location", "http://"+r.Header.Get("X-Backend-Host")+"/v1/users?id=1")
+			w.Header().Add("Set-Cookie", "session=1; Domain=backend.local; Path=/v1/users; HttpOnly")
+			w.Header().Set("Server", "backend")
+			w.WriteHeader(http.StatusFound)
+			return
+		}
+
+		fmt.Fprintf(w, "%s|%s|%s|%s|%s|%s|%s|%s", r.URL.Path, r.Host,
+			r.Header.Get("X-Forwarded-Proto"), r.Header.Get("X-Forwarded-Host"),
+			r.Header.Get("X-Forwarded-Prefix"), r.Header.Get("Forwarded"),
+			r.Header.Get("X-Secret"), r.Header.Get("X-Added"))
+	}))
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL + "/v1")
+	proxy := stdhttptest.NewServer(host.NewReverseProxy(target, host.ProxyOptions{
+		StripPrefix:     "/api",
+		PreserveHost:    true,
+		RequestHeaders:  map[string]string{"X-Secret": "", "X-Added": "1", "X-Backend-Host": target.Host},
+		ResponseHeaders: map[string]string{"Server": ""},
+		RewriteLocation: true,
+		RewriteCookies:  true,
+	}))
+	defer proxy.Close()
+	proxyHost := strings.TrimPrefix(proxy.URL, "http://")
+
+	req, _ := http.NewRequest("GET", proxy.URL+"/api/users", nil)
+	req.Header.Set("X-Secret", "secret")
+	req.Header.Set("Forwarded", "for=192.0.2.60")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	expected := "/v1/users|" + proxyHost + "|http|" + proxyHost + "|/api|" +
+		`for=192.0.2.60, for=127.0.0.1;proto=http;host="` + proxyHost + `"||1`
+	if got := string(b); got != expected {
+		t.Fatalf("expected the forwarded request to be\n%s\nbut got\n%s", expected, got)
+	}
+
+	resp, err = http.DefaultTransport.RoundTrip(mustRequest("GET", proxy.URL+"/api/login"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	if expected, got := proxy.URL+"/api/users?id=1", resp.Header.Get("Location"); expected != got {
+		t.Fatalf("expected the location %s but got %s", expected, got)
+	}
+	if expected, got := "session=1; Domain=127.0.0.1; Path=/api/users; HttpOnly", resp.Header.Get("Set-Cookie"); expected != got {
+		t.Fatalf("expected the cookie %s but got %s", expected, got)
+	}
+	if got := resp.Header.Get("Server"); got != "" {
+		t.Fatalf("expected the Server header to be removed but got %s", got)
+	}
+}
+
+func mustRequest(method, u string) *http.Request {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		panic(err)
+	}
+	return req
+}
+
+// echoUpgrade is a backend of an echo protocol.
+var echoUpgrade = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Upgrade") != "echo" {
+		http.Error(w, "upgrade required", http.StatusUpgradeRequired)
+		return
+	}
+
+	conn, buf, err := w.(http.Hijacker).Hijack()
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	buf.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: echo\r\n" +
+		"X-Forwarded-For: " + r.Header.Get("X-Forwarded-For") + "\r\n\r\n")
+	buf.Flush()
+	io.Copy(conn, buf)
+})
+
+// expectEchoUpgrade upgrades a connection to the "proxyURL" and expects the echo protocol.
+func expectEchoUpgrade(t *testing.T, proxyURL string) {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(proxyURL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	req := mustRequest("GET", proxyURL+"/echo")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "echo")
+	if err = req.Write(conn); err != nil {
+		t.Fatal(err)
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Upgrade") != "echo" {
+		t.Fatalf("expected the upgrade response but got %s %v", resp.Status, resp.Header)
+	}
+	if got := resp.Header.Get("X-Forwarded-For"); got != "127.0.0.1" {
+		t.Fatalf("expected the X-Forwarded-For of the client but got %s", got)
+	}
+
+	for _, msg := range []string{"ping\n", "pong\n"} {
+		conn.Write([]byte(msg))
+		line, err := br.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		if line != msg {
+			t.Fatalf("expected the echo %q but got %q", msg, line)
+		}
+	}
+}
+
+func TestProxyUpgrade(t *testing.T) {
+	backend := stdhttptest.NewServer(echoUpgrade)
+	defer backend.Close()
+
+	target, _ := url.Parse(backend.URL)
+	proxy := stdhttptest.NewServer(host.NewReverseProxy(target))
+	defer proxy.Close()
+
+	expectEchoUpgrade(t, proxy.URL)
+
+	// the refused upgrade is sent as it's.
+	req := mustRequest("GET", proxy.URL+"/echo")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired {
+		t.Fatalf("expected the refused upgrade's status code but got %d", resp.StatusCode)
+	}
+}
+
+func TestProxyUpgradeTransport(t *testing.T) {
+	// the certificate of the backend is trusted only by the proxy's transport.
+	backend := stdhttptest.NewTLSServer(echoUpgrade)
+	defer backend.Close()
+
+	dialed := make(chan struct{}, 1)
+	transport := backend.Client().Transport.(*http.Transport).Clone()
+	transport.DialContext = func(ctx stdcontext.Context, network, addr string) (net.Conn, error) {
+		select {
+		case dialed <- struct{}{}:
+		default:
+		}
+		return new(net.Dialer).DialContext(ctx, network, addr)
+	}
+
+	target, _ := url.Parse(backend.URL)
+	proxy := stdhttptest.NewServer(host.NewReverseProxy(target, host.ProxyOptions{Transport: transport}))
+	defer proxy.Close()
+
+	expectEchoUpgrade(t, proxy.URL)
+
+	select {
+	case <-dialed:
+	default:
+		t.Fatalf("expected the upgrade to be sent by the proxy's transport")
+	}
+}
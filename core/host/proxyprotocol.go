@@ -0,0 +1,53 @@
+package host
+
+import (
+	"context"
+	"net"
+
+	"github.com/get-ion/ion/core/netutil"
+)
+
+// SetProxyProtocol enables the PROXY protocol, the version 1 and 2 headers
+// which are sent by the proxies and the load balancers in TCP mode,
+// i.e the HAProxy's "send-proxy" or the AWS Network Load Balancer,
+// for the connections of the trusted networks of the "cfg", see `netutil#ProxyProtocolWithConfig`.
+// It should be called before the `ListenAndServe`, the `ListenAndServeTLS`, the `ListenAndServeCerts` or the `Serve`.
+// It returns an error if the trusted networks are empty.
+//
+// The request's `RemoteAddr` is the address of the client instead of the proxy's one
+// and the header, its TLVs too, is available to the handlers by the `netutil#ProxyHeaderOf`.
+func (su *Supervisor) SetProxyProtocol(cfg netutil.ProxyProtocolConfig) error {
+	// validate the configuration before the listener.
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+
+	su.mu.Lock()
+	defer su.mu.Unlock()
+
+	if !su.proxyProtocol {
+		// keep the header of the connection to the request's context.
+		connContext := su.server.ConnContext
+		su.server.ConnContext = func(ctx context.Context, c net.Conn) context.Context {
+			if connContext != nil {
+				ctx = connContext(ctx, c)
+			}
+			return netutil.ProxyConnContext(ctx, c)
+		}
+	}
+
+	su.proxyProtocol = true
+	su.proxyConfig = cfg
+	return nil
+}
+
+// proxyListener returns a PROXY protocol listener of "l" if it's enabled, otherwise the "l".
+func (su *Supervisor) proxyListener(l net.Listener) (net.Listener, error) {
+	su.mu.Lock()
+	defer su.mu.Unlock()
+
+	if !su.proxyProtocol {
+		return l, nil
+	}
+	return netutil.ProxyProtocolWithConfig(l, su.proxyConfig)
+}
@@ -0,0 +1,202 @@
+package host
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+	"github.com/get-ion/ion/core/netutil"
+)
+
+// the environment variable of the file descriptor which a restarted child process
+// writes to when it's ready to serve, see `Restart`.
+const readyFDEnv = "ION_READY_FD"
+
+var (
+	errRestartNotSupported = errors.New("graceful restart is not supported on this operating system")
+	errRestartNoListeners  = errors.New("graceful restart: no listeners to pass to the new process")
+	errRestartTimeout      = errors.New("graceful restart: the new process was not ready after %s")
+	errRestartExited       = errors.New("graceful restart: the new process exited before it was ready")
+)
+
+// filer is implemented by the tcp and unix listeners,
+// their files are passed to the new process on a restart.
+type filer interface {
+	File() (*os.File, error)
+}
+
+// handoff keeps the listeners of the process,
+// the inherited ones that are not used yet and the ones that are passed on a restart.
+var handoff = struct {
+	mu   sync.Mutex
+	once sync.Once
+	// the inherited listeners that are not used by a supervisor yet.
+	inherited []net.Listener
+	// the listeners which are passed to the new process, by their addresses.
+	listeners map[string]filer
+	// the order of the listeners.
+	addrs []string
+
+	// the current restart, if any, see `restartShared`.
+	restartDone chan struct{}
+	restartErr  error
+}{
+	listeners: make(map[string]filer),
+}
+
+func loadInherited() {
+	handoff.once.Do(func() {
+		// the errors are ignored, the supervisors create new listeners instead.
+		handoff.inherited, _ = netutil.InheritedListeners()
+	})
+}
+
+// takeInheritedListener returns the inherited listener of the "addr", if any,
+// so a restarted or a socket activated process uses it instead of a new one.
+func takeInheritedListener(addr string) net.Listener {
+	loadInherited()
+
+	handoff.mu.Lock()
+	defer handoff.mu.Unlock()
+
+	for i, l := range handoff.inherited {
+		if netutil.IsSameAddr(l.Addr(), addr) {
+			handoff.inherited = append(handoff.inherited[:i], handoff.inherited[i+1:]...)
+			return l
+		}
+	}
+	return nil
+}
+
+// registerListener keeps the listener "l" in order to be passed to the new process on a restart.
+func registerListener(l net.Listener) {
+	f, ok := l.(filer)
+	if !ok {
+		return
+	}
+
+	addr := l.Addr().String()
+
+	handoff.mu.Lock()
+	if _, exists := handoff.listeners[addr]; !exists {
+		handoff.addrs = append(handoff.addrs, addr)
+	}
+	handoff.listeners[addr] = f
+	handoff.mu.Unlock()
+}
+
+// notifyReady notifies the parent process, if it's restarted, that the process is ready to serve,
+// when all the inherited listeners are used by the supervisors.
+func notifyReady() {
+	loadInherited()
+
+	handoff.mu.Lock()
+	pending := len(handoff.inherited)
+	handoff.mu.Unlock()
+	if pending > 0 {
+		return
+	}
+
+	fd := os.Getenv(readyFDEnv)
+	if fd == "" {
+		return
+	}
+	os.Unsetenv(readyFDEnv)
+
+	if n, err := strconv.Atoi(fd); err == nil {
+		f := os.NewFile(uintptr(n), "ready")
+		f.Write([]byte{1})
+		f.Close()
+	}
+}
+
+// listenerFiles returns the files of the registered listeners,
+// the closed listeners are skipped.
+func listenerFiles() []*os.File {
+	handoff.mu.Lock()
+	defer handoff.mu.Unlock()
+
+	files := make([]*os.File, 0, len(handoff.addrs))
+	for _, addr := range handoff.addrs {
+		if f, err := handoff.listeners[addr].File(); err == nil {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// restartShared restarts the process once for all the supervisors which call it at the same time,
+// a failed restart can be retried.
+func restartShared(readyTimeout time.Duration) error {
+	handoff.mu.Lock()
+	done := handoff.restartDone
+	if done == nil {
+		done = make(chan struct{})
+		handoff.restartDone = done
+		go func() {
+			_, err := Restart(readyTimeout)
+
+			handoff.mu.Lock()
+			handoff.restartErr = err
+			if err != nil {
+				handoff.restartDone = nil
+			}
+			handoff.mu.Unlock()
+			close(done)
+		}()
+	}
+	handoff.mu.Unlock()
+
+	<-done
+
+	handoff.mu.Lock()
+	err := handoff.restartErr
+	handoff.mu.Unlock()
+	return err
+}
+
+// RestartOnSignalTask returns a supervisor's built'n task which restarts the process gracefully
+// when a SIGHUP or a SIGUSR2 signal is received, see `Restart`.
+// When the new process is ready, the host is shut down, the active connections are drained
+// for the "shutdownTimeout", so no connection is dropped on deploys.
+// If the restart fails the host keeps serving and the error is sent to the host's `Err`.
+//
+// The listeners of the supervisors that use the `ListenAndServe`, `ListenAndServeTLS`
+// and `ListenAndServeAutoTLS` or the `Serve` with a tcp or unix listener are passed to the new process,
+// its supervisors use them, instead of new ones, if their addresses are the same.
+//
+// Usage:
+// app.Scheduler.Schedule(host.RestartOnSignalTask(10 * time.Second))
+func RestartOnSignalTask(shutdownTimeout time.Duration) TaskRunner {
+	return TaskRunnerFunc(func(proc TaskProcess) {
+		signals := notifyRestartSignals()
+		if signals == nil {
+			return
+		}
+		defer stopRestartSignals(signals)
+
+		for {
+			select {
+			case <-proc.Done():
+				return
+			case <-signals:
+				if err := restartShared(shutdownTimeout); err != nil {
+					proc.Host().su.notifyErr(err)
+					continue
+				}
+
+				// wait for the active connections before the exit.
+				proc.Host().DeferFlow()
+				ctx, cancel := context.WithTimeout(context.TODO(), shutdownTimeout)
+				proc.Host().Shutdown(ctx)
+				cancel()
+				proc.Host().RestoreFlow()
+				return
+			}
+		}
+	})
+}
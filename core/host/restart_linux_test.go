@@ -0,0 +1,122 @@
+// white-box testing
+
+package host
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion/core/netutil"
+)
+
+const (
+	restartChildEnv = "ION_TEST_RESTART_CHILD"
+	restartAddrEnv  = "ION_TEST_RESTART_ADDR"
+)
+
+// TestMain runs the restarted process of the TestRestart
+// instead of the tests, the child serves the listener of its parent.
+func TestMain(m *testing.M) {
+	if os.Getenv(restartChildEnv) == "" {
+		os.Exit(m.Run())
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("child"))
+	})
+	mux.HandleFunc("/exit", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("bye"))
+		go func() {
+			time.Sleep(50 * time.Millisecond)
+			os.Exit(0)
+		}()
+	})
+
+	// if the listener is not inherited, the address is in use.
+	su := New(&http.Server{Addr: os.Getenv(restartAddrEnv), Handler: mux})
+	go func() {
+		time.Sleep(10 * time.Second)
+		os.Exit(1)
+	}()
+	su.ListenAndServe()
+	os.Exit(1)
+}
+
+func TestRestart(t *testing.T) {
+	l, err := netutil.TCP("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+
+	su := New(&http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("parent"))
+	})})
+	go su.Serve(l)
+
+	get := func(path string) string {
+		client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+		resp, err := client.Get("http://" + addr + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return string(b)
+	}
+
+	if got := get("/"); got != "parent" {
+		t.Fatalf("expected a response from the parent but got '%s'", got)
+	}
+
+	os.Setenv(restartChildEnv, "1")
+	os.Setenv(restartAddrEnv, addr)
+	defer os.Unsetenv(restartChildEnv)
+	defer os.Unsetenv(restartAddrEnv)
+
+	proc, err := Restart(5 * time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.TODO(), 5*time.Second)
+	defer cancel()
+	if err = su.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := get("/"); got != "child" {
+		t.Fatalf("expected a response from the child after the shutdown of the parent but got '%s'", got)
+	}
+
+	get("/exit")
+	state, err := proc.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !state.Success() {
+		t.Fatalf("expected the child to exit successfully but it exited with %s", state)
+	}
+}
+
+func TestRestartNoListeners(t *testing.T) {
+	handoff.mu.Lock()
+	listeners, addrs := handoff.listeners, handoff.addrs
+	handoff.listeners, handoff.addrs = make(map[string]filer), nil
+	handoff.mu.Unlock()
+
+	defer func() {
+		handoff.mu.Lock()
+		handoff.listeners, handoff.addrs = listeners, addrs
+		handoff.mu.Unlock()
+	}()
+
+	if _, err := Restart(time.Second); err == nil || err.Error() != errRestartNoListeners.Error() {
+		t.Fatalf("expected the '%v' error but got '%v'", errRestartNoListeners, err)
+	}
+}
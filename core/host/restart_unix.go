@@ -0,0 +1,105 @@
+//go:build !windows
+// +build !windows
+
+package host
+
+import (
+	"os"
+	"os/exec"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/get-ion/ion/core/netutil"
+)
+
+// Restart starts a new process of the same executable, with the same arguments,
+// which inherits the listeners of the supervisors by the LISTEN_FDS convention.
+// It returns the new process when it's ready to serve, when its supervisors serve the inherited listeners,
+// otherwise, after the "readyTimeout", the new process is killed and an error is returned.
+//
+// The current process should shut down its supervisors after, see `RestartOnSignalTask`.
+func Restart(readyTimeout time.Duration) (*os.Process, error) {
+	files := listenerFiles()
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	if len(files) == 0 {
+		return nil, errRestartNoListeners
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, err
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var env []string
+	for _, v := range os.Environ() {
+		if !strings.HasPrefix(v, "LISTEN_") && !strings.HasPrefix(v, readyFDEnv+"=") {
+			env = append(env, v)
+		}
+	}
+	env = append(env, netutil.ListenFDsEnv(len(files))...)
+	// the ready pipe is passed after the listeners.
+	env = append(env, readyFDEnv+"="+strconv.Itoa(3+len(files)))
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = env
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.ExtraFiles = append(files, w)
+
+	err = cmd.Start()
+	// the child has its own copy.
+	w.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	ready := make(chan error, 1)
+	go func() {
+		b := make([]byte, 1)
+		if n, _ := r.Read(b); n == 1 {
+			ready <- nil
+			return
+		}
+		// the pipe is closed without a write, the child exited.
+		ready <- errRestartExited
+	}()
+
+	select {
+	case err = <-ready:
+	case <-time.After(readyTimeout):
+		err = errRestartTimeout.Format(readyTimeout)
+	}
+
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	return cmd.Process, nil
+}
+
+func notifyRestartSignals() chan os.Signal {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGHUP, syscall.SIGUSR2)
+	return ch
+}
+
+func stopRestartSignals(ch chan os.Signal) {
+	signal.Stop(ch)
+}
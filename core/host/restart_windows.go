@@ -0,0 +1,21 @@
+//go:build windows
+// +build windows
+
+package host
+
+import (
+	"os"
+	"time"
+)
+
+// Restart is not supported on windows, it returns an error.
+func Restart(readyTimeout time.Duration) (*os.Process, error) {
+	return nil, errRestartNotSupported
+}
+
+// notifyRestartSignals returns nil, the restart task does nothing.
+func notifyRestartSignals() chan os.Signal {
+	return nil
+}
+
+func stopRestartSignals(ch chan os.Signal) {}
@@ -4,6 +4,37 @@ import (
 	"sync/atomic"
 )
 
+// TaskState is the state of a scheduled task, see `Scheduler#TaskStates`.
+type TaskState uint32
+
+const (
+	// TaskScheduled is the state of the tasks that didn't run yet,
+	// i.e the interrupt tasks until the interrupt signal.
+	TaskScheduled TaskState = iota
+	// TaskRunning is the state of the tasks that are running.
+	TaskRunning
+	// TaskDone is the state of the tasks that returned.
+	TaskDone
+	// TaskCanceled is the state of the canceled tasks.
+	TaskCanceled
+)
+
+// String returns the name of the state, i.e "running".
+func (s TaskState) String() string {
+	switch s {
+	case TaskScheduled:
+		return "scheduled"
+	case TaskRunning:
+		return "running"
+	case TaskDone:
+		return "done"
+	case TaskCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
 type task struct {
 	runner TaskRunner
 	proc   TaskProcess
@@ -12,12 +43,24 @@ type task struct {
 	// canceled before it ever ran, this happens to interrupt handlers too.
 	alreadyCanceled int32
 	Cancel          func()
+	// atomic-accessed, the TaskState.
+	state uint32
+	// closed when the runner returns, after the state is updated.
+	done chan struct{}
 }
 
 func (t *task) isCanceled() bool {
 	return atomic.LoadInt32(&t.alreadyCanceled) != 0
 }
 
+func (t *task) setState(state TaskState) {
+	atomic.StoreUint32(&t.state, uint32(state))
+}
+
+func (t *task) getState() TaskState {
+	return TaskState(atomic.LoadUint32(&t.state))
+}
+
 // Scheduler is a type of an event emmiter.
 // Can register a specific task for a specific event
 // when host is starting the server or host is interrupted by CTRL+C/CMD+C.
@@ -43,6 +86,7 @@ func (s *Scheduler) Schedule(runner TaskRunner) TaskCancelFunc {
 		// it's not running yet, so if canceled now
 		// set to already canceled to not run it at all.
 		atomic.StoreInt32(&t.alreadyCanceled, 1)
+		t.setState(TaskCanceled)
 	}
 
 	if _, ok := runner.(OnInterrupt); ok {
@@ -103,10 +147,18 @@ func runTaskNow(task *task, host TaskHost) {
 	proc := newTaskProcess(host)
 	task.proc = proc
 	task.Cancel = func() {
+		task.setState(TaskCanceled)
 		proc.canceledChan <- struct{}{}
 	}
 
-	go task.runner.Run(proc)
+	task.setState(TaskRunning)
+	task.done = make(chan struct{})
+	go func() {
+		task.runner.Run(proc)
+		// keep the canceled state of the tasks that returned on cancel.
+		atomic.CompareAndSwapUint32(&task.state, uint32(TaskRunning), uint32(TaskDone))
+		close(task.done)
+	}()
 }
 
 func runTasks(tasks []*task, host TaskHost) {
@@ -152,6 +204,16 @@ func (s *Scheduler) notifyErr(err error) {
 	})
 }
 
+// TaskStates returns the number of the scheduled tasks per state,
+// i.e for the metrics middleware.
+func (s *Scheduler) TaskStates() map[TaskState]int {
+	states := make(map[TaskState]int)
+	s.visit(func(t *task) {
+		states[t.getState()]++
+	})
+	return states
+}
+
 // CopyTo copies all tasks from "s" to "to" Scheduler.
 // It doesn't care about anything else.
 func (s *Scheduler) CopyTo(to *Scheduler) {
@@ -8,6 +8,8 @@ import (
 	"net"
 	"net/http"
 	"os"
+	"reflect"
+	"testing"
 	"time"
 )
 
@@ -78,3 +80,25 @@ func SchedulerSchedule() {
 	// Supervisor: cancel sent
 	// Supervisor: canceled, exiting from task AND SHUTDOWN the server...
 }
+
+func TestSchedulerTaskStates(t *testing.T) {
+	su := New(&http.Server{})
+
+	release := make(chan struct{})
+	su.ScheduleFunc(func(proc TaskProcess) {})
+	su.ScheduleFunc(func(proc TaskProcess) { <-release })
+	cancel := su.ScheduleFunc(func(proc TaskProcess) {})
+	cancel()
+	su.OnInterrupt(func() {})
+
+	su.Scheduler.runOnServe(createTaskHost(su))
+	// wait for the first task to return.
+	<-su.Scheduler.onServeTasks[0].done
+
+	expected := map[TaskState]int{TaskDone: 1, TaskRunning: 1, TaskCanceled: 1, TaskScheduled: 1}
+	if got := su.Scheduler.TaskStates(); !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected task states %v but got %v", expected, got)
+	}
+
+	close(release)
+}
@@ -0,0 +1,149 @@
+package host
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// Stats are the connection statistics of a Supervisor's server, see `Supervisor#Stats`.
+type Stats struct {
+	// OpenConnections is the number of the open connections, the hijacked ones are not counted.
+	OpenConnections int
+	// NewConnections, ActiveConnections and IdleConnections are the number of the open connections
+	// per state, see `http.ConnState`.
+	NewConnections    int
+	ActiveConnections int
+	IdleConnections   int
+	// AcceptedConnections is the total number of the accepted connections.
+	AcceptedConnections uint64
+	// TLSHandshakes is the total number of the completed tls handshakes.
+	TLSHandshakes uint64
+	// TLSHandshakeErrors is the total number of the tls connections
+	// which were closed before the handshake was completed.
+	TLSHandshakeErrors uint64
+	// HijackedConnections is the total number of the hijacked connections, i.e the websocket ones.
+	HijackedConnections uint64
+	// RejectedConnections is the total number of the connections
+	// which were rejected by the limits, see `Supervisor#SetConnLimits`.
+	RejectedConnections uint64
+	// DrainedConnections is the total number of the connections
+	// which were closed gracefully, before the context's expiration, on the shutdowns, see `Supervisor#Shutdown`.
+	DrainedConnections uint64
+	// ForciblyClosedConnections is the total number of the connections
+	// which were still open and closed forcibly by the `Supervisor#Close`, i.e after an expired shutdown.
+	ForciblyClosedConnections uint64
+}
+
+// connTracker tracks the states of the server's connections, see `http.Server#ConnState`.
+type connTracker struct {
+	mu    sync.Mutex
+	conns map[net.Conn]http.ConnState
+	stats Stats
+	// the connections which are closed while the server is shutting down.
+	draining bool
+	drained  int
+}
+
+func newConnTracker() *connTracker {
+	return &connTracker{conns: make(map[net.Conn]http.ConnState)}
+}
+
+// track records the new "state" of the connection "c".
+func (t *connTracker) track(c net.Conn, state http.ConnState) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	prev, ok := t.conns[c]
+	if state == http.StateNew {
+		t.stats.AcceptedConnections++
+	} else if ok && prev == http.StateNew {
+		// the tls handshake is done by the server before the first request.
+		if tlsConn, isTLS := c.(*tls.Conn); isTLS {
+			if tlsConn.ConnectionState().HandshakeComplete {
+				t.stats.TLSHandshakes++
+			} else {
+				t.stats.TLSHandshakeErrors++
+			}
+		}
+	}
+
+	switch state {
+	case http.StateHijacked:
+		t.stats.HijackedConnections++
+		delete(t.conns, c)
+	case http.StateClosed:
+		if ok && t.draining {
+			t.drained++
+		}
+		delete(t.conns, c)
+	default:
+		t.conns[c] = state
+	}
+}
+
+// beginShutdown starts counting the drained connections, it returns the number of the open connections.
+func (t *connTracker) beginShutdown() int {
+	t.mu.Lock()
+	t.draining = true
+	t.drained = 0
+	n := len(t.conns)
+	t.mu.Unlock()
+	return n
+}
+
+// endShutdown records the drained connections of a shutdown and returns them
+// with the connections which are still open, if the shutdown is "expired".
+// Otherwise the connections which are not closed yet are closing, they're drained too.
+func (t *connTracker) endShutdown(expired bool) (drained int, open int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.draining = false
+	drained = t.drained
+	if expired {
+		open = len(t.conns)
+	} else {
+		drained += len(t.conns)
+		t.conns = make(map[net.Conn]http.ConnState)
+	}
+
+	t.stats.DrainedConnections += uint64(drained)
+	return
+}
+
+// close records the open connections as forcibly closed and returns their number.
+func (t *connTracker) close() int {
+	t.mu.Lock()
+	n := len(t.conns)
+	t.stats.ForciblyClosedConnections += uint64(n)
+	t.conns = make(map[net.Conn]http.ConnState)
+	t.mu.Unlock()
+	return n
+}
+
+func (t *connTracker) snapshot() Stats {
+	t.mu.Lock()
+	stats := t.stats
+	stats.OpenConnections = len(t.conns)
+	for _, state := range t.conns {
+		switch state {
+		case http.StateNew:
+			stats.NewConnections++
+		case http.StateActive:
+			stats.ActiveConnections++
+		case http.StateIdle:
+			stats.IdleConnections++
+		}
+	}
+	t.mu.Unlock()
+	return stats
+}
+
+// Stats returns the connection statistics of the server, i.e for the metrics middleware.
+func (su *Supervisor) Stats() Stats {
+	stats := su.conns.snapshot()
+	stats.RejectedConnections = su.rejectedConnections()
+	return stats
+}
@@ -3,6 +3,7 @@ package host
 import (
 	"context"
 	"crypto/tls"
+	"crypto/x509"
 	"net"
 	"net/http"
 	"os"
@@ -30,6 +31,20 @@ type Supervisor struct {
 	shutdownChan chan struct{}
 	errChan      chan error
 
+	onShutdown []func()
+	// tracks the connections of the server, see `Stats`.
+	conns *connTracker
+
+	// the client certificates verification, see `SetClientAuth`.
+	clientCAs  *x509.CertPool
+	clientAuth tls.ClientAuthType
+	// the PROXY protocol, see `SetProxyProtocol`.
+	proxyProtocol bool
+	proxyConfig   netutil.ProxyProtocolConfig
+	// the limits of the concurrent connections, see `SetConnLimits`.
+	connLimits netutil.ConnLimits
+	limiters   []*netutil.LimitedListener
+
 	mu sync.Mutex
 }
 
@@ -41,12 +56,24 @@ type Supervisor struct {
 // It has its own flow, which means that you can prevent
 // to return and exit and restore the flow too.
 func New(srv *http.Server) *Supervisor {
-	return &Supervisor{
+	su := &Supervisor{
 		server:       srv,
 		unblockChan:  make(chan struct{}, 1),
 		shutdownChan: make(chan struct{}),
 		errChan:      make(chan error),
+		conns:        newConnTracker(),
+	}
+
+	// track the connections, the server's ConnState is still called.
+	connState := srv.ConnState
+	srv.ConnState = func(c net.Conn, state http.ConnState) {
+		su.conns.track(c, state)
+		if connState != nil {
+			connState(c, state)
+		}
 	}
+
+	return su
 }
 
 // DeferFlow defers the flow of the exeuction,
@@ -79,6 +106,11 @@ func (su *Supervisor) isWaiting() bool {
 	return atomic.LoadInt32(&su.shouldWait) != 0
 }
 
+// Addr returns the address of the server, i.e ":8080".
+func (su *Supervisor) Addr() string {
+	return su.server.Addr
+}
+
 // Done is being received when in server Shutdown.
 // This can be used to gracefully shutdown connections that have
 // undergone NPN/ALPN protocol upgrade or that have been hijacked.
@@ -101,6 +133,25 @@ func (su *Supervisor) notifyShutdown() {
 	su.Scheduler.notifyShutdown()
 }
 
+// RegisterOnShutdown registers a function to call on Shutdown.
+// This can be used to gracefully shutdown connections that have
+// been hijacked, i.e websocket connections.
+// This function should start protocol-specific graceful shutdown,
+// but should not wait for shutdown to complete.
+func (su *Supervisor) RegisterOnShutdown(cb func()) {
+	su.mu.Lock()
+	su.onShutdown = append(su.onShutdown, cb)
+	su.mu.Unlock()
+}
+
+func (su *Supervisor) callOnShutdown() {
+	su.mu.Lock()
+	for _, cb := range su.onShutdown {
+		go cb()
+	}
+	su.mu.Unlock()
+}
+
 func (su *Supervisor) notifyErr(err error) {
 	// if err == http.ErrServerClosed {
 	// 	return
@@ -150,6 +201,9 @@ func (su *Supervisor) supervise(blockFunc func() error) error {
 		}()
 	}
 
+	// notify the parent process, if any, that the inherited listeners are served, see `Restart`.
+	notifyReady()
+
 	err := blockFunc()
 	su.notifyErr(err)
 
@@ -172,13 +226,29 @@ func (su *Supervisor) newListener() (net.Listener, error) {
 	// restarts we may want for the server.
 	//
 	// User still be able to call .Serve instead.
-	l, err := netutil.TCPKeepAlive(su.server.Addr)
+	//
+	// The listener which is inherited from the parent process,
+	// on a graceful restart or on a systemd socket activation, is used instead of a new one.
+	l := takeInheritedListener(su.server.Addr)
+	if l == nil {
+		var err error
+		l, err = netutil.TCPKeepAlive(su.server.Addr)
+		if err != nil {
+			return nil, err
+		}
+	}
+	// keep it to be passed to the new process on a graceful restart.
+	registerListener(l)
+	l = su.limitListener(l)
+	// the PROXY protocol header is sent before the tls handshake.
+	l, err := su.proxyListener(l)
 	if err != nil {
 		return nil, err
 	}
 
 	if netutil.IsTLS(su.server) {
 		// means tls
+		su.configureClientAuth(su.server.TLSConfig)
 		tlsl := tls.NewListener(l, su.server.TLSConfig)
 		return tlsl, nil
 	}
@@ -197,7 +267,20 @@ func (su *Supervisor) newListener() (net.Listener, error) {
 //
 // Serve always returns a non-nil error. After Shutdown or Close, the
 // returned error is http.ErrServerClosed.
+//
+// The PROXY protocol header, if enabled by the `SetProxyProtocol`, is read from the connections of "l",
+// so it should not be a tls listener, see `netutil#ProxyProtocol`.
+// The connections of "l" are limited by the `SetConnLimits` too.
 func (su *Supervisor) Serve(l net.Listener) error {
+	registerListener(l)
+	pl, err := su.proxyListener(su.limitListener(l))
+	if err != nil {
+		return err
+	}
+	return su.serve(pl)
+}
+
+func (su *Supervisor) serve(l net.Listener) error {
 	return su.supervise(func() error { return su.server.Serve(l) })
 }
 
@@ -210,7 +293,7 @@ func (su *Supervisor) ListenAndServe() error {
 	if err != nil {
 		return err
 	}
-	return su.Serve(l)
+	return su.serve(l)
 }
 
 func setupHTTP2(cfg *tls.Config) {
@@ -222,19 +305,30 @@ func setupHTTP2(cfg *tls.Config) {
 // matching private key for the server must be provided. If the certificate
 // is signed by a certificate authority, the certFile should be the concatenation
 // of the server's certificate, any intermediates, and the CA's certificate.
+// The files are reloaded when they're changed, without a restart, see `CertManager`.
 func (su *Supervisor) ListenAndServeTLS(certFile string, keyFile string) error {
 	if certFile == "" || keyFile == "" {
 		return errors.New("certFile or keyFile missing")
 	}
-	cfg := new(tls.Config)
-	var err error
-	cfg.Certificates = make([]tls.Certificate, 1)
-	if cfg.Certificates[0], err = tls.LoadX509KeyPair(certFile, keyFile); err != nil {
+
+	// the files are reloaded when they're changed, i.e on renewals.
+	m := NewCertManager()
+	if err := m.Add(certFile, keyFile); err != nil {
 		return err
 	}
 
-	setupHTTP2(cfg)
-	su.server.TLSConfig = cfg
+	return su.ListenAndServeCerts(m)
+}
+
+// ListenAndServeCerts acts identically to ListenAndServe, except that it
+// expects HTTPS connections and it serves the certificates of the
+// certificate manager "m", chosen by the server name of each client.
+// The manager watches its files and reloads the changed ones
+// until the server's shutdown, see `CertManager`.
+func (su *Supervisor) ListenAndServeCerts(m *CertManager) error {
+	su.server.TLSConfig = m.TLSConfig()
+	m.Watch()
+	su.RegisterOnShutdown(m.Close)
 
 	return su.ListenAndServe()
 }
@@ -264,11 +358,49 @@ func (su *Supervisor) ListenAndServeAutoTLS() error {
 // Shutdown does not attempt to close nor wait for hijacked
 // connections such as WebSockets. The caller of Shutdown should
 // separately notify such long-lived connections of shutdown and wait
-// for them to close, if desired.
+// for them to close, if desired, see `RegisterOnShutdown`.
+//
+// The connections which are still open when the context expires are not closed,
+// call the `Close` to close them forcibly.
+// The number of the drained and the still open connections
+// are reported to the server's ErrorLog, if any, the drained ones are counted by the `Stats`.
 func (su *Supervisor) Shutdown(ctx context.Context) error {
 	// println("Running Shutdown from Supervisor")
 
 	atomic.AddInt32(&su.closedManually, 1) // future-use
 	su.notifyShutdown()
-	return su.server.Shutdown(ctx)
+	su.callOnShutdown()
+	return su.shutdownServer(ctx)
+}
+
+// shutdownServer shuts down the server gracefully and reports the drained connections, see `Shutdown`.
+func (su *Supervisor) shutdownServer(ctx context.Context) error {
+	open := su.conns.beginShutdown()
+	err := su.server.Shutdown(ctx)
+	drained, remaining := su.conns.endShutdown(err != nil)
+
+	if open > 0 && su.server.ErrorLog != nil {
+		su.server.ErrorLog.Printf("shutdown: %d connection(s) drained, %d still open", drained, remaining)
+	}
+
+	return err
+}
+
+// Close closes the listeners and the connections of the server immediately,
+// i.e the connections which are still open after an expired `Shutdown`.
+// It does not close nor wait for the hijacked connections, same as the `Shutdown`.
+//
+// The number of the forcibly closed connections
+// is reported to the server's ErrorLog, if any, and counted by the `Stats`.
+func (su *Supervisor) Close() error {
+	atomic.AddInt32(&su.closedManually, 1) // future-use
+
+	forced := su.conns.close()
+	err := su.server.Close()
+
+	if forced > 0 && su.server.ErrorLog != nil {
+		su.server.ErrorLog.Printf("close: %d connection(s) forcibly closed", forced)
+	}
+
+	return err
 }
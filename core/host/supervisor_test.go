@@ -11,6 +11,7 @@ import (
 	"net/http"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/get-ion/httpexpect"
 )
@@ -108,3 +109,28 @@ func TestSupervisor(t *testing.T) {
 		return su
 	})
 }
+
+func TestSupervisorRegisterOnShutdown(t *testing.T) {
+	srv := &http.Server{Handler: http.NewServeMux()}
+	su := New(srv)
+
+	ln, err := net.Listen("tcp4", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	called := make(chan struct{})
+	su.RegisterOnShutdown(func() { close(called) })
+
+	go su.Serve(ln)
+
+	if err = su.Shutdown(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case <-called:
+	case <-time.After(3 * time.Second):
+		t.Fatalf("expected the registered function to be called on shutdown")
+	}
+}
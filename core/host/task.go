@@ -36,6 +36,11 @@ type TaskHost struct {
 	errChan  chan error
 }
 
+// Supervisor returns the host supervisor of the task.
+func (h TaskHost) Supervisor() *Supervisor {
+	return h.su
+}
+
 // Done filled when server was shutdown.
 func (h TaskHost) Done() <-chan struct{} {
 	return h.doneChan
@@ -83,10 +88,23 @@ func (h TaskHost) Hostname() string {
 // Shutdown does not attempt to close nor wait for hijacked
 // connections such as WebSockets. The caller of Shutdown should
 // separately notify such long-lived connections of shutdown and wait
-// for them to close, if desired.
+// for them to close, if desired, see `RegisterOnShutdown`.
+//
+// The connections which are still open when the context expires are not closed,
+// see `Supervisor#Shutdown` and `Supervisor#Close`.
 func (h TaskHost) Shutdown(ctx context.Context) error {
+	h.su.callOnShutdown()
 	// the underline server's Shutdown (otherwise we will cancel all tasks and do cycles)
-	return h.su.server.Shutdown(ctx)
+	return h.su.shutdownServer(ctx)
+}
+
+// RegisterOnShutdown registers a function to call on Shutdown.
+// This can be used to gracefully shutdown connections that have
+// been hijacked, i.e websocket connections.
+//
+// See `Supervisor#RegisterOnShutdown` too.
+func (h TaskHost) RegisterOnShutdown(cb func()) {
+	h.su.RegisterOnShutdown(cb)
 }
 
 // TaskProcess is the context of the Task runner.
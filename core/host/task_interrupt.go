@@ -16,11 +16,15 @@ func (t OnInterrupt) Run(proc TaskProcess) {
 
 // ShutdownOnInterruptTask returns a supervisor's built'n task which
 // shutdowns the server when InterruptSignalTask fire this task.
+// The connections which are still open after the "shutdownTimeout"
+// are closed forcibly, see `Supervisor#Close`.
 func ShutdownOnInterruptTask(shutdownTimeout time.Duration) TaskRunner {
 	return OnInterrupt(func(proc TaskProcess) {
 		ctx, cancel := context.WithTimeout(context.TODO(), shutdownTimeout)
 		defer cancel()
-		proc.Host().Shutdown(ctx)
+		if err := proc.Host().Shutdown(ctx); err != nil {
+			proc.Host().Supervisor().Close()
+		}
 		proc.Host().RestoreFlow()
 	})
 }
@@ -1,6 +1,7 @@
 package netutil
 
 import (
+	"net"
 	"os"
 	"regexp"
 	"strconv"
@@ -203,3 +204,22 @@ func ResolveScheme(vhost string) string {
 	}
 	return SchemeHTTP
 }
+
+// ParseIPNet parses an ip address or a CIDR, i.e "127.0.0.1" or "10.0.0.0/8",
+// an ip address is the network of that address only.
+func ParseIPNet(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		// a single ip address.
+		if ip := net.ParseIP(s); ip != nil {
+			bits := 8 * net.IPv4len
+			if ip.To4() == nil {
+				bits = 8 * net.IPv6len
+			}
+			s += "/" + strconv.Itoa(bits)
+		}
+	}
+
+	_, ipNet, err := net.ParseCIDR(s)
+	return ipNet, err
+}
@@ -0,0 +1,114 @@
+package netutil
+
+import (
+	"net"
+	"os"
+	"strconv"
+
+	"github.com/get-ion/ion/core/errors"
+)
+
+const (
+	// the environment variables of the systemd socket activation,
+	// see https://www.freedesktop.org/software/systemd/man/sd_listen_fds.html.
+	listenFDsEnv     = "LISTEN_FDS"
+	listenPIDEnv     = "LISTEN_PID"
+	listenFDNamesEnv = "LISTEN_FDNAMES"
+	// the first inherited file descriptor, after the stdin, stdout and stderr.
+	listenFDsStart = 3
+)
+
+var errListenFDs = errors.New("invalid %s=%q")
+
+// InheritedListeners returns the listeners which are passed to the process by its parent,
+// following the LISTEN_FDS convention of the systemd socket activation,
+// the file descriptors start from 3.
+// It's used by the systemd socket activation and by the graceful restart of the host supervisors.
+//
+// The LISTEN_PID, if it's set, should be the pid of the process, otherwise no listeners are returned.
+// The environment variables are unset, so the children of the process don't inherit them.
+func InheritedListeners() ([]net.Listener, error) {
+	fds := os.Getenv(listenFDsEnv)
+	if fds == "" {
+		return nil, nil
+	}
+
+	// the parent doesn't know the pid of a forked child, so it may be missing.
+	if pid := os.Getenv(listenPIDEnv); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	os.Unsetenv(listenFDsEnv)
+	os.Unsetenv(listenPIDEnv)
+	os.Unsetenv(listenFDNamesEnv)
+
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 0 {
+		return nil, errListenFDs.Format(listenFDsEnv, fds)
+	}
+
+	listeners := make([]net.Listener, 0, n)
+	for fd := listenFDsStart; fd < listenFDsStart+n; fd++ {
+		f := os.NewFile(uintptr(fd), "listener"+strconv.Itoa(fd))
+		l, err := net.FileListener(f)
+		// the listener has its own copy of the file descriptor.
+		f.Close()
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+
+		if tl, ok := l.(*net.TCPListener); ok {
+			l = tcpKeepAliveListener{tl}
+		}
+		listeners = append(listeners, l)
+	}
+
+	return listeners, nil
+}
+
+// ListenFDsEnv returns the environment variables of a child process
+// which inherits "n" listeners, see `InheritedListeners`.
+func ListenFDsEnv(n int) []string {
+	return []string{listenFDsEnv + "=" + strconv.Itoa(n)}
+}
+
+// IsSameAddr reports whether the address of a listener, "laddr",
+// is the address "addr" of a server, i.e "[::]:8080" and ":8080".
+func IsSameAddr(laddr net.Addr, addr string) bool {
+	if laddr.String() == addr {
+		return true
+	}
+
+	tcpAddr, ok := laddr.(*net.TCPAddr)
+	if !ok {
+		return false
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || port != strconv.Itoa(tcpAddr.Port) {
+		return false
+	}
+
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		return tcpAddr.IP.IsUnspecified()
+	}
+
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.Equal(tcpAddr.IP)
+	}
+
+	// a hostname, i.e localhost.
+	ips, err := net.LookupIP(host)
+	if err != nil {
+		return false
+	}
+	for _, ip := range ips {
+		if ip.Equal(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package netutil
+
+import (
+	"net"
+	"sync"
+	"sync/atomic"
+
+	"github.com/get-ion/ion/core/errors"
+)
+
+var errListenerClosed = errors.New("limit listener: use of closed listener")
+
+// ConnLimits are the limits of the concurrent connections of a `LimitListener`.
+type ConnLimits struct {
+	// MaxConnections is the max number of the concurrent connections, zero means no limit.
+	MaxConnections int
+	// Queue waits for a connection to be closed when the MaxConnections is reached,
+	// the new connections are queued by the listener's backlog.
+	// Otherwise the new connections are accepted and closed immediately, they're rejected.
+	Queue bool
+	// MaxConnectionsPerIP is the max number of the concurrent connections of an ip address,
+	// the extra connections are rejected, zero means no limit.
+	// The address is the one of the connection, the proxy's one behind a proxy.
+	MaxConnectionsPerIP int
+}
+
+// LimitedListener is a listener which limits its concurrent connections, see `LimitListener`.
+type LimitedListener struct {
+	net.Listener
+	limits ConnLimits
+
+	// the free slots of the MaxConnections.
+	slots chan struct{}
+	// closed on Close, it stops the wait for a free slot.
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu    sync.Mutex
+	perIP map[string]int
+
+	rejected uint64 // accessed atomically.
+}
+
+// LimitListener returns a listener which accepts up to the "limits" concurrent connections of "l",
+// the slot of a connection is released when it's closed, the hijacked connections too.
+func LimitListener(l net.Listener, limits ConnLimits) *LimitedListener {
+	ll := &LimitedListener{Listener: l, limits: limits, perIP: make(map[string]int), done: make(chan struct{})}
+	if limits.MaxConnections > 0 {
+		ll.slots = make(chan struct{}, limits.MaxConnections)
+	}
+	return ll
+}
+
+// Rejected returns the total number of the rejected connections.
+func (l *LimitedListener) Rejected() uint64 {
+	return atomic.LoadUint64(&l.rejected)
+}
+
+// Accept waits for and returns the next connection which is in the limits.
+func (l *LimitedListener) Accept() (net.Conn, error) {
+	for {
+		if l.slots != nil && l.limits.Queue {
+			// wait for a free slot before the accept, or for the close.
+			select {
+			case l.slots <- struct{}{}:
+			case <-l.done:
+				return nil, errListenerClosed
+			}
+		}
+
+		c, err := l.Listener.Accept()
+		if err != nil {
+			if l.slots != nil && l.limits.Queue {
+				<-l.slots
+			}
+			return nil, err
+		}
+
+		if l.slots != nil && !l.limits.Queue {
+			select {
+			case l.slots <- struct{}{}:
+			default:
+				l.reject(c)
+				continue
+			}
+		}
+
+		ip := connIP(c)
+		if !l.acquireIP(ip) {
+			if l.slots != nil {
+				<-l.slots
+			}
+			l.reject(c)
+			continue
+		}
+
+		return &limitedConn{Conn: c, release: func() { l.release(ip) }}, nil
+	}
+}
+
+// Close closes the listener, the Accept which waits for a free slot returns an error.
+func (l *LimitedListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return l.Listener.Close()
+}
+
+func (l *LimitedListener) reject(c net.Conn) {
+	atomic.AddUint64(&l.rejected, 1)
+	c.Close()
+}
+
+func (l *LimitedListener) acquireIP(ip string) bool {
+	if l.limits.MaxConnectionsPerIP <= 0 || ip == "" {
+		return true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.perIP[ip] >= l.limits.MaxConnectionsPerIP {
+		return false
+	}
+	l.perIP[ip]++
+	return true
+}
+
+// release releases the slots of a closed connection of the "ip".
+func (l *LimitedListener) release(ip string) {
+	if l.limits.MaxConnectionsPerIP > 0 && ip != "" {
+		l.mu.Lock()
+		if l.perIP[ip]--; l.perIP[ip] <= 0 {
+			delete(l.perIP, ip)
+		}
+		l.mu.Unlock()
+	}
+
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// connIP returns the ip address of the connection "c", it's empty for the unix sockets.
+func connIP(c net.Conn) string {
+	if addr, ok := c.RemoteAddr().(*net.TCPAddr); ok {
+		return addr.IP.String()
+	}
+	return ""
+}
+
+// limitedConn releases its slots once, when it's closed.
+type limitedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *limitedConn) Close() error {
+	err := c.Conn.Close()
+	c.once.Do(c.release)
+	return err
+}
@@ -0,0 +1,164 @@
+package netutil
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+// serveLimited accepts the connections of a limit listener of "limits" and sends them to the returned channel.
+func serveLimited(t *testing.T, limits ConnLimits) (*LimitedListener, <-chan net.Conn) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := LimitListener(ln, limits)
+	accepted := make(chan net.Conn, 8)
+	go func() {
+		defer close(accepted)
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			accepted <- c
+		}
+	}()
+
+	return l, accepted
+}
+
+func dialFrom(t *testing.T, l net.Listener, localIP string) net.Conn {
+	d := net.Dialer{Timeout: time.Second}
+	if localIP != "" {
+		d.LocalAddr = &net.TCPAddr{IP: net.ParseIP(localIP)}
+	}
+	c, err := d.Dial("tcp4", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+func expectAccepted(t *testing.T, accepted <-chan net.Conn) net.Conn {
+	select {
+	case c := <-accepted:
+		return c
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected an accepted connection")
+		return nil
+	}
+}
+
+func expectNotAccepted(t *testing.T, accepted <-chan net.Conn) {
+	select {
+	case <-accepted:
+		t.Fatalf("expected the connection to not be accepted")
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+// expectRejected expects the connection "c" to be closed by the listener.
+func expectRejected(t *testing.T, c net.Conn) {
+	c.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := c.Read(make([]byte, 1)); err != io.EOF {
+		t.Fatalf("expected the connection to be closed by the listener but got: %v", err)
+	}
+}
+
+func TestLimitListenerReject(t *testing.T) {
+	l, accepted := serveLimited(t, ConnLimits{MaxConnections: 1})
+	defer l.Close()
+
+	c1 := dialFrom(t, l, "")
+	defer c1.Close()
+	a1 := expectAccepted(t, accepted)
+
+	c2 := dialFrom(t, l, "")
+	defer c2.Close()
+	expectRejected(t, c2)
+	expectNotAccepted(t, accepted)
+	if expected, got := uint64(1), l.Rejected(); expected != got {
+		t.Fatalf("expected %d rejected connections but got %d", expected, got)
+	}
+
+	// the slot is released on close, once.
+	a1.Close()
+	a1.Close()
+	c3 := dialFrom(t, l, "")
+	defer c3.Close()
+	expectAccepted(t, accepted).Close()
+
+	c4 := dialFrom(t, l, "")
+	defer c4.Close()
+	expectAccepted(t, accepted)
+}
+
+func TestLimitListenerQueue(t *testing.T) {
+	l, accepted := serveLimited(t, ConnLimits{MaxConnections: 1, Queue: true})
+	defer l.Close()
+
+	c1 := dialFrom(t, l, "")
+	defer c1.Close()
+	a1 := expectAccepted(t, accepted)
+
+	// waits in the backlog for a free slot.
+	c2 := dialFrom(t, l, "")
+	defer c2.Close()
+	expectNotAccepted(t, accepted)
+
+	a1.Close()
+	expectAccepted(t, accepted)
+	if got := l.Rejected(); got != 0 {
+		t.Fatalf("expected zero rejected connections but got %d", got)
+	}
+}
+
+func TestLimitListenerQueueClose(t *testing.T) {
+	l, accepted := serveLimited(t, ConnLimits{MaxConnections: 1, Queue: true})
+
+	c1 := dialFrom(t, l, "")
+	defer c1.Close()
+	a1 := expectAccepted(t, accepted)
+	defer a1.Close()
+
+	// the Accept waits for a free slot, the close stops it.
+	l.Close()
+	select {
+	case _, ok := <-accepted:
+		if ok {
+			t.Fatalf("expected the connection to not be accepted")
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("expected the Accept to return on close")
+	}
+}
+
+func TestLimitListenerPerIP(t *testing.T) {
+	l, accepted := serveLimited(t, ConnLimits{MaxConnectionsPerIP: 1})
+	defer l.Close()
+
+	c1 := dialFrom(t, l, "127.0.0.1")
+	defer c1.Close()
+	a1 := expectAccepted(t, accepted)
+
+	c2 := dialFrom(t, l, "127.0.0.1")
+	defer c2.Close()
+	expectRejected(t, c2)
+
+	// an other ip address has its own slots.
+	c3 := dialFrom(t, l, "127.0.0.2")
+	defer c3.Close()
+	expectAccepted(t, accepted)
+
+	a1.Close()
+	c4 := dialFrom(t, l, "127.0.0.1")
+	defer c4.Close()
+	expectAccepted(t, accepted)
+
+	if expected, got := uint64(1), l.Rejected(); expected != got {
+		t.Fatalf("expected %d rejected connections but got %d", expected, got)
+	}
+}
@@ -0,0 +1,454 @@
+package netutil
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+)
+
+var (
+	errProxyHeaderInvalid = errors.New("proxy protocol: invalid header: %s")
+	errProxyHeaderTimeout = errors.New("proxy protocol: header timeout")
+	errProxyHeaderMissing = errors.New("proxy protocol: missing header")
+	errProxyTrustedEmpty  = errors.New("proxy protocol: the trusted proxies are required")
+)
+
+const (
+	// the max length of a v1 header, including the CRLF.
+	proxyV1MaxLength = 107
+	// the length of the v2 fixed header, the signature, the version and command, the family and the length.
+	proxyV2HeaderLength = 16
+)
+
+// proxyV2Signature is the signature of the binary header of the version 2.
+var proxyV2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// ProxyHeaderTimeout is the time that a connection of a trusted address
+// has to send its PROXY protocol header, see `ProxyProtocol`.
+var ProxyHeaderTimeout = 10 * time.Second
+
+// The types of the TLVs, the type-length-value vectors, of the version 2 headers.
+//
+// See https://www.haproxy.org/download/2.0/doc/proxy-protocol.txt.
+const (
+	ProxyTLVTypeALPN      byte = 0x01
+	ProxyTLVTypeAuthority byte = 0x02
+	ProxyTLVTypeCRC32C    byte = 0x03
+	ProxyTLVTypeNoop      byte = 0x04
+	ProxyTLVTypeUniqueID  byte = 0x05
+	ProxyTLVTypeSSL       byte = 0x20
+	ProxyTLVTypeNetNS     byte = 0x30
+	// ProxyTLVTypeAWS is the type of the TLV of the AWS Network Load Balancer,
+	// its first byte is the subtype, 0x01 is the id of the VPC endpoint.
+	ProxyTLVTypeAWS byte = 0xEA
+)
+
+// ProxyTLV is a type-length-value vector of a version 2 header,
+// the additional information of the proxy, i.e the server name of the client.
+type ProxyTLV struct {
+	Type  byte
+	Value []byte
+}
+
+// ProxyHeader is the PROXY protocol header of a connection,
+// the addresses of the connection between the client and the proxy.
+type ProxyHeader struct {
+	// Version is the version of the header, 1 or 2.
+	Version int
+	// Local is true if the connection is made by the proxy itself,
+	// i.e a health check, its addresses are the addresses of the connection.
+	Local bool
+	// SourceAddr is the address of the client, the original address of the connection.
+	SourceAddr net.Addr
+	// DestinationAddr is the address that the client connected to, the proxy's one.
+	DestinationAddr net.Addr
+	// TLVs are the type-length-value vectors of a version 2 header.
+	TLVs []ProxyTLV
+}
+
+// TLV returns the value of the first TLV of the type "typ" and true, if any.
+func (h *ProxyHeader) TLV(typ byte) ([]byte, bool) {
+	for _, tlv := range h.TLVs {
+		if tlv.Type == typ {
+			return tlv.Value, true
+		}
+	}
+	return nil, false
+}
+
+// Authority returns the server name that the client requested, the SNI, if the proxy sent it.
+func (h *ProxyHeader) Authority() string {
+	v, _ := h.TLV(ProxyTLVTypeAuthority)
+	return string(v)
+}
+
+// AWSVPCEndpointID returns the id of the VPC endpoint of an AWS Network Load Balancer, if any.
+func (h *ProxyHeader) AWSVPCEndpointID() string {
+	if v, ok := h.TLV(ProxyTLVTypeAWS); ok && len(v) > 1 && v[0] == 0x01 {
+		return string(v[1:])
+	}
+	return ""
+}
+
+// ProxyProtocolConfig is the configuration of a `ProxyProtocolWithConfig` listener.
+type ProxyProtocolConfig struct {
+	// Trusted are the networks of the proxies, only their connections can send a header,
+	// the header of the other connections is not read. It's required.
+	// The connections of the unix sockets are trusted too.
+	Trusted []*net.IPNet
+	// HeaderOptional accepts the connections of the trusted proxies without a header,
+	// they keep their address, i.e the health checks of a load balancer.
+	// Defaults to false, the connections of the trusted proxies without a header are failed.
+	HeaderOptional bool
+}
+
+// Validate returns an error if the trusted networks are empty,
+// a client could spoof its address otherwise.
+func (cfg ProxyProtocolConfig) Validate() error {
+	if len(cfg.Trusted) == 0 {
+		return errProxyTrustedEmpty
+	}
+	return nil
+}
+
+// proxyListener reads the PROXY protocol headers of the connections of the trusted addresses.
+type proxyListener struct {
+	net.Listener
+	cfg ProxyProtocolConfig
+}
+
+// ProxyProtocol returns a listener which reads the PROXY protocol header, the version 1 (text)
+// or the version 2 (binary) one, which a proxy or a load balancer in TCP mode
+// sends at the start of each connection, i.e the HAProxy's "send-proxy" or the AWS Network Load Balancer,
+// so the `RemoteAddr` of the connections is the address of the client instead of the proxy's one.
+//
+// Only the connections of the "trusted" networks can send a header and they must send it,
+// the header of the other connections is ignored, they keep their address.
+// It returns an error if the "trusted" networks are empty, a client could spoof its address otherwise.
+//
+// The header is read on the first `RemoteAddr` or `Read` call of the connection, not on `Accept`.
+// A tls listener should wrap the PROXY protocol listener, not the opposite,
+// i.e tls.NewListener(ProxyProtocol(l, trusted), tlsConfig).
+//
+// See `ProxyProtocolWithConfig`, `ProxyHeaderOf` and `ProxyConnContext` for the header itself.
+func ProxyProtocol(l net.Listener, trusted []*net.IPNet) (net.Listener, error) {
+	return ProxyProtocolWithConfig(l, ProxyProtocolConfig{Trusted: trusted})
+}
+
+// ProxyProtocolWithConfig acts identically to `ProxyProtocol`
+// but it accepts the whole configuration, i.e to make the header optional.
+// It returns the error of the `ProxyProtocolConfig#Validate`.
+func ProxyProtocolWithConfig(l net.Listener, cfg ProxyProtocolConfig) (net.Listener, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return &proxyListener{Listener: l, cfg: cfg}, nil
+}
+
+// Accept waits for and returns the next connection, the header is read later.
+func (l *proxyListener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+
+	if !l.isTrusted(c.RemoteAddr()) {
+		return c, nil
+	}
+	return &ProxyConn{Conn: c, reader: bufio.NewReader(c), optional: l.cfg.HeaderOptional}, nil
+}
+
+func (l *proxyListener) isTrusted(addr net.Addr) bool {
+	tcpAddr, ok := addr.(*net.TCPAddr)
+	if !ok {
+		// the unix sockets are local.
+		_, isUnix := addr.(*net.UnixAddr)
+		return isUnix
+	}
+
+	for _, ipNet := range l.cfg.Trusted {
+		if ipNet.Contains(tcpAddr.IP) {
+			return true
+		}
+	}
+	return false
+}
+
+// ProxyConn is a connection of a trusted address of a `ProxyProtocol` listener.
+type ProxyConn struct {
+	net.Conn
+	reader *bufio.Reader
+	// accepts a connection without a header, see `ProxyProtocolConfig#HeaderOptional`.
+	optional bool
+
+	once   sync.Once
+	header *ProxyHeader
+	err    error
+}
+
+// readHeader reads the header, once, the connection should send it in the `ProxyHeaderTimeout`.
+func (c *ProxyConn) readHeader() {
+	c.once.Do(func() {
+		c.Conn.SetReadDeadline(time.Now().Add(ProxyHeaderTimeout))
+		c.header, c.err = readProxyHeader(c.reader)
+		c.Conn.SetReadDeadline(time.Time{})
+
+		if ne, ok := c.err.(net.Error); ok && ne.Timeout() {
+			c.err = errProxyHeaderTimeout
+		} else if c.err == nil && c.header == nil && !c.optional {
+			c.err = errProxyHeaderMissing
+		}
+	})
+}
+
+// Header returns the PROXY protocol header of the connection,
+// it's nil if the connection sent no header and the header is optional.
+func (c *ProxyConn) Header() (*ProxyHeader, error) {
+	c.readHeader()
+	return c.header, c.err
+}
+
+// Read reads from the connection, after its header.
+// The connections with an invalid or a missing, if it's not optional, header are failed.
+func (c *ProxyConn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns the source address of the header, the client's one,
+// or the address of the connection if it sent no header.
+func (c *ProxyConn) RemoteAddr() net.Addr {
+	c.readHeader()
+	if c.header != nil && !c.header.Local && c.header.SourceAddr != nil {
+		return c.header.SourceAddr
+	}
+	return c.Conn.RemoteAddr()
+}
+
+// LocalAddr returns the destination address of the header, the proxy's one,
+// or the address of the connection if it sent no header.
+func (c *ProxyConn) LocalAddr() net.Addr {
+	c.readHeader()
+	if c.header != nil && !c.header.Local && c.header.DestinationAddr != nil {
+		return c.header.DestinationAddr
+	}
+	return c.Conn.LocalAddr()
+}
+
+// readProxyHeader reads the header of the version 1 or 2,
+// it returns a nil header if the "r" doesn't start with a header.
+func readProxyHeader(r *bufio.Reader) (*ProxyHeader, error) {
+	// peek the minimum bytes, a request may be shorter than the v2 signature.
+	first, err := r.Peek(1)
+	if err != nil {
+		return nil, err
+	}
+
+	switch first[0] {
+	case 'P':
+		if b, err := r.Peek(6); err == nil && string(b) == "PROXY " {
+			return readProxyV1(r)
+		}
+	case '\r':
+		if b, err := r.Peek(len(proxyV2Signature)); err == nil && bytes.Equal(b, proxyV2Signature) {
+			return readProxyV2(r)
+		}
+	}
+
+	return nil, nil
+}
+
+// readProxyV1 reads a text header,
+// i.e "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n".
+func readProxyV1(r *bufio.Reader) (*ProxyHeader, error) {
+	var line []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		line = append(line, b)
+		if b == '\n' {
+			break
+		}
+		if len(line) >= proxyV1MaxLength {
+			return nil, errProxyHeaderInvalid.Format("v1 header is too long")
+		}
+	}
+
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errProxyHeaderInvalid.Format("v1 header should end with CRLF")
+	}
+
+	fields := strings.Split(string(line[:len(line)-2]), " ")
+	h := &ProxyHeader{Version: 1}
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		// the proxy can't tell the addresses, the connection's ones are used.
+		h.Local = true
+		return h, nil
+	}
+
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, errProxyHeaderInvalid.Format(strconv.Quote(string(line)))
+	}
+
+	src, dst := net.ParseIP(fields[2]), net.ParseIP(fields[3])
+	srcPort, srcErr := parseProxyPort(fields[4])
+	dstPort, dstErr := parseProxyPort(fields[5])
+	if src == nil || dst == nil || srcErr != nil || dstErr != nil ||
+		(fields[1] == "TCP4") != (src.To4() != nil && dst.To4() != nil) {
+		return nil, errProxyHeaderInvalid.Format(strconv.Quote(string(line)))
+	}
+
+	h.SourceAddr = &net.TCPAddr{IP: src, Port: srcPort}
+	h.DestinationAddr = &net.TCPAddr{IP: dst, Port: dstPort}
+	return h, nil
+}
+
+func parseProxyPort(s string) (int, error) {
+	// no leading zeros or signs.
+	if s == "" || (len(s) > 1 && s[0] == '0') {
+		return 0, errProxyHeaderInvalid.Format("invalid port " + s)
+	}
+	port, err := strconv.ParseUint(s, 10, 16)
+	return int(port), err
+}
+
+// readProxyV2 reads a binary header.
+func readProxyV2(r *bufio.Reader) (*ProxyHeader, error) {
+	fixed := make([]byte, proxyV2HeaderLength)
+	if _, err := io.ReadFull(r, fixed); err != nil {
+		return nil, err
+	}
+
+	verCmd, family := fixed[12], fixed[13]
+	if verCmd>>4 != 2 {
+		return nil, errProxyHeaderInvalid.Format("v2 header with version " + strconv.Itoa(int(verCmd>>4)))
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(fixed[14:16]))
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, err
+	}
+
+	h := &ProxyHeader{Version: 2}
+	switch verCmd & 0x0F {
+	case 0x00:
+		// LOCAL, the addresses are ignored but the TLVs are kept.
+		h.Local = true
+	case 0x01:
+		// PROXY.
+	default:
+		return nil, errProxyHeaderInvalid.Format("v2 header with command " + strconv.Itoa(int(verCmd&0x0F)))
+	}
+
+	var addrLength int
+	switch family >> 4 {
+	case 0x1: // AF_INET
+		addrLength = 12
+	case 0x2: // AF_INET6
+		addrLength = 36
+	case 0x3: // AF_UNIX
+		addrLength = 216
+	case 0x0: // AF_UNSPEC
+		h.Local = true
+	default:
+		return nil, errProxyHeaderInvalid.Format("v2 header with family " + strconv.Itoa(int(family>>4)))
+	}
+
+	if len(payload) < addrLength {
+		return nil, errProxyHeaderInvalid.Format("v2 header addresses are truncated")
+	}
+
+	if !h.Local {
+		addrs := payload[:addrLength]
+		switch family {
+		case 0x11, 0x21: // TCP over IPv4 and IPv6.
+			ipLength := (addrLength - 4) / 2
+			h.SourceAddr = &net.TCPAddr{IP: net.IP(addrs[:ipLength]), Port: int(binary.BigEndian.Uint16(addrs[2*ipLength:]))}
+			h.DestinationAddr = &net.TCPAddr{IP: net.IP(addrs[ipLength : 2*ipLength]), Port: int(binary.BigEndian.Uint16(addrs[2*ipLength+2:]))}
+		case 0x12, 0x22: // UDP over IPv4 and IPv6.
+			ipLength := (addrLength - 4) / 2
+			h.SourceAddr = &net.UDPAddr{IP: net.IP(addrs[:ipLength]), Port: int(binary.BigEndian.Uint16(addrs[2*ipLength:]))}
+			h.DestinationAddr = &net.UDPAddr{IP: net.IP(addrs[ipLength : 2*ipLength]), Port: int(binary.BigEndian.Uint16(addrs[2*ipLength+2:]))}
+		case 0x31, 0x32: // UNIX stream and datagram.
+			network := "unix"
+			if family == 0x32 {
+				network = "unixgram"
+			}
+			h.SourceAddr = &net.UnixAddr{Name: string(bytes.TrimRight(addrs[:108], "\x00")), Net: network}
+			h.DestinationAddr = &net.UnixAddr{Name: string(bytes.TrimRight(addrs[108:], "\x00")), Net: network}
+		default:
+			return nil, errProxyHeaderInvalid.Format("v2 header with protocol " + strconv.Itoa(int(family&0x0F)))
+		}
+	}
+
+	tlvs, err := parseProxyTLVs(payload[addrLength:])
+	if err != nil {
+		return nil, err
+	}
+	h.TLVs = tlvs
+	return h, nil
+}
+
+// parseProxyTLVs parses the type-length-value vectors after the addresses of a v2 header.
+func parseProxyTLVs(b []byte) ([]ProxyTLV, error) {
+	var tlvs []ProxyTLV
+	for len(b) > 0 {
+		if len(b) < 3 {
+			return nil, errProxyHeaderInvalid.Format("v2 header TLV is truncated")
+		}
+
+		length := int(binary.BigEndian.Uint16(b[1:3]))
+		if len(b) < 3+length {
+			return nil, errProxyHeaderInvalid.Format("v2 header TLV is truncated")
+		}
+
+		if b[0] != ProxyTLVTypeNoop {
+			tlvs = append(tlvs, ProxyTLV{Type: b[0], Value: b[3 : 3+length]})
+		}
+		b = b[3+length:]
+	}
+	return tlvs, nil
+}
+
+type proxyConnContextKey struct{}
+
+// ProxyConnContext stores the PROXY protocol connection "c", or the connection of a tls one,
+// to the "ctx", it's the `http.Server#ConnContext`, see `ProxyHeaderOf`.
+func ProxyConnContext(ctx context.Context, c net.Conn) context.Context {
+	if nc, ok := c.(interface{ NetConn() net.Conn }); ok {
+		// the tls connections.
+		c = nc.NetConn()
+	}
+
+	if pc, ok := c.(*ProxyConn); ok {
+		return context.WithValue(ctx, proxyConnContextKey{}, pc)
+	}
+	return ctx
+}
+
+// ProxyHeaderOf returns the PROXY protocol header of the request's connection,
+// it's nil if the connection sent no header or if the server's `ConnContext` is not the `ProxyConnContext`.
+func ProxyHeaderOf(r *http.Request) *ProxyHeader {
+	pc, ok := r.Context().Value(proxyConnContextKey{}).(*ProxyConn)
+	if !ok {
+		return nil
+	}
+
+	h, _ := pc.Header()
+	return h
+}
@@ -0,0 +1,292 @@
+package netutil
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// proxyV2 returns a version 2 header of the "family" and the "addrs" followed by the "tlvs".
+func proxyV2(command, family byte, addrs []byte, tlvs ...ProxyTLV) []byte {
+	payload := append([]byte(nil), addrs...)
+	for _, tlv := range tlvs {
+		payload = append(payload, tlv.Type, 0, 0)
+		binary.BigEndian.PutUint16(payload[len(payload)-2:], uint16(len(tlv.Value)))
+		payload = append(payload, tlv.Value...)
+	}
+
+	b := append([]byte(nil), proxyV2Signature...)
+	b = append(b, 0x20|command, family, 0, 0)
+	binary.BigEndian.PutUint16(b[14:], uint16(len(payload)))
+	return append(b, payload...)
+}
+
+func proxyV2Addrs(src, dst net.IP, srcPort, dstPort uint16) []byte {
+	b := append(append([]byte(nil), src...), dst...)
+	b = append(b, 0, 0, 0, 0)
+	binary.BigEndian.PutUint16(b[len(b)-4:], srcPort)
+	binary.BigEndian.PutUint16(b[len(b)-2:], dstPort)
+	return b
+}
+
+func TestReadProxyHeader(t *testing.T) {
+	unixAddrs := make([]byte, 216)
+	copy(unixAddrs, "/var/run/client.sock")
+	copy(unixAddrs[108:], "/var/run/server.sock")
+
+	tests := []struct {
+		name   string
+		input  []byte
+		source string
+		dest   string
+		local  bool
+		tlvs   int
+		err    bool
+	}{
+		{"v1 tcp4", []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"), "192.0.2.1:56324", "198.51.100.1:443", false, 0, false},
+		{"v1 tcp6", []byte("PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n"), "[2001:db8::1]:56324", "[2001:db8::2]:443", false, 0, false},
+		{"v1 unknown", []byte("PROXY UNKNOWN\r\n"), "", "", true, 0, false},
+		{"v1 without CRLF", []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\n"), "", "", false, 0, true},
+		{"v1 mixed families", []byte("PROXY TCP4 2001:db8::1 198.51.100.1 56324 443\r\n"), "", "", false, 0, true},
+		{"v1 invalid port", []byte("PROXY TCP4 192.0.2.1 198.51.100.1 065536 443\r\n"), "", "", false, 0, true},
+		{"v1 too long", []byte("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n"), "", "", false, 0, true},
+		{
+			"v2 tcp4 with tlvs",
+			proxyV2(0x01, 0x11, proxyV2Addrs(net.IPv4(192, 0, 2, 1).To4(), net.IPv4(198, 51, 100, 1).To4(), 56324, 443),
+				ProxyTLV{ProxyTLVTypeAuthority, []byte("example.com")},
+				ProxyTLV{ProxyTLVTypeNoop, []byte("pad")},
+				ProxyTLV{ProxyTLVTypeAWS, []byte("\x01vpce-08d2bf15fac5001c9")}),
+			"192.0.2.1:56324", "198.51.100.1:443", false, 2, false,
+		},
+		{
+			"v2 tcp6",
+			proxyV2(0x01, 0x21, proxyV2Addrs(net.ParseIP("2001:db8::1"), net.ParseIP("2001:db8::2"), 56324, 443)),
+			"[2001:db8::1]:56324", "[2001:db8::2]:443", false, 0, false,
+		},
+		{"v2 unix", proxyV2(0x01, 0x31, unixAddrs), "/var/run/client.sock", "/var/run/server.sock", false, 0, false},
+		{
+			"v2 local",
+			proxyV2(0x00, 0x11, proxyV2Addrs(net.IPv4(192, 0, 2, 1).To4(), net.IPv4(198, 51, 100, 1).To4(), 56324, 443)),
+			"", "", true, 0, false,
+		},
+		{"v2 unspec", proxyV2(0x01, 0x00, nil), "", "", true, 0, false},
+		{"v2 truncated addresses", proxyV2(0x01, 0x11, []byte{192, 0, 2, 1}), "", "", false, 0, true},
+		{"v2 truncated tlv", proxyV2(0x01, 0x00, []byte{ProxyTLVTypeAuthority, 0, 10, 'a'}), "", "", false, 0, true},
+		{"v2 invalid command", proxyV2(0x02, 0x00, nil), "", "", false, 0, true},
+	}
+
+	for _, tt := range tests {
+		r := bufio.NewReader(bytes.NewReader(append(tt.input, "GET / HTTP/1.1\r\n"...)))
+		h, err := readProxyHeader(r)
+		if tt.err {
+			if err == nil {
+				t.Fatalf("%s: expected an error", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if h == nil {
+			t.Fatalf("%s: expected a header", tt.name)
+		}
+
+		if h.Local != tt.local {
+			t.Fatalf("%s: expected local %t but got %t", tt.name, tt.local, h.Local)
+		}
+		if !tt.local {
+			if got := h.SourceAddr.String(); got != tt.source {
+				t.Fatalf("%s: expected the source %s but got %s", tt.name, tt.source, got)
+			}
+			if got := h.DestinationAddr.String(); got != tt.dest {
+				t.Fatalf("%s: expected the destination %s but got %s", tt.name, tt.dest, got)
+			}
+		}
+		if len(h.TLVs) != tt.tlvs {
+			t.Fatalf("%s: expected %d TLVs but got %d", tt.name, tt.tlvs, len(h.TLVs))
+		}
+
+		// the rest of the connection.
+		if rest, _ := ioutil.ReadAll(r); string(rest) != "GET / HTTP/1.1\r\n" {
+			t.Fatalf("%s: expected the request after the header but got %q", tt.name, rest)
+		}
+	}
+
+	// the header's TLVs.
+	r := bufio.NewReader(bytes.NewReader(tests[7].input))
+	h, _ := readProxyHeader(r)
+	if expected, got := "example.com", h.Authority(); expected != got {
+		t.Fatalf("expected the authority %s but got %s", expected, got)
+	}
+	if expected, got := "vpce-08d2bf15fac5001c9", h.AWSVPCEndpointID(); expected != got {
+		t.Fatalf("expected the VPC endpoint id %s but got %s", expected, got)
+	}
+
+	// without a header, even a short one.
+	for _, input := range []string{"GET / HTTP/1.1\r\n", "POST / HTTP/1.1\r\n", "P"} {
+		r := bufio.NewReader(strings.NewReader(input))
+		if h, err := readProxyHeader(r); h != nil || err != nil {
+			t.Fatalf("%q: expected no header but got %v, %v", input, h, err)
+		}
+		if rest, _ := ioutil.ReadAll(r); string(rest) != input {
+			t.Fatalf("%q: expected the input to be kept but got %q", input, rest)
+		}
+	}
+}
+
+// serveProxyProtocol serves the remote address and the authority of the requests
+// of a PROXY protocol listener of the "cfg".
+func serveProxyProtocol(t *testing.T, cfg ProxyProtocolConfig) (string, func()) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authority := ""
+			if h := ProxyHeaderOf(r); h != nil {
+				authority = h.Authority()
+			}
+			w.Write([]byte(r.RemoteAddr + " " + authority))
+		}),
+		ConnContext: ProxyConnContext,
+	}
+
+	pl, err := ProxyProtocolWithConfig(l, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(pl)
+	return l.Addr().String(), func() { srv.Close() }
+}
+
+func sendProxyHeader(t *testing.T, addr string, header []byte) (int, string) {
+	code, body, err := trySendProxyHeader(addr, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, body
+}
+
+func trySendProxyHeader(addr string, header []byte) (int, string, error) {
+	conn, err := net.Dial("tcp", addr)
+	if err != nil {
+		return 0, "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	conn.Write(append(header, "GET / HTTP/1.0\r\n\r\n"...))
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(b), nil
+}
+
+func TestProxyProtocol(t *testing.T) {
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	_, other, _ := net.ParseCIDR("10.0.0.0/8")
+
+	addr, closeServer := serveProxyProtocol(t, ProxyProtocolConfig{Trusted: []*net.IPNet{loopback}})
+	defer closeServer()
+
+	if _, got := sendProxyHeader(t, addr, []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n")); got != "192.0.2.1:56324 " {
+		t.Fatalf("expected the v1 source address but got %q", got)
+	}
+
+	v2 := proxyV2(0x01, 0x11, proxyV2Addrs(net.IPv4(192, 0, 2, 2).To4(), net.IPv4(198, 51, 100, 1).To4(), 40000, 443),
+		ProxyTLV{ProxyTLVTypeAuthority, []byte("example.com")})
+	if _, got := sendProxyHeader(t, addr, v2); got != "192.0.2.2:40000 example.com" {
+		t.Fatalf("expected the v2 source address and authority but got %q", got)
+	}
+
+	// the header of a trusted proxy is required, the connection is failed before the request.
+	if code, _, err := trySendProxyHeader(addr, nil); err == nil && code != http.StatusBadRequest {
+		t.Fatalf("expected the connection without a header to be failed but got %d", code)
+	}
+
+	optionalAddr, closeOptional := serveProxyProtocol(t, ProxyProtocolConfig{Trusted: []*net.IPNet{loopback}, HeaderOptional: true})
+	defer closeOptional()
+
+	if _, got := sendProxyHeader(t, optionalAddr, nil); !strings.HasPrefix(got, "127.0.0.1:") {
+		t.Fatalf("expected the address of the connection without a header but got %q", got)
+	}
+
+	// the connections of the untrusted addresses can't send a header.
+	untrustedAddr, closeUntrusted := serveProxyProtocol(t, ProxyProtocolConfig{Trusted: []*net.IPNet{other}})
+	defer closeUntrusted()
+
+	if code, _ := sendProxyHeader(t, untrustedAddr, []byte("PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n")); code != http.StatusBadRequest {
+		t.Fatalf("expected the header of an untrusted address to be a bad request but got %d", code)
+	}
+}
+
+func TestProxyProtocolTrustedRequired(t *testing.T) {
+	if _, err := ProxyProtocol(nil, nil); err == nil {
+		t.Fatalf("expected an error without trusted proxies")
+	}
+	if err := (ProxyProtocolConfig{HeaderOptional: true}).Validate(); err == nil {
+		t.Fatalf("expected the configuration without trusted proxies to be invalid")
+	}
+
+	_, loopback, _ := net.ParseCIDR("127.0.0.0/8")
+	if err := (ProxyProtocolConfig{Trusted: []*net.IPNet{loopback}}).Validate(); err != nil {
+		t.Fatalf("expected a valid configuration but got: %v", err)
+	}
+}
+
+func TestProxyProtocolUntrustedHeaderIgnored(t *testing.T) {
+	_, other, _ := net.ParseCIDR("10.0.0.0/8")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := ProxyProtocol(ln, []*net.IPNet{other})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	header := "PROXY TCP4 192.0.2.1 198.51.100.1 56324 443\r\n"
+	client, err := net.Dial("tcp", l.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	client.Write([]byte(header))
+
+	c, err := l.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	c.SetDeadline(time.Now().Add(5 * time.Second))
+
+	if _, ok := c.(*ProxyConn); ok {
+		t.Fatalf("expected the connection of an untrusted address to not read a header")
+	}
+	if expected, got := client.LocalAddr().String(), c.RemoteAddr().String(); expected != got {
+		t.Fatalf("expected the address of the connection %s but got the spoofed %s", expected, got)
+	}
+
+	// the header is part of the data, it's not parsed.
+	b := make([]byte, len(header))
+	if _, err := io.ReadFull(c, b); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != header {
+		t.Fatalf("expected the header to be kept as data but got %q", b)
+	}
+}
@@ -2,6 +2,7 @@ package router
 
 import (
 	"net/http"
+	"net/url"
 	"os"
 	"path"
 	"strings"
@@ -9,6 +10,8 @@ import (
 
 	"github.com/get-ion/ion/context"
 	"github.com/get-ion/ion/core/errors"
+	"github.com/get-ion/ion/core/handlerconv"
+	"github.com/get-ion/ion/core/host"
 	"github.com/get-ion/ion/core/router/macro"
 )
 
@@ -121,17 +124,7 @@ func (rb *APIBuilder) Handle(method string, registeredPath string, handlers ...c
 		return rb.Any(registeredPath, handlers...)[0]
 	}
 
-	// no clean path yet because of subdomain indicator/separator which contains a dot.
-	// but remove the first slash if the relative has already ending with a slash
-	// it's not needed because later on we do normalize/clean the path, but better do it here too
-	// for any future updates.
-	if rb.relativePath[len(rb.relativePath)-1] == '/' {
-		if registeredPath[0] == '/' {
-			registeredPath = registeredPath[1:]
-		}
-	}
-
-	fullpath := rb.relativePath + registeredPath // for now, keep the last "/" if any,  "/xyz/"
+	fullpath := rb.fullPath(registeredPath)
 
 	routeHandlers := joinHandlers(rb.middleware, handlers)
 
@@ -146,6 +139,7 @@ func (rb *APIBuilder) Handle(method string, registeredPath string, handlers ...c
 		rb.reporter.Add("%v -> %s:%s:%s", err, method, subdomain, path)
 		return nil
 	}
+	r.middleware = joinHandlers(rb.middleware, nil)
 
 	// global
 	rb.routes.register(r)
@@ -156,6 +150,48 @@ func (rb *APIBuilder) Handle(method string, registeredPath string, handlers ...c
 	return r
 }
 
+// fullPath returns the party's relative path joined with the "registeredPath",
+// it may contain the subdomain too.
+func (rb *APIBuilder) fullPath(registeredPath string) string {
+	// no clean path yet because of subdomain indicator/separator which contains a dot.
+	// but remove the first slash if the relative has already ending with a slash
+	// it's not needed because later on we do normalize/clean the path, but better do it here too
+	// for any future updates.
+	if rb.relativePath[len(rb.relativePath)-1] == '/' {
+		if registeredPath[0] == '/' {
+			registeredPath = registeredPath[1:]
+		}
+	}
+
+	return rb.relativePath + registeredPath // for now, keep the last "/" if any,  "/xyz/"
+}
+
+// HandleFunc registers a route with a func of any input arguments and return values,
+// the path parameters and the request body are bound to its input arguments
+// and its return values are sent to the client, see `handlerconv#FromFunc`.
+// The func is validated here, once.
+//
+// Usage:
+// app.HandleFunc("PUT", "/users/{id:int}", func(id int, req UpdateUserRequest) (User, error) {...})
+//
+// Returns a *Route, app will throw any errors later on.
+func (rb *APIBuilder) HandleFunc(method string, registeredPath string, fn interface{}) *Route {
+	_, path := splitSubdomainAndPath(rb.fullPath(registeredPath))
+	tmpl, err := macro.Parse(path, rb.macros)
+	if err != nil {
+		rb.reporter.Add("%v -> %s:%s", err, method, path)
+		return nil
+	}
+
+	h, err := handlerconv.FromFunc(fn, *tmpl)
+	if err != nil {
+		rb.reporter.Add("%v -> %s:%s", err, method, path)
+		return nil
+	}
+
+	return rb.Handle(method, registeredPath, h)
+}
+
 // Party is just a group joiner of routes which have the same prefix and share same middleware(s) also.
 // Party could also be named as 'Join' or 'Node' or 'Group' , Party chosen because it is fun.
 func (rb *APIBuilder) Party(relativePath string, handlers ...context.Handler) Party {
@@ -276,6 +312,7 @@ func (rb *APIBuilder) Done(handlers ...context.Handler) {
 func (rb *APIBuilder) UseGlobal(handlers ...context.Handler) {
 	for _, r := range rb.routes.routes {
 		r.Handlers = append(handlers, r.Handlers...) // prepend the handlers
+		r.middleware = joinHandlers(handlers, r.middleware)
 	}
 	rb.middleware = append(handlers, rb.middleware...) // set as middleware on the next routes too
 	// rb.Use(handlers...)
@@ -367,6 +404,31 @@ func (rb *APIBuilder) Any(registeredPath string, handlers ...context.Handler) []
 	return routes
 }
 
+// Proxy forwards the requests of the "relativePath" and its sub paths to the "target",
+// the party's path and the "relativePath" are stripped, i.e
+// app.Proxy("/api", target) forwards the /api/users to the target's /users.
+// The "Location" and "Set-Cookie" response headers of the target are rewritten to the proxy's path
+// and the upgrade requests, i.e websockets, are tunnelled too.
+// The "handlers" are executed before the proxy.
+//
+// Use the `host#NewReverseProxy` with the `handlerconv#FromStd` for more options.
+//
+// Returns the registered routes.
+func (rb *APIBuilder) Proxy(relativePath string, target *url.URL, handlers ...context.Handler) []*Route {
+	_, prefix := splitSubdomainAndPath(rb.fullPath(relativePath))
+	p := host.NewReverseProxy(target, host.ProxyOptions{
+		StripPrefix:     prefix,
+		RewriteLocation: true,
+		RewriteCookies:  true,
+	})
+
+	routeHandlers := make([]context.Handler, 0, len(handlers)+1)
+	routeHandlers = append(append(routeHandlers, handlers...), handlerconv.FromStd(p))
+
+	routes := rb.Any(relativePath, routeHandlers...)
+	return append(routes, rb.Any(joinPath(relativePath, WildcardParam("proxyPath")), routeHandlers...)...)
+}
+
 // StaticCacheDuration expiration duration for INACTIVE file handlers, it's the only one global configuration
 // which can be changed.
 var StaticCacheDuration = 20 * time.Second
@@ -0,0 +1,370 @@
+package router
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/errors"
+)
+
+// baseController is implemented by the controllers which run code
+// before and after each request, i.e the `mvc.BaseController`.
+type baseController interface {
+	BeginRequest(ctx context.Context)
+	EndRequest(ctx context.Context)
+}
+
+// pathParam is implemented by the string input arguments of a controller's method
+// which receive the rest of the request path, i.e the `mvc.Path`.
+type pathParam interface {
+	IsPath() bool
+}
+
+var (
+	contextTyp        = reflect.TypeOf((*context.Context)(nil)).Elem()
+	baseControllerTyp = reflect.TypeOf((*baseController)(nil)).Elem()
+	pathParamTyp      = reflect.TypeOf((*pathParam)(nil)).Elem()
+)
+
+var (
+	errUnsupportedControllerArg = errors.New("unsupported input argument of type %s, expected an int, uint, string or mvc.Path")
+	errControllerArgsMismatch   = errors.New("the input arguments don't match the 'By' words of the method's name")
+)
+
+// controllerMethodPrefixes are the prefixes of the controller's methods which are registered as routes,
+// "Any" registers the method to all http methods.
+var controllerMethodPrefixes = []struct {
+	prefix string
+	method string
+}{
+	{"Get", http.MethodGet},
+	{"Post", http.MethodPost},
+	{"Put", http.MethodPut},
+	{"Delete", http.MethodDelete},
+	{"Connect", http.MethodConnect},
+	{"Head", http.MethodHead},
+	{"Patch", http.MethodPatch},
+	{"Options", http.MethodOptions},
+	{"Trace", http.MethodTrace},
+	{"Any", ""},
+}
+
+// Controller registers the exported methods of a "controller" as routes
+// under the "relativePath" of this Party.
+//
+// The "controller" should be a pointer to a struct,
+// a new controller is created per request, the values of the fields
+// of the given "controller" are copied to it.
+// The "bindValues" are set to the zero exported fields that they are assignable to,
+// in order to share dependencies, i.e a database, with the controller.
+// The exported fields of type `context.Context` are set to the request's context.
+//
+// The methods that start with an http method (or "Any") and they have no return values are the routes,
+// the rest of the name is the path, each word is a path segment and each "By" is a path parameter
+// of the next input argument, the last "By" takes all the remaining input arguments, i.e:
+// Get() -> GET /relativePath
+// GetBy(id int) -> GET /relativePath/{paramA:int}
+// GetProfileBy(username string) -> GET /relativePath/profile/{paramA:string}
+// PostByFollow(id int64) -> POST /relativePath/{paramA:int}/follow
+// GetFilesBy(file mvc.Path) -> GET /relativePath/files/{paramA:path}
+// The input arguments can be any int, uint, string or a mvc.Path.
+//
+// If the controller implements the `mvc.BaseController`, i.e embeds the `mvc.Controller`,
+// then its BeginRequest and EndRequest are called before and after the method.
+//
+// Returns the registered routes.
+//
+// Usage:
+// app.Controller("/users", new(UserController), db)
+func (rb *APIBuilder) Controller(relativePath string, controller interface{}, bindValues ...interface{}) []*Route {
+	typ := reflect.TypeOf(controller)
+	if typ == nil || typ.Kind() != reflect.Ptr || typ.Elem().Kind() != reflect.Struct {
+		rb.reporter.Add("controller: %T should be a pointer to a struct -> %s", controller, relativePath)
+		return nil
+	}
+
+	c := newControllerActivator(reflect.ValueOf(controller), bindValues)
+	basePath := strings.TrimSuffix(relativePath, "/")
+
+	var routes []*Route
+	for i, n := 0, typ.NumMethod(); i < n; i++ {
+		m := typ.Method(i)
+		httpMethod, words, ok := parseControllerMethodName(m.Name)
+		if !ok || m.Type.NumOut() > 0 {
+			// not a route.
+			continue
+		}
+
+		args, path, err := controllerMethodPath(m, words)
+		if err != nil {
+			rb.reporter.Add("controller: %v -> %s.%s", err, typ.Elem().Name(), m.Name)
+			continue
+		}
+
+		path = basePath + path
+		if path == "" {
+			path = "/"
+		}
+
+		h := c.handler(m.Index, args)
+		if httpMethod == "" {
+			routes = append(routes, rb.Any(path, h)...)
+			continue
+		}
+
+		if r := rb.Handle(httpMethod, path, h); r != nil {
+			routes = append(routes, r)
+		}
+	}
+
+	return routes
+}
+
+// parseControllerMethodName returns the http method and the
+// words of the rest of a controller's method name, i.e
+// "GetProfileBy" -> "GET", ["Profile", "By"].
+func parseControllerMethodName(name string) (httpMethod string, words []string, ok bool) {
+	for _, p := range controllerMethodPrefixes {
+		if !strings.HasPrefix(name, p.prefix) {
+			continue
+		}
+
+		rest := name[len(p.prefix):]
+		if rest != "" && !unicode.IsUpper(rune(rest[0])) {
+			// i.e "Getter"
+			return "", nil, false
+		}
+
+		return p.method, splitCamelCase(rest), true
+	}
+
+	return "", nil, false
+}
+
+// splitCamelCase splits a camel case name to its words,
+// i.e "UserHTMLBy" -> ["User", "HTML", "By"].
+func splitCamelCase(s string) (words []string) {
+	runes := []rune(s)
+	start := 0
+	for i := 1; i < len(runes); i++ {
+		if !unicode.IsUpper(runes[i]) {
+			continue
+		}
+
+		if !unicode.IsUpper(runes[i-1]) || (i+1 < len(runes) && !unicode.IsUpper(runes[i+1])) {
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	if start < len(runes) {
+		words = append(words, string(runes[start:]))
+	}
+	return
+}
+
+// controllerParamNames are the names of the path parameters of a controller's method,
+// the path parameter names can contain only letters, i.e "paramA", "paramB".
+var controllerParamNames = func() []string {
+	names := make([]string, 0, 26)
+	for c := 'A'; c <= 'Z'; c++ {
+		names = append(names, "param"+string(c))
+	}
+	return names
+}()
+
+// controllerArg is a path parameter which is passed to a controller's method.
+type controllerArg struct {
+	name string
+	typ  reflect.Type
+}
+
+func (a controllerArg) value(ctx context.Context) (reflect.Value, bool) {
+	v := reflect.New(a.typ).Elem()
+	s := ctx.Params().Get(a.name)
+
+	switch a.typ.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, a.typ.Bits())
+		if err != nil {
+			return v, false
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, a.typ.Bits())
+		if err != nil {
+			return v, false
+		}
+		v.SetUint(n)
+	}
+
+	return v, true
+}
+
+func macroTypeOf(typ reflect.Type) (string, bool) {
+	if typ.Kind() == reflect.String && typ.Implements(pathParamTyp) &&
+		reflect.Zero(typ).Interface().(pathParam).IsPath() {
+		return "path", true
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return "string", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "int", true
+	}
+
+	return "", false
+}
+
+// controllerMethodPath returns the path parameters and the path of a controller's method,
+// based on its words and its input arguments.
+func controllerMethodPath(m reflect.Method, words []string) ([]controllerArg, string, error) {
+	// the first input argument is the receiver.
+	numIn := m.Type.NumIn() - 1
+	args := make([]controllerArg, 0, numIn)
+
+	addArg := func() (string, error) {
+		i := len(args)
+		typ := m.Type.In(i + 1)
+		macroTyp, ok := macroTypeOf(typ)
+		if !ok {
+			return "", errUnsupportedControllerArg.Format(typ)
+		}
+
+		if i >= len(controllerParamNames) {
+			return "", errControllerArgsMismatch
+		}
+
+		name := controllerParamNames[i]
+		args = append(args, controllerArg{name: name, typ: typ})
+		return "/{" + name + ":" + macroTyp + "}", nil
+	}
+
+	path := ""
+	for i, word := range words {
+		if word != "By" {
+			path += "/" + strings.ToLower(word)
+			continue
+		}
+
+		// the last "By" takes the rest of the input arguments.
+		n := 1
+		if rest := numIn - len(args); i == len(words)-1 && rest > n {
+			n = rest
+		}
+
+		for ; n > 0; n-- {
+			if len(args) == numIn {
+				return nil, "", errControllerArgsMismatch
+			}
+			p, err := addArg()
+			if err != nil {
+				return nil, "", err
+			}
+			path += p
+		}
+	}
+
+	if len(args) != numIn {
+		return nil, "", errControllerArgsMismatch
+	}
+
+	return args, path, nil
+}
+
+// controllerActivator creates the controllers per request.
+type controllerActivator struct {
+	typ reflect.Type // the struct type of the controller.
+	// the value of the controller which is copied to each new controller,
+	// it contains the bind values.
+	value reflect.Value
+	// the indexes of the exported fields of type context.Context.
+	contextFields []int
+	isBase        bool
+}
+
+func newControllerActivator(controller reflect.Value, bindValues []interface{}) *controllerActivator {
+	typ := controller.Type().Elem()
+	value := reflect.New(typ).Elem()
+	value.Set(controller.Elem())
+
+	c := &controllerActivator{
+		typ:    typ,
+		value:  value,
+		isBase: controller.Type().Implements(baseControllerTyp),
+	}
+
+	for i, n := 0, typ.NumField(); i < n; i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			// unexported.
+			continue
+		}
+
+		if field.Type == contextTyp {
+			c.contextFields = append(c.contextFields, i)
+			continue
+		}
+
+		f := value.Field(i)
+		if !isZero(f) {
+			// set by the caller already.
+			continue
+		}
+
+		for _, v := range bindValues {
+			bindValue := reflect.ValueOf(v)
+			if bindValue.IsValid() && bindValue.Type().AssignableTo(field.Type) {
+				f.Set(bindValue)
+				break
+			}
+		}
+	}
+
+	return c
+}
+
+func isZero(v reflect.Value) bool {
+	return reflect.DeepEqual(v.Interface(), reflect.Zero(v.Type()).Interface())
+}
+
+func (c *controllerActivator) handler(methodIndex int, args []controllerArg) context.Handler {
+	return func(ctx context.Context) {
+		in := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			v, ok := arg.value(ctx)
+			if !ok {
+				// i.e an int which overflows.
+				ctx.NotFound()
+				return
+			}
+			in[i] = v
+		}
+
+		controller := reflect.New(c.typ)
+		elem := controller.Elem()
+		elem.Set(c.value)
+		for _, idx := range c.contextFields {
+			elem.Field(idx).Set(reflect.ValueOf(ctx))
+		}
+
+		if c.isBase {
+			b := controller.Interface().(baseController)
+			b.BeginRequest(ctx)
+			if ctx.IsStopped() {
+				return
+			}
+			defer b.EndRequest(ctx)
+		}
+
+		controller.Method(methodIndex).Call(in)
+	}
+}
@@ -33,7 +33,10 @@ type tree struct {
 
 type routerHandler struct {
 	trees []*tree
-	hosts bool // true if at least one route contains a Subdomain.
+	// optionsTrees are the OPTIONS trees of the paths which
+	// have no OPTIONS route registered, see `serveOptions`.
+	optionsTrees []*tree
+	hosts        bool // true if at least one route contains a Subdomain.
 }
 
 var _ RequestHandler = &routerHandler{}
@@ -49,7 +52,7 @@ func (h *routerHandler) getTree(method, subdomain string) *tree {
 	return nil
 }
 
-func (h *routerHandler) addRoute(method, subdomain, path string, handlers context.Handlers) error {
+func (h *routerHandler) addRoute(routeName, method, subdomain, path string, handlers context.Handlers) error {
 	t := h.getTree(method, subdomain)
 
 	if t == nil {
@@ -58,7 +61,106 @@ func (h *routerHandler) addRoute(method, subdomain, path string, handlers contex
 		t = &tree{Method: method, Subdomain: subdomain, Nodes: &n}
 		h.trees = append(h.trees, t)
 	}
-	return t.Nodes.Add(path, handlers)
+	return t.Nodes.Add(routeName, path, handlers)
+}
+
+func (h *routerHandler) addOptionsRoute(subdomain, path string, handlers context.Handlers) error {
+	var t *tree
+	for _, optionsTree := range h.optionsTrees {
+		if optionsTree.Subdomain == subdomain {
+			t = optionsTree
+			break
+		}
+	}
+
+	if t == nil {
+		n := make(node.Nodes, 0)
+		t = &tree{Method: http.MethodOptions, Subdomain: subdomain, Nodes: &n}
+		h.optionsTrees = append(h.optionsTrees, t)
+	}
+	// same as the default name of a route.
+	return t.Nodes.Add(http.MethodOptions+subdomain+path, path, handlers)
+}
+
+// buildOptionsRoutes adds an OPTIONS route to each path which has routes
+// on other methods but not an OPTIONS one, it runs the party's middleware of a route of the path,
+// i.e a CORS middleware, never the route's own handlers, and it ends with the `serveOptions`.
+func (h *routerHandler) buildOptionsRoutes(routes []*Route) error {
+	rp := errors.NewReporter()
+
+	hasOptions := make(map[string]bool)
+	for _, r := range routes {
+		if r.Method == http.MethodOptions {
+			hasOptions[r.Subdomain+r.Path] = true
+		}
+	}
+
+	for _, r := range routes {
+		key := r.Subdomain + r.Path
+		if hasOptions[key] || !r.IsOnline() {
+			continue
+		}
+		// the party's middleware of the first route of the path.
+		hasOptions[key] = true
+
+		if err := h.addOptionsRoute(r.Subdomain, r.Path, r.optionsHandlers(h.serveOptions)); err != nil {
+			rp.Add("%v -> OPTIONS %s%s", err, r.Subdomain, r.Tmpl().Src)
+		}
+	}
+
+	return rp.Return()
+}
+
+// serveOptions answers an OPTIONS request with the allowed methods of the path
+// and the 204 No Content status code.
+func (h *routerHandler) serveOptions(ctx context.Context) {
+	ctx.Header("Allow", strings.Join(h.allowedMethods(ctx, ctx.Path()), ", "))
+	ctx.StatusCode(http.StatusNoContent)
+}
+
+// allowedMethods returns the sorted methods of the routes which match the "path",
+// the HEAD is allowed if the GET is, see `headResponseWriter`,
+// and the OPTIONS is always allowed for an existing path.
+func (h *routerHandler) allowedMethods(ctx context.Context, path string) []string {
+	var (
+		methods []string
+		hasGet  bool
+		hasHead bool
+	)
+
+	for i := range h.trees {
+		t := h.trees[i]
+		if !h.matchHost(ctx, t) {
+			continue
+		}
+
+		// don't touch the request's parameters.
+		params := context.RequestParams{}
+		if _, handlers := t.Nodes.Find(path, &params); len(handlers) == 0 {
+			continue
+		}
+
+		switch t.Method {
+		case http.MethodGet:
+			hasGet = true
+		case http.MethodHead:
+			hasHead = true
+		case http.MethodOptions:
+			continue
+		}
+		methods = append(methods, t.Method)
+	}
+
+	if len(methods) == 0 {
+		return nil
+	}
+
+	if hasGet && !hasHead {
+		methods = append(methods, http.MethodHead)
+	}
+	methods = append(methods, http.MethodOptions)
+	sort.Strings(methods)
+	return methods
 }
 
 // NewDefaultHandler returns the handler which is responsible
@@ -78,6 +180,7 @@ type RoutesProvider interface { // api builder
 func (h *routerHandler) Build(provider RoutesProvider) error {
 	registeredRoutes := provider.GetRoutes()
 	h.trees = h.trees[0:0] // reset, inneed when rebuilding.
+	h.optionsTrees = h.optionsTrees[0:0]
 
 	// sort, subdomains goes first.
 	sort.Slice(registeredRoutes, func(i, j int) bool {
@@ -95,12 +198,13 @@ func (h *routerHandler) Build(provider RoutesProvider) error {
 		// on route, it will be stacked shown in this build state
 		// and no in the lines of the user's action, they should read
 		// the docs better. Or TODO: add a link here in order to help new users.
-		if err := h.addRoute(r.Method, r.Subdomain, r.Path, r.Handlers); err != nil {
+		if err := h.addRoute(r.Name, r.Method, r.Subdomain, r.Path, r.Handlers); err != nil {
 			// node errors:
 			rp.Add("%v -> %s", err, r.String())
 		}
 	}
 
+	rp.Describe("options routes: %v", h.buildOptionsRoutes(registeredRoutes))
 	return rp.Return()
 }
 
@@ -135,67 +239,96 @@ func (h *routerHandler) HandleRequest(ctx context.Context) {
 		}
 	}
 
-	for i := range h.trees {
-		t := h.trees[i]
+	if handlers := h.findHandlers(ctx, h.trees, method, path); len(handlers) > 0 {
+		ctx.Do(handlers)
+		return
+	}
+
+	switch method {
+	case http.MethodHead:
+		// serve the HEAD from the GET route, the net/http drops the body
+		// and keeps the headers of the GET, i.e the Content-Length.
+		if handlers := h.findHandlers(ctx, h.trees, http.MethodGet, path); len(handlers) > 0 {
+			ctx.Do(handlers)
+			return
+		}
+	case http.MethodOptions:
+		// the automatic OPTIONS, it answers the CORS preflight requests too.
+		if handlers := h.findHandlers(ctx, h.optionsTrees, method, path); len(handlers) > 0 {
+			ctx.Do(handlers)
+			return
+		}
+	}
+
+	if ctx.Application().ConfigurationReadOnly().GetFireMethodNotAllowed() {
+		if methods := h.allowedMethods(ctx, path); len(methods) > 0 {
+			// RCF rfc2616 https://www.w3.org/Protocols/rfc2616/rfc2616-sec10.html
+			// The response MUST include an Allow header containing a list of valid methods for the requested resource.
+			ctx.Header("Allow", strings.Join(methods, ", "))
+			ctx.StatusCode(http.StatusMethodNotAllowed)
+			return
+		}
+	}
+	ctx.StatusCode(http.StatusNotFound)
+}
+
+// findHandlers returns the handlers of the route of the "trees" which matches the request's
+// "method", host and "path", if any, and it sets the route's name to the context.
+func (h *routerHandler) findHandlers(ctx context.Context, trees []*tree, method, path string) context.Handlers {
+	for i := range trees {
+		t := trees[i]
 		if method != t.Method {
 			continue
 		}
 
-		if h.hosts && t.Subdomain != "" {
-			requestHost := ctx.Host()
-			if netutil.IsLoopbackSubdomain(requestHost) {
-				// this fixes a bug when listening on
-				// 127.0.0.1:8080 for example
-				// and have a wildcard subdomain and a route registered to root domain.
-				continue // it's not a subdomain, it's something like 127.0.0.1 probably
-			}
-			// it's a dynamic wildcard subdomain, we have just to check if ctx.subdomain is not empty
-			if t.Subdomain == SubdomainWildcardIndicator {
-				// mydomain.com -> invalid
-				// localhost -> invalid
-				// sub.mydomain.com -> valid
-				// sub.localhost -> valid
-				serverHost := ctx.Application().ConfigurationReadOnly().GetVHost()
-				if serverHost == requestHost {
-					continue // it's not a subdomain, it's a full domain (with .com...)
-				}
-
-				dotIdx := strings.IndexByte(requestHost, '.')
-				slashIdx := strings.IndexByte(requestHost, '/')
-				if dotIdx > 0 && (slashIdx == -1 || slashIdx > dotIdx) {
-					// if "." was found anywhere but not at the first path segment (host).
-				} else {
-					continue
-				}
-				// continue to that, any subdomain is valid.
-			} else if !strings.HasPrefix(requestHost, t.Subdomain) { // t.Subdomain contains the dot.
-				continue
-			}
+		if !h.matchHost(ctx, t) {
+			continue
 		}
-		handlers := t.Nodes.Find(path, ctx.Params())
+
+		// found, not found or method not allowed.
+		routeName, handlers := t.Nodes.Find(path, ctx.Params())
 		if len(handlers) > 0 {
-			ctx.Do(handlers)
-			// found
-			return
+			ctx.SetCurrentRouteName(routeName)
 		}
-		// not found or method not allowed.
-		break
+		return handlers
 	}
 
-	if ctx.Application().ConfigurationReadOnly().GetFireMethodNotAllowed() {
-		var methodAllowed string
-		for i := range h.trees {
-			t := h.trees[i]
-			methodAllowed = t.Method // keep track of the allowed method of the last checked tree
-			if ctx.Method() != methodAllowed {
-				continue
+	return nil
+}
+
+// matchHost reports whether the request's host matches the subdomain of the tree "t".
+func (h *routerHandler) matchHost(ctx context.Context, t *tree) bool {
+	if h.hosts && t.Subdomain != "" {
+		requestHost := ctx.Host()
+		if netutil.IsLoopbackSubdomain(requestHost) {
+			// this fixes a bug when listening on
+			// 127.0.0.1:8080 for example
+			// and have a wildcard subdomain and a route registered to root domain.
+			return false // it's not a subdomain, it's something like 127.0.0.1 probably
+		}
+		// it's a dynamic wildcard subdomain, we have just to check if ctx.subdomain is not empty
+		if t.Subdomain == SubdomainWildcardIndicator {
+			// mydomain.com -> invalid
+			// localhost -> invalid
+			// sub.mydomain.com -> valid
+			// sub.localhost -> valid
+			serverHost := ctx.Application().ConfigurationReadOnly().GetVHost()
+			if serverHost == requestHost {
+				return false // it's not a subdomain, it's a full domain (with .com...)
 			}
+
+			dotIdx := strings.IndexByte(requestHost, '.')
+			slashIdx := strings.IndexByte(requestHost, '/')
+			if dotIdx > 0 && (slashIdx == -1 || slashIdx > dotIdx) {
+				// if "." was found anywhere but not at the first path segment (host).
+			} else {
+				return false
+			}
+			// any subdomain is valid.
+		} else if !strings.HasPrefix(requestHost, t.Subdomain) { // t.Subdomain contains the dot.
+			return false
 		}
-		// RCF rfc2616 https://www.w3.org/Protocols/rfc2616/rfc2616-sec10.html
-		// The response MUST include an Allow header containing a list of valid methods for the requested resource.
-		ctx.Header("Allow", methodAllowed)
-		ctx.StatusCode(http.StatusMethodNotAllowed)
-		return
 	}
-	ctx.StatusCode(http.StatusNotFound)
+
+	return true
 }
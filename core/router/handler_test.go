@@ -0,0 +1,114 @@
+// black-box testing
+package router_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	stdhttptest "net/http/httptest"
+	"testing"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/httptest"
+)
+
+func TestAutomaticOptions(t *testing.T) {
+	app := ion.New()
+
+	party := app.Party("/api", func(ctx context.Context) {
+		ctx.Header("X-Party", "yes")
+		ctx.Next()
+	})
+	{
+		// the route's own middleware, i.e an authentication, should not run on OPTIONS.
+		auth := func(ctx context.Context) {
+			ctx.Header("X-Auth", "yes")
+			ctx.StatusCode(ion.StatusUnauthorized)
+			ctx.StopExecution()
+		}
+		party.Get("/users/{id:int}", auth, func(ctx context.Context) {
+			ctx.Writef("user")
+		})
+		party.Post("/users/{id:int}", auth, func(ctx context.Context) {
+			ctx.Writef("updated")
+		})
+
+		party.Get("/custom", func(ctx context.Context) {})
+		party.Options("/custom", func(ctx context.Context) {
+			ctx.Header("Allow", "GET")
+			ctx.Writef("custom")
+		})
+	}
+
+	// the global middleware, even if they're registered after the routes.
+	app.UseGlobal(func(ctx context.Context) {
+		ctx.Header("X-Global", "yes")
+		ctx.Next()
+	})
+
+	e := httptest.New(t, app)
+
+	for _, preflight := range []bool{false, true} {
+		req := e.OPTIONS("/api/users/42")
+		if preflight {
+			req.WithHeader("Origin", "https://example.com").
+				WithHeader("Access-Control-Request-Method", "POST")
+		}
+
+		r := req.Expect().Status(httptest.StatusNoContent)
+		r.Header("Allow").Equal("GET, HEAD, OPTIONS, POST")
+		r.Header("X-Party").Equal("yes")
+		r.Header("X-Global").Equal("yes")
+		r.Header("X-Auth").Empty()
+		r.Body().Empty()
+	}
+
+	// the route's middleware still run on the route's method.
+	e.GET("/api/users/42").Expect().Status(httptest.StatusUnauthorized).Header("X-Auth").Equal("yes")
+
+	// the path parameters are validated.
+	e.OPTIONS("/api/users/abc").Expect().Status(httptest.StatusNotFound)
+	e.OPTIONS("/api/products").Expect().Status(httptest.StatusNotFound)
+
+	// a registered OPTIONS route is not replaced.
+	r := e.OPTIONS("/api/custom").Expect().Status(httptest.StatusOK)
+	r.Header("Allow").Equal("GET")
+	r.Body().Equal("custom")
+}
+
+func TestAutomaticHead(t *testing.T) {
+	app := ion.New()
+	app.Get("/hello", func(ctx context.Context) {
+		ctx.Header("X-Hello", "yes")
+		ctx.Writef("Hello %s", "world")
+	})
+	if err := app.Build(); err != nil {
+		t.Fatal(err)
+	}
+
+	// a real server, it sets the Content-Length and drops the body of the HEAD.
+	srv := stdhttptest.NewServer(app.Router)
+	defer srv.Close()
+
+	get, err := http.Get(srv.URL + "/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	get.Body.Close()
+
+	head, err := http.Head(srv.URL + "/hello")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(head.Body)
+	head.Body.Close()
+
+	if head.StatusCode != http.StatusOK || len(body) != 0 {
+		t.Fatalf("expected an empty 200 OK but got %d: %q", head.StatusCode, body)
+	}
+	for _, key := range []string{"Content-Length", "Content-Type", "X-Hello"} {
+		if expected, got := get.Header.Get(key), head.Header.Get(key); expected == "" || expected != got {
+			t.Fatalf("expected the %s header of the HEAD to be '%s' like the GET but got '%s'", key, expected, got)
+		}
+	}
+}
@@ -17,6 +17,7 @@ type node struct {
 	paramNames        []string // only-names
 	children          Nodes
 	handlers          context.Handlers
+	routeName         string // the name of the route of the handlers
 	root              bool
 }
 
@@ -24,7 +25,7 @@ type node struct {
 var ErrDublicate = errors.New("two or more routes have the same registered path")
 
 // Add adds a node to the tree, returns an ErrDublicate error on failure.
-func (nodes *Nodes) Add(path string, handlers context.Handlers) error {
+func (nodes *Nodes) Add(routeName string, path string, handlers context.Handlers) error {
 	// resolve params and if that node should be added as root
 	var params []string
 	var paramStart, paramEnd int
@@ -60,18 +61,18 @@ func (nodes *Nodes) Add(path string, handlers context.Handlers) error {
 
 	for _, idx := range p {
 
-		if err := nodes.add(path[:idx], nil, nil, true); err != nil {
+		if err := nodes.add("", path[:idx], nil, nil, true); err != nil {
 			return err
 		}
 
 		if nidx := idx + 1; len(path) > nidx {
-			if err := nodes.add(path[:nidx], nil, nil, true); err != nil {
+			if err := nodes.add("", path[:nidx], nil, nil, true); err != nil {
 				return err
 			}
 		}
 	}
 
-	if err := nodes.add(path, params, handlers, true); err != nil {
+	if err := nodes.add(routeName, path, params, handlers, true); err != nil {
 		return err
 	}
 
@@ -80,7 +81,7 @@ func (nodes *Nodes) Add(path string, handlers context.Handlers) error {
 	return nil
 }
 
-func (nodes *Nodes) add(path string, paramNames []string, handlers context.Handlers, root bool) (err error) {
+func (nodes *Nodes) add(routeName, path string, paramNames []string, handlers context.Handlers, root bool) (err error) {
 	// wraia etsi doulevei ara
 	// na to kanw na exei to node to diko tou wildcard parameter name
 	// kai sto telos na pernei auto, me vasi to *paramname
@@ -94,6 +95,12 @@ func (nodes *Nodes) add(path string, paramNames []string, handlers context.Handl
 		path = path[0:wildcardIdx-1] + "/" // replace *paramName with single slash
 	}
 
+	return nodes.addNode(routeName, path, wildcardParamName, paramNames, handlers, root)
+}
+
+// addNode adds the "path", which its wildcard is already removed, to the nodes,
+// the children keep the "wildcardParamName" too.
+func (nodes *Nodes) addNode(routeName, path string, wildcardParamName string, paramNames []string, handlers context.Handlers, root bool) (err error) {
 loop:
 	for _, n := range *nodes {
 
@@ -115,16 +122,18 @@ loop:
 				children: Nodes{
 					{
 						s:                 n.s[i:],
-						wildcardParamName: wildcardParamName,
+						wildcardParamName: n.wildcardParamName,
 						paramNames:        n.paramNames,
 						children:          n.children,
 						handlers:          n.handlers,
+						routeName:         n.routeName,
 					},
 					{
 						s:                 path[i:],
 						wildcardParamName: wildcardParamName,
 						paramNames:        paramNames,
 						handlers:          handlers,
+						routeName:         routeName,
 					},
 				},
 				root: n.root,
@@ -140,21 +149,23 @@ loop:
 				children: Nodes{
 					{
 						s:                 n.s[len(path):],
-						wildcardParamName: wildcardParamName,
+						wildcardParamName: n.wildcardParamName,
 						paramNames:        n.paramNames,
 						children:          n.children,
 						handlers:          n.handlers,
+						routeName:         n.routeName,
 					},
 				},
-				handlers: handlers,
-				root:     n.root,
+				handlers:  handlers,
+				routeName: routeName,
+				root:      n.root,
 			}
 
 			return
 		}
 
 		if len(path) > len(n.s) {
-			err = n.children.add(path[len(n.s):], paramNames, handlers, false)
+			err = n.children.addNode(routeName, path[len(n.s):], wildcardParamName, paramNames, handlers, false)
 			return err
 		}
 
@@ -164,8 +175,10 @@ loop:
 		if len(n.handlers) > 0 { // n.handlers already setted
 			return ErrDublicate
 		}
+		n.wildcardParamName = wildcardParamName
 		n.paramNames = paramNames
 		n.handlers = handlers
+		n.routeName = routeName
 
 		return
 	}
@@ -175,6 +188,7 @@ loop:
 		wildcardParamName: wildcardParamName,
 		paramNames:        paramNames,
 		handlers:          handlers,
+		routeName:         routeName,
 		root:              root,
 	}
 
@@ -183,8 +197,8 @@ loop:
 }
 
 // Find resolves the path, fills its params
-// and returns the registered to the resolved node's handlers.
-func (nodes Nodes) Find(path string, params *context.RequestParams) context.Handlers {
+// and returns the registered to the resolved node's route name and handlers.
+func (nodes Nodes) Find(path string, params *context.RequestParams) (string, context.Handlers) {
 	n, paramValues := nodes.findChild(path, nil)
 	if n != nil {
 		//	map the params,
@@ -202,10 +216,10 @@ func (nodes Nodes) Find(path string, params *context.RequestParams) context.Hand
 				params.Set(n.wildcardParamName, lastWildcardVal)
 			}
 		}
-		return n.handlers
+		return n.routeName, n.handlers
 	}
 
-	return nil
+	return "", nil
 }
 
 func (nodes Nodes) findChild(path string, params []string) (*node, []string) {
@@ -0,0 +1,57 @@
+// black-box testing
+package node_test
+
+import (
+	"testing"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/router/node"
+)
+
+func TestWildcardParamNameOnSplit(t *testing.T) {
+	h := context.Handlers{func(ctx context.Context) {}}
+
+	// the nodes of the wildcard routes are split by the routes which are added later,
+	// the split nodes keep the wildcard parameter name of their routes.
+	var nodes node.Nodes
+	for _, r := range []struct {
+		name string
+		path string
+	}{
+		{"files", "/files/*file"},
+		{"fi", "/fi"},
+		{"fa", "/fa"},
+		{"static", "/static/*asset"},
+		{"stats", "/stats"},
+	} {
+		if err := nodes.Add(r.name, r.path, h); err != nil {
+			t.Fatalf("%s: %v", r.path, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		path  string
+		name  string
+		param string
+		value string
+	}{
+		{"/files/css/style.css", "files", "file", "css/style.css"},
+		{"/static/js/app.js", "static", "asset", "js/app.js"},
+		{"/fi", "fi", "", ""},
+		{"/fa", "fa", "", ""},
+		{"/stats", "stats", "", ""},
+	} {
+		params := new(context.RequestParams)
+		name, handlers := nodes.Find(tt.path, params)
+		if name != tt.name || len(handlers) == 0 {
+			t.Fatalf("%s: expected the route '%s' but got '%s'", tt.path, tt.name, name)
+		}
+
+		if tt.param == "" {
+			continue
+		}
+		if got := params.Get(tt.param); got != tt.value {
+			t.Fatalf("%s: expected the '%s' parameter to be '%s' but got '%s'", tt.path, tt.param, tt.value, got)
+		}
+	}
+}
@@ -1,6 +1,8 @@
 package router
 
 import (
+	"net/url"
+
 	"github.com/get-ion/ion/context"
 ) // Party is here to separate the concept of
 // api builder and the sub api builder.
@@ -32,6 +34,12 @@ type Party interface {
 	//
 	// Returns the read-only route information.
 	Handle(method string, registeredPath string, handlers ...context.Handler) *Route
+	// HandleFunc registers a route with a func of any input arguments and return values,
+	// the path parameters and the request body are bound to its input arguments
+	// and its return values are sent to the client, see `handlerconv#FromFunc`.
+	//
+	// Returns the read-only route information.
+	HandleFunc(method string, registeredPath string, fn interface{}) *Route
 
 	// None registers an "offline" route
 	// see context.ExecRoute(routeName) and
@@ -80,6 +88,26 @@ type Party interface {
 	// Any registers a route for ALL of the http methods
 	// (Get,Post,Put,Head,Patch,Options,Connect,Delete).
 	Any(registeredPath string, handlers ...context.Handler) []*Route
+	// Controller registers the exported methods of a "controller" as routes
+	// under the "relativePath" of this Party, i.e
+	// app.Controller("/users", new(UserController), db)
+	// registers the UserController's GetBy(id int) to GET /users/{paramA:int}.
+	// The "bindValues" are set to the controller's exported fields that they are assignable to.
+	//
+	// See `APIBuilder#Controller` and the `mvc` package for more.
+	//
+	// Returns the registered routes.
+	Controller(relativePath string, controller interface{}, bindValues ...interface{}) []*Route
+	// Proxy forwards the requests of the "relativePath" and its sub paths to the "target",
+	// the party's path and the "relativePath" are stripped, i.e
+	// app.Proxy("/api", target) forwards the /api/users to the target's /users.
+	// The upgrade requests, i.e websockets, are tunnelled too.
+	// The "handlers" are executed before the proxy.
+	//
+	// See `host#NewReverseProxy` for more.
+	//
+	// Returns the registered routes.
+	Proxy(relativePath string, target *url.URL, handlers ...context.Handler) []*Route
 
 	// StaticHandler returns a new Handler which is ready
 	// to serve all kind of static files.
@@ -21,6 +21,10 @@ type Route struct {
 	// FormattedPath all dynamic named parameters (if any) replaced with %v,
 	// used by Application to validate param values of a Route based on its name.
 	FormattedPath string
+	// middleware are the party's middleware of the route, the global ones too,
+	// not the route's own handlers, see `Party#Use` and `Party#UseGlobal`.
+	// They run on the automatic OPTIONS requests of the route's path.
+	middleware context.Handlers
 }
 
 // NewRoute returns a new route based on its method,
@@ -57,6 +61,20 @@ func NewRoute(method, subdomain, unparsedPath string,
 	return route, nil
 }
 
+// optionsHandlers returns the handlers of the automatic OPTIONS route of the route's path,
+// the macro evaluator, if needed, and the party's middleware, i.e a CORS middleware, followed by the "serve".
+// The route's own handlers never run, they may be an authentication or a rate limiter of the route itself.
+func (r *Route) optionsHandlers(serve context.Handler) context.Handlers {
+	var handlers context.Handlers
+	if len(r.tmpl.Params) > 0 {
+		if macroEvaluatorHandler := convertTmplToHandler(r.tmpl); macroEvaluatorHandler != nil {
+			handlers = append(handlers, macroEvaluatorHandler)
+		}
+	}
+	handlers = append(handlers, r.middleware...)
+	return append(handlers, serve)
+}
+
 // String returns the form of METHOD, SUBDOMAIN, TMPL PATH
 func (r Route) String() string {
 	return fmt.Sprintf("%s %s%s",
@@ -51,8 +51,18 @@ type Application struct {
 
 	// view engine
 	view view.View
+	// the template functions of the AddViewFunc.
+	viewFuncs map[string]interface{}
+	// true after the Build, the AddViewFunc panics then.
+	viewFuncsAdded bool
 	// used for build
 	once sync.Once
+	// the PROXY protocol configuration, see `ProxyProtocol`.
+	proxyProtocol *netutil.ProxyProtocolConfig
+	// the HTTP/2 options, see `HTTP2`.
+	http2 *host.HTTP2
+	// the limits of the concurrent connections, see `ConnLimits`.
+	connLimits *netutil.ConnLimits
 
 	mu       sync.Mutex
 	Shutdown func(stdContext.Context) error
@@ -153,6 +163,27 @@ func (app *Application) RegisterView(viewEngine view.Engine) {
 	app.view.Register(viewEngine)
 }
 
+var errViewFuncAfterBuild = errors.New("view: AddViewFunc(%s) is called after the Build, the templates are already loaded")
+
+// AddViewFunc adds a template function to all the registered view engines
+// that support functions, see `view.View#AddFunc`.
+// The functions are added on `Build`, so the engines can be registered after,
+// i.e the csrf_token and csrf_field of the csrf middleware.
+//
+// It panics if it's called after the `Build` or the `Run`, the function would never be added.
+func (app *Application) AddViewFunc(funcName string, funcBody interface{}) {
+	app.mu.Lock()
+	defer app.mu.Unlock()
+	if app.viewFuncsAdded {
+		panic(errViewFuncAfterBuild.Format(funcName))
+	}
+
+	if app.viewFuncs == nil {
+		app.viewFuncs = make(map[string]interface{})
+	}
+	app.viewFuncs[funcName] = funcBody
+}
+
 // View executes and writes the result of a template file to the writer.
 //
 // First parameter is the writer to write the parsed template.
@@ -250,6 +281,15 @@ func (app *Application) NewHost(srv *http.Server) *host.Supervisor {
 		su.Schedule(host.ShutdownOnInterruptTask(shutdownTimeout))
 	}
 
+	if app.proxyProtocol != nil {
+		// validated by the runner.
+		su.SetProxyProtocol(*app.proxyProtocol)
+	}
+
+	if app.connLimits != nil {
+		su.SetConnLimits(*app.connLimits)
+	}
+
 	if app.Shutdown == nil {
 		app.Shutdown = su.Shutdown
 	}
@@ -257,6 +297,25 @@ func (app *Application) NewHost(srv *http.Server) *host.Supervisor {
 	return su
 }
 
+// newHost returns a new host of the "srv", like the `NewHost`,
+// and configures its HTTP/2 by the options of the `HTTP2` runner, if any.
+// It's used by the built-in runners.
+func (app *Application) newHost(srv *http.Server) (*host.Supervisor, error) {
+	su := app.NewHost(srv)
+
+	app.mu.Lock()
+	opts := app.http2
+	app.mu.Unlock()
+
+	if opts != nil {
+		if err := su.ConfigureHTTP2(*opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return su, nil
+}
+
 // Runner is just an interface which accepts the framework instance
 // and returns an error.
 //
@@ -275,8 +334,11 @@ type Runner func(*Application) error
 func Listener(l net.Listener) Runner {
 	return func(app *Application) error {
 		app.config.vhost = netutil.ResolveVHost(l.Addr().String())
-		return app.NewHost(new(http.Server)).
-			Serve(l)
+		su, err := app.newHost(new(http.Server))
+		if err != nil {
+			return err
+		}
+		return su.Serve(l)
 	}
 }
 
@@ -286,8 +348,11 @@ func Listener(l net.Listener) Runner {
 // See `Run` for more.
 func Server(srv *http.Server) Runner {
 	return func(app *Application) error {
-		return app.NewHost(srv).
-			ListenAndServe()
+		su, err := app.newHost(srv)
+		if err != nil {
+			return err
+		}
+		return su.ListenAndServe()
 	}
 }
 
@@ -300,8 +365,11 @@ func Server(srv *http.Server) Runner {
 // See `Run` for more.
 func Addr(addr string) Runner {
 	return func(app *Application) error {
-		return app.NewHost(&http.Server{Addr: addr}).
-			ListenAndServe()
+		su, err := app.newHost(&http.Server{Addr: addr})
+		if err != nil {
+			return err
+		}
+		return su.ListenAndServe()
 	}
 }
 
@@ -311,13 +379,81 @@ func Addr(addr string) Runner {
 // Use it like you used to use the http.ListenAndServeTLS function.
 //
 // Addr should have the form of [host]:port, i.e localhost:443 or :443.
-// CertFile & KeyFile should be filenames with their extensions.
+// CertFile & KeyFile should be filenames with their extensions,
+// they're reloaded when they're changed, without a restart, see `Certs`.
 //
 // See `Run` for more.
 func TLS(addr string, certFile, keyFile string) Runner {
 	return func(app *Application) error {
-		return app.NewHost(&http.Server{Addr: addr}).
-			ListenAndServeTLS(certFile, keyFile)
+		m := host.NewCertManager()
+		if err := m.Add(certFile, keyFile); err != nil {
+			return err
+		}
+
+		return Certs(addr, m)(app)
+	}
+}
+
+// Certs can be used as an argument for the `Run` method.
+// It will start the Application's secure server which serves
+// the certificates of the certificate manager "m", chosen by the server name
+// of each client, the SNI.
+// The changed certificate files are reloaded without a restart and the certificates which expire soon
+// are logged by the Application's logger, see `host#CertManager`.
+//
+// Addr should have the form of [host]:port, i.e localhost:443 or :443.
+//
+// Usage:
+// m := host.NewCertManager()
+// m.Add("example.com.crt", "example.com.key")
+// m.Add("example.org.crt", "example.org.key")
+// app.Run(ion.Certs(":443", m))
+//
+// See `Run` for more.
+func Certs(addr string, m *host.CertManager) Runner {
+	return func(app *Application) error {
+		if m.Logger == nil {
+			m.Logger = app.logger
+			// log the certificates which expire soon now.
+			m.Reload()
+		}
+
+		su, err := app.newHost(&http.Server{Addr: addr})
+		if err != nil {
+			return err
+		}
+		return su.ListenAndServeCerts(m)
+	}
+}
+
+// MutualTLS can be used as an argument for the `Run` method.
+// It will start the Application's secure server, like the `Certs`,
+// which authenticates the clients by their certificates too,
+// the certificates should be signed by one of the certificate authorities of the "auth".
+//
+// The verified client certificates are available to the handlers
+// by the `Context#ClientCertificate` and the `Context#PeerIdentity`,
+// the routes can be authorized by their attributes with the "middleware/mtls".
+//
+// Usage:
+// app.Run(ion.MutualTLS(":443", m, host.ClientAuth{CAFiles: []string{"clients-ca.crt"}}))
+//
+// See `Run` for more.
+func MutualTLS(addr string, m *host.CertManager, auth host.ClientAuth) Runner {
+	return func(app *Application) error {
+		if m.Logger == nil {
+			m.Logger = app.logger
+			m.Reload()
+		}
+
+		su, err := app.newHost(&http.Server{Addr: addr})
+		if err != nil {
+			return err
+		}
+		if err := su.SetClientAuth(auth); err != nil {
+			return err
+		}
+		return su.ListenAndServeCerts(m)
 	}
 }
 
@@ -331,8 +467,132 @@ func TLS(addr string, certFile, keyFile string) Runner {
 // See `Run` for more.
 func AutoTLS(addr string) Runner {
 	return func(app *Application) error {
-		return app.NewHost(&http.Server{Addr: addr}).
-			ListenAndServeAutoTLS()
+		su, err := app.newHost(&http.Server{Addr: addr})
+		if err != nil {
+			return err
+		}
+		return su.ListenAndServeAutoTLS()
+	}
+}
+
+// ProxyProtocol can be used as an argument for the `Run` method.
+// It wraps the "runner", i.e the `Addr`, the `TLS`, the `Certs` or the `Listener` of a UNIX socket,
+// and reads the PROXY protocol header, the version 1 or 2, which the proxies and the load balancers
+// in TCP mode send at the start of each connection, i.e the HAProxy's "send-proxy" or the AWS Network Load Balancer.
+//
+// The "trustedProxies" are the ip addresses or the CIDRs, i.e "10.0.0.0/8", of the proxies,
+// only their connections can send a header and they must send it, the header of the other connections is ignored.
+// The "trustedProxies" are required, the runner fails without them.
+// The connections of the UNIX sockets are trusted too.
+//
+// The address of the client flows to the request's RemoteAddr, the `Context#RemoteAddr`
+// and the request logger, the header is available by the `netutil#ProxyHeaderOf`.
+//
+// Usage:
+// app.Run(ion.ProxyProtocol(ion.Addr(":8080"), "10.0.0.0/8"))
+//
+// See `ProxyProtocolOptional`, `Run` and `host#Supervisor.SetProxyProtocol` for more.
+func ProxyProtocol(runner Runner, trustedProxies ...string) Runner {
+	return proxyProtocol(runner, false, trustedProxies)
+}
+
+// ProxyProtocolOptional acts identically to `ProxyProtocol`, except that the connections
+// of the trusted proxies can omit the header, they keep their address,
+// i.e the health checks of a load balancer which can't send the header.
+//
+// Usage:
+// app.Run(ion.ProxyProtocolOptional(ion.Addr(":8080"), "10.0.0.0/8"))
+//
+// See `ProxyProtocol` and `Run` for more.
+func ProxyProtocolOptional(runner Runner, trustedProxies ...string) Runner {
+	return proxyProtocol(runner, true, trustedProxies)
+}
+
+func proxyProtocol(runner Runner, optional bool, trustedProxies []string) Runner {
+	return func(app *Application) error {
+		cfg := netutil.ProxyProtocolConfig{HeaderOptional: optional}
+		for _, proxy := range trustedProxies {
+			ipNet, err := netutil.ParseIPNet(proxy)
+			if err != nil {
+				return err
+			}
+			cfg.Trusted = append(cfg.Trusted, ipNet)
+		}
+
+		// fail before the server, a client could spoof its address otherwise.
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+
+		app.mu.Lock()
+		app.proxyProtocol = &cfg
+		app.mu.Unlock()
+		return runner(app)
+	}
+}
+
+// HTTP2 can be used as an argument for the `Run` method.
+// It wraps the "runner", i.e the `Addr`, the `TLS` or the `Certs` one,
+// and configures the HTTP/2 of the server by the "opts", its settings and the h2c,
+// the HTTP/2 over cleartext TCP, see `host#HTTP2`.
+//
+// Usage:
+// app.Run(ion.HTTP2(ion.Addr(":8080"), host.HTTP2{H2C: true, MaxConcurrentStreams: 500}))
+//
+// The built-in runners return the error of the configuration, if any, before the server starts,
+// a custom runner should configure its host by the `host#Supervisor.ConfigureHTTP2`.
+//
+// See `H2C` and `Run` for more.
+func HTTP2(runner Runner, opts host.HTTP2) Runner {
+	return func(app *Application) error {
+		app.mu.Lock()
+		app.http2 = &opts
+		app.mu.Unlock()
+		return runner(app)
+	}
+}
+
+// H2C can be used as an argument for the `Run` method.
+// It will start the Application's server which serves the HTTP/1.1
+// and the HTTP/2 over cleartext TCP, without tls, i.e behind a tls-terminating proxy or a service mesh.
+// The clients can start with the HTTP/2 connection preface, the prior knowledge,
+// or upgrade an HTTP/1.1 request with the "Upgrade: h2c" header.
+//
+// Addr should have the form of [host]:port, i.e localhost:8080 or :8080.
+//
+// The optional "opts" are the settings of the HTTP/2, see `HTTP2`.
+//
+// See `Run` for more.
+func H2C(addr string, opts ...host.HTTP2) Runner {
+	var o host.HTTP2
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	o.H2C = true
+
+	return HTTP2(Addr(addr), o)
+}
+
+// ConnLimits can be used as an argument for the `Run` method.
+// It wraps the "runner", i.e the `Addr`, the `TLS`, the `Certs` or the `Listener`,
+// and limits the concurrent connections of the server by the "limits",
+// the max connections, which are queued or rejected, and the max connections per ip address,
+// see `netutil#ConnLimits`.
+//
+// The number of the open connections per state, the rejected ones
+// and the drained or forcibly closed ones on shutdown are available by the `host#Supervisor.Stats`
+// and the metrics middleware.
+//
+// Usage:
+// app.Run(ion.ConnLimits(ion.Addr(":8080"), netutil.ConnLimits{MaxConnections: 1000, MaxConnectionsPerIP: 20}))
+//
+// See `Run` and `host#Supervisor.SetConnLimits` for more.
+func ConnLimits(runner Runner, limits netutil.ConnLimits) Runner {
+	return func(app *Application) error {
+		app.mu.Lock()
+		app.connLimits = &limits
+		app.mu.Unlock()
+		return runner(app)
 	}
 }
 
@@ -360,6 +620,11 @@ func (app *Application) Build() error {
 	app.once.Do(func() {
 		rp.Describe("api builder: %v", app.APIBuilder.GetReport())
 
+		app.mu.Lock()
+		viewFuncs := app.viewFuncs
+		app.viewFuncsAdded = true
+		app.mu.Unlock()
+
 		if !app.Router.Downgraded() {
 			// router
 			// create the request handler, the default routing handler
@@ -377,6 +642,9 @@ func (app *Application) Build() error {
 			rv := router.NewRoutePathReverser(app.APIBuilder)
 			app.view.AddFunc("urlpath", rv.Path)
 			// app.view.AddFunc("url", rv.URL)
+			for funcName, funcBody := range viewFuncs {
+				app.view.AddFunc(funcName, funcBody)
+			}
 			rp.Describe("view: %v", app.view.Load())
 		}
 	})
@@ -402,7 +670,7 @@ var ErrServerClosed = http.ErrServerClosed
 //
 // The Application can go online with any type of server or ion's host with the help of
 // the following runners:
-// `Listener`, `Server`, `Addr`, `TLS`, `AutoTLS` and `Raw`.
+// `Listener`, `Server`, `Addr`, `TLS`, `Certs`, `MutualTLS`, `AutoTLS`, `ProxyProtocol`, `ProxyProtocolOptional`, `HTTP2`, `H2C`, `ConnLimits` and `Raw`.
 func (app *Application) Run(serve Runner, withOrWithout ...Configurator) error {
 	// first Build because it doesn't need anything from configuration,
 	//  this give the user the chance to modify the router inside a configurator as well.
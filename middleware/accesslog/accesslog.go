@@ -0,0 +1,336 @@
+// Package accesslog provides structured access logging via middleware. See _examples/http_request/access-log
+package accesslog
+
+// test file: ../../_examples/http_request/access-log/main_test.go
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/errors"
+)
+
+var (
+	errUnknownField  = errors.New("accesslog: unknown field '%s'")
+	errUnclosedField = errors.New("accesslog: unclosed field of the format '%s'")
+)
+
+const requestIDHeaderKey = "X-Request-Id"
+
+// entry is the data of a logged request.
+type entry struct {
+	ctx     context.Context
+	start   time.Time
+	latency time.Duration
+	status  int
+	bytes   int
+}
+
+type fieldValue func(e *entry) interface{}
+
+type field struct {
+	key   string
+	value fieldValue
+}
+
+func headerValue(name string) fieldValue {
+	return func(e *entry) interface{} { return e.ctx.GetHeader(name) }
+}
+
+var fieldValues = map[string]fieldValue{
+	"time":      func(e *entry) interface{} { return e.start.Format(time.RFC3339) },
+	"time_clf":  func(e *entry) interface{} { return e.start.Format("02/Jan/2006:15:04:05 -0700") },
+	"remote_ip": func(e *entry) interface{} { return e.ctx.RemoteAddr() },
+	"remote_user": func(e *entry) interface{} {
+		username, _, _ := e.ctx.Request().BasicAuth()
+		return username
+	},
+	"method": func(e *entry) interface{} { return e.ctx.Method() },
+	"uri":    func(e *entry) interface{} { return e.ctx.Request().RequestURI },
+	"path":   func(e *entry) interface{} { return e.ctx.Path() },
+	"proto":  func(e *entry) interface{} { return e.ctx.Request().Proto },
+	"host":   func(e *entry) interface{} { return e.ctx.Host() },
+	"status": func(e *entry) interface{} { return e.status },
+	"bytes":  func(e *entry) interface{} { return e.bytes },
+	"bytes_clf": func(e *entry) interface{} {
+		if e.bytes == 0 {
+			return ""
+		}
+		return e.bytes
+	},
+	"latency":    func(e *entry) interface{} { return e.latency.String() },
+	"latency_ms": func(e *entry) interface{} { return float64(e.latency) / float64(time.Millisecond) },
+	"referer":    headerValue("Referer"),
+	"user_agent": headerValue("User-Agent"),
+	"route":      func(e *entry) interface{} { return e.ctx.GetCurrentRouteName() },
+	"request_id": func(e *entry) interface{} {
+		if id := e.ctx.RequestID(); id != "" {
+			return id
+		}
+		return e.ctx.GetHeader(requestIDHeaderKey)
+	},
+	"trace_id": func(e *entry) interface{} {
+		traceID, _ := e.ctx.TraceIDs()
+		return traceID
+	},
+	"span_id": func(e *entry) interface{} {
+		_, spanID := e.ctx.TraceIDs()
+		return spanID
+	},
+}
+
+// parseField returns the field of a name, i.e "status" or "header:Accept".
+func parseField(name string) (field, error) {
+	if v, ok := fieldValues[name]; ok {
+		return field{key: name, value: v}, nil
+	}
+
+	if idx := strings.IndexByte(name, ':'); idx > 0 && idx < len(name)-1 {
+		key := name[idx+1:]
+		switch name[:idx] {
+		case "header":
+			return field{key: key, value: headerValue(key)}, nil
+		case "response_header":
+			return field{key: key, value: func(e *entry) interface{} {
+				return e.ctx.ResponseWriter().Header().Get(key)
+			}}, nil
+		case "query":
+			return field{key: key, value: func(e *entry) interface{} {
+				return e.ctx.URLParam(key)
+			}}, nil
+		}
+	}
+
+	return field{}, errUnknownField.Format(name)
+}
+
+// formatter writes an entry to a buffer, without the new line.
+type formatter func(buf *bytes.Buffer, e *entry)
+
+func newFormatter(format string, fieldNames []string) (formatter, error) {
+	switch format {
+	case JSONFormat, LogfmtFormat:
+		fields := make([]field, len(fieldNames))
+		for i, name := range fieldNames {
+			f, err := parseField(name)
+			if err != nil {
+				return nil, err
+			}
+			fields[i] = f
+		}
+
+		if format == JSONFormat {
+			return jsonFormatter(fields), nil
+		}
+		return logfmtFormatter(fields), nil
+	default:
+		return newTemplateFormatter(format)
+	}
+}
+
+// newTemplateFormatter parses a template of literal text and {field} fields.
+func newTemplateFormatter(format string) (formatter, error) {
+	var (
+		literals []string
+		fields   []field
+	)
+
+	s := format
+	for {
+		start := strings.IndexByte(s, '{')
+		if start == -1 {
+			literals = append(literals, s)
+			break
+		}
+
+		end := strings.IndexByte(s[start:], '}')
+		if end == -1 {
+			return nil, errUnclosedField.Format(format)
+		}
+		end += start
+
+		f, err := parseField(s[start+1 : end])
+		if err != nil {
+			return nil, err
+		}
+
+		literals = append(literals, s[:start])
+		fields = append(fields, f)
+		s = s[end+1:]
+	}
+
+	return func(buf *bytes.Buffer, e *entry) {
+		for i, f := range fields {
+			buf.WriteString(literals[i])
+			s := valueString(f.value(e))
+			if s == "" {
+				s = "-"
+			}
+			writeEscaped(buf, s)
+		}
+		buf.WriteString(literals[len(literals)-1])
+	}, nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeEscaped writes the "s" as the Apache and the nginx servers do,
+// the quotes and the backslashes are escaped by a backslash and the control characters
+// and the non-ascii bytes are written as \xHH, so a client can't forge the fields of an entry or a new entry.
+func writeEscaped(buf *bytes.Buffer, s string) {
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '"' || c == '\\':
+			buf.WriteByte('\\')
+			buf.WriteByte(c)
+		case c < ' ' || c >= 0x7f:
+			buf.WriteString(`\x`)
+			buf.WriteByte(hexDigits[c>>4])
+			buf.WriteByte(hexDigits[c&0xf])
+		default:
+			buf.WriteByte(c)
+		}
+	}
+}
+
+func jsonFormatter(fields []field) formatter {
+	return func(buf *bytes.Buffer, e *entry) {
+		buf.WriteByte('{')
+		for i, f := range fields {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			writeJSONString(buf, f.key)
+			buf.WriteByte(':')
+			switch v := f.value(e).(type) {
+			case string:
+				writeJSONString(buf, v)
+			default:
+				buf.WriteString(valueString(v))
+			}
+		}
+		buf.WriteByte('}')
+	}
+}
+
+func writeJSONString(buf *bytes.Buffer, s string) {
+	b, _ := json.Marshal(s)
+	buf.Write(b)
+}
+
+func logfmtFormatter(fields []field) formatter {
+	return func(buf *bytes.Buffer, e *entry) {
+		for i, f := range fields {
+			if i > 0 {
+				buf.WriteByte(' ')
+			}
+			buf.WriteString(f.key)
+			buf.WriteByte('=')
+			s := valueString(f.value(e))
+			if s == "" || strings.ContainsAny(s, " =\"\\") || strings.IndexFunc(s, isControl) != -1 {
+				s = strconv.Quote(s)
+			}
+			buf.WriteString(s)
+		}
+	}
+}
+
+func isControl(r rune) bool {
+	return r < ' ' || r == 0x7f
+}
+
+func valueString(v interface{}) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case int:
+		return strconv.Itoa(value)
+	case float64:
+		return strconv.FormatFloat(value, 'f', 3, 64)
+	}
+	return ""
+}
+
+// the buffers of the entries.
+var bufPool = sync.Pool{New: func() interface{} { return new(bytes.Buffer) }}
+
+type accessLogMiddleware struct {
+	config Config
+	format formatter
+	// serializes the writes to the Output.
+	mu sync.Mutex
+}
+
+// New returns a new access log middleware which writes an entry per request,
+// after the execution of the next handlers, to the Output in the format of the Config.
+// Register it with the `Application#UseGlobal` in order to log the requests of all routes.
+// Note that the requests of unknown routes are not logged, as they don't execute any middleware
+// and that the bytes of the error code handlers' responses are not counted,
+// they're written after the middleware.
+//
+// Receives an optional configuration, the empty fields are filled with the defaults.
+// It panics if the Format or the Fields contain an unknown field.
+func New(cfg ...Config) context.Handler {
+	c := DefaultConfig()
+	if len(cfg) > 0 {
+		c = cfg[0]
+		def := DefaultConfig()
+		if c.Format == "" {
+			c.Format = def.Format
+		}
+		if len(c.Fields) == 0 {
+			c.Fields = def.Fields
+		}
+		if c.Output == nil {
+			c.Output = def.Output
+		}
+	}
+
+	format, err := newFormatter(c.Format, c.Fields)
+	if err != nil {
+		panic(err)
+	}
+
+	m := &accessLogMiddleware{
+		config: c,
+		format: format,
+	}
+	return m.ServeHTTP
+}
+
+// ServeHTTP serves the middleware.
+func (m *accessLogMiddleware) ServeHTTP(ctx context.Context) {
+	if m.config.Skip != nil && m.config.Skip(ctx) {
+		ctx.Next()
+		return
+	}
+
+	start := time.Now()
+	ctx.Next()
+
+	e := &entry{
+		ctx:     ctx,
+		start:   start,
+		latency: time.Since(start),
+		status:  ctx.GetStatusCode(),
+	}
+	if written := ctx.ResponseWriter().Written(); written > 0 {
+		e.bytes = written
+	}
+
+	buf := bufPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	m.format(buf, e)
+	buf.WriteByte('\n')
+
+	m.mu.Lock()
+	// the write errors don't fail the request.
+	m.config.Output.Write(buf.Bytes())
+	m.mu.Unlock()
+	bufPool.Put(buf)
+}
@@ -0,0 +1,96 @@
+package accesslog
+
+import (
+	"io"
+	"os"
+
+	"github.com/get-ion/ion/context"
+)
+
+const (
+	// CommonFormat is the Common Log Format of the Apache and the NCSA servers.
+	CommonFormat = `{remote_ip} - {remote_user} [{time_clf}] "{method} {uri} {proto}" {status} {bytes_clf}`
+	// CombinedFormat is the Combined Log Format, the CommonFormat with the referer and the user agent.
+	CombinedFormat = CommonFormat + ` "{referer}" "{user_agent}"`
+	// JSONFormat writes each entry as a json object of the Fields, one per line.
+	JSONFormat = "json"
+	// LogfmtFormat writes each entry as key=value pairs of the Fields, one per line.
+	LogfmtFormat = "logfmt"
+)
+
+// DefaultFields are the default fields of the JSONFormat and the LogfmtFormat.
+var DefaultFields = []string{
+	"time", "remote_ip", "method", "uri", "proto", "status",
+	"bytes", "latency_ms", "referer", "user_agent", "route", "request_id", "trace_id",
+}
+
+// Config are the options of the access log middleware.
+type Config struct {
+	// Format is the format of the entries, the CommonFormat, the CombinedFormat,
+	// the JSONFormat, the LogfmtFormat or a custom template of fields, i.e
+	// "{time} {method} {path} {status} {latency} {header:X-Forwarded-For}".
+	//
+	// The fields are:
+	// time, the start time of the request in RFC3339
+	// time_clf, the start time in the Common Log Format, 02/Jan/2006:15:04:05 -0700
+	// remote_ip, the client's ip address, see `Context#RemoteAddr`
+	// remote_user, the username of the basic authentication
+	// method, uri, path, proto and host of the request
+	// status, the status code of the response
+	// bytes, the number of the response body's bytes, bytes_clf writes "-" instead of 0
+	// latency, the duration of the request, i.e 1.2ms, latency_ms in milliseconds
+	// referer and user_agent of the request
+	// route, the name of the route, see `Context#GetCurrentRouteName`
+	// request_id, the id of the request, see `Context#RequestID`, or its "X-Request-Id" header
+	// trace_id and span_id, the ids of the server span of the trace middleware, see `Context#TraceIDs`
+	// header:Name, a request header, i.e header:Accept-Language
+	// response_header:Name, a response header, i.e response_header:Content-Type
+	// query:name, a url query parameter, i.e query:page.
+	//
+	// The empty values of a template are written as "-", the quotes and the backslashes
+	// of the values are escaped by a backslash and the control characters and the non-ascii bytes
+	// are written as \xHH, same as the Apache and the nginx servers.
+	// Default is the CombinedFormat.
+	Format string
+	// Fields are the fields of the JSONFormat and the LogfmtFormat, by their order.
+	// The keys of the header:, response_header: and query: fields are their names
+	// without the prefix, i.e "X-Forwarded-For".
+	// Default is the `DefaultFields`.
+	Fields []string
+	// Output is the writer of the entries, i.e a `NewRotatingFile`.
+	// The entries are formatted concurrently but they're written one at a time,
+	// wrap it with the `NewAsyncWriter` in order to write the entries asynchronously.
+	// Default is the os.Stdout.
+	Output io.Writer
+	// Skip reports whether a request should not be logged, i.e the health checks or the static assets.
+	// Default is nil, all requests are logged.
+	Skip func(ctx context.Context) bool
+}
+
+// DefaultConfig returns the default options of the access log middleware,
+// the combined format to the standard output.
+func DefaultConfig() Config {
+	return Config{
+		Format: CombinedFormat,
+		Fields: DefaultFields,
+		Output: os.Stdout,
+	}
+}
+
+// SkipPaths returns a Skip func which skips the requests of the exact "paths"
+// or of the paths that start with a "prefix/*" of them, i.e SkipPaths("/health", "/assets/*").
+func SkipPaths(paths ...string) func(ctx context.Context) bool {
+	return func(ctx context.Context) bool {
+		path := ctx.Path()
+		for _, p := range paths {
+			if p == path {
+				return true
+			}
+
+			if n := len(p) - 1; n > 0 && p[n] == '*' && len(path) >= n && path[:n] == p[:n] {
+				return true
+			}
+		}
+		return false
+	}
+}
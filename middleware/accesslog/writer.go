@@ -0,0 +1,233 @@
+package accesslog
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+)
+
+// ErrClosed is returned by the writes to a closed `AsyncWriter` or `RotatingFile`.
+var ErrClosed = errors.New("accesslog: writer is closed")
+
+// DefaultBufferSize is the default number of the entries that an `AsyncWriter` can queue.
+const DefaultBufferSize = 1024
+
+// AsyncWriter is a buffered writer which writes to its underline writer from a different goroutine,
+// so the requests don't wait for the writes of the log.
+// The queued entries are written together, the writes block only when the queue is full.
+//
+// Close it on shutdown, in order to write the queued entries.
+type AsyncWriter struct {
+	w       io.Writer
+	entries chan []byte
+	done    chan struct{}
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewAsyncWriter returns a new `AsyncWriter` which writes to "w",
+// it can queue "bufferSize" entries, if it's not positive the `DefaultBufferSize` is used.
+func NewAsyncWriter(w io.Writer, bufferSize int) *AsyncWriter {
+	if bufferSize <= 0 {
+		bufferSize = DefaultBufferSize
+	}
+
+	aw := &AsyncWriter{
+		w:       w,
+		entries: make(chan []byte, bufferSize),
+		done:    make(chan struct{}),
+	}
+	go aw.run()
+	return aw
+}
+
+func (w *AsyncWriter) run() {
+	defer close(w.done)
+
+	var buf bytes.Buffer
+	for p := range w.entries {
+		buf.Write(p)
+		// write the rest of the queued entries together.
+	drain:
+		for {
+			select {
+			case p, ok := <-w.entries:
+				if !ok {
+					break drain
+				}
+				buf.Write(p)
+			default:
+				break drain
+			}
+		}
+
+		w.w.Write(buf.Bytes())
+		buf.Reset()
+	}
+}
+
+// Write queues a copy of "p", it returns the `ErrClosed` if the writer is closed.
+func (w *AsyncWriter) Write(p []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	if w.closed {
+		return 0, ErrClosed
+	}
+
+	w.entries <- append([]byte(nil), p...)
+	return len(p), nil
+}
+
+// Close writes the queued entries and closes the underline writer, if it's an io.Closer.
+func (w *AsyncWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	close(w.entries)
+	w.mu.Unlock()
+
+	<-w.done
+	if closer, ok := w.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// RotatingFile is a file writer which moves the file to a backup
+// and creates a new one when the file reaches a size or at an interval.
+// The backups are named after the rotation time, i.e access.log -> access-2017-07-10T15-04-05.000.log.
+type RotatingFile struct {
+	filename string
+	maxSize  int64
+	interval time.Duration
+
+	mu   sync.Mutex
+	file *os.File
+	size int64
+	// the time of the next rotation, if interval.
+	rotateAt time.Time
+}
+
+// NewRotatingFile opens, or creates, the "filename" for appending and returns a new `RotatingFile` of it.
+// The file is rotated before a write that makes its size larger than the "maxSize" bytes
+// and at every "interval", the intervals are aligned to the UTC time,
+// i.e 24 * time.Hour rotates the file at midnight UTC.
+// Zero "maxSize" or "interval" disables the rotations by size or by time.
+func NewRotatingFile(filename string, maxSize int64, interval time.Duration) (*RotatingFile, error) {
+	f := &RotatingFile{
+		filename: filename,
+		maxSize:  maxSize,
+		interval: interval,
+	}
+
+	if err := f.open(time.Now()); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *RotatingFile) open(now time.Time) error {
+	file, err := os.OpenFile(f.filename, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	f.file = file
+	f.size = info.Size()
+	if f.interval > 0 {
+		f.rotateAt = now.Truncate(f.interval).Add(f.interval)
+	}
+	return nil
+}
+
+// backupName returns the name of the backup of a rotation at "t",
+// a counter is added if a backup of the same time exists.
+func (f *RotatingFile) backupName(t time.Time) string {
+	ext := filepath.Ext(f.filename)
+	name := f.filename[:len(f.filename)-len(ext)] + "-" + t.Format("2006-01-02T15-04-05.000")
+
+	backup := name + ext
+	for i := 1; ; i++ {
+		if _, err := os.Stat(backup); os.IsNotExist(err) {
+			return backup
+		}
+		backup = name + "-" + strconv.Itoa(i) + ext
+	}
+}
+
+func (f *RotatingFile) rotate(now time.Time) error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	f.file = nil
+
+	// keep writing to the same file if it can't be moved.
+	renameErr := os.Rename(f.filename, f.backupName(now))
+	if err := f.open(now); err != nil {
+		return err
+	}
+	return renameErr
+}
+
+// Write writes "p" to the file, it rotates the file first if needed.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, ErrClosed
+	}
+
+	now := time.Now()
+	if (f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize) ||
+		(f.interval > 0 && !now.Before(f.rotateAt)) {
+		if err := f.rotate(now); err != nil && f.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Rotate rotates the file now, i.e on a SIGHUP signal.
+func (f *RotatingFile) Rotate() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return ErrClosed
+	}
+	return f.rotate(time.Now())
+}
+
+// Close closes the file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
@@ -0,0 +1,51 @@
+package cors
+
+import (
+	"net/http"
+	"regexp"
+	"time"
+)
+
+// Config are the options of the cors middleware.
+type Config struct {
+	// AllowedOrigins are the origins that can make cross-origin requests,
+	// an origin can be exact, i.e "https://example.com",
+	// a wildcard subdomain, i.e "https://*.example.com",
+	// or "*" which allows all origins.
+	// Default is empty, no origins are allowed unless
+	// the AllowedOriginRegexps or the AllowOriginFunc allow them.
+	AllowedOrigins []string
+	// AllowedOriginRegexps are the regular expressions
+	// that the origins which can make cross-origin requests match.
+	AllowedOriginRegexps []*regexp.Regexp
+	// AllowOriginFunc is a custom function to validate the origin,
+	// an origin is allowed if any of the AllowedOrigins, the AllowedOriginRegexps
+	// or the AllowOriginFunc allows it.
+	AllowOriginFunc func(origin string) bool
+	// AllowedMethods are the methods that the client can use on cross-origin requests.
+	// Default is GET, POST and HEAD.
+	AllowedMethods []string
+	// AllowedHeaders are the headers that the client can send on cross-origin requests,
+	// "*" allows any header.
+	// Default is Origin, Accept, Content-Type and X-Requested-With.
+	AllowedHeaders []string
+	// ExposedHeaders are the response headers that the client's scripts can read,
+	// see the "Access-Control-Expose-Headers" header.
+	ExposedHeaders []string
+	// AllowCredentials indicates whether the request can include user credentials,
+	// like cookies, http authentication or client side ssl certificates.
+	// Default is false.
+	AllowCredentials bool
+	// MaxAge indicates how long the results of a preflight request can be cached by the client,
+	// it's sent in seconds. Default is 0, the "Access-Control-Max-Age" header is not sent.
+	MaxAge time.Duration
+}
+
+// DefaultConfig returns the default options of the cors middleware,
+// no origins are allowed.
+func DefaultConfig() Config {
+	return Config{
+		AllowedMethods: []string{http.MethodGet, http.MethodPost, http.MethodHead},
+		AllowedHeaders: []string{"Origin", "Accept", "Content-Type", "X-Requested-With"},
+	}
+}
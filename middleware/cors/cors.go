@@ -0,0 +1,273 @@
+// Package cors provides cross-origin resource sharing via middleware. See _examples/miscellaneous/cors
+package cors
+
+// test file: ../../_examples/miscellaneous/cors/main_test.go
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/get-ion/ion/context"
+)
+
+const (
+	originHeaderKey            = "Origin"
+	varyHeaderKey              = "Vary"
+	requestMethodHeaderKey     = "Access-Control-Request-Method"
+	requestHeadersHeaderKey    = "Access-Control-Request-Headers"
+	allowOriginHeaderKey       = "Access-Control-Allow-Origin"
+	allowMethodsHeaderKey      = "Access-Control-Allow-Methods"
+	allowHeadersHeaderKey      = "Access-Control-Allow-Headers"
+	allowCredentialsHeaderKey  = "Access-Control-Allow-Credentials"
+	exposeHeadersHeaderKey     = "Access-Control-Expose-Headers"
+	maxAgeHeaderKey            = "Access-Control-Max-Age"
+	wildcard                   = "*"
+	wildcardSubdomainIndicator = "*."
+)
+
+// wildcardOrigin is an allowed origin with a wildcard subdomain,
+// i.e "https://*.example.com" -> {"https://", ".example.com"}.
+type wildcardOrigin struct {
+	prefix string
+	suffix string
+}
+
+func (w wildcardOrigin) match(origin string) bool {
+	return len(origin) > len(w.prefix)+len(w.suffix) &&
+		strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix)
+}
+
+type corsMiddleware struct {
+	config Config
+	// these are filled from the config at the startup.
+	allowAllOrigins   bool
+	origins           map[string]bool
+	wildcardOrigins   []wildcardOrigin
+	methods           map[string]bool
+	allowAllHeaders   bool
+	headers           map[string]bool
+	methodsValue      string
+	headersValue      string
+	exposedValue      string
+	maxAgeValue       string
+	credentialsHeader bool
+}
+
+// New returns a new cors middleware,
+// it answers the preflight requests of the allowed origins with the 204 No Content status code
+// and the forbidden ones with the 403 Forbidden status code,
+// the rest of the requests of the allowed origins continue to the next handler
+// with the "Access-Control-Allow-Origin" header set.
+//
+// The router answers the preflight requests of the paths that have routes on other methods automatically,
+// by running the middleware of those routes, so the cors middleware can be registered as any other middleware,
+// i.e app.Use(cors.New(cors.Config{AllowedOrigins: []string{"https://*.example.com"}})).
+//
+// Receives an optional configuration, the empty AllowedMethods and AllowedHeaders are filled with the defaults.
+func New(cfg ...Config) context.Handler {
+	c := DefaultConfig()
+	if len(cfg) > 0 {
+		c = cfg[0]
+		def := DefaultConfig()
+		if len(c.AllowedMethods) == 0 {
+			c.AllowedMethods = def.AllowedMethods
+		}
+		if len(c.AllowedHeaders) == 0 {
+			c.AllowedHeaders = def.AllowedHeaders
+		}
+	}
+
+	m := &corsMiddleware{config: c}
+	m.init()
+	return m.ServeHTTP
+}
+
+func (m *corsMiddleware) init() {
+	m.origins = make(map[string]bool)
+	for _, origin := range m.config.AllowedOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		if origin == wildcard {
+			m.allowAllOrigins = true
+			continue
+		}
+
+		if idx := strings.Index(origin, wildcardSubdomainIndicator); idx != -1 {
+			m.wildcardOrigins = append(m.wildcardOrigins, wildcardOrigin{
+				prefix: origin[:idx],
+				suffix: origin[idx+1:], // keep the dot.
+			})
+			continue
+		}
+
+		m.origins[origin] = true
+	}
+
+	m.methods = make(map[string]bool)
+	methods := make([]string, 0, len(m.config.AllowedMethods))
+	for _, method := range m.config.AllowedMethods {
+		method = strings.ToUpper(strings.TrimSpace(method))
+		m.methods[method] = true
+		methods = append(methods, method)
+	}
+	m.methodsValue = strings.Join(methods, ", ")
+
+	m.headers = make(map[string]bool)
+	headers := make([]string, 0, len(m.config.AllowedHeaders))
+	for _, header := range m.config.AllowedHeaders {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if header == wildcard {
+			m.allowAllHeaders = true
+			continue
+		}
+		m.headers[header] = true
+		headers = append(headers, header)
+	}
+	m.headersValue = strings.Join(headers, ", ")
+
+	exposed := make([]string, 0, len(m.config.ExposedHeaders))
+	for _, header := range m.config.ExposedHeaders {
+		exposed = append(exposed, http.CanonicalHeaderKey(strings.TrimSpace(header)))
+	}
+	m.exposedValue = strings.Join(exposed, ", ")
+
+	if seconds := int64(m.config.MaxAge.Seconds()); seconds > 0 {
+		m.maxAgeValue = strconv.FormatInt(seconds, 10)
+	}
+}
+
+// ServeHTTP serves the middleware.
+func (m *corsMiddleware) ServeHTTP(ctx context.Context) {
+	origin := ctx.GetHeader(originHeaderKey)
+	if origin == "" {
+		// not a cross-origin request.
+		ctx.Next()
+		return
+	}
+
+	if ctx.Method() == http.MethodOptions && ctx.GetHeader(requestMethodHeaderKey) != "" {
+		m.handlePreflight(ctx, origin)
+		return
+	}
+
+	addVary(ctx, originHeaderKey)
+	if !m.isOriginAllowed(origin) {
+		// the client will block the response.
+		ctx.Next()
+		return
+	}
+
+	m.setAllowOrigin(ctx, origin)
+	if m.exposedValue != "" {
+		ctx.Header(exposeHeadersHeaderKey, m.exposedValue)
+	}
+	ctx.Next()
+}
+
+func (m *corsMiddleware) handlePreflight(ctx context.Context, origin string) {
+	addVary(ctx, originHeaderKey)
+	addVary(ctx, requestMethodHeaderKey)
+	addVary(ctx, requestHeadersHeaderKey)
+
+	method := strings.ToUpper(ctx.GetHeader(requestMethodHeaderKey))
+	requestHeaders := parseHeaderList(ctx.GetHeader(requestHeadersHeaderKey))
+	if !m.isOriginAllowed(origin) || !m.methods[method] || !m.areHeadersAllowed(requestHeaders) {
+		ctx.StatusCode(http.StatusForbidden)
+		ctx.StopExecution()
+		return
+	}
+
+	m.setAllowOrigin(ctx, origin)
+	ctx.Header(allowMethodsHeaderKey, m.methodsValue)
+
+	if m.allowAllHeaders {
+		if len(requestHeaders) > 0 {
+			ctx.Header(allowHeadersHeaderKey, strings.Join(requestHeaders, ", "))
+		}
+	} else if m.headersValue != "" {
+		ctx.Header(allowHeadersHeaderKey, m.headersValue)
+	}
+
+	if m.maxAgeValue != "" {
+		ctx.Header(maxAgeHeaderKey, m.maxAgeValue)
+	}
+
+	ctx.StatusCode(http.StatusNoContent)
+	ctx.StopExecution()
+}
+
+func (m *corsMiddleware) setAllowOrigin(ctx context.Context, origin string) {
+	if m.allowAllOrigins && !m.config.AllowCredentials {
+		ctx.Header(allowOriginHeaderKey, wildcard)
+	} else {
+		// the "*" can't be used with credentials.
+		ctx.Header(allowOriginHeaderKey, origin)
+	}
+
+	if m.config.AllowCredentials {
+		ctx.Header(allowCredentialsHeaderKey, "true")
+	}
+}
+
+func (m *corsMiddleware) isOriginAllowed(origin string) bool {
+	if m.allowAllOrigins {
+		return true
+	}
+
+	lowerOrigin := strings.ToLower(origin)
+	if m.origins[lowerOrigin] {
+		return true
+	}
+
+	for _, w := range m.wildcardOrigins {
+		if w.match(lowerOrigin) {
+			return true
+		}
+	}
+
+	for _, re := range m.config.AllowedOriginRegexps {
+		if re.MatchString(origin) {
+			return true
+		}
+	}
+
+	return m.config.AllowOriginFunc != nil && m.config.AllowOriginFunc(origin)
+}
+
+func (m *corsMiddleware) areHeadersAllowed(headers []string) bool {
+	if m.allowAllHeaders {
+		return true
+	}
+
+	for _, header := range headers {
+		if !m.headers[header] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseHeaderList parses the "Access-Control-Request-Headers" header's value
+// to its canonical header names.
+func parseHeaderList(value string) []string {
+	var headers []string
+	for _, header := range strings.Split(value, ",") {
+		if header = strings.TrimSpace(header); header != "" {
+			headers = append(headers, http.CanonicalHeaderKey(header))
+		}
+	}
+	return headers
+}
+
+// addVary adds a header name to the "Vary" response header, if it's not there already.
+func addVary(ctx context.Context, name string) {
+	h := ctx.ResponseWriter().Header()
+	for _, v := range h[varyHeaderKey] {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s == wildcard || strings.EqualFold(s, name) {
+				return
+			}
+		}
+	}
+	h.Add(varyHeaderKey, name)
+}
@@ -0,0 +1,69 @@
+package csrf
+
+import (
+	"net/http"
+
+	"github.com/get-ion/ion/context"
+)
+
+const (
+	// DefaultCookieName is the default name of the cookie of the client's csrf secret.
+	DefaultCookieName = "_csrf"
+	// DefaultHeaderName is the default request header of the token.
+	DefaultHeaderName = "X-CSRF-Token"
+	// DefaultFieldName is the default form field of the token.
+	DefaultFieldName = "csrf_token"
+)
+
+// Config are the options of the csrf middleware.
+type Config struct {
+	// Secret is the key of the tokens which are generated by the golang.org/x/net/xsrftoken package,
+	// the tokens are valid for 24 hours.
+	// If it's empty then the tokens are verified against the cookie, the double-submit cookie pattern.
+	// Default is empty.
+	Secret string
+	// UserID returns the id of the user that the tokens are issued for, it's used with the Secret.
+	// Default is the random id of the client's cookie.
+	UserID func(ctx context.Context) string
+	// CookieName is the name of the cookie which keeps the client's random secret.
+	// Default is "_csrf".
+	CookieName string
+	// CookiePath is the path of the cookie.
+	// Default is "/".
+	CookiePath string
+	// CookieSecure marks the cookie as secure, set it to true when the app is served over https.
+	// Default is false.
+	CookieSecure bool
+	// CookieMaxAge is the max age of the cookie in seconds, zero means a session cookie.
+	// Default is 0.
+	CookieMaxAge int
+	// HeaderName is the request header which the token is read from, i.e by ajax requests.
+	// Default is "X-CSRF-Token".
+	HeaderName string
+	// FieldName is the form field which the token is read from, if the header is missing,
+	// it's the name of the hidden input of the "csrf_field" template function too.
+	// Default is "csrf_token".
+	FieldName string
+	// ErrorHandler is called when the token of an unsafe request is missing or it's not valid.
+	// Default sends the 403 Forbidden status code, which fires the app's `OnErrorCode` handler.
+	ErrorHandler func(ctx context.Context, err error)
+}
+
+// DefaultConfig returns the default options of the csrf middleware,
+// the double-submit cookie pattern.
+func DefaultConfig() Config {
+	return Config{
+		CookieName:   DefaultCookieName,
+		CookiePath:   "/",
+		HeaderName:   DefaultHeaderName,
+		FieldName:    DefaultFieldName,
+		ErrorHandler: DefaultErrorHandler,
+	}
+}
+
+// DefaultErrorHandler sends the 403 Forbidden status code
+// and stops the execution of the next handlers.
+func DefaultErrorHandler(ctx context.Context, err error) {
+	ctx.StatusCode(http.StatusForbidden)
+	ctx.StopExecution()
+}
@@ -0,0 +1,264 @@
+// Package csrf provides cross-site request forgery protection via middleware. See _examples/miscellaneous/csrf
+package csrf
+
+// test file: ../../_examples/miscellaneous/csrf/main_test.go
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"html"
+	"html/template"
+	"net/http"
+	"reflect"
+
+	"github.com/get-ion/ion"
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/errors"
+	"golang.org/x/net/xsrftoken"
+)
+
+var (
+	// ErrTokenMissing is passed to the ErrorHandler when an unsafe request has no token.
+	ErrTokenMissing = errors.New("csrf: token is missing")
+	// ErrTokenInvalid is passed to the ErrorHandler when the token of an unsafe request is not valid.
+	ErrTokenInvalid = errors.New("csrf: token is invalid")
+)
+
+const (
+	// the keys of the view data, see `RegisterViewFuncs`.
+	tokenViewDataKey = "csrf_token"
+	fieldViewDataKey = "csrf_field"
+	// the keys of the context's values, see `Token` and `TemplateField`.
+	tokenContextKey = "csrf.token"
+	fieldContextKey = "csrf.field"
+	// the size of the client's random secret.
+	secretLength = 32
+)
+
+var b64 = base64.RawURLEncoding
+
+type csrfMiddleware struct {
+	config Config
+	// true if the client's secret cookie is used,
+	// by the double-submit cookie pattern or as the default user id of the xsrf tokens.
+	useCookie bool
+}
+
+// New returns a new csrf middleware which protects the unsafe requests,
+// the requests which are not GET, HEAD, OPTIONS or TRACE, against cross-site request forgery.
+// The unsafe requests should send the token of the `Token` by the header or the form field of the Config,
+// the requests with a missing or an invalid token are passed to the ErrorHandler.
+//
+// The token and a hidden input of the token are added to the view data as "csrf_token" and "csrf_field",
+// see `RegisterViewFuncs` for the template functions.
+//
+// The routes and the parties that have the `Exempt` handler are not protected.
+//
+// Receives an optional configuration, the empty fields are filled with the defaults.
+func New(cfg ...Config) context.Handler {
+	c := DefaultConfig()
+	if len(cfg) > 0 {
+		c = cfg[0]
+		def := DefaultConfig()
+		if c.CookieName == "" {
+			c.CookieName = def.CookieName
+		}
+		if c.CookiePath == "" {
+			c.CookiePath = def.CookiePath
+		}
+		if c.HeaderName == "" {
+			c.HeaderName = def.HeaderName
+		}
+		if c.FieldName == "" {
+			c.FieldName = def.FieldName
+		}
+		if c.ErrorHandler == nil {
+			c.ErrorHandler = def.ErrorHandler
+		}
+	}
+
+	m := &csrfMiddleware{
+		config:    c,
+		useCookie: c.Secret == "" || c.UserID == nil,
+	}
+	return m.ServeHTTP
+}
+
+// Exempt excludes the routes or the parties that it's registered to from the csrf protection,
+// i.e app.Party("/webhooks", csrf.Exempt) or app.Post("/webhook", csrf.Exempt, handler),
+// it's detected in the route's handlers even if the csrf middleware runs before it.
+func Exempt(ctx context.Context) {
+	ctx.Next()
+}
+
+var exemptPointer = reflect.ValueOf(Exempt).Pointer()
+
+func isExempt(ctx context.Context) bool {
+	for _, h := range ctx.Handlers() {
+		if reflect.ValueOf(h).Pointer() == exemptPointer {
+			return true
+		}
+	}
+	return false
+}
+
+// ServeHTTP serves the middleware.
+func (m *csrfMiddleware) ServeHTTP(ctx context.Context) {
+	if isExempt(ctx) {
+		ctx.Next()
+		return
+	}
+
+	var (
+		secret []byte
+		isNew  bool
+	)
+	if m.useCookie {
+		secret, isNew = m.clientSecret(ctx)
+	}
+
+	token := m.generate(ctx, secret)
+	field := template.HTML(`<input type="hidden" name="` + html.EscapeString(m.config.FieldName) +
+		`" value="` + token + `">`)
+
+	ctx.Values().Set(tokenContextKey, token)
+	ctx.Values().Set(fieldContextKey, field)
+	ctx.ViewData(tokenViewDataKey, token)
+	ctx.ViewData(fieldViewDataKey, field)
+
+	switch ctx.Method() {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		ctx.Next()
+		return
+	}
+
+	requestToken := ctx.GetHeader(m.config.HeaderName)
+	if requestToken == "" {
+		requestToken = ctx.FormValue(m.config.FieldName)
+	}
+
+	if requestToken == "" {
+		m.config.ErrorHandler(ctx, ErrTokenMissing)
+		return
+	}
+
+	if isNew || !m.valid(ctx, secret, requestToken) {
+		// a new secret means that the token was not issued for this client.
+		m.config.ErrorHandler(ctx, ErrTokenInvalid)
+		return
+	}
+
+	ctx.Next()
+}
+
+// clientSecret returns the random secret of the client's cookie,
+// it creates a new one if the cookie is missing or invalid.
+func (m *csrfMiddleware) clientSecret(ctx context.Context) ([]byte, bool) {
+	if secret, err := b64.DecodeString(ctx.GetCookie(m.config.CookieName)); err == nil && len(secret) == secretLength {
+		return secret, false
+	}
+
+	secret := make([]byte, secretLength)
+	rand.Read(secret)
+
+	ctx.SetCookie(&http.Cookie{
+		Name:     m.config.CookieName,
+		Value:    b64.EncodeToString(secret),
+		Path:     m.config.CookiePath,
+		MaxAge:   m.config.CookieMaxAge,
+		Secure:   m.config.CookieSecure,
+		HttpOnly: true,
+	})
+	return secret, true
+}
+
+func (m *csrfMiddleware) userID(ctx context.Context, secret []byte) string {
+	if m.config.UserID != nil {
+		return m.config.UserID(ctx)
+	}
+	return b64.EncodeToString(secret)
+}
+
+// generate returns a new token, a xsrf token if the Secret is set,
+// otherwise the client's secret masked by a random one time pad,
+// so the token is different on each response.
+func (m *csrfMiddleware) generate(ctx context.Context, secret []byte) string {
+	if m.config.Secret != "" {
+		return xsrftoken.Generate(m.config.Secret, m.userID(ctx, secret), "")
+	}
+
+	token := make([]byte, 2*secretLength)
+	pad, masked := token[:secretLength], token[secretLength:]
+	rand.Read(pad)
+	for i := range secret {
+		masked[i] = pad[i] ^ secret[i]
+	}
+	return b64.EncodeToString(token)
+}
+
+func (m *csrfMiddleware) valid(ctx context.Context, secret []byte, token string) bool {
+	if m.config.Secret != "" {
+		return xsrftoken.Valid(token, m.config.Secret, m.userID(ctx, secret), "")
+	}
+
+	b, err := b64.DecodeString(token)
+	if err != nil || len(b) != 2*secretLength {
+		return false
+	}
+
+	pad, masked := b[:secretLength], b[secretLength:]
+	unmasked := make([]byte, secretLength)
+	for i := range unmasked {
+		unmasked[i] = pad[i] ^ masked[i]
+	}
+	return subtle.ConstantTimeCompare(unmasked, secret) == 1
+}
+
+// Token returns the csrf token of the request,
+// it's empty if the request is not served by the csrf middleware.
+func Token(ctx context.Context) string {
+	return ctx.Values().GetString(tokenContextKey)
+}
+
+// TemplateField returns a hidden input of the csrf token of the request, for the forms.
+func TemplateField(ctx context.Context) template.HTML {
+	field, _ := ctx.Values().Get(fieldContextKey).(template.HTML)
+	return field
+}
+
+// RegisterViewFuncs registers the "csrf_token" and "csrf_field" template functions
+// to all the view engines of the "app", see `Application#AddViewFunc`.
+// They receive the view data, see `Context#ViewData`, and they return the token
+// and the hidden input of the token:
+// html: <form method="POST">{{ csrf_field . }}</form>
+// handlebars: <form method="POST">{{{csrf_field this}}}</form>
+// The engines without access to the view data, i.e the django, can use the view data directly:
+// django: <form method="POST">{{ csrf_field|safe }}</form>
+//
+// It should be called before the `Application#Build` or `Application#Run`.
+func RegisterViewFuncs(app *ion.Application) {
+	app.AddViewFunc(tokenViewDataKey, func(data interface{}) string {
+		token, _ := viewDataValue(data, tokenViewDataKey).(string)
+		return token
+	})
+
+	app.AddViewFunc(fieldViewDataKey, func(data interface{}) template.HTML {
+		field, _ := viewDataValue(data, fieldViewDataKey).(template.HTML)
+		return field
+	})
+}
+
+// viewDataValue returns the value of the "key" of a view data map, of any map type.
+func viewDataValue(data interface{}, key string) interface{} {
+	v := reflect.ValueOf(data)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return nil
+	}
+
+	value := v.MapIndex(reflect.ValueOf(key).Convert(v.Type().Key()))
+	if !value.IsValid() {
+		return nil
+	}
+	return value.Interface()
+}
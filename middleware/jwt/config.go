@@ -0,0 +1,81 @@
+package jwt
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/errors"
+)
+
+// DefaultContextKey is the default key of the verified claims in the `Context#Values`.
+const DefaultContextKey = "jwt"
+
+// KeyResolver returns the verification key of a token by its key id, the "kid" header,
+// and its algorithm, i.e the `JWKS#Key`.
+type KeyResolver func(kid string, alg string) (interface{}, error)
+
+// Config are the options of the jwt middleware.
+type Config struct {
+	// Key is the verification key of the tokens, if the KeyResolver is nil:
+	// a []byte secret for the HS256,
+	// an *rsa.PublicKey for the RS256,
+	// an *ecdsa.PublicKey of the P-256 curve for the ES256,
+	// an ed25519.PublicKey for the EdDSA.
+	// The private keys are accepted too.
+	Key interface{}
+	// KeyResolver resolves the verification key of a token by its "kid" header,
+	// it's used for the key rotation, see `LoadJWKS`.
+	KeyResolver KeyResolver
+	// Algorithms are the accepted algorithms of the tokens,
+	// the algorithm of a token should match the type of its key too.
+	// Default is all the supported algorithms: HS256, RS256, ES256 and EdDSA.
+	Algorithms []string
+	// Extractors extract the token from the request, the first non-empty token is verified,
+	// see `FromAuthHeader`, `FromCookie` and `FromQuery`.
+	// Default is the `FromAuthHeader`.
+	Extractors []TokenExtractor
+	// Issuer is the expected "iss" claim, if not empty.
+	Issuer string
+	// Audience is the expected value of the "aud" claim, if not empty.
+	Audience string
+	// ClockSkew is the allowed difference between the server's clock
+	// and the issuer's clock, for the "exp" and "nbf" claims.
+	// Default is 0.
+	ClockSkew time.Duration
+	// ContextKey is the key of the verified claims in the `Context#Values`, see `Get`.
+	// Default is "jwt".
+	ContextKey string
+	// CredentialsOptional lets the requests without a token continue to the next handler,
+	// the requests with an invalid token are rejected.
+	// Default is false.
+	CredentialsOptional bool
+	// ErrorHandler is called when the token is missing or it's not valid.
+	// Default sends the 401 Unauthorized status code, which fires the app's `OnErrorCode` handler.
+	ErrorHandler func(ctx context.Context, err error)
+}
+
+// DefaultConfig returns the default options of the jwt middleware,
+// the Key or the KeyResolver should be set.
+func DefaultConfig() Config {
+	return Config{
+		Algorithms:   []string{HS256, RS256, ES256, EdDSA},
+		Extractors:   []TokenExtractor{FromAuthHeader},
+		ContextKey:   DefaultContextKey,
+		ErrorHandler: DefaultErrorHandler,
+	}
+}
+
+// DefaultErrorHandler sends the 401 Unauthorized status code
+// with the "WWW-Authenticate" header and stops the execution of the next handlers.
+func DefaultErrorHandler(ctx context.Context, err error) {
+	challenge := `Bearer error="invalid_token"`
+	if e, ok := err.(errors.Error); ok && ErrTokenMissing.Equal(e) {
+		// see https://tools.ietf.org/html/rfc6750#section-3.1.
+		challenge = "Bearer"
+	}
+
+	ctx.Header("WWW-Authenticate", challenge)
+	ctx.StatusCode(http.StatusUnauthorized)
+	ctx.StopExecution()
+}
@@ -0,0 +1,212 @@
+package jwt
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+	"golang.org/x/crypto/ed25519"
+)
+
+var (
+	errJWKSRead = errors.New("jwt: read the key set '%s': %v")
+	errJWK      = errors.New("jwt: invalid key '%s': %v")
+	errJWKType  = errors.New("unsupported key type '%s'")
+	errJWKCurve = errors.New("unsupported curve '%s'")
+	errJWKSize  = errors.New("invalid Ed25519 public key size %d")
+)
+
+// jwk is a JSON Web Key of a key set, see https://tools.ietf.org/html/rfc7517.
+type jwk struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Algorithm string `json:"alg"`
+	// the RSA public key.
+	N string `json:"n"`
+	E string `json:"e"`
+	// the EC and OKP public keys.
+	Curve string `json:"crv"`
+	X     string `json:"x"`
+	Y     string `json:"y"`
+	// the symmetric key.
+	K string `json:"k"`
+}
+
+// JWKS is a JSON Web Key Set which is loaded from a local file,
+// its keys are resolved by their key id, the "kid" header of the tokens.
+//
+// The file is reloaded when a token has an unknown key id and the file has been modified,
+// so the keys can be rotated without a restart, add the new key to the file
+// before signing tokens with it.
+type JWKS struct {
+	filename string
+
+	mu      sync.RWMutex
+	keys    map[string]jwksKey
+	modTime time.Time
+}
+
+// jwksKey is a parsed key of a key set.
+type jwksKey struct {
+	key interface{}
+	// the "alg" of the jwk, if not empty the tokens of the key should use this algorithm.
+	alg string
+}
+
+// LoadJWKS loads the key set from the "filename",
+// the file is a json object with a "keys" array, see https://tools.ietf.org/html/rfc7517#section-5.
+// The RSA, the EC of the P-256 curve, the OKP of the Ed25519 curve and the symmetric (oct) keys are supported.
+//
+// Usage:
+// keys, err := jwt.LoadJWKS("./jwks.json")
+// app.Use(jwt.New(jwt.Config{KeyResolver: keys.Key}))
+func LoadJWKS(filename string) (*JWKS, error) {
+	s := &JWKS{filename: filename}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Reload reads the key set's file again.
+func (s *JWKS) Reload() error {
+	info, err := os.Stat(s.filename)
+	if err != nil {
+		return errJWKSRead.Format(s.filename, err)
+	}
+
+	b, err := ioutil.ReadFile(s.filename)
+	if err != nil {
+		return errJWKSRead.Format(s.filename, err)
+	}
+
+	keys, err := parseJWKS(b)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.modTime = info.ModTime()
+	s.mu.Unlock()
+	return nil
+}
+
+// Key returns the key with the id "kid", it's a `KeyResolver`.
+// If the key has an "alg" then the "alg" of the token should match it.
+func (s *JWKS) Key(kid string, alg string) (interface{}, error) {
+	if key, ok := s.lookup(kid); ok {
+		return key.check(alg)
+	}
+
+	// maybe a new key, reload if the file has been modified.
+	if info, err := os.Stat(s.filename); err == nil {
+		s.mu.RLock()
+		modified := info.ModTime().After(s.modTime)
+		s.mu.RUnlock()
+
+		if modified {
+			if err = s.Reload(); err != nil {
+				return nil, err
+			}
+			if key, ok := s.lookup(kid); ok {
+				return key.check(alg)
+			}
+		}
+	}
+
+	return nil, ErrKeyNotFound.Format(kid)
+}
+
+func (s *JWKS) lookup(kid string) (jwksKey, bool) {
+	s.mu.RLock()
+	key, ok := s.keys[kid]
+	s.mu.RUnlock()
+	return key, ok
+}
+
+func (k jwksKey) check(alg string) (interface{}, error) {
+	if k.alg != "" && k.alg != alg {
+		return nil, ErrTokenAlgorithm.Format(alg)
+	}
+	return k.key, nil
+}
+
+// parseJWKS parses a JSON Web Key Set, it returns the keys by their id.
+func parseJWKS(b []byte) (map[string]jwksKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(b, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]jwksKey, len(set.Keys))
+	for _, k := range set.Keys {
+		key, err := k.key()
+		if err != nil {
+			return nil, errJWK.Format(k.KeyID, err)
+		}
+		keys[k.KeyID] = jwksKey{key: key, alg: k.Algorithm}
+	}
+	return keys, nil
+}
+
+func (k jwk) key() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		if k.Curve != "P-256" {
+			return nil, errJWKCurve.Format(k.Curve)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, errJWKCurve.Format(k.Curve)
+		}
+		x, err := b64.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errJWKSize.Format(len(x))
+		}
+		return ed25519.PublicKey(x), nil
+	case "oct":
+		return b64.DecodeString(k.K)
+	default:
+		return nil, errJWKType.Format(k.KeyType)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := b64.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
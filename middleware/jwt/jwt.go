@@ -0,0 +1,95 @@
+// Package jwt provides json web token authentication via middleware. See _examples/authentication/jwt
+package jwt
+
+// test file: ../../_examples/authentication/jwt/main_test.go
+
+import (
+	"strings"
+
+	"github.com/get-ion/ion/context"
+)
+
+// TokenExtractor extracts the token from a request, it returns an empty string if the request has no token.
+type TokenExtractor func(ctx context.Context) string
+
+// FromAuthHeader extracts the token from the "Authorization: Bearer <token>" header.
+func FromAuthHeader(ctx context.Context) string {
+	authHeader := ctx.GetHeader("Authorization")
+	if len(authHeader) > 7 && strings.EqualFold(authHeader[:7], "Bearer ") {
+		return strings.TrimSpace(authHeader[7:])
+	}
+	return ""
+}
+
+// FromCookie returns a `TokenExtractor` which extracts the token from the cookie with the "name".
+func FromCookie(name string) TokenExtractor {
+	return func(ctx context.Context) string {
+		return ctx.GetCookie(name)
+	}
+}
+
+// FromQuery returns a `TokenExtractor` which extracts the token from the url query parameter with the "name".
+func FromQuery(name string) TokenExtractor {
+	return func(ctx context.Context) string {
+		return ctx.URLParam(name)
+	}
+}
+
+type jwtMiddleware struct {
+	verifier *Verifier
+}
+
+// New returns a new jwt middleware which verifies the token of the requests,
+// the verified claims are stored to the `Context#Values`, see `Get`.
+// The requests without a token or with an invalid token are passed to the ErrorHandler.
+//
+// The empty fields of the "c" are filled with the defaults, the Key or the KeyResolver should be set.
+//
+// Usage:
+// app.Use(jwt.New(jwt.Config{Key: secret, Algorithms: []string{jwt.HS256}}))
+func New(c Config) context.Handler {
+	m := &jwtMiddleware{verifier: NewVerifier(c)}
+	return m.ServeHTTP
+}
+
+// ServeHTTP serves the middleware.
+func (m *jwtMiddleware) ServeHTTP(ctx context.Context) {
+	config := m.verifier.config
+
+	var token string
+	for _, extract := range config.Extractors {
+		if token = extract(ctx); token != "" {
+			break
+		}
+	}
+
+	if token == "" {
+		if config.CredentialsOptional {
+			ctx.Next()
+			return
+		}
+		config.ErrorHandler(ctx, ErrTokenMissing)
+		return
+	}
+
+	claims, err := m.verifier.Verify(token)
+	if err != nil {
+		config.ErrorHandler(ctx, err)
+		return
+	}
+
+	ctx.Values().Set(config.ContextKey, claims)
+	ctx.Next()
+}
+
+// Get returns the verified claims of the request which are stored by the middleware
+// with the `DefaultContextKey`, it returns nil if the request has no verified token.
+func Get(ctx context.Context) Claims {
+	return GetByKey(ctx, DefaultContextKey)
+}
+
+// GetByKey same as `Get` but for a custom `Config#ContextKey`.
+func GetByKey(ctx context.Context, key string) Claims {
+	claims, _ := ctx.Values().Get(key).(Claims)
+	return claims
+}
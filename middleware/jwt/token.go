@@ -0,0 +1,407 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+	"golang.org/x/crypto/ed25519"
+)
+
+// The supported algorithms.
+const (
+	// HS256 is the HMAC with SHA-256.
+	HS256 = "HS256"
+	// RS256 is the RSASSA-PKCS1-v1_5 with SHA-256.
+	RS256 = "RS256"
+	// ES256 is the ECDSA with the P-256 curve and SHA-256.
+	ES256 = "ES256"
+	// EdDSA is the Ed25519 signature.
+	EdDSA = "EdDSA"
+)
+
+var (
+	// ErrTokenMissing is returned when the request has no token.
+	ErrTokenMissing = errors.New("jwt: token is missing")
+	// ErrTokenMalformed is returned when the token is not a valid jwt.
+	ErrTokenMalformed = errors.New("jwt: token is malformed")
+	// ErrTokenAlgorithm is returned when the algorithm of the token is not accepted.
+	ErrTokenAlgorithm = errors.New("jwt: algorithm '%s' is not accepted")
+	// ErrTokenSignature is returned when the signature of the token is not valid.
+	ErrTokenSignature = errors.New("jwt: signature is invalid")
+	// ErrTokenExpired is returned when the "exp" claim is in the past.
+	ErrTokenExpired = errors.New("jwt: token is expired")
+	// ErrTokenNotValidYet is returned when the "nbf" claim is in the future.
+	ErrTokenNotValidYet = errors.New("jwt: token is not valid yet")
+	// ErrTokenIssuer is returned when the "iss" claim is not the expected one.
+	ErrTokenIssuer = errors.New("jwt: issuer '%s' is not accepted")
+	// ErrTokenAudience is returned when the "aud" claim does not contain the expected audience.
+	ErrTokenAudience = errors.New("jwt: audience is not accepted")
+	// ErrKeyType is returned when the type of a key does not match the algorithm.
+	ErrKeyType = errors.New("jwt: key of type %T can't be used with the '%s' algorithm")
+	// ErrKeyNotFound is returned when the key id of a token is unknown.
+	ErrKeyNotFound = errors.New("jwt: key '%s' not found")
+)
+
+var b64 = base64.RawURLEncoding
+
+// Header is the header of a token.
+type Header struct {
+	Algorithm string `json:"alg"`
+	Type      string `json:"typ,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+}
+
+// Claims are the claims of a token, the payload.
+type Claims map[string]interface{}
+
+// NewClaims returns the claims of a new token of the "subject"
+// which is issued now and it expires after the "maxAge".
+func NewClaims(subject string, maxAge time.Duration) Claims {
+	now := time.Now()
+	return Claims{
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(maxAge).Unix(),
+	}
+}
+
+// Subject returns the "sub" claim.
+func (c Claims) Subject() string {
+	return c.String("sub")
+}
+
+// Issuer returns the "iss" claim.
+func (c Claims) Issuer() string {
+	return c.String("iss")
+}
+
+// Audience returns the "aud" claim, a string or an array of strings.
+func (c Claims) Audience() []string {
+	switch v := c["aud"].(type) {
+	case string:
+		return []string{v}
+	case []string:
+		return v
+	case []interface{}:
+		aud := make([]string, 0, len(v))
+		for _, s := range v {
+			if s, ok := s.(string); ok {
+				aud = append(aud, s)
+			}
+		}
+		return aud
+	}
+	return nil
+}
+
+// ExpiresAt returns the "exp" claim, false if it's missing.
+func (c Claims) ExpiresAt() (time.Time, bool) {
+	return c.Time("exp")
+}
+
+// NotBefore returns the "nbf" claim, false if it's missing.
+func (c Claims) NotBefore() (time.Time, bool) {
+	return c.Time("nbf")
+}
+
+// String returns the string value of the claim, empty if it's missing or it's not a string.
+func (c Claims) String(name string) string {
+	s, _ := c[name].(string)
+	return s
+}
+
+// Time returns the time of a numeric date claim, i.e "exp", false if it's missing or it's not a number.
+func (c Claims) Time(name string) (time.Time, bool) {
+	var seconds float64
+	switch v := c[name].(type) {
+	case float64:
+		seconds = v
+	case int64:
+		seconds = float64(v)
+	case int:
+		seconds = float64(v)
+	default:
+		return time.Time{}, false
+	}
+
+	sec, frac := splitSeconds(seconds)
+	return time.Unix(sec, frac), true
+}
+
+func splitSeconds(seconds float64) (int64, int64) {
+	sec := int64(seconds)
+	return sec, int64((seconds - float64(sec)) * float64(time.Second))
+}
+
+// Sign returns a new token of the "claims" which is signed with the "key"
+// by the "alg" algorithm:
+// a []byte secret for the HS256,
+// an *rsa.PrivateKey for the RS256,
+// an *ecdsa.PrivateKey of the P-256 curve for the ES256,
+// an ed25519.PrivateKey for the EdDSA.
+func Sign(alg string, key interface{}, claims Claims) (string, error) {
+	return SignWithKeyID(alg, "", key, claims)
+}
+
+// SignWithKeyID same as `Sign` but it sets the "kid" header of the token,
+// the id of the key which verifies it, see `KeyResolver`.
+func SignWithKeyID(alg string, kid string, key interface{}, claims Claims) (string, error) {
+	header, err := json.Marshal(Header{Algorithm: alg, Type: "JWT", KeyID: kid})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := b64.EncodeToString(header) + "." + b64.EncodeToString(payload)
+	signature, err := sign(alg, key, []byte(signingInput))
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + b64.EncodeToString(signature), nil
+}
+
+func sign(alg string, key interface{}, signingInput []byte) ([]byte, error) {
+	digest := sha256.Sum256(signingInput)
+
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return nil, ErrKeyType.Format(key, alg)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signingInput)
+		return mac.Sum(nil), nil
+	case RS256:
+		privateKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, ErrKeyType.Format(key, alg)
+		}
+		return rsa.SignPKCS1v15(rand.Reader, privateKey, crypto.SHA256, digest[:])
+	case ES256:
+		privateKey, ok := key.(*ecdsa.PrivateKey)
+		if !ok || privateKey.Curve != elliptic.P256() {
+			return nil, ErrKeyType.Format(key, alg)
+		}
+		r, s, err := ecdsa.Sign(rand.Reader, privateKey, digest[:])
+		if err != nil {
+			return nil, err
+		}
+		// the signature is the r and s, 32 bytes each.
+		signature := make([]byte, 64)
+		rBytes, sBytes := r.Bytes(), s.Bytes()
+		copy(signature[32-len(rBytes):32], rBytes)
+		copy(signature[64-len(sBytes):], sBytes)
+		return signature, nil
+	case EdDSA:
+		privateKey, ok := key.(ed25519.PrivateKey)
+		if !ok || len(privateKey) != ed25519.PrivateKeySize {
+			return nil, ErrKeyType.Format(key, alg)
+		}
+		return ed25519.Sign(privateKey, signingInput), nil
+	default:
+		return nil, ErrTokenAlgorithm.Format(alg)
+	}
+}
+
+// verifySignature verifies the "signature" of the "signingInput" with the "key" by the "alg" algorithm,
+// the public key of a private key is used.
+func verifySignature(alg string, key interface{}, signingInput, signature []byte) error {
+	digest := sha256.Sum256(signingInput)
+
+	switch alg {
+	case HS256:
+		secret, ok := key.([]byte)
+		if !ok {
+			return ErrKeyType.Format(key, alg)
+		}
+		mac := hmac.New(sha256.New, secret)
+		mac.Write(signingInput)
+		if !hmac.Equal(signature, mac.Sum(nil)) {
+			return ErrTokenSignature
+		}
+	case RS256:
+		var publicKey *rsa.PublicKey
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			publicKey = k
+		case *rsa.PrivateKey:
+			publicKey = &k.PublicKey
+		default:
+			return ErrKeyType.Format(key, alg)
+		}
+		if rsa.VerifyPKCS1v15(publicKey, crypto.SHA256, digest[:], signature) != nil {
+			return ErrTokenSignature
+		}
+	case ES256:
+		var publicKey *ecdsa.PublicKey
+		switch k := key.(type) {
+		case *ecdsa.PublicKey:
+			publicKey = k
+		case *ecdsa.PrivateKey:
+			publicKey = &k.PublicKey
+		default:
+			return ErrKeyType.Format(key, alg)
+		}
+		if publicKey.Curve != elliptic.P256() {
+			return ErrKeyType.Format(key, alg)
+		}
+		if len(signature) != 64 {
+			return ErrTokenSignature
+		}
+		r := new(big.Int).SetBytes(signature[:32])
+		s := new(big.Int).SetBytes(signature[32:])
+		if !ecdsa.Verify(publicKey, digest[:], r, s) {
+			return ErrTokenSignature
+		}
+	case EdDSA:
+		var publicKey ed25519.PublicKey
+		switch k := key.(type) {
+		case ed25519.PublicKey:
+			publicKey = k
+		case ed25519.PrivateKey:
+			publicKey = k.Public().(ed25519.PublicKey)
+		default:
+			return ErrKeyType.Format(key, alg)
+		}
+		if len(publicKey) != ed25519.PublicKeySize {
+			return ErrKeyType.Format(key, alg)
+		}
+		if !ed25519.Verify(publicKey, signingInput, signature) {
+			return ErrTokenSignature
+		}
+	default:
+		return ErrTokenAlgorithm.Format(alg)
+	}
+
+	return nil
+}
+
+// Verifier verifies the tokens and their claims,
+// it's used by the middleware and it can be used to verify tokens outside of a request too.
+type Verifier struct {
+	config Config
+}
+
+// NewVerifier returns a new `Verifier`, the empty fields of the "c" are filled with the defaults.
+func NewVerifier(c Config) *Verifier {
+	def := DefaultConfig()
+	if len(c.Algorithms) == 0 {
+		c.Algorithms = def.Algorithms
+	}
+	if len(c.Extractors) == 0 {
+		c.Extractors = def.Extractors
+	}
+	if c.ContextKey == "" {
+		c.ContextKey = def.ContextKey
+	}
+	if c.ErrorHandler == nil {
+		c.ErrorHandler = def.ErrorHandler
+	}
+	return &Verifier{config: c}
+}
+
+// Verify verifies the signature of the "token" and its "exp", "nbf", "iss" and "aud" claims,
+// it returns the token's claims.
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenMalformed
+	}
+
+	var header Header
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if !v.acceptsAlgorithm(header.Algorithm) {
+		return nil, ErrTokenAlgorithm.Format(header.Algorithm)
+	}
+
+	signature, err := b64.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrTokenMalformed
+	}
+
+	key := v.config.Key
+	if v.config.KeyResolver != nil {
+		if key, err = v.config.KeyResolver(header.KeyID, header.Algorithm); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = verifySignature(header.Algorithm, key, []byte(parts[0]+"."+parts[1]), signature); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err = decodeSegment(parts[1], &claims); err != nil || claims == nil {
+		return nil, ErrTokenMalformed
+	}
+
+	if err = v.validate(claims, time.Now()); err != nil {
+		return nil, err
+	}
+
+	return claims, nil
+}
+
+func (v *Verifier) acceptsAlgorithm(alg string) bool {
+	for _, a := range v.config.Algorithms {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *Verifier) validate(claims Claims, now time.Time) error {
+	skew := v.config.ClockSkew
+
+	if exp, ok := claims.ExpiresAt(); ok && !now.Add(-skew).Before(exp) {
+		return ErrTokenExpired
+	}
+
+	if nbf, ok := claims.NotBefore(); ok && now.Add(skew).Before(nbf) {
+		return ErrTokenNotValidYet
+	}
+
+	if expected := v.config.Issuer; expected != "" && claims.Issuer() != expected {
+		return ErrTokenIssuer.Format(claims.Issuer())
+	}
+
+	if expected := v.config.Audience; expected != "" {
+		for _, aud := range claims.Audience() {
+			if aud == expected {
+				return nil
+			}
+		}
+		return ErrTokenAudience
+	}
+
+	return nil
+}
+
+func decodeSegment(segment string, v interface{}) error {
+	b, err := b64.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -45,12 +45,18 @@ func (l *requestLoggerMiddleware) ServeHTTP(ctx context.Context) {
 	}
 
 	//finally print the logs, no new line, the framework's logger is responsible how to render each log.
+	if id := ctx.RequestID(); id != "" {
+		// the request id of the trace middleware, if any.
+		ctx.Application().Logger().Infof("%v %4v %s %s %s %s", status, latency, ip, method, path, id)
+		return
+	}
 	ctx.Application().Logger().Infof("%v %4v %s %s %s", status, latency, ip, method, path)
 }
 
 // New creates and returns a new request logger middleware.
 // Do not confuse it with the framework's Logger.
 // This is for the http requests.
+// See the accesslog middleware for the Common, Combined, JSON and custom formats.
 //
 // Receives an optional configuation.
 func New(cfg ...Config) context.Handler {
@@ -0,0 +1,19 @@
+package metrics
+
+// DefaultBuckets are the default upper bounds of the latency histogram's buckets, in seconds.
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Config are the options of the metrics middleware.
+type Config struct {
+	// Registry is the registry which the request metrics are recorded to,
+	// expose it with the `Handler`.
+	// Default is the `DefaultRegistry`.
+	Registry *Registry
+}
+
+// DefaultConfig returns the default options of the metrics middleware.
+func DefaultConfig() Config {
+	return Config{
+		Registry: DefaultRegistry,
+	}
+}
@@ -0,0 +1,71 @@
+// Package metrics provides prometheus metrics of the requests and of the hosts via middleware. See _examples/miscellaneous/metrics
+package metrics
+
+// test file: ../../_examples/miscellaneous/metrics/main_test.go
+
+import (
+	"time"
+
+	"github.com/get-ion/ion/context"
+)
+
+// ContentType is the content type of the Prometheus text exposition format.
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+type metricsMiddleware struct {
+	config Config
+}
+
+// New returns a new metrics middleware which records the number, the latency
+// and the in-flight requests per route name, method and status code to the Registry of the Config.
+// Register it with the `Application#UseGlobal` and expose the metrics with the `Handler`.
+//
+// The route name is the `Route#Name`, the method and the path by default,
+// so the metrics don't grow with the different values of the path parameters.
+//
+// Receives an optional configuration, the empty fields are filled with the defaults.
+func New(cfg ...Config) context.Handler {
+	c := DefaultConfig()
+	if len(cfg) > 0 {
+		c = cfg[0]
+		if c.Registry == nil {
+			c.Registry = DefaultRegistry
+		}
+	}
+
+	m := &metricsMiddleware{config: c}
+	return m.ServeHTTP
+}
+
+// ServeHTTP serves the middleware.
+func (m *metricsMiddleware) ServeHTTP(ctx context.Context) {
+	route, method := ctx.GetCurrentRouteName(), ctx.Method()
+	registry := m.config.Registry
+
+	registry.addInFlight(route, method, 1)
+	// decremented even if a handler panics.
+	defer registry.addInFlight(route, method, -1)
+	start := time.Now()
+
+	ctx.Next()
+
+	registry.observe(route, method, ctx.GetStatusCode(), time.Since(start).Seconds())
+}
+
+// Handler returns a handler which writes the metrics of the "registry",
+// or of the `DefaultRegistry` if it's missing, in the Prometheus text exposition format.
+//
+// Usage:
+// app.Get("/metrics", metrics.Handler())
+func Handler(registry ...*Registry) context.Handler {
+	r := DefaultRegistry
+	if len(registry) > 0 && registry[0] != nil {
+		r = registry[0]
+	}
+
+	return func(ctx context.Context) {
+		// not the ctx.ContentType, the version is not a file extension.
+		ctx.Header("Content-Type", ContentType)
+		r.WriteTo(ctx)
+	}
+}
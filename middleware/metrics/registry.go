@@ -0,0 +1,279 @@
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/get-ion/ion/core/host"
+)
+
+// the labels of the request metrics, the route name keeps the cardinality low,
+// unlike the raw request path.
+type requestLabels struct {
+	route  string
+	method string
+	code   string
+}
+
+type inFlightLabels struct {
+	route  string
+	method string
+}
+
+type requestMetrics struct {
+	count uint64
+	sum   float64
+	// the non-cumulative counts of the buckets, the last one is the +Inf.
+	buckets []uint64
+}
+
+// Registry keeps the metrics of the requests and of the registered hosts.
+type Registry struct {
+	namespace string
+	buckets   []float64
+
+	mu       sync.Mutex
+	requests map[requestLabels]*requestMetrics
+	inFlight map[inFlightLabels]int64
+	hosts    []*host.Supervisor
+}
+
+// DefaultRegistry is the default registry of the metrics middleware and of the `Handler`,
+// its metric names are prefixed with "ion_".
+var DefaultRegistry = NewRegistry("ion", DefaultBuckets)
+
+// NewRegistry returns a new registry, the "namespace" is the prefix of its metric names
+// and the "buckets" are the upper bounds of the latency histogram's buckets in seconds,
+// if they're empty the `DefaultBuckets` are used.
+func NewRegistry(namespace string, buckets []float64) *Registry {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+
+	return &Registry{
+		namespace: namespace,
+		buckets:   buckets,
+		requests:  make(map[requestLabels]*requestMetrics),
+		inFlight:  make(map[inFlightLabels]int64),
+	}
+}
+
+func (r *Registry) addInFlight(route, method string, n int64) {
+	r.mu.Lock()
+	r.inFlight[inFlightLabels{route: route, method: method}] += n
+	r.mu.Unlock()
+}
+
+func (r *Registry) observe(route, method string, statusCode int, seconds float64) {
+	labels := requestLabels{route: route, method: method, code: strconv.Itoa(statusCode)}
+
+	r.mu.Lock()
+	m, ok := r.requests[labels]
+	if !ok {
+		m = &requestMetrics{buckets: make([]uint64, len(r.buckets)+1)}
+		r.requests[labels] = m
+	}
+
+	m.count++
+	m.sum += seconds
+	m.buckets[sort.SearchFloat64s(r.buckets, seconds)]++
+	r.mu.Unlock()
+}
+
+// RegisterHost registers a host supervisor, its connections, tls handshakes and tasks are reported,
+// see `host.Supervisor#Stats` and `host.Scheduler#TaskStates`.
+//
+// Usage:
+// su := app.NewHost(&http.Server{Addr: ":8080"})
+// metrics.DefaultRegistry.RegisterHost(su)
+// su.ListenAndServe()
+//
+// See `HostTask` too.
+func (r *Registry) RegisterHost(su *host.Supervisor) {
+	r.mu.Lock()
+	r.hosts = append(r.hosts, su)
+	r.mu.Unlock()
+}
+
+// HostTask returns a task which registers the host supervisors that it runs on,
+// i.e app.Scheduler.Schedule(metrics.DefaultRegistry.HostTask()) before the app.Run.
+func (r *Registry) HostTask() host.TaskRunner {
+	return host.TaskRunnerFunc(func(proc host.TaskProcess) {
+		r.RegisterHost(proc.Host().Supervisor())
+	})
+}
+
+// labelValueReplacer escapes the label values of the text exposition format.
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func writeHeader(buf *bytes.Buffer, name, typ, help string) {
+	buf.WriteString("# HELP " + name + " " + help + "\n")
+	buf.WriteString("# TYPE " + name + " " + typ + "\n")
+}
+
+// writeSample writes a sample line, the "labels" are name and value pairs.
+func writeSample(buf *bytes.Buffer, name string, value string, labels ...string) {
+	buf.WriteString(name)
+	if len(labels) > 0 {
+		buf.WriteByte('{')
+		for i := 0; i < len(labels); i += 2 {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			buf.WriteString(labels[i] + `="` + labelValueReplacer.Replace(labels[i+1]) + `"`)
+		}
+		buf.WriteByte('}')
+	}
+	buf.WriteString(" " + value + "\n")
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+func formatUint(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
+
+func (r *Registry) name(name string) string {
+	if r.namespace == "" {
+		return name
+	}
+	return r.namespace + "_" + name
+}
+
+// WriteTo writes the metrics to "w" in the Prometheus text exposition format,
+// the samples of each metric are sorted by their labels.
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var buf bytes.Buffer
+	r.write(&buf)
+	return buf.WriteTo(w)
+}
+
+func (r *Registry) write(buf *bytes.Buffer) {
+	r.mu.Lock()
+	requests := make([]requestLabels, 0, len(r.requests))
+	for labels := range r.requests {
+		requests = append(requests, labels)
+	}
+	sort.Slice(requests, func(i, j int) bool {
+		a, b := requests[i], requests[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		if a.method != b.method {
+			return a.method < b.method
+		}
+		return a.code < b.code
+	})
+
+	inFlight := make([]inFlightLabels, 0, len(r.inFlight))
+	for labels := range r.inFlight {
+		inFlight = append(inFlight, labels)
+	}
+	sort.Slice(inFlight, func(i, j int) bool {
+		a, b := inFlight[i], inFlight[j]
+		if a.route != b.route {
+			return a.route < b.route
+		}
+		return a.method < b.method
+	})
+
+	name := r.name("http_requests_total")
+	writeHeader(buf, name, "counter", "The total number of the http requests.")
+	for _, l := range requests {
+		writeSample(buf, name, formatUint(r.requests[l].count), "route", l.route, "method", l.method, "code", l.code)
+	}
+
+	name = r.name("http_request_duration_seconds")
+	writeHeader(buf, name, "histogram", "The latency of the http requests in seconds.")
+	for _, l := range requests {
+		m := r.requests[l]
+		var cumulative uint64
+		for i, le := range r.buckets {
+			cumulative += m.buckets[i]
+			writeSample(buf, name+"_bucket", formatUint(cumulative),
+				"route", l.route, "method", l.method, "code", l.code, "le", formatFloat(le))
+		}
+		writeSample(buf, name+"_bucket", formatUint(m.count), "route", l.route, "method", l.method, "code", l.code, "le", "+Inf")
+		writeSample(buf, name+"_sum", formatFloat(m.sum), "route", l.route, "method", l.method, "code", l.code)
+		writeSample(buf, name+"_count", formatUint(m.count), "route", l.route, "method", l.method, "code", l.code)
+	}
+
+	name = r.name("http_requests_in_flight")
+	writeHeader(buf, name, "gauge", "The number of the http requests which are being served.")
+	for _, l := range inFlight {
+		writeSample(buf, name, strconv.FormatInt(r.inFlight[l], 10), "route", l.route, "method", l.method)
+	}
+
+	hosts := append([]*host.Supervisor(nil), r.hosts...)
+	r.mu.Unlock()
+
+	if len(hosts) == 0 {
+		return
+	}
+
+	stats := make([]host.Stats, len(hosts))
+	tasks := make([]map[host.TaskState]int, len(hosts))
+	for i, su := range hosts {
+		stats[i] = su.Stats()
+		tasks[i] = su.Scheduler.TaskStates()
+	}
+
+	hostMetrics := []struct {
+		name, typ, help string
+		value           func(s host.Stats) string
+	}{
+		{"host_connections_open", "gauge", "The number of the open connections.",
+			func(s host.Stats) string { return strconv.Itoa(s.OpenConnections) }},
+		{"host_connections_accepted_total", "counter", "The total number of the accepted connections.",
+			func(s host.Stats) string { return formatUint(s.AcceptedConnections) }},
+		{"host_connections_hijacked_total", "counter", "The total number of the hijacked connections.",
+			func(s host.Stats) string { return formatUint(s.HijackedConnections) }},
+		{"host_connections_rejected_total", "counter", "The total number of the connections rejected by the limits.",
+			func(s host.Stats) string { return formatUint(s.RejectedConnections) }},
+		{"host_connections_drained_total", "counter", "The total number of the connections closed gracefully on shutdown.",
+			func(s host.Stats) string { return formatUint(s.DrainedConnections) }},
+		{"host_connections_forcibly_closed_total", "counter", "The total number of the connections closed forcibly on shutdown.",
+			func(s host.Stats) string { return formatUint(s.ForciblyClosedConnections) }},
+		{"host_tls_handshakes_total", "counter", "The total number of the completed tls handshakes.",
+			func(s host.Stats) string { return formatUint(s.TLSHandshakes) }},
+		{"host_tls_handshake_errors_total", "counter", "The total number of the failed tls handshakes.",
+			func(s host.Stats) string { return formatUint(s.TLSHandshakeErrors) }},
+	}
+
+	for _, m := range hostMetrics {
+		name = r.name(m.name)
+		writeHeader(buf, name, m.typ, m.help)
+		for i, su := range hosts {
+			writeSample(buf, name, m.value(stats[i]), "addr", su.Addr())
+		}
+	}
+
+	name = r.name("host_connections")
+	writeHeader(buf, name, "gauge", "The number of the open connections per state.")
+	for i, su := range hosts {
+		states := []struct {
+			state string
+			n     int
+		}{{"new", stats[i].NewConnections}, {"active", stats[i].ActiveConnections}, {"idle", stats[i].IdleConnections}}
+		for _, s := range states {
+			writeSample(buf, name, strconv.Itoa(s.n), "addr", su.Addr(), "state", s.state)
+		}
+	}
+
+	name = r.name("host_tasks")
+	writeHeader(buf, name, "gauge", "The number of the scheduled tasks per state.")
+	for i, su := range hosts {
+		for _, state := range []host.TaskState{host.TaskScheduled, host.TaskRunning, host.TaskDone, host.TaskCanceled} {
+			writeSample(buf, name, strconv.Itoa(tasks[i][state]), "addr", su.Addr(), "state", state.String())
+		}
+	}
+}
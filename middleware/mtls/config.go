@@ -0,0 +1,55 @@
+package mtls
+
+import (
+	"net/http"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/errors"
+)
+
+// Config are the rules of the mtls middleware, each one of the non-empty rules should be matched
+// by the identity of the client's certificate, a rule is matched by one of its values.
+// Without rules any verified client certificate is authorized.
+type Config struct {
+	// CommonNames are the allowed common names of the certificate's subject.
+	CommonNames []string
+	// DNSNames are the allowed DNS names of the certificate,
+	// the wildcard names are allowed too, i.e "*.internal.example.com".
+	DNSNames []string
+	// SPIFFEIDs are the allowed SPIFFE ids of the certificate,
+	// an id which ends with "/*" allows all the ids under its path,
+	// i.e "spiffe://example.org/ns/prod/*".
+	SPIFFEIDs []string
+	// TrustDomains are the allowed trust domains of the certificate's SPIFFE id, i.e "example.org".
+	TrustDomains []string
+	// Organizations are the allowed organizations of the certificate's subject.
+	Organizations []string
+	// OrganizationalUnits are the allowed organizational units of the certificate's subject.
+	OrganizationalUnits []string
+	// Allow reports whether the identity is authorized, it's checked after the rules, if not nil.
+	Allow func(ctx context.Context, id *context.PeerIdentity) bool
+	// ErrorHandler is called when the client has no verified certificate or its identity is not authorized.
+	// Default sends the 401 Unauthorized or the 403 Forbidden status code,
+	// which fires the app's `OnErrorCode` handler.
+	ErrorHandler func(ctx context.Context, err error)
+}
+
+// DefaultConfig returns the default options of the mtls middleware,
+// any verified client certificate is authorized.
+func DefaultConfig() Config {
+	return Config{
+		ErrorHandler: DefaultErrorHandler,
+	}
+}
+
+// DefaultErrorHandler sends the 401 Unauthorized status code when the client has no verified certificate
+// or the 403 Forbidden when its identity is not authorized and stops the execution of the next handlers.
+func DefaultErrorHandler(ctx context.Context, err error) {
+	statusCode := http.StatusForbidden
+	if e, ok := err.(errors.Error); ok && ErrCertificateMissing.Equal(e) {
+		statusCode = http.StatusUnauthorized
+	}
+
+	ctx.StatusCode(statusCode)
+	ctx.StopExecution()
+}
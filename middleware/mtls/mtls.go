@@ -0,0 +1,128 @@
+// Package mtls provides the authorization of the clients by their certificates, the mutual tls, via middleware.
+// See _examples/authentication/mtls
+package mtls
+
+// test file: ../../_examples/authentication/mtls/main_test.go
+
+import (
+	"strings"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/errors"
+)
+
+var (
+	// ErrCertificateMissing is passed to the ErrorHandler when the client has no verified certificate.
+	ErrCertificateMissing = errors.New("mtls: client certificate is missing")
+	// ErrIdentityForbidden is passed to the ErrorHandler when the identity of the client's certificate is not authorized.
+	ErrIdentityForbidden = errors.New("mtls: client identity is not authorized")
+)
+
+type mtlsMiddleware struct {
+	config Config
+}
+
+// New returns a new mtls middleware which authorizes the clients by their verified certificates,
+// see `ion#MutualTLS`, it's usually registered to the parties of the service-to-service endpoints,
+// i.e app.Party("/internal", mtls.New(mtls.Config{TrustDomains: []string{"example.org"}})).
+//
+// The clients without a verified certificate or whose identity doesn't match the rules of the Config
+// are passed to the ErrorHandler.
+//
+// Receives an optional configuration, the empty fields are filled with the defaults.
+func New(cfg ...Config) context.Handler {
+	c := DefaultConfig()
+	if len(cfg) > 0 {
+		c = cfg[0]
+		if c.ErrorHandler == nil {
+			c.ErrorHandler = DefaultErrorHandler
+		}
+	}
+
+	m := &mtlsMiddleware{config: c}
+	return m.ServeHTTP
+}
+
+func (m *mtlsMiddleware) ServeHTTP(ctx context.Context) {
+	id := ctx.PeerIdentity()
+	if id == nil {
+		m.config.ErrorHandler(ctx, ErrCertificateMissing)
+		return
+	}
+
+	if !m.authorized(ctx, id) {
+		m.config.ErrorHandler(ctx, ErrIdentityForbidden)
+		return
+	}
+
+	ctx.Next()
+}
+
+// authorized reports whether the identity matches all the non-empty rules.
+func (m *mtlsMiddleware) authorized(ctx context.Context, id *context.PeerIdentity) bool {
+	c := m.config
+
+	if len(c.CommonNames) > 0 && !matchAny(c.CommonNames, []string{id.CommonName}, equal) {
+		return false
+	}
+	if len(c.DNSNames) > 0 && !matchAny(c.DNSNames, id.DNSNames, matchDNSName) {
+		return false
+	}
+	if len(c.SPIFFEIDs) > 0 && (id.SPIFFEID == "" || !matchAny(c.SPIFFEIDs, []string{id.SPIFFEID}, matchSPIFFEID)) {
+		return false
+	}
+	if len(c.TrustDomains) > 0 && (id.TrustDomain == "" || !matchAny(c.TrustDomains, []string{id.TrustDomain}, strings.EqualFold)) {
+		return false
+	}
+	if len(c.Organizations) > 0 && !matchAny(c.Organizations, id.Subject.Organization, equal) {
+		return false
+	}
+	if len(c.OrganizationalUnits) > 0 && !matchAny(c.OrganizationalUnits, id.Subject.OrganizationalUnit, equal) {
+		return false
+	}
+
+	return c.Allow == nil || c.Allow(ctx, id)
+}
+
+func equal(pattern, value string) bool {
+	return pattern == value
+}
+
+// matchAny reports whether one of the "values" matches one of the "patterns".
+func matchAny(patterns []string, values []string, match func(pattern, value string) bool) bool {
+	for _, pattern := range patterns {
+		for _, value := range values {
+			if match(pattern, value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// matchDNSName reports whether the DNS name "value" matches the "pattern",
+// a wildcard pattern matches one label, i.e "*.example.com" matches "api.example.com"
+// but not the "example.com" or the "v1.api.example.com".
+func matchDNSName(pattern, value string) bool {
+	pattern, value = strings.ToLower(pattern), strings.ToLower(value)
+	if pattern == value {
+		return true
+	}
+
+	if strings.HasPrefix(pattern, "*.") {
+		idx := strings.IndexByte(value, '.')
+		return idx > 0 && value[idx:] == pattern[1:]
+	}
+	return false
+}
+
+// matchSPIFFEID reports whether the SPIFFE id "value" matches the "pattern",
+// a pattern which ends with "/*" matches the ids under its path,
+// i.e "spiffe://example.org/ns/prod/*" matches "spiffe://example.org/ns/prod/sa/billing".
+func matchSPIFFEID(pattern, value string) bool {
+	if strings.HasSuffix(pattern, "/*") {
+		prefix := pattern[:len(pattern)-1]
+		return len(value) > len(prefix) && strings.HasPrefix(value, prefix)
+	}
+	return pattern == value
+}
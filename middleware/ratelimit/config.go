@@ -0,0 +1,61 @@
+package ratelimit
+
+import (
+	"time"
+)
+
+// Algorithm is the algorithm of a limit, see `TokenBucket` and `SlidingWindow`.
+type Algorithm uint8
+
+const (
+	// TokenBucket allows bursts of up to Limit requests,
+	// the tokens are refilled at the rate of Limit per Period.
+	TokenBucket Algorithm = iota
+	// SlidingWindow allows Limit requests in any Period,
+	// the requests of the previous window are weighted by its overlap with the sliding window.
+	SlidingWindow
+)
+
+const (
+	// DefaultLimit is the default number of the requests per Period, 60.
+	DefaultLimit = 60
+	// DefaultPeriod is the default period of the limit, one minute.
+	DefaultPeriod = time.Minute
+)
+
+// Config are the options of the ratelimit middleware.
+type Config struct {
+	// Algorithm is the algorithm of the limit.
+	// Default is the TokenBucket.
+	Algorithm Algorithm
+	// Limit is the number of the requests which are allowed per Period,
+	// it's the burst size of the TokenBucket too.
+	// Default is 60.
+	Limit int
+	// Period is the period of the Limit.
+	// Default is one minute.
+	Period time.Duration
+	// KeyFunc returns the key that the requests are limited by,
+	// see `ByIP`, `ByUser`, `ByRoute` and `ByRouteAndIP`.
+	// The requests with an empty key are not limited.
+	// Default is the `ByIP`.
+	KeyFunc KeyFunc
+	// Store keeps the state of the limits of the keys.
+	// Default is a new in-memory store, see `NewMemoryStore`.
+	Store Store
+	// Prefix is prepended to the keys of the Store,
+	// set different prefixes to the limits which share a Store.
+	// Default is empty.
+	Prefix string
+}
+
+// DefaultConfig returns the default options of the ratelimit middleware,
+// 60 requests per minute per client ip, with the token bucket algorithm.
+func DefaultConfig() Config {
+	return Config{
+		Algorithm: TokenBucket,
+		Limit:     DefaultLimit,
+		Period:    DefaultPeriod,
+		KeyFunc:   ByIP,
+	}
+}
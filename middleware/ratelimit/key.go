@@ -0,0 +1,48 @@
+package ratelimit
+
+import (
+	"crypto/subtle"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/middleware/basicauth"
+)
+
+// KeyFunc returns the key that a request is limited by.
+type KeyFunc func(ctx context.Context) string
+
+// ByIP limits the requests by the client's ip address,
+// see `Context#RemoteAddr` and the `TrustedProxies` configuration field.
+func ByIP(ctx context.Context) string {
+	return "ip:" + ctx.RemoteAddr()
+}
+
+// ByUser limits the requests by the username of the basic authentication,
+// the credentials are verified against the users of the "auth",
+// the requests without credentials or with wrong ones are limited by the client's ip address,
+// so a client can't exhaust the limit of an other user, even if the limit runs before the authentication.
+func ByUser(auth basicauth.Config) KeyFunc {
+	return func(ctx context.Context) string {
+		username, password, ok := auth.User(ctx)
+		if !ok || username == "" {
+			return ByIP(ctx)
+		}
+
+		expected, found := auth.Users[username]
+		if !found || subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
+			return ByIP(ctx)
+		}
+		return "user:" + username
+	}
+}
+
+// ByRoute limits the requests by the name of the route which serves them,
+// all the clients share the limit of a route.
+func ByRoute(ctx context.Context) string {
+	return "route:" + ctx.GetCurrentRouteName()
+}
+
+// ByRouteAndIP limits the requests by the name of the route and the client's ip address,
+// each client has its own limit on each route.
+func ByRouteAndIP(ctx context.Context) string {
+	return ByRoute(ctx) + "|" + ByIP(ctx)
+}
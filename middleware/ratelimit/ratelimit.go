@@ -0,0 +1,206 @@
+// Package ratelimit provides rate limiting via middleware. See _examples/miscellaneous/ratelimit
+package ratelimit
+
+// test file: ../../_examples/miscellaneous/ratelimit/main_test.go
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/get-ion/ion/context"
+)
+
+const (
+	limitHeaderKey      = "RateLimit-Limit"
+	remainingHeaderKey  = "RateLimit-Remaining"
+	resetHeaderKey      = "RateLimit-Reset"
+	retryAfterHeaderKey = "Retry-After"
+)
+
+// result is the result of a request against a limit.
+type result struct {
+	allowed   bool
+	remaining int
+	// the time until the limit is fully available again.
+	reset time.Duration
+	// the time until the next request is allowed, if not allowed.
+	retryAfter time.Duration
+}
+
+type rateLimitMiddleware struct {
+	config Config
+	// the tokens per second of the token bucket.
+	rate float64
+	// the expiration of the states of the store.
+	expiration time.Duration
+}
+
+// New returns a new ratelimit middleware,
+// the requests which exceed the limit of their key are stopped
+// with the 429 Too Many Requests status code, which fires the app's `OnErrorCode` handler.
+// The responses contain the "RateLimit-Limit", "RateLimit-Remaining" and "RateLimit-Reset" headers
+// and the "Retry-After" header when the limit is exceeded.
+//
+// Each Party can have its own limits, i.e
+// app.Party("/api", ratelimit.New(ratelimit.Config{Limit: 100, Period: time.Minute}))
+// app.Party("/internal", ratelimit.New(ratelimit.Config{Limit: 10000, Period: time.Minute}))
+//
+// Receives an optional configuration, the empty fields are filled with the defaults.
+func New(cfg ...Config) context.Handler {
+	c := DefaultConfig()
+	if len(cfg) > 0 {
+		c = cfg[0]
+		def := DefaultConfig()
+		if c.Limit <= 0 {
+			c.Limit = def.Limit
+		}
+		if c.Period <= 0 {
+			c.Period = def.Period
+		}
+		if c.KeyFunc == nil {
+			c.KeyFunc = def.KeyFunc
+		}
+	}
+
+	if c.Store == nil {
+		c.Store = NewMemoryStore(DefaultShards)
+	}
+
+	m := &rateLimitMiddleware{
+		config:     c,
+		rate:       float64(c.Limit) / c.Period.Seconds(),
+		expiration: c.Period,
+	}
+
+	if c.Algorithm == SlidingWindow {
+		// the previous window is needed until the end of the current one.
+		m.expiration = 2 * c.Period
+	}
+
+	return m.ServeHTTP
+}
+
+// ServeHTTP serves the middleware.
+func (m *rateLimitMiddleware) ServeHTTP(ctx context.Context) {
+	key := m.config.KeyFunc(ctx)
+	if key == "" {
+		ctx.Next()
+		return
+	}
+
+	var (
+		now = time.Now()
+		res result
+	)
+
+	err := m.config.Store.Update(m.config.Prefix+key, m.expiration, func(state State, ok bool) State {
+		if m.config.Algorithm == SlidingWindow {
+			state, res = m.slidingWindow(state, ok, now)
+		} else {
+			state, res = m.tokenBucket(state, ok, now)
+		}
+		return state
+	})
+
+	if err != nil {
+		// don't block the clients because of the store.
+		ctx.Application().Logger().Warnf("ratelimit: %v", err)
+		ctx.Next()
+		return
+	}
+
+	ctx.Header(limitHeaderKey, strconv.Itoa(m.config.Limit))
+	ctx.Header(remainingHeaderKey, strconv.Itoa(res.remaining))
+	ctx.Header(resetHeaderKey, seconds(res.reset))
+
+	if !res.allowed {
+		ctx.Header(retryAfterHeaderKey, seconds(res.retryAfter))
+		ctx.StatusCode(http.StatusTooManyRequests)
+		ctx.StopExecution()
+		return
+	}
+
+	ctx.Next()
+}
+
+func (m *rateLimitMiddleware) tokenBucket(state State, ok bool, now time.Time) (State, result) {
+	limit := float64(m.config.Limit)
+	if !ok {
+		state = State{Value: limit, Time: now}
+	} else if elapsed := now.Sub(state.Time).Seconds(); elapsed > 0 {
+		state.Value = math.Min(limit, state.Value+elapsed*m.rate)
+		state.Time = now
+	}
+
+	res := result{}
+	if state.Value >= 1 {
+		state.Value--
+		res.allowed = true
+	} else {
+		res.retryAfter = secondsDuration((1 - state.Value) / m.rate)
+	}
+
+	res.remaining = int(state.Value)
+	res.reset = secondsDuration((limit - state.Value) / m.rate)
+	return state, res
+}
+
+func (m *rateLimitMiddleware) slidingWindow(state State, ok bool, now time.Time) (State, result) {
+	period := m.config.Period
+	switch {
+	case !ok || now.Sub(state.Time) >= 2*period:
+		state = State{Time: now.Truncate(period)}
+	case now.Sub(state.Time) >= period:
+		state = State{Previous: state.Value, Time: state.Time.Add(period)}
+	}
+
+	limit := float64(m.config.Limit)
+	// the weight of the previous window in the sliding window.
+	elapsed := now.Sub(state.Time)
+	weight := 1 - elapsed.Seconds()/period.Seconds()
+	count := state.Previous*weight + state.Value
+
+	res := result{reset: state.Time.Add(period).Sub(now)}
+	if count+1 <= limit {
+		state.Value++
+		count++
+		res.allowed = true
+	} else {
+		res.retryAfter = m.slidingWindowRetryAfter(state, elapsed)
+	}
+
+	res.remaining = int(math.Max(0, limit-count))
+	return state, res
+}
+
+// slidingWindowRetryAfter returns the time until the weighted count of the sliding window
+// drops enough to allow one more request.
+func (m *rateLimitMiddleware) slidingWindowRetryAfter(state State, elapsed time.Duration) time.Duration {
+	period := m.config.Period.Seconds()
+	allowed := float64(m.config.Limit) - 1
+
+	if state.Value <= allowed && state.Previous > 0 {
+		// in the current window, when the previous window's weight drops.
+		t := period * (1 - (allowed-state.Value)/state.Previous)
+		return secondsDuration(t - elapsed.Seconds())
+	}
+
+	// in the next window, the current one becomes the previous.
+	t := 0.0
+	if state.Value > 0 {
+		t = period * math.Max(0, 1-allowed/state.Value)
+	}
+	return secondsDuration(period - elapsed.Seconds() + t)
+}
+
+// secondsDuration converts seconds to a duration.
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+// seconds returns the duration in seconds, rounded up, for the headers.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
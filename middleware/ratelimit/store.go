@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// State is the state of the limit of a key.
+type State struct {
+	// Value is the available tokens of the TokenBucket
+	// or the requests of the current window of the SlidingWindow.
+	Value float64
+	// Previous is the requests of the previous window of the SlidingWindow.
+	Previous float64
+	// Time is the time of the last refill of the TokenBucket
+	// or the start of the current window of the SlidingWindow.
+	Time time.Time
+}
+
+// Store keeps the states of the limits of the keys.
+//
+// An in-memory Store is provided, see `NewMemoryStore`,
+// a Store which is shared between servers, i.e on redis,
+// can implement the Update with an optimistic transaction.
+type Store interface {
+	// Update calls the "update" with the current state of the "key",
+	// "ok" is false if the key has no state or its state has been expired,
+	// and it saves the returned state, which expires after the "expiration".
+	// The update of a key should be atomic.
+	Update(key string, expiration time.Duration, update func(state State, ok bool) State) error
+}
+
+// DefaultShards is the default number of the shards of the `NewMemoryStore`.
+const DefaultShards = 32
+
+// sweepInterval is the minimum interval between the removals of the expired states of a shard.
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	state   State
+	expires time.Time
+}
+
+type memoryShard struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// MemoryStore is an in-memory `Store`, the keys are split to shards
+// in order to reduce the lock contention.
+type MemoryStore struct {
+	shards []*memoryShard
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+// NewMemoryStore returns a new in-memory `Store` with "shards" number of shards,
+// a zero or a negative "shards" means the DefaultShards.
+func NewMemoryStore(shards int) *MemoryStore {
+	if shards <= 0 {
+		shards = DefaultShards
+	}
+
+	s := &MemoryStore{shards: make([]*memoryShard, shards)}
+	for i := range s.shards {
+		s.shards[i] = &memoryShard{entries: make(map[string]memoryEntry)}
+	}
+	return s
+}
+
+func (s *MemoryStore) shard(key string) *memoryShard {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	return s.shards[h.Sum32()%uint32(len(s.shards))]
+}
+
+// Update calls the "update" with the current state of the "key" and it saves the returned state,
+// it never returns an error.
+func (s *MemoryStore) Update(key string, expiration time.Duration, update func(state State, ok bool) State) error {
+	now := time.Now()
+	shard := s.shard(key)
+
+	shard.mu.Lock()
+	if now.Sub(shard.lastSweep) >= sweepInterval {
+		shard.sweep(now)
+	}
+
+	entry, ok := shard.entries[key]
+	if ok && !now.Before(entry.expires) {
+		ok = false
+	}
+
+	shard.entries[key] = memoryEntry{
+		state:   update(entry.state, ok),
+		expires: now.Add(expiration),
+	}
+	shard.mu.Unlock()
+	return nil
+}
+
+// sweep removes the expired entries, the shard should be locked.
+func (shard *memoryShard) sweep(now time.Time) {
+	for key, entry := range shard.entries {
+		if !now.Before(entry.expires) {
+			delete(shard.entries, key)
+		}
+	}
+	shard.lastSweep = now
+}
@@ -16,7 +16,15 @@ func getRequestLogs(ctx context.Context) string {
 	method = ctx.Method()
 	ip = ctx.RemoteAddr()
 	// the date should be logged by ion' Logger, so we skip them
-	return fmt.Sprintf("%v %s %s %s", status, path, method, ip)
+	logs := fmt.Sprintf("%v %s %s %s", status, path, method, ip)
+	// the ids of the trace middleware, if any.
+	if id := ctx.RequestID(); id != "" {
+		logs += " request_id=" + id
+	}
+	if traceID, _ := ctx.TraceIDs(); traceID != "" {
+		logs += " trace_id=" + traceID
+	}
+	return logs
 }
 
 // New returns a new recover middleware,
@@ -0,0 +1,33 @@
+package trace
+
+import (
+	"github.com/get-ion/ion/context"
+)
+
+// DefaultRequestIDHeader is the default header of the request id.
+const DefaultRequestIDHeader = "X-Request-Id"
+
+// Config are the options of the trace middleware.
+type Config struct {
+	// RequestIDHeader is the request header which the request id is accepted from
+	// and the response header which the request id is sent with.
+	// Default is "X-Request-Id".
+	RequestIDHeader string
+	// GenerateRequestID returns the id of the requests that don't send a valid one.
+	// Default is the trace id of the request, so the logs and the spans of a request share the same id.
+	GenerateRequestID func(ctx context.Context) string
+	// Exporter records the spans of the sampled traces, i.e a `NewOTLPExporter`.
+	// Default is nil, the spans are not recorded, only the trace context is propagated.
+	Exporter Exporter
+	// Sample reports whether a new trace, a request without a valid "traceparent", is sampled.
+	// The traces of the requests with a "traceparent" follow its sampled flag.
+	// Default is nil, all new traces are sampled.
+	Sample func(ctx context.Context) bool
+}
+
+// DefaultConfig returns the default options of the trace middleware.
+func DefaultConfig() Config {
+	return Config{
+		RequestIDHeader: DefaultRequestIDHeader,
+	}
+}
@@ -0,0 +1,162 @@
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// Exporter records the ended spans of the sampled traces.
+type Exporter interface {
+	Export(span *Span)
+}
+
+// ExporterFunc is an `Exporter` of a func.
+type ExporterFunc func(span *Span)
+
+// Export calls the func.
+func (f ExporterFunc) Export(span *Span) {
+	f(span)
+}
+
+// the OTLP/JSON encoding of the spans,
+// see https://github.com/open-telemetry/opentelemetry-proto.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		TraceState        string         `json:"traceState,omitempty"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              SpanKind       `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+
+	otlpStatus struct {
+		Code    SpanStatus `json:"code,omitempty"`
+		Message string     `json:"message,omitempty"`
+	}
+
+	otlpKeyValue struct {
+		Key   string    `json:"key"`
+		Value otlpValue `json:"value"`
+	}
+
+	otlpValue struct {
+		StringValue *string  `json:"stringValue,omitempty"`
+		IntValue    *string  `json:"intValue,omitempty"`
+		DoubleValue *float64 `json:"doubleValue,omitempty"`
+		BoolValue   *bool    `json:"boolValue,omitempty"`
+	}
+)
+
+func newOTLPValue(v interface{}) otlpValue {
+	var value otlpValue
+	switch x := v.(type) {
+	case string:
+		value.StringValue = &x
+	case int:
+		s := strconv.Itoa(x)
+		value.IntValue = &s
+	case int64:
+		s := strconv.FormatInt(x, 10)
+		value.IntValue = &s
+	case float64:
+		value.DoubleValue = &x
+	case bool:
+		value.BoolValue = &x
+	}
+	return value
+}
+
+func newOTLPSpan(span *Span) otlpSpan {
+	s := otlpSpan{
+		TraceID:           span.Context.TraceID.String(),
+		SpanID:            span.Context.SpanID.String(),
+		TraceState:        span.Context.TraceState,
+		Name:              span.Name,
+		Kind:              span.Kind,
+		StartTimeUnixNano: strconv.FormatInt(span.StartTime.UnixNano(), 10),
+		EndTimeUnixNano:   strconv.FormatInt(span.EndTime.UnixNano(), 10),
+		Status:            otlpStatus{Code: span.Status, Message: span.StatusMessage},
+	}
+
+	if span.ParentSpanID.IsValid() {
+		s.ParentSpanID = span.ParentSpanID.String()
+	}
+
+	keys := make([]string, 0, len(span.Attributes))
+	for k := range span.Attributes {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		s.Attributes = append(s.Attributes, otlpKeyValue{Key: k, Value: newOTLPValue(span.Attributes[k])})
+	}
+
+	return s
+}
+
+// OTLPExporter writes the spans as OTLP/JSON export requests, one per line,
+// the format of the OpenTelemetry collector's file receiver,
+// so the traces can be recorded without a live collector.
+type OTLPExporter struct {
+	mu       sync.Mutex
+	enc      *json.Encoder
+	resource otlpResource
+}
+
+// NewOTLPExporter returns a new `OTLPExporter` which writes to "w", i.e a file,
+// the "serviceName" is the "service.name" attribute of the spans' resource.
+func NewOTLPExporter(w io.Writer, serviceName string) *OTLPExporter {
+	return &OTLPExporter{
+		enc: json.NewEncoder(w),
+		resource: otlpResource{
+			Attributes: []otlpKeyValue{{Key: "service.name", Value: newOTLPValue(serviceName)}},
+		},
+	}
+}
+
+// Export writes the span as an export request line, the write errors are ignored.
+func (e *OTLPExporter) Export(span *Span) {
+	req := otlpRequest{
+		ResourceSpans: []otlpResourceSpans{{
+			Resource: e.resource,
+			ScopeSpans: []otlpScopeSpans{{
+				Scope: otlpScope{Name: "github.com/get-ion/ion/middleware/trace"},
+				Spans: []otlpSpan{newOTLPSpan(span)},
+			}},
+		}},
+	}
+
+	e.mu.Lock()
+	e.enc.Encode(req)
+	e.mu.Unlock()
+}
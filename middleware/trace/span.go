@@ -0,0 +1,200 @@
+package trace
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/errors"
+)
+
+// ErrTraceparent is returned by the `ParseTraceparent` when the value is not a valid "traceparent".
+var ErrTraceparent = errors.New("trace: invalid traceparent '%s'")
+
+// TraceID is the 16 bytes id of a trace.
+type TraceID [16]byte
+
+// String returns the lowercase hex form of the id.
+func (id TraceID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the id is not all zeros.
+func (id TraceID) IsValid() bool {
+	return id != TraceID{}
+}
+
+// SpanID is the 8 bytes id of a span.
+type SpanID [8]byte
+
+// String returns the lowercase hex form of the id.
+func (id SpanID) String() string {
+	return hex.EncodeToString(id[:])
+}
+
+// IsValid reports whether the id is not all zeros.
+func (id SpanID) IsValid() bool {
+	return id != SpanID{}
+}
+
+func newTraceID() (id TraceID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return
+}
+
+func newSpanID() (id SpanID) {
+	for !id.IsValid() {
+		rand.Read(id[:])
+	}
+	return
+}
+
+// FlagSampled is the trace flag which marks a trace as sampled, its spans are recorded.
+const FlagSampled byte = 0x01
+
+// SpanContext is the propagated part of a span, the W3C trace context.
+//
+// See https://www.w3.org/TR/trace-context/.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+	Flags   byte
+	// TraceState is the vendor specific data of the "tracestate" header, it's propagated as it's.
+	TraceState string
+}
+
+// IsSampled reports whether the FlagSampled is set.
+func (sc SpanContext) IsSampled() bool {
+	return sc.Flags&FlagSampled != 0
+}
+
+// Traceparent returns the "traceparent" header's value of the span context.
+func (sc SpanContext) Traceparent() string {
+	return "00-" + sc.TraceID.String() + "-" + sc.SpanID.String() + "-" + hex.EncodeToString([]byte{sc.Flags})
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseTraceparent parses a "traceparent" header's value, i.e
+// "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01".
+// The values of future versions are accepted, their extra fields are ignored.
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+
+	s = strings.TrimSpace(s)
+	// version-trace_id-parent_id-flags.
+	if len(s) < 55 || s[2] != '-' || s[35] != '-' || s[52] != '-' || !isLowerHex(s[:2]) {
+		return sc, ErrTraceparent.Format(s)
+	}
+
+	version := s[:2]
+	if version == "ff" || (version == "00" && len(s) != 55) || (len(s) > 55 && s[55] != '-') {
+		return sc, ErrTraceparent.Format(s)
+	}
+
+	traceID, spanID, flags := s[3:35], s[36:52], s[53:55]
+	if !isLowerHex(traceID) || !isLowerHex(spanID) || !isLowerHex(flags) {
+		return sc, ErrTraceparent.Format(s)
+	}
+
+	hex.Decode(sc.TraceID[:], []byte(traceID))
+	hex.Decode(sc.SpanID[:], []byte(spanID))
+	var b [1]byte
+	hex.Decode(b[:], []byte(flags))
+	sc.Flags = b[0]
+
+	if !sc.TraceID.IsValid() || !sc.SpanID.IsValid() {
+		return SpanContext{}, ErrTraceparent.Format(s)
+	}
+
+	return sc, nil
+}
+
+// SpanKind is the kind of a span.
+type SpanKind int
+
+const (
+	// SpanKindInternal is the kind of the spans of the handlers, see `Segment` and `StartSpan`.
+	SpanKindInternal SpanKind = 1
+	// SpanKindServer is the kind of the span of a request, see `New`.
+	SpanKindServer SpanKind = 2
+)
+
+// SpanStatus is the status code of a span.
+type SpanStatus int
+
+const (
+	// StatusUnset is the default status of a span.
+	StatusUnset SpanStatus = 0
+	// StatusOK marks a span as successful.
+	StatusOK SpanStatus = 1
+	// StatusError marks a span as failed, i.e the 5xx responses.
+	StatusError SpanStatus = 2
+)
+
+// Span is a timed operation of a trace, a request or a segment of its handlers.
+type Span struct {
+	Name         string
+	Kind         SpanKind
+	Context      SpanContext
+	ParentSpanID SpanID
+	StartTime    time.Time
+	EndTime      time.Time
+	Attributes   map[string]interface{}
+	Status       SpanStatus
+	// StatusMessage is the description of an error status.
+	StatusMessage string
+
+	exporter        Exporter
+	requestIDHeader string
+	// the request's context and the span that was current before this one,
+	// they're used to restore the parent on `End`.
+	ctx    context.Context
+	parent *Span
+	ended  bool
+}
+
+// SetAttribute sets an attribute of the span, the values can be
+// a string, an int, an int64, a float64 or a bool.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]interface{})
+	}
+	s.Attributes[key] = value
+}
+
+// SetStatus sets the status code and the status message of the span.
+func (s *Span) SetStatus(status SpanStatus, message string) {
+	s.Status = status
+	s.StatusMessage = message
+}
+
+// End ends the span, it's exported if the trace is sampled,
+// and its parent becomes the current span of the request again.
+// Only the first call has effect.
+func (s *Span) End() {
+	if s.ended {
+		return
+	}
+	s.ended = true
+	s.EndTime = time.Now()
+
+	if s.ctx != nil && s.parent != nil {
+		setCurrentSpan(s.ctx, s.parent)
+	}
+
+	if s.exporter != nil && s.Context.IsSampled() {
+		s.exporter.Export(s)
+	}
+}
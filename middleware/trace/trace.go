@@ -0,0 +1,213 @@
+// Package trace provides request ids and W3C trace context propagation via middleware. See _examples/miscellaneous/trace
+package trace
+
+// test file: ../../_examples/miscellaneous/trace/main_test.go
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/get-ion/ion/context"
+)
+
+const (
+	traceparentHeaderKey = "traceparent"
+	tracestateHeaderKey  = "tracestate"
+	// the key of the current span in the context's values.
+	spanContextKey = "trace.span"
+	// the max length of an accepted request id.
+	maxRequestIDLength = 200
+)
+
+type traceMiddleware struct {
+	config Config
+}
+
+// New returns a new trace middleware which starts a server span per request.
+// The span continues the trace of the request's "traceparent" and "tracestate" headers,
+// if they're valid, otherwise it starts a new trace.
+//
+// The request id is accepted from the RequestIDHeader or it's generated,
+// it's set to the `Context#SetRequestID` and to the response's RequestIDHeader.
+//
+// The request's "traceparent" and RequestIDHeader headers are replaced with the current span's ones,
+// so the requests which are forwarded by the `host.ProxyHandler` carry them, see `Inject` for other outgoing requests.
+//
+// Register it with the `Application#UseGlobal`, before the middlewares which log the request ids.
+//
+// Receives an optional configuration, the empty fields are filled with the defaults.
+func New(cfg ...Config) context.Handler {
+	c := DefaultConfig()
+	if len(cfg) > 0 {
+		c = cfg[0]
+		if c.RequestIDHeader == "" {
+			c.RequestIDHeader = DefaultRequestIDHeader
+		}
+	}
+
+	m := &traceMiddleware{config: c}
+	return m.ServeHTTP
+}
+
+func isValidRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		// visible ascii characters.
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// ServeHTTP serves the middleware.
+func (m *traceMiddleware) ServeHTTP(ctx context.Context) {
+	r := ctx.Request()
+
+	parent, err := ParseTraceparent(r.Header.Get(traceparentHeaderKey))
+	if err == nil {
+		parent.TraceState = strings.Join(r.Header[http.CanonicalHeaderKey(tracestateHeaderKey)], ",")
+	} else {
+		parent = SpanContext{TraceID: newTraceID()}
+		if m.config.Sample == nil || m.config.Sample(ctx) {
+			parent.Flags = FlagSampled
+		}
+	}
+
+	routeName := ctx.GetCurrentRouteName()
+	name := routeName
+	if name == "" {
+		name = ctx.Method()
+	}
+
+	span := &Span{
+		Name: name,
+		Kind: SpanKindServer,
+		Context: SpanContext{
+			TraceID:    parent.TraceID,
+			SpanID:     newSpanID(),
+			Flags:      parent.Flags,
+			TraceState: parent.TraceState,
+		},
+		ParentSpanID:    parent.SpanID,
+		StartTime:       time.Now(),
+		exporter:        m.config.Exporter,
+		requestIDHeader: m.config.RequestIDHeader,
+		ctx:             ctx,
+	}
+
+	requestID := r.Header.Get(m.config.RequestIDHeader)
+	if !isValidRequestID(requestID) {
+		if m.config.GenerateRequestID != nil {
+			requestID = m.config.GenerateRequestID(ctx)
+		} else {
+			requestID = span.Context.TraceID.String()
+		}
+	}
+
+	ctx.SetRequestID(requestID)
+	ctx.Header(m.config.RequestIDHeader, requestID)
+	r.Header.Set(m.config.RequestIDHeader, requestID)
+	setCurrentSpan(ctx, span)
+
+	span.SetAttribute("http.method", ctx.Method())
+	span.SetAttribute("http.target", r.RequestURI)
+	span.SetAttribute("http.route", routeName)
+	span.SetAttribute("http.client_ip", ctx.RemoteAddr())
+	span.SetAttribute("request.id", requestID)
+
+	ctx.Next()
+
+	statusCode := ctx.GetStatusCode()
+	span.SetAttribute("http.status_code", statusCode)
+	if statusCode >= http.StatusInternalServerError {
+		span.SetStatus(StatusError, strconv.Itoa(statusCode)+" "+http.StatusText(statusCode))
+	}
+	span.End()
+}
+
+// setCurrentSpan makes the "span" the current span of the request, its ids are
+// set to the context for the logs, see `Context#TraceIDs`,
+// and its trace context to the request's headers, for the forwarded requests.
+func setCurrentSpan(ctx context.Context, span *Span) {
+	ctx.Values().Set(spanContextKey, span)
+	ctx.SetTraceIDs(span.Context.TraceID.String(), span.Context.SpanID.String())
+	injectHeaders(ctx.Request().Header, span.Context)
+}
+
+func injectHeaders(h http.Header, sc SpanContext) {
+	h.Set(traceparentHeaderKey, sc.Traceparent())
+	if sc.TraceState != "" {
+		h.Set(tracestateHeaderKey, sc.TraceState)
+	} else {
+		h.Del(tracestateHeaderKey)
+	}
+}
+
+// CurrentSpan returns the current span of the request, the server span of the middleware
+// or the span of a `Segment`, it's nil if the request is not served by the trace middleware.
+func CurrentSpan(ctx context.Context) *Span {
+	span, _ := ctx.Values().Get(spanContextKey).(*Span)
+	return span
+}
+
+// StartSpan starts a child span of the request's current span, it becomes the current span until its `End`.
+// The span is not recorded if the request is not served by the trace middleware.
+//
+// Usage:
+// span := trace.StartSpan(ctx, "load user")
+// user, err := db.Load(id)
+// span.End()
+func StartSpan(ctx context.Context, name string) *Span {
+	span := &Span{
+		Name:      name,
+		Kind:      SpanKindInternal,
+		StartTime: time.Now(),
+	}
+
+	parent := CurrentSpan(ctx)
+	if parent == nil {
+		return span
+	}
+
+	span.Context = parent.Context
+	span.Context.SpanID = newSpanID()
+	span.ParentSpanID = parent.Context.SpanID
+	span.exporter = parent.exporter
+	span.requestIDHeader = parent.requestIDHeader
+	span.ctx = ctx
+	span.parent = parent
+
+	setCurrentSpan(ctx, span)
+	return span
+}
+
+// Segment returns a handler which records a span of the rest of the handlers' chain,
+// from itself until the end of the next handlers.
+//
+// Usage:
+// app.Get("/users/{id:int}", auth, trace.Segment("load user"), loadUser, trace.Segment("render"), render)
+func Segment(name string) context.Handler {
+	return func(ctx context.Context) {
+		span := StartSpan(ctx, name)
+		ctx.Next()
+		span.End()
+	}
+}
+
+// Inject sets the trace context of the request's current span
+// and the request id to an outgoing request, i.e of a http.Client.
+func Inject(ctx context.Context, req *http.Request) {
+	span := CurrentSpan(ctx)
+	if span == nil {
+		return
+	}
+
+	injectHeaders(req.Header, span.Context)
+	req.Header.Set(span.requestIDHeader, ctx.RequestID())
+}
@@ -0,0 +1,59 @@
+// Package mvc provides the base Controller for the controllers
+// which are registered with the `Party#Controller`, i.e
+// app.Controller("/users", new(UserController)).
+//
+// The exported methods of a controller which start with an http method,
+// i.e Get, GetBy(id int), PostProfile, are registered as routes,
+// see the `Party#Controller` for the naming rules.
+//
+// See _examples/mvc
+package mvc
+
+import (
+	"github.com/get-ion/ion/context"
+)
+
+// BaseController is the interface which the controllers may implement
+// in order to run code before and after each request.
+//
+// A new controller is created per request, so the fields can be used as
+// per-request state.
+type BaseController interface {
+	// BeginRequest is called before the method of the controller,
+	// the method is not called if the context is stopped, see `Context#StopExecution`.
+	BeginRequest(ctx context.Context)
+	// EndRequest is called after the method of the controller.
+	EndRequest(ctx context.Context)
+}
+
+// Controller is the base controller, embed it to a controller
+// in order to implement the `BaseController` and keep the request's context.
+//
+// Usage:
+// type UserController struct { mvc.Controller }
+// func (c *UserController) GetBy(id int) { c.Ctx.Writef("user: %d", id) }
+type Controller struct {
+	// Ctx is the context of the current request.
+	Ctx context.Context
+}
+
+var _ BaseController = &Controller{}
+
+// BeginRequest keeps the context of the request,
+// a controller which overrides it should call the embedded one, i.e c.Controller.BeginRequest(ctx).
+func (c *Controller) BeginRequest(ctx context.Context) {
+	c.Ctx = ctx
+}
+
+// EndRequest does nothing, it's here to be overridden.
+func (c *Controller) EndRequest(ctx context.Context) {}
+
+// Path is the type of an input argument which receives
+// the rest of the request path, it's the "path" macro type.
+// It can be only the last input argument of a method, i.e
+// func (c *FilesController) GetBy(file mvc.Path) for "/files/{paramA:path}".
+type Path string
+
+// IsPath returns true, the `Party#Controller` registers
+// the input arguments which are paths with the "path" macro type.
+func (p Path) IsPath() bool { return true }
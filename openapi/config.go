@@ -0,0 +1,43 @@
+package openapi
+
+const (
+	// DefaultTitle is the default title of the API, "ion".
+	DefaultTitle = "ion"
+	// DefaultVersion is the default version of the API, "1.0.0".
+	DefaultVersion = "1.0.0"
+)
+
+// Config the configuration for the OpenAPI document,
+// all of these are optional.
+type Config struct {
+	// Title is the title of the API.
+	// Defaults to "ion".
+	Title string
+	// Description is a short description of the API,
+	// CommonMark syntax may be used for rich text representation.
+	Description string
+	// Version is the version of the API (not of the OpenAPI specification).
+	// Defaults to "1.0.0".
+	Version string
+	// Servers are the base urls of the API, i.e "https://mydomain.com",
+	// the routes with a subdomain are described with their subdomain prepended to the host of these urls.
+	// Defaults to empty, which means the host of the document itself.
+	Servers []string
+	// ExcludeRoute reports whether a route should be excluded from the document.
+	// The offline routes and the route which serves the document are always excluded.
+	// Defaults to nil.
+	ExcludeRoute func(method, path string) bool
+}
+
+// Validate validates the configuration
+func (c Config) Validate() Config {
+	if c.Title == "" {
+		c.Title = DefaultTitle
+	}
+
+	if c.Version == "" {
+		c.Version = DefaultVersion
+	}
+
+	return c
+}
@@ -0,0 +1,136 @@
+package openapi
+
+import (
+	"encoding/json"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Version is the version of the OpenAPI specification
+// which the generated documents follow.
+const Version = "3.0.0"
+
+// The types below are a subset of the OpenAPI 3 specification,
+// the parts which can be described from the registered routes.
+//
+// See https://github.com/OAI/OpenAPI-Specification/blob/master/versions/3.0.0.md
+type (
+	// Document is the root object of an OpenAPI document.
+	Document struct {
+		OpenAPI    string              `json:"openapi"`
+		Info       Info                `json:"info"`
+		Servers    []Server            `json:"servers,omitempty"`
+		Paths      map[string]PathItem `json:"paths"`
+		Components *Components         `json:"components,omitempty"`
+	}
+
+	// Info provides metadata about the API.
+	Info struct {
+		Title       string `json:"title"`
+		Description string `json:"description,omitempty"`
+		Version     string `json:"version"`
+	}
+
+	// Server represents a server of the API.
+	Server struct {
+		URL       string                    `json:"url"`
+		Variables map[string]ServerVariable `json:"variables,omitempty"`
+	}
+
+	// ServerVariable represents a variable for server url template substitution.
+	ServerVariable struct {
+		Default     string `json:"default"`
+		Description string `json:"description,omitempty"`
+	}
+
+	// PathItem describes the operations available on a single path,
+	// the keys are the lowercase http methods, i.e "get".
+	PathItem map[string]*Operation
+
+	// Operation describes a single API operation on a path.
+	Operation struct {
+		Tags        []string             `json:"tags,omitempty"`
+		Summary     string               `json:"summary,omitempty"`
+		Description string               `json:"description,omitempty"`
+		OperationID string               `json:"operationId,omitempty"`
+		Parameters  []*Parameter         `json:"parameters,omitempty"`
+		RequestBody *RequestBody         `json:"requestBody,omitempty"`
+		Responses   map[string]*Response `json:"responses"`
+		Deprecated  bool                 `json:"deprecated,omitempty"`
+		Servers     []Server             `json:"servers,omitempty"`
+	}
+
+	// Parameter describes a single operation parameter.
+	Parameter struct {
+		Name        string  `json:"name"`
+		In          string  `json:"in"` // "path", "query", "header" or "cookie".
+		Description string  `json:"description,omitempty"`
+		Required    bool    `json:"required,omitempty"`
+		Schema      *Schema `json:"schema,omitempty"`
+	}
+
+	// RequestBody describes a single request body.
+	RequestBody struct {
+		Description string               `json:"description,omitempty"`
+		Content     map[string]MediaType `json:"content"`
+		Required    bool                 `json:"required,omitempty"`
+	}
+
+	// Response describes a single response of an operation.
+	Response struct {
+		Description string               `json:"description"`
+		Content     map[string]MediaType `json:"content,omitempty"`
+	}
+
+	// MediaType provides the schema of a content type.
+	MediaType struct {
+		Schema *Schema `json:"schema,omitempty"`
+	}
+
+	// Components holds the reusable schemas of the document,
+	// the named struct types are described here and referenced by their name.
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas,omitempty"`
+	}
+
+	// Schema describes a data type.
+	Schema struct {
+		Ref                  string             `json:"$ref,omitempty"`
+		Type                 string             `json:"type,omitempty"`
+		Format               string             `json:"format,omitempty"`
+		Description          string             `json:"description,omitempty"`
+		Pattern              string             `json:"pattern,omitempty"`
+		Minimum              *float64           `json:"minimum,omitempty"`
+		Maximum              *float64           `json:"maximum,omitempty"`
+		MinLength            *int               `json:"minLength,omitempty"`
+		MaxLength            *int               `json:"maxLength,omitempty"`
+		Nullable             bool               `json:"nullable,omitempty"`
+		Items                *Schema            `json:"items,omitempty"`
+		Properties           map[string]*Schema `json:"properties,omitempty"`
+		Required             []string           `json:"required,omitempty"`
+		AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+		AllOf                []*Schema          `json:"allOf,omitempty"`
+	}
+)
+
+// JSON returns the json form of the document.
+func (d *Document) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
+
+// YAML returns the yaml form of the document.
+func (d *Document) YAML() ([]byte, error) {
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+
+	// json is valid yaml, decode it to a MapSlice
+	// in order to keep the order of the fields as they're declared above.
+	var out yaml.MapSlice
+	if err = yaml.Unmarshal(b, &out); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(out)
+}
@@ -0,0 +1,349 @@
+// Package openapi provides a generator of OpenAPI 3 documents
+// which are built from the registered routes of an application.
+//
+// The path parameters are described by their macro types and funcs,
+// i.e {id:int min(1)} is an integer with a minimum of 1,
+// the rest of an operation, summary, tags, request and response schemas
+// can be attached to each route with the `Spec#Describe`.
+//
+// See _examples/routing/openapi
+package openapi
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/router"
+)
+
+const contentJSONHeaderValue = "application/json"
+
+// RoutesProvider is the source of the routes which are described by the document,
+// the `ion#Application` and the `router#APIBuilder` are RoutesProviders.
+type RoutesProvider interface {
+	GetRoutes() []*router.Route
+}
+
+// Spec keeps the descriptions of the routes
+// and generates the OpenAPI documents.
+type Spec struct {
+	config Config
+
+	mu           sync.RWMutex
+	descriptions map[*router.Route]*Description
+	// the routes which serve the document, they're excluded from it.
+	specRoutes map[*router.Route]struct{}
+}
+
+// New returns a new Spec based on a configuration.
+func New(cfg Config) *Spec {
+	return &Spec{
+		config:       cfg.Validate(),
+		descriptions: make(map[*router.Route]*Description),
+		specRoutes:   make(map[*router.Route]struct{}),
+	}
+}
+
+// Describe returns the description of a route,
+// it's created on the first call. The route is the result of a route registration,
+// i.e spec.Describe(app.Get("/users/{id:int min(1)}", getUser)).Summary("Get a user by id").
+//
+// A nil route, i.e a route that failed to be registered, returns a description which is not kept.
+func (s *Spec) Describe(r *router.Route) *Description {
+	if r == nil {
+		return newDescription()
+	}
+
+	s.mu.Lock()
+	d, ok := s.descriptions[r]
+	if !ok {
+		d = newDescription()
+		s.descriptions[r] = d
+	}
+	s.mu.Unlock()
+	return d
+}
+
+// Exclude excludes the routes from the document,
+// i.e spec.Exclude(app.Get("/swagger.json", spec.JSONHandler(app))).
+func (s *Spec) Exclude(routes ...*router.Route) {
+	s.mu.Lock()
+	for _, r := range routes {
+		if r != nil {
+			s.specRoutes[r] = struct{}{}
+		}
+	}
+	s.mu.Unlock()
+}
+
+// Document generates the OpenAPI document of the routes.
+func (s *Spec) Document(routes []*router.Route) *Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schemas := newSchemaRegistry()
+	doc := &Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       s.config.Title,
+			Description: s.config.Description,
+			Version:     s.config.Version,
+		},
+		Paths: make(map[string]PathItem),
+	}
+
+	for _, u := range s.config.Servers {
+		doc.Servers = append(doc.Servers, Server{URL: u})
+	}
+
+	for _, r := range routes {
+		if _, excluded := s.specRoutes[r]; excluded || !r.IsOnline() {
+			continue
+		}
+
+		method := strings.ToLower(r.Method)
+		if !isOperationMethod(method) {
+			continue
+		}
+
+		path := pathOf(r)
+		if s.config.ExcludeRoute != nil && s.config.ExcludeRoute(r.Method, path) {
+			continue
+		}
+
+		item, ok := doc.Paths[path]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[path] = item
+		}
+
+		item[method] = s.operation(r, schemas)
+	}
+
+	if len(schemas.schemas) > 0 {
+		doc.Components = &Components{Schemas: schemas.schemas}
+	}
+
+	return doc
+}
+
+func isOperationMethod(method string) bool {
+	switch method {
+	case "get", "put", "post", "delete", "options", "head", "patch", "trace":
+		return true
+	}
+	// connect is not an OpenAPI operation.
+	return false
+}
+
+func (s *Spec) operation(r *router.Route, schemas *schemaRegistry) *Operation {
+	op := &Operation{
+		Parameters: pathParameters(r),
+		Responses:  make(map[string]*Response),
+		Servers:    s.subdomainServers(r.Subdomain),
+	}
+
+	// the default route name is the method + subdomain + path,
+	// a custom name is a good operation id.
+	if r.Name != r.Method+r.Subdomain+r.Path {
+		op.OperationID = r.Name
+	}
+
+	if d, ok := s.descriptions[r]; ok {
+		d.describe(op, schemas)
+	}
+
+	if len(op.Responses) == 0 {
+		op.Responses[strconv.Itoa(http.StatusOK)] = &Response{Description: http.StatusText(http.StatusOK)}
+	}
+
+	// the macro parameters fire an error code, 404 by default, on invalid values.
+	for _, p := range r.Tmpl().Params {
+		code := strconv.Itoa(p.ErrCode)
+		if _, ok := op.Responses[code]; !ok && p.ErrCode > 0 {
+			op.Responses[code] = &Response{Description: http.StatusText(p.ErrCode)}
+		}
+	}
+
+	return op
+}
+
+// subdomainServers returns the servers of a subdomain route,
+// the subdomain is prepended to the host of the document's servers.
+func (s *Spec) subdomainServers(subdomain string) []Server {
+	if subdomain == "" {
+		return nil
+	}
+
+	var (
+		prefix    = subdomain
+		variables map[string]ServerVariable
+	)
+
+	if subdomain == router.SubdomainWildcardIndicator {
+		prefix = "{subdomain}."
+		variables = map[string]ServerVariable{
+			"subdomain": {Default: "www", Description: "Any subdomain."},
+		}
+	}
+
+	if len(s.config.Servers) == 0 {
+		return []Server{{URL: "//" + prefix + "{host}", Variables: withHostVariable(variables)}}
+	}
+
+	servers := make([]Server, 0, len(s.config.Servers))
+	for _, u := range s.config.Servers {
+		if idx := strings.Index(u, "://"); idx != -1 {
+			u = u[:idx+3] + prefix + u[idx+3:]
+		} else {
+			u = prefix + u
+		}
+		servers = append(servers, Server{URL: u, Variables: variables})
+	}
+	return servers
+}
+
+func withHostVariable(variables map[string]ServerVariable) map[string]ServerVariable {
+	if variables == nil {
+		variables = make(map[string]ServerVariable, 1)
+	}
+	variables["host"] = ServerVariable{Default: "localhost", Description: "The host of the API."}
+	return variables
+}
+
+// JSONHandler returns a handler which serves the json document of the "routes",
+// the document is generated on the first request, when all the routes are registered.
+//
+// Usage:
+// spec.Exclude(app.Get("/swagger.json", spec.JSONHandler(app)))
+func (s *Spec) JSONHandler(routes RoutesProvider) context.Handler {
+	return s.handler(routes, contentJSONHeaderValue, (*Document).JSON)
+}
+
+// YAMLHandler returns a handler which serves the yaml document of the "routes",
+// the document is generated on the first request, when all the routes are registered.
+//
+// Usage:
+// spec.Exclude(app.Get("/swagger.yaml", spec.YAMLHandler(app)))
+func (s *Spec) YAMLHandler(routes RoutesProvider) context.Handler {
+	return s.handler(routes, "application/x-yaml", (*Document).YAML)
+}
+
+func (s *Spec) handler(routes RoutesProvider, contentType string, marshal func(*Document) ([]byte, error)) context.Handler {
+	var (
+		once sync.Once
+		body []byte
+		err  error
+	)
+
+	return func(ctx context.Context) {
+		once.Do(func() {
+			body, err = marshal(s.Document(routes.GetRoutes()))
+		})
+
+		if err != nil {
+			ctx.Application().Logger().Warnf("openapi: %v", err)
+			ctx.StatusCode(http.StatusInternalServerError)
+			return
+		}
+
+		ctx.ContentType(contentType)
+		ctx.Write(body)
+	}
+}
+
+// Description describes the operation of a route,
+// its methods can be chained, i.e
+// spec.Describe(route).Summary("Create a user").Tags("users").Request(User{}).Response(201, User{})
+type Description struct {
+	summary     string
+	description string
+	operationID string
+	tags        []string
+	deprecated  bool
+	request     interface{}
+	responses   map[int]interface{}
+}
+
+func newDescription() *Description {
+	return &Description{responses: make(map[int]interface{})}
+}
+
+// Summary sets a short summary of what the operation does.
+func (d *Description) Summary(summary string) *Description {
+	d.summary = summary
+	return d
+}
+
+// Description sets a verbose explanation of the operation.
+func (d *Description) Description(description string) *Description {
+	d.description = description
+	return d
+}
+
+// OperationID sets the unique id of the operation,
+// defaults to the route's name if it's not the default one.
+func (d *Description) OperationID(id string) *Description {
+	d.operationID = id
+	return d
+}
+
+// Tags adds tags to the operation, the tags group the operations.
+func (d *Description) Tags(tags ...string) *Description {
+	d.tags = append(d.tags, tags...)
+	return d
+}
+
+// Deprecated marks the operation as deprecated.
+func (d *Description) Deprecated() *Description {
+	d.deprecated = true
+	return d
+}
+
+// Request sets the json request body of the operation,
+// its schema is the type of the "v", i.e User{} or []User{}.
+// A *Schema "v" is used as it's.
+func (d *Description) Request(v interface{}) *Description {
+	d.request = v
+	return d
+}
+
+// Response adds a response of the operation,
+// its json schema is the type of the "v", a nil "v" means a response without body.
+// A *Schema "v" is used as it's.
+func (d *Description) Response(statusCode int, v interface{}) *Description {
+	d.responses[statusCode] = v
+	return d
+}
+
+func (d *Description) describe(op *Operation, schemas *schemaRegistry) {
+	op.Summary = d.summary
+	op.Description = d.description
+	op.Tags = d.tags
+	op.Deprecated = d.deprecated
+	if d.operationID != "" {
+		op.OperationID = d.operationID
+	}
+
+	if d.request != nil {
+		op.RequestBody = &RequestBody{
+			Content:  jsonContent(schemas.schemaOf(d.request)),
+			Required: true,
+		}
+	}
+
+	for code, v := range d.responses {
+		resp := &Response{Description: http.StatusText(code)}
+		if schema := schemas.schemaOf(v); schema != nil {
+			resp.Content = jsonContent(schema)
+		}
+		op.Responses[strconv.Itoa(code)] = resp
+	}
+}
+
+func jsonContent(schema *Schema) map[string]MediaType {
+	return map[string]MediaType{contentJSONHeaderValue: {Schema: schema}}
+}
@@ -0,0 +1,144 @@
+package openapi
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/router"
+)
+
+type testUser struct {
+	ID      int         `json:"id"`
+	Name    string      `json:"name,omitempty"`
+	Friends []*testUser `json:"friends"`
+	secret  string
+}
+
+func testHandler(ctx context.Context) {}
+
+func TestDocumentPathParameters(t *testing.T) {
+	api := router.NewAPIBuilder()
+	api.Get("/users/{id:int min(1)}/posts/{slug:string prefix(p-) max(10)}", testHandler)
+	api.Get("/files/{file:path}", testHandler)
+	api.Get("/static", testHandler)
+	api.None("/offline", testHandler)
+
+	if err := api.GetReport(); err != nil {
+		t.Fatal(err)
+	}
+
+	doc := New(Config{}).Document(api.GetRoutes())
+
+	if expected, got := 3, len(doc.Paths); expected != got {
+		t.Fatalf("expected %d paths but got %d: %v", expected, got, doc.Paths)
+	}
+
+	op := doc.Paths["/users/{id}/posts/{slug}"]["get"]
+	if op == nil {
+		t.Fatalf("expected the get operation of the users posts path but got: %v", doc.Paths)
+	}
+
+	if expected, got := 2, len(op.Parameters); expected != got {
+		t.Fatalf("expected %d parameters but got %d", expected, got)
+	}
+
+	id := op.Parameters[0]
+	if id.Name != "id" || id.In != "path" || !id.Required || id.Schema.Type != "integer" ||
+		id.Schema.Minimum == nil || *id.Schema.Minimum != 1 {
+		t.Fatalf("unexpected id parameter: %#v %#v", id, id.Schema)
+	}
+
+	slug := op.Parameters[1].Schema
+	if slug.Type != "string" || slug.Pattern != "^p-" || slug.MaxLength == nil || *slug.MaxLength != 10 {
+		t.Fatalf("unexpected slug schema: %#v", slug)
+	}
+
+	if _, ok := op.Responses["404"]; !ok {
+		t.Fatalf("expected the 404 response of the macro parameters but got: %v", op.Responses)
+	}
+
+	if op := doc.Paths["/files/{file}"]["get"]; op == nil || len(op.Parameters) != 1 {
+		t.Fatalf("expected the wildcard path parameter but got: %v", doc.Paths)
+	}
+
+	if _, ok := doc.Paths["/static"]["get"].Responses["200"]; !ok {
+		t.Fatalf("expected the default 200 response")
+	}
+}
+
+func TestDocumentDescribe(t *testing.T) {
+	api := router.NewAPIBuilder()
+	spec := New(Config{Title: "test", Servers: []string{"https://mydomain.com"}})
+
+	spec.Describe(api.Post("/users", testHandler)).
+		Summary("Create a user").
+		Tags("users").
+		Request(testUser{}).
+		Response(201, testUser{}).
+		Response(204, nil)
+
+	spec.Describe(api.Subdomain("admin.").Get("/", testHandler)).OperationID("admin")
+	spec.Exclude(api.Get("/swagger.json", spec.JSONHandler(api)))
+
+	doc := spec.Document(api.GetRoutes())
+
+	if _, ok := doc.Paths["/swagger.json"]; ok {
+		t.Fatalf("expected the excluded route to be missing")
+	}
+
+	op := doc.Paths["/users"]["post"]
+	if op.Summary != "Create a user" || len(op.Tags) != 1 || op.Tags[0] != "users" {
+		t.Fatalf("unexpected operation: %#v", op)
+	}
+
+	if ref := op.RequestBody.Content["application/json"].Schema.Ref; ref != "#/components/schemas/testUser" {
+		t.Fatalf("unexpected request schema ref: %s", ref)
+	}
+
+	if op.Responses["201"].Content == nil || op.Responses["204"].Content != nil {
+		t.Fatalf("unexpected responses: %#v", op.Responses)
+	}
+
+	user := doc.Components.Schemas["testUser"]
+	if user == nil || len(user.Properties) != 3 {
+		t.Fatalf("unexpected user schema: %#v", user)
+	}
+
+	if friends := user.Properties["friends"]; friends.Type != "array" || friends.Items.Ref != "#/components/schemas/testUser" {
+		t.Fatalf("unexpected friends schema: %#v", friends)
+	}
+
+	if expected, got := "id,friends", strings.Join(user.Required, ","); expected != got {
+		t.Fatalf("expected required fields %s but got %s", expected, got)
+	}
+
+	admin := doc.Paths["/"]["get"]
+	if admin.OperationID != "admin" || len(admin.Servers) != 1 || admin.Servers[0].URL != "https://admin.mydomain.com" {
+		t.Fatalf("unexpected subdomain operation: %#v", admin)
+	}
+
+	b, err := doc.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded map[string]interface{}
+	if err = json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	if decoded["openapi"] != Version {
+		t.Fatalf("expected openapi version %s but got %v", Version, decoded["openapi"])
+	}
+
+	y, err := doc.YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasPrefix(string(y), "openapi: "+Version) {
+		t.Fatalf("unexpected yaml document:\n%s", y)
+	}
+}
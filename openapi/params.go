@@ -0,0 +1,184 @@
+package openapi
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/get-ion/ion/core/router"
+	"github.com/get-ion/ion/core/router/macro/interpreter/ast"
+	"github.com/get-ion/ion/core/router/macro/interpreter/parser"
+)
+
+// pathOf returns the OpenAPI form of the route's path,
+// the named parameters are enclosed in curly braces, i.e "/users/:id" -> "/users/{id}".
+func pathOf(r *router.Route) string {
+	parts := strings.Split(r.Path, "/")
+	for i, part := range parts {
+		if name, ok := paramName(part); ok {
+			parts[i] = "{" + name + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func paramName(pathPart string) (string, bool) {
+	if strings.HasPrefix(pathPart, router.ParamStart) || strings.HasPrefix(pathPart, router.WildcardParamStart) {
+		return pathPart[1:], true
+	}
+	return "", false
+}
+
+// pathParameters returns the path parameters of the route,
+// their schemas are derived from the macro types and the macro funcs of the route's template,
+// i.e {id:int min(1)} is an integer with a minimum of 1.
+func pathParameters(r *router.Route) []*Parameter {
+	// the macro.Template keeps the evaluators of the macro funcs,
+	// parse its source again in order to get their names and arguments.
+	stmts, err := parser.Parse(r.Tmpl().Src)
+	if err != nil {
+		// unreachable, the route couldn't be registered.
+		stmts = nil
+	}
+
+	byName := make(map[string]*ast.ParamStatement, len(stmts))
+	for _, stmt := range stmts {
+		byName[stmt.Name] = stmt
+	}
+
+	var params []*Parameter
+	for _, part := range strings.Split(r.Path, "/") {
+		name, ok := paramName(part)
+		if !ok {
+			continue
+		}
+
+		p := &Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+		}
+
+		if stmt, ok := byName[name]; ok {
+			p.Schema = paramSchema(stmt)
+		} else {
+			p.Schema = &Schema{Type: "string"}
+		}
+
+		if strings.HasPrefix(part, router.WildcardParamStart) {
+			p.Description = "The rest of the path, it may contain slashes."
+		}
+
+		params = append(params, p)
+	}
+
+	return params
+}
+
+var (
+	alphabeticalPattern = "^[a-zA-Z]+$"
+	filePattern         = "^[a-zA-Z0-9_.-]*$"
+)
+
+// paramSchema returns the schema of a macro parameter,
+// the custom macro funcs are not described.
+func paramSchema(stmt *ast.ParamStatement) *Schema {
+	s := &Schema{Type: "string"}
+
+	switch stmt.Type {
+	case ast.ParamTypeInt:
+		s.Type = "integer"
+	case ast.ParamTypeAlphabetical:
+		s.Pattern = alphabeticalPattern
+	case ast.ParamTypeFile:
+		s.Pattern = filePattern
+	}
+
+	for _, fn := range stmt.Funcs {
+		if s.Type == "integer" {
+			describeIntFunc(s, fn)
+			continue
+		}
+		describeStringFunc(s, fn)
+	}
+
+	return s
+}
+
+func describeIntFunc(s *Schema, fn ast.ParamFunc) {
+	switch fn.Name {
+	case "min":
+		if min, ok := intArg(fn, 0); ok {
+			s.Minimum = newFloat(float64(min))
+		}
+	case "max":
+		if max, ok := intArg(fn, 0); ok {
+			s.Maximum = newFloat(float64(max))
+		}
+	case "range":
+		min, okMin := intArg(fn, 0)
+		max, okMax := intArg(fn, 1)
+		if okMin && okMax {
+			s.Minimum = newFloat(float64(min))
+			s.Maximum = newFloat(float64(max))
+		}
+	}
+}
+
+func describeStringFunc(s *Schema, fn ast.ParamFunc) {
+	switch fn.Name {
+	case "min":
+		if min, ok := intArg(fn, 0); ok {
+			s.MinLength = newInt(min)
+		}
+	case "max":
+		if max, ok := intArg(fn, 0); ok {
+			s.MaxLength = newInt(max)
+		}
+	case "regexp":
+		if expr, ok := stringArg(fn, 0); ok && expr != "" {
+			// like the regexp macro func does.
+			if last := expr[len(expr)-1]; last != '$' && last != '*' {
+				expr += "$"
+			}
+			addPattern(s, expr)
+		}
+	case "prefix":
+		if prefix, ok := stringArg(fn, 0); ok {
+			addPattern(s, "^"+regexp.QuoteMeta(prefix))
+		}
+	case "suffix":
+		if suffix, ok := stringArg(fn, 0); ok {
+			addPattern(s, regexp.QuoteMeta(suffix)+"$")
+		}
+	case "contains":
+		if sub, ok := stringArg(fn, 0); ok {
+			addPattern(s, regexp.QuoteMeta(sub))
+		}
+	}
+}
+
+// addPattern sets the pattern of the schema,
+// a schema has only one pattern so the rest of the patterns are added with "allOf".
+func addPattern(s *Schema, expr string) {
+	if s.Pattern == "" {
+		s.Pattern = expr
+		return
+	}
+	s.AllOf = append(s.AllOf, &Schema{Pattern: expr})
+}
+
+func intArg(fn ast.ParamFunc, idx int) (int, bool) {
+	if len(fn.Args) <= idx {
+		return 0, false
+	}
+	n, err := ast.ParamFuncArgToInt(fn.Args[idx])
+	return n, err == nil
+}
+
+func stringArg(fn ast.ParamFunc, idx int) (string, bool) {
+	if len(fn.Args) <= idx {
+		return "", false
+	}
+	s, ok := fn.Args[idx].(string)
+	return s, ok
+}
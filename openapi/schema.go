@@ -0,0 +1,178 @@
+package openapi
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+const componentsSchemasRef = "#/components/schemas/"
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+// schemaRegistry describes the Go types as schemas,
+// the named structs are kept as components and they are referenced by their name.
+type schemaRegistry struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+// schemaOf returns the schema of the type of "v", nil "v" returns nil.
+func (r *schemaRegistry) schemaOf(v interface{}) *Schema {
+	if v == nil {
+		return nil
+	}
+
+	if s, ok := v.(*Schema); ok {
+		// a custom schema.
+		return s
+	}
+
+	return r.schema(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) schema(typ reflect.Type) *Schema {
+	switch typ {
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case bytesType:
+		return &Schema{Type: "string", Format: "byte"}
+	}
+
+	switch typ.Kind() {
+	case reflect.Ptr:
+		return r.schema(typ.Elem())
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int, reflect.Int64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32", Minimum: newFloat(0)}
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer", Format: "int64", Minimum: newFloat(0)}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		return &Schema{Type: "array", Items: r.schema(typ.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schema(typ.Elem())}
+	case reflect.Struct:
+		if typ.Name() == "" {
+			// anonymous struct, describe it inline.
+			return r.structSchema(typ)
+		}
+		return &Schema{Ref: componentsSchemasRef + r.register(typ)}
+	}
+
+	// interface{} and the types that can't be encoded, i.e func, accept anything.
+	return &Schema{}
+}
+
+// register adds the schema of a named struct type to the components
+// and returns its component name.
+func (r *schemaRegistry) register(typ reflect.Type) string {
+	if name, ok := r.names[typ]; ok {
+		return name
+	}
+
+	name := typ.Name()
+	if _, exists := r.schemas[name]; exists {
+		// same name but different package.
+		name = strings.Replace(typ.String(), ".", "_", -1)
+	}
+
+	// register the name before its fields, a struct may contain itself.
+	r.names[typ] = name
+	r.schemas[name] = nil
+	r.schemas[name] = r.structSchema(typ)
+	return name
+}
+
+func (r *schemaRegistry) structSchema(typ reflect.Type) *Schema {
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	r.addFields(s, typ)
+	return s
+}
+
+// addFields adds the exported fields of the struct "typ" as properties of the schema "s",
+// like the encoding/json does the embedded structs' fields are promoted to the parent.
+func (r *schemaRegistry) addFields(s *Schema, typ reflect.Type) {
+	for i, n := 0, typ.NumField(); i < n; i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			// unexported.
+			continue
+		}
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts := tag, ""
+		if idx := strings.IndexByte(tag, ','); idx != -1 {
+			name, opts = tag[:idx], tag[idx+1:]
+		}
+
+		fieldTyp := field.Type
+		if field.Anonymous && name == "" {
+			if fieldTyp.Kind() == reflect.Ptr {
+				fieldTyp = fieldTyp.Elem()
+			}
+			if fieldTyp.Kind() == reflect.Struct {
+				r.addFields(s, fieldTyp)
+				continue
+			}
+			if field.PkgPath != "" {
+				continue
+			}
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		prop := r.schema(fieldTyp)
+		if desc := field.Tag.Get("description"); desc != "" && prop.Ref == "" {
+			prop.Description = desc
+		}
+		s.Properties[name] = prop
+
+		if !hasOption(opts, "omitempty") && fieldTyp.Kind() != reflect.Ptr {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+func hasOption(opts string, option string) bool {
+	for _, o := range strings.Split(opts, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func newFloat(f float64) *float64 {
+	return &f
+}
+
+func newInt(i int) *int {
+	return &i
+}
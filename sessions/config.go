@@ -0,0 +1,128 @@
+package sessions
+
+import (
+	"time"
+
+	"github.com/satori/go.uuid"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultCookieName the secret cookie's name for sessions
+	DefaultCookieName = "ionsessionid"
+)
+
+type (
+	// Encoding is the Cookie Encoder/Decoder interface, which can be passed as configuration field
+	// alternatively to the `Encode` and `Decode` fields.
+	//
+	// https://github.com/gorilla/securecookie implements it.
+	Encoding interface {
+		// Encode the cookie value if not nil.
+		// Should accept as first argument the cookie name (config.Cookie)
+		//         as second argument the server's generated session id.
+		// Should return the new session id, if error the session id setted to empty which is invalid.
+		Encode(cookieName string, value interface{}) (string, error)
+		// Decode the cookie value if not nil.
+		// Should accept as first argument the cookie name (config.Cookie)
+		//               as second second accepts the client's cookie value (the encoded session id).
+		// Should return an error if decode operation failed.
+		Decode(cookieName string, cookieValue string, v interface{}) error
+	}
+)
+
+// Config is the configuration for sessions. Please read it before using sessions.
+type Config struct {
+	// Cookie string, the session's client cookie name, for example: "mysessionid"
+	//
+	// Defaults to "ionsessionid".
+	Cookie string
+
+	// CookieSecureTLS set to true if server is running over TLS
+	// and you need the session's cookie "Secure" field to be setted true.
+	//
+	// Note: The user should fill the Decode configuation field in order for this to work.
+	// Recommendation: You don't need this to be setted to true, just fill the Encode and Decode fields
+	// with a third-party library like secure cookie, example is provided at the _examples folder.
+	//
+	// Defaults to false.
+	CookieSecureTLS bool
+
+	// Encode the cookie value if not nil.
+	// Should accept as first argument the cookie name (config.Cookie)
+	//         as second argument the server's generated session id.
+	// Should return the new session id, if error the session id setted to empty which is invalid.
+	//
+	// Note: Errors are not printed, so you have to know what you're doing,
+	// and remember: if you use AES it only supports key sizes of 16, 24 or 32 bytes.
+	// You either need to provide exactly that amount or you derive the key from what you type in.
+	//
+	// Defaults to nil.
+	Encode func(cookieName string, value interface{}) (string, error)
+	// Decode the cookie value if not nil.
+	// Should accept as first argument the cookie name (config.Cookie)
+	//               as second second accepts the client's cookie value (the encoded session id).
+	// Should return an error if decode operation failed.
+	//
+	// Note: Errors are not printed, so you have to know what you're doing,
+	// and remember: if you use AES it only supports key sizes of 16, 24 or 32 bytes.
+	// You either need to provide exactly that amount or you derive the key from what you type in.
+	//
+	// Defaults to nil.
+	Decode func(cookieName string, cookieValue string, v interface{}) error
+
+	// Encoding same as Encode and Decode but receives a single instance which
+	// completes the "Encoding" interface.
+	//
+	// Defaults to nil.
+	Encoding Encoding
+
+	// Expires the duration of which the cookie must expires (created_time.Add(Expires)).
+	// If you want to delete the cookie when the browser closes, set it to -1.
+	//
+	// 0 means no expire, (24 years)
+	// -1 means when browser closes
+	// > 0 is the time.Duration which the session cookies should expire.
+	//
+	// Defaults to 0.
+	Expires time.Duration
+
+	// SessionIDGenerator should returns a random session id.
+	// By default we will use a uuid impl package to generate
+	// that, but developers can change that with simple assignment.
+	SessionIDGenerator func() string
+
+	// DisableSubdomainPersistence set it to true in order dissallow your subdomains to have access to the session cookie
+	//
+	// Defaults to false.
+	DisableSubdomainPersistence bool
+
+	// Logger reports the errors of the session database, i.e the app.Logger().
+	//
+	// Defaults to the logrus' standard logger.
+	Logger *logrus.Logger
+}
+
+// Validate corrects missing fields configuration fields and returns the right configuration
+func (c Config) Validate() Config {
+	if c.Cookie == "" {
+		c.Cookie = DefaultCookieName
+	}
+
+	if c.SessionIDGenerator == nil {
+		c.SessionIDGenerator = func() string {
+			return uuid.NewV4().String()
+		}
+	}
+
+	if c.Encoding != nil {
+		c.Encode = c.Encoding.Encode
+		c.Decode = c.Encoding.Decode
+	}
+
+	if c.Logger == nil {
+		c.Logger = logrus.StandardLogger()
+	}
+
+	return c
+}
@@ -0,0 +1,42 @@
+package sessions
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/get-ion/ion/context"
+)
+
+var (
+	// CookieExpireDelete may be set on Cookie.Expire for expiring the given cookie.
+	CookieExpireDelete = time.Date(2009, time.November, 10, 23, 0, 0, 0, time.UTC)
+
+	// CookieExpireUnlimited indicates that the cookie doesn't expire.
+	CookieExpireUnlimited = time.Now().AddDate(24, 10, 10)
+)
+
+// GetCookie returns cookie's value by it's name
+// returns empty string if nothing was found
+func GetCookie(ctx context.Context, name string) string {
+	return ctx.GetCookie(name)
+}
+
+// AddCookie adds a cookie to the response
+// and replaces the request's cookie with the same name,
+// so the cookie is visible to the rest of the request's handlers.
+func AddCookie(ctx context.Context, cookie *http.Cookie) {
+	ctx.SetCookie(cookie)
+
+	r := ctx.Request()
+	cookies := r.Cookies()
+	values := make([]string, 0, len(cookies)+1)
+	for _, c := range cookies {
+		if c.Name == cookie.Name {
+			continue
+		}
+		values = append(values, (&http.Cookie{Name: c.Name, Value: c.Value}).String())
+	}
+	values = append(values, (&http.Cookie{Name: cookie.Name, Value: cookie.Value}).String())
+	r.Header.Set("Cookie", strings.Join(values, "; "))
+}
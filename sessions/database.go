@@ -0,0 +1,55 @@
+package sessions
+
+import (
+	"bytes"
+	"encoding/gob"
+	"time"
+)
+
+// Database is the interface which all session databases should implement.
+//
+// The sessions are always kept in memory, a database
+// makes them persistent, i.e after a server restart.
+// Look the `sessiondb/file` and `sessiondb/boltdb` packages for the implementations
+// that come with ion.
+type Database interface {
+	// Load returns the stored record of the "sid" session.
+	// A missing session should return an empty `Record` and a nil error.
+	Load(sid string) (Record, error)
+	// Sync saves the record of the "sid" session,
+	// it's called every time a session's value or its expiration time is changed.
+	Sync(sid string, record Record) error
+	// Destroy removes the "sid" session from the database.
+	Destroy(sid string) error
+}
+
+// Record is the form of a session that a `Database` stores.
+type Record struct {
+	// Values are the session's key-value entries.
+	// Custom value types should be registered through `gob.Register`
+	// in order to be encoded by the `Record#Marshal`.
+	Values map[string]interface{}
+	// Expires is the time that the session expires,
+	// zero means that the session never expires.
+	Expires time.Time
+}
+
+// IsExpired reports whether the record's expiration time has passed.
+func (r Record) IsExpired() bool {
+	return !r.Expires.IsZero() && time.Now().After(r.Expires)
+}
+
+// Marshal returns the gob encoding of the record,
+// it's the format that the built'n databases are using to store a session.
+func (r Record) Marshal() ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(r)
+	return buf.Bytes(), err
+}
+
+// UnmarshalRecord decodes a record which was encoded by the `Record#Marshal`.
+func UnmarshalRecord(b []byte) (Record, error) {
+	var r Record
+	err := gob.NewDecoder(bytes.NewReader(b)).Decode(&r)
+	return r, err
+}
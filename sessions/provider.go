@@ -0,0 +1,264 @@
+package sessions
+
+import (
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+// provider contains the sessions that are kept in memory
+// and, optionally, the database that makes them persistent.
+type provider struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+	db       Database
+	// logger is used to report the database errors, see `Config#Logger`.
+	logger *logrus.Logger
+}
+
+// newProvider returns a new sessions provider
+func newProvider(logger *logrus.Logger) *provider {
+	return &provider{
+		sessions: make(map[string]*Session, 0),
+		logger:   logger,
+	}
+}
+
+// RegisterDatabase sets the database which will be used to load and sync the sessions.
+func (p *provider) RegisterDatabase(db Database) {
+	p.mu.Lock()
+	p.db = db
+	p.mu.Unlock()
+}
+
+func (p *provider) reportError(sid string, err error) {
+	p.logger.Warnf("sessions: database error for session '%s': %v", sid, err)
+}
+
+// newSession returns a new session which is kept in memory by the caller.
+func (p *provider) newSession(sid string, expires time.Duration) *Session {
+	s := &Session{
+		sid:      sid,
+		provider: p,
+		isNew:    true,
+		stored:   true,
+	}
+	p.setExpiration(s, expires)
+	return s
+}
+
+// setExpiration resets the lifetime of a session,
+// when it expires it is removed from the memory and the database.
+func (p *provider) setExpiration(s *Session, expires time.Duration) {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.expires = time.Time{}
+
+	if expires > 0 {
+		s.expires = time.Now().Add(expires)
+		sid := s.sid
+		s.timer = time.AfterFunc(expires, func() {
+			p.Destroy(sid)
+		})
+	}
+	s.mu.Unlock()
+}
+
+// Init creates a new session, it's stored on its first value, see `store`,
+// so the requests which never set a value, i.e the bots, don't fill the memory.
+func (p *provider) Init(sid string, expires time.Duration) *Session {
+	s := &Session{
+		sid:      sid,
+		provider: p,
+		isNew:    true,
+	}
+	if expires > 0 {
+		s.expires = time.Now().Add(expires)
+	}
+	return s
+}
+
+// store keeps a new session in memory and starts its expiration timer, once.
+func (p *provider) store(s *Session) {
+	s.mu.Lock()
+	if s.stored {
+		s.mu.Unlock()
+		return
+	}
+	s.stored = true
+	if !s.expires.IsZero() {
+		sid := s.sid
+		s.timer = time.AfterFunc(s.expires.Sub(time.Now()), func() {
+			p.Destroy(sid)
+		})
+	}
+	s.mu.Unlock()
+
+	p.mu.Lock()
+	p.sessions[s.sid] = s
+	p.mu.Unlock()
+}
+
+// Read returns the "sid" session from the memory or the database,
+// it returns false if the session does not exist or has been expired.
+func (p *provider) Read(sid string) (*Session, bool) {
+	p.mu.RLock()
+	s, found := p.sessions[sid]
+	db := p.db
+	p.mu.RUnlock()
+
+	if found {
+		s.mu.Lock()
+		s.isNew = false
+		s.mu.Unlock()
+		return s, true
+	}
+
+	if db == nil {
+		return nil, false
+	}
+
+	record, err := db.Load(sid)
+	if err != nil {
+		p.reportError(sid, err)
+		return nil, false
+	}
+
+	if record.Values == nil && record.Expires.IsZero() {
+		return nil, false
+	}
+
+	if record.IsExpired() {
+		if err = db.Destroy(sid); err != nil {
+			p.reportError(sid, err)
+		}
+		return nil, false
+	}
+
+	var expires time.Duration
+	if !record.Expires.IsZero() {
+		expires = record.Expires.Sub(time.Now())
+	}
+
+	s = p.newSession(sid, expires)
+	s.isNew = false
+	for k, v := range record.Values {
+		s.values.Set(k, v)
+	}
+
+	p.mu.Lock()
+	// another request may loaded it meanwhile.
+	if loaded, ok := p.sessions[sid]; ok {
+		p.mu.Unlock()
+		s.stopTimer()
+		return loaded, true
+	}
+	p.sessions[sid] = s
+	p.mu.Unlock()
+
+	return s, true
+}
+
+// UpdateExpiration resets the expiration time of a session.
+func (p *provider) UpdateExpiration(s *Session, expires time.Duration) {
+	p.setExpiration(s, expires)
+	p.sync(s)
+}
+
+// Rotate moves the values and the lifetime of the "s" session
+// to a new session with id "sid" and destroys the "s".
+func (p *provider) Rotate(s *Session, sid string) *Session {
+	s.mu.RLock()
+	var expires time.Duration
+	if !s.expires.IsZero() {
+		expires = s.expires.Sub(time.Now())
+	}
+	values := make([]valueEntry, 0, s.values.Len())
+	s.values.Visit(func(k string, v interface{}) {
+		values = append(values, valueEntry{k, v})
+	})
+	s.mu.RUnlock()
+
+	p.Destroy(s.sid)
+
+	rotated := p.newSession(sid, expires)
+	rotated.isNew = false
+	for _, entry := range values {
+		rotated.values.Set(entry.key, entry.value)
+	}
+
+	p.mu.Lock()
+	p.sessions[sid] = rotated
+	p.mu.Unlock()
+
+	p.sync(rotated)
+	return rotated
+}
+
+type valueEntry struct {
+	key   string
+	value interface{}
+}
+
+// Destroy removes the "sid" session from the memory and the database.
+func (p *provider) Destroy(sid string) {
+	p.mu.Lock()
+	s, found := p.sessions[sid]
+	if found {
+		delete(p.sessions, sid)
+	}
+	db := p.db
+	p.mu.Unlock()
+
+	if found {
+		s.stopTimer()
+	}
+
+	if db != nil {
+		if err := db.Destroy(sid); err != nil {
+			p.reportError(sid, err)
+		}
+	}
+}
+
+// DestroyAll removes all sessions from the memory and the database.
+func (p *provider) DestroyAll() {
+	p.mu.RLock()
+	sids := make([]string, 0, len(p.sessions))
+	for sid := range p.sessions {
+		sids = append(sids, sid)
+	}
+	p.mu.RUnlock()
+
+	for _, sid := range sids {
+		p.Destroy(sid)
+	}
+}
+
+// Len returns the number of the sessions that are kept in memory.
+func (p *provider) Len() int {
+	p.mu.RLock()
+	n := len(p.sessions)
+	p.mu.RUnlock()
+	return n
+}
+
+// sync saves the current state of the "s" session to the database, if any.
+func (p *provider) sync(s *Session) {
+	p.mu.RLock()
+	db := p.db
+	_, alive := p.sessions[s.sid]
+	p.mu.RUnlock()
+
+	if db == nil || !alive {
+		return
+	}
+
+	if err := db.Sync(s.sid, s.record()); err != nil {
+		p.reportError(s.sid, err)
+	}
+}
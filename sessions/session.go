@@ -0,0 +1,218 @@
+package sessions
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/get-ion/ion/core/errors"
+	"github.com/get-ion/ion/core/memstore"
+)
+
+// Session should expose the Sessions's end-user API.
+// It is the session's storage controller which you can
+// save or retrieve values based on a key.
+//
+// This is what will be returned when sess := sessions.Start().
+type Session struct {
+	sid      string
+	isNew    bool
+	values   memstore.Store
+	expires  time.Time
+	timer    *time.Timer
+	stored   bool // kept in memory, a new session is stored on its first value.
+	mu       sync.RWMutex
+	provider *provider
+}
+
+// ID returns the session's ID.
+func (s *Session) ID() string {
+	return s.sid
+}
+
+// IsNew returns true if the session was created by the current request.
+func (s *Session) IsNew() bool {
+	s.mu.RLock()
+	isNew := s.isNew
+	s.mu.RUnlock()
+	return isNew
+}
+
+// Expires returns the time that the session expires,
+// zero time means that the session never expires.
+func (s *Session) Expires() time.Time {
+	s.mu.RLock()
+	expires := s.expires
+	s.mu.RUnlock()
+	return expires
+}
+
+// Get returns a value based on its "key".
+func (s *Session) Get(key string) interface{} {
+	s.mu.RLock()
+	value := s.values.Get(key)
+	s.mu.RUnlock()
+	return value
+}
+
+// GetString same as Get but returns as string, if nil then returns an empty string.
+func (s *Session) GetString(key string) string {
+	if value := s.Get(key); value != nil {
+		if v, ok := value.(string); ok {
+			return v
+		}
+
+		if v, ok := value.(int); ok {
+			return strconv.Itoa(v)
+		}
+	}
+
+	return ""
+}
+
+var errFindParse = errors.New("unable to find the %s with key: %s. Found? %#v")
+
+// GetInt same as Get but returns as int, if not found then returns -1 and an error.
+func (s *Session) GetInt(key string) (int, error) {
+	v := s.Get(key)
+
+	if vint, ok := v.(int); ok {
+		return vint, nil
+	}
+
+	if vstring, ok := v.(string); ok {
+		return strconv.Atoi(vstring)
+	}
+
+	return -1, errFindParse.Format("int", key, v)
+}
+
+// GetInt64 same as Get but returns as int64, if not found then returns -1 and an error.
+func (s *Session) GetInt64(key string) (int64, error) {
+	v := s.Get(key)
+
+	if vint64, ok := v.(int64); ok {
+		return vint64, nil
+	}
+
+	if vint, ok := v.(int); ok {
+		return int64(vint), nil
+	}
+
+	if vstring, ok := v.(string); ok {
+		return strconv.ParseInt(vstring, 10, 64)
+	}
+
+	return -1, errFindParse.Format("int64", key, v)
+}
+
+// GetBoolean same as Get but returns as boolean, if not found then returns false and an error.
+func (s *Session) GetBoolean(key string) (bool, error) {
+	v := s.Get(key)
+
+	if vb, ok := v.(bool); ok {
+		return vb, nil
+	}
+
+	if vstring, ok := v.(string); ok {
+		return strconv.ParseBool(vstring)
+	}
+
+	return false, errFindParse.Format("bool", key, v)
+}
+
+// GetAll returns a copy of all session's values.
+func (s *Session) GetAll() map[string]interface{} {
+	items := make(map[string]interface{}, s.Len())
+	s.Visit(func(key string, value interface{}) {
+		items[key] = value
+	})
+	return items
+}
+
+// Visit loops each of the entries and calls the callback function func(key, value).
+func (s *Session) Visit(cb func(key string, value interface{})) {
+	s.mu.RLock()
+	s.values.Visit(cb)
+	s.mu.RUnlock()
+}
+
+// Len returns the total number of stored values in this session.
+func (s *Session) Len() int {
+	s.mu.RLock()
+	n := s.values.Len()
+	s.mu.RUnlock()
+	return n
+}
+
+func (s *Session) set(key string, value interface{}, immutable bool) {
+	s.mu.Lock()
+	if immutable {
+		s.values.SetImmutable(key, value)
+	} else {
+		s.values.Set(key, value)
+	}
+	s.mu.Unlock()
+
+	s.provider.store(s)
+	s.provider.sync(s)
+}
+
+// Set fills the session with an entry "value", based on its "key".
+func (s *Session) Set(key string, value interface{}) {
+	s.set(key, value, false)
+}
+
+// SetImmutable fills the session with an entry "value", based on its "key".
+// Unlike `Set`, the output value cannot be changed by the caller later on (when .Get)
+// An Immutable entry should be only changed with a `SetImmutable`, simple `Set` will not work
+// if the entry was immutable, for your own safety.
+// Use it consistently, it's far slower than `Set`.
+func (s *Session) SetImmutable(key string, value interface{}) {
+	s.set(key, value, true)
+}
+
+// Delete removes an entry by its key,
+// returns true if actually something was removed.
+func (s *Session) Delete(key string) bool {
+	s.mu.Lock()
+	removed := s.values.Remove(key)
+	s.mu.Unlock()
+
+	if removed {
+		s.provider.sync(s)
+	}
+	return removed
+}
+
+// Clear removes all entries.
+func (s *Session) Clear() {
+	s.mu.Lock()
+	s.values.Reset()
+	s.mu.Unlock()
+
+	s.provider.sync(s)
+}
+
+// record returns the persistent form of the session.
+func (s *Session) record() Record {
+	s.mu.RLock()
+	r := Record{
+		Values:  make(map[string]interface{}, s.values.Len()),
+		Expires: s.expires,
+	}
+	s.values.Visit(func(key string, value interface{}) {
+		r.Values[key] = value
+	})
+	s.mu.RUnlock()
+	return r
+}
+
+func (s *Session) stopTimer() {
+	s.mu.Lock()
+	if s.timer != nil {
+		s.timer.Stop()
+		s.timer = nil
+	}
+	s.mu.Unlock()
+}
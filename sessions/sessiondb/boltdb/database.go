@@ -0,0 +1,135 @@
+// Package boltdb provides a session database based on the BoltDB key/value store.
+package boltdb
+
+import (
+	"os"
+
+	"github.com/get-ion/ion/core/errors"
+	"github.com/get-ion/ion/sessions"
+	bolt "go.etcd.io/bbolt"
+)
+
+// DefaultFileMode used as the default database's "fileMode"
+// for creating the database file.
+var DefaultFileMode = 0600
+
+// DefaultBucketName is the default bucket name that the sessions are stored.
+const DefaultBucketName = "sessions"
+
+// Database the BoltDB(file-based) session storage.
+type Database struct {
+	// Service is the underline BoltDB database connection,
+	// it's initialized at `New` or `NewFromDB`.
+	// Can be used to get stats.
+	Service *bolt.DB
+	bucket  []byte
+}
+
+var errPathMissing = errors.New("sessiondb/boltdb: path is required")
+
+// New creates and returns a new BoltDB(file-based) storage
+// instance based on the "path".
+// Path should include the filename and the directory(aka fullpath), i.e sessions/store.db.
+//
+// The expired sessions that were left from a previous run are removed.
+func New(path string, fileMode os.FileMode, bucketName string) (*Database, error) {
+	if path == "" {
+		return nil, errPathMissing
+	}
+
+	if fileMode <= 0 {
+		fileMode = os.FileMode(DefaultFileMode)
+	}
+
+	service, err := bolt.Open(path, fileMode, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewFromDB(service, bucketName)
+}
+
+// NewFromDB same as `New` but accepts an already-created custom boltdb connection instead.
+func NewFromDB(service *bolt.DB, bucketName string) (*Database, error) {
+	if bucketName == "" {
+		bucketName = DefaultBucketName
+	}
+
+	bucket := []byte(bucketName)
+
+	err := service.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	db := &Database{Service: service, bucket: bucket}
+	return db, db.Cleanup()
+}
+
+// Cleanup removes any invalid(have expired) session entries,
+// it's being called automatically on `New`.
+func (db *Database) Cleanup() error {
+	return db.Service.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(db.bucket)
+		var expired [][]byte
+
+		err := b.ForEach(func(k, v []byte) error {
+			if record, err := sessions.UnmarshalRecord(v); err != nil || record.IsExpired() {
+				expired = append(expired, k)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, k := range expired {
+			if err = b.Delete(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Load loads the values and the expiration time of the "sid" session.
+func (db *Database) Load(sid string) (record sessions.Record, err error) {
+	err = db.Service.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(db.bucket).Get([]byte(sid))
+		if v == nil {
+			return nil
+		}
+
+		record, err = sessions.UnmarshalRecord(v)
+		return err
+	})
+
+	return
+}
+
+// Sync saves the values and the expiration time of the "sid" session.
+func (db *Database) Sync(sid string, record sessions.Record) error {
+	v, err := record.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return db.Service.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(db.bucket).Put([]byte(sid), v)
+	})
+}
+
+// Destroy removes the "sid" session from the database.
+func (db *Database) Destroy(sid string) error {
+	return db.Service.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(db.bucket).Delete([]byte(sid))
+	})
+}
+
+// Close shutdowns the BoltDB connection.
+func (db *Database) Close() error {
+	return db.Service.Close()
+}
@@ -0,0 +1,94 @@
+// black-box testing
+package boltdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion/sessions"
+	"github.com/get-ion/ion/sessions/sessiondb/boltdb"
+)
+
+func expectRecord(t *testing.T, db sessions.Database, sid string, expected sessions.Record) {
+	got, err := db.Load(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Values, expected.Values) || !got.Expires.Equal(expected.Expires) {
+		t.Fatalf("expected the record %#v of the session '%s' but got %#v", expected, sid, got)
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessiondb-boltdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sessions.db")
+
+	db, err := boltdb.New(path, 0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	record := sessions.Record{
+		Values:  map[string]interface{}{"name": "ion", "visits": 2},
+		Expires: time.Now().Add(time.Hour),
+	}
+	if err = db.Sync("sid", record); err != nil {
+		t.Fatal(err)
+	}
+	expectRecord(t, db, "sid", record)
+	// a missing session is an empty record.
+	expectRecord(t, db, "missing", sessions.Record{})
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("expected the file mode 0600 but got %v %v", info.Mode().Perm(), err)
+	}
+
+	if err = db.Destroy("sid"); err != nil {
+		t.Fatal(err)
+	}
+	expectRecord(t, db, "sid", sessions.Record{})
+}
+
+func TestDatabaseExpired(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessiondb-boltdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "sessions.db")
+
+	db, err := boltdb.New(path, 0, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := sessions.Record{Values: map[string]interface{}{"name": "active"}, Expires: time.Now().Add(time.Hour)}
+	forever := sessions.Record{Values: map[string]interface{}{"name": "forever"}}
+	expired := sessions.Record{Values: map[string]interface{}{"name": "expired"}, Expires: time.Now().Add(-time.Second)}
+	for sid, record := range map[string]sessions.Record{"active": active, "forever": forever, "expired": expired} {
+		if err = db.Sync(sid, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.Close()
+
+	// a restart removes the expired sessions.
+	db, err = boltdb.New(path, 0, "users")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	expectRecord(t, db, "active", active)
+	expectRecord(t, db, "forever", forever)
+	expectRecord(t, db, "expired", sessions.Record{})
+}
@@ -0,0 +1,125 @@
+// Package file provides a file-system session database,
+// each session is stored in its own file inside a directory.
+package file
+
+import (
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/get-ion/ion/core/errors"
+	"github.com/get-ion/ion/sessions"
+)
+
+// DefaultFileMode used as the default database's "fileMode"
+// for creating the session files, only the owner can read and write them.
+// The sessions directory takes the same mode, plus the execute permission of the readers.
+var DefaultFileMode = 0600
+
+// Database the file-system session database.
+type Database struct {
+	dir      string
+	fileMode os.FileMode
+	mu       sync.RWMutex
+}
+
+var errInvalidDirectory = errors.New("sessiondb/file: unable to create the sessions directory '%s'")
+
+// New returns a new file-system session database
+// which stores the sessions as files inside the "directoryPath",
+// the directory is created if it does not exist.
+//
+// The expired sessions that were left from a previous run are removed.
+func New(directoryPath string, fileMode os.FileMode) (*Database, error) {
+	if fileMode <= 0 {
+		fileMode = os.FileMode(DefaultFileMode)
+	}
+
+	// the directory should be searchable by the readers of its files, i.e 0700 for 0600.
+	if err := os.MkdirAll(directoryPath, fileMode|(fileMode&0444)>>2); err != nil {
+		return nil, errInvalidDirectory.Format(directoryPath).AppendErr(err)
+	}
+
+	db := &Database{dir: directoryPath, fileMode: fileMode}
+	return db, db.Cleanup()
+}
+
+// Cleanup removes the expired and the corrupted session files of the database directory.
+func (db *Database) Cleanup() error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	files, err := ioutil.ReadDir(db.dir)
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+
+		filename := filepath.Join(db.dir, f.Name())
+		b, err := ioutil.ReadFile(filename)
+		if err != nil {
+			return err
+		}
+
+		if record, err := sessions.UnmarshalRecord(b); err != nil || record.IsExpired() {
+			if err = os.Remove(filename); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// sessPath returns the file path of the "sid" session,
+// the session id is hex-encoded because it's coming from the client's cookie.
+func (db *Database) sessPath(sid string) string {
+	return filepath.Join(db.dir, hex.EncodeToString([]byte(sid)))
+}
+
+// Load loads the values and the expiration time of the "sid" session.
+func (db *Database) Load(sid string) (sessions.Record, error) {
+	db.mu.RLock()
+	b, err := ioutil.ReadFile(db.sessPath(sid))
+	db.mu.RUnlock()
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return sessions.Record{}, nil
+		}
+		return sessions.Record{}, err
+	}
+
+	return sessions.UnmarshalRecord(b)
+}
+
+// Sync saves the values and the expiration time of the "sid" session to its file.
+func (db *Database) Sync(sid string, record sessions.Record) error {
+	b, err := record.Marshal()
+	if err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	err = ioutil.WriteFile(db.sessPath(sid), b, db.fileMode)
+	db.mu.Unlock()
+	return err
+}
+
+// Destroy removes the file of the "sid" session.
+func (db *Database) Destroy(sid string) error {
+	db.mu.Lock()
+	err := os.Remove(db.sessPath(sid))
+	db.mu.Unlock()
+
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
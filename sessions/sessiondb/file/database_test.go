@@ -0,0 +1,107 @@
+// black-box testing
+package file_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/get-ion/ion/sessions"
+	"github.com/get-ion/ion/sessions/sessiondb/file"
+)
+
+func expectRecord(t *testing.T, db sessions.Database, sid string, expected sessions.Record) {
+	got, err := db.Load(sid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Values, expected.Values) || !got.Expires.Equal(expected.Expires) {
+		t.Fatalf("expected the record %#v of the session '%s' but got %#v", expected, sid, got)
+	}
+}
+
+func TestDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessiondb-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dir = filepath.Join(dir, "sessions")
+
+	db, err := file.New(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	record := sessions.Record{
+		Values:  map[string]interface{}{"name": "ion", "visits": 2},
+		Expires: time.Now().Add(time.Hour),
+	}
+	if err = db.Sync("sid", record); err != nil {
+		t.Fatal(err)
+	}
+	expectRecord(t, db, "sid", record)
+	// a missing session is an empty record.
+	expectRecord(t, db, "missing", sessions.Record{})
+
+	// only the owner can access the sessions.
+	if info, err := os.Stat(dir); err != nil || info.Mode().Perm() != 0700 {
+		t.Fatalf("expected the directory mode 0700 but got %v %v", info.Mode().Perm(), err)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 || files[0].Mode().Perm() != 0600 {
+		t.Fatalf("expected one session file with mode 0600 but got %v", files)
+	}
+
+	// the id of the client's cookie can't point outside of the directory.
+	if err = db.Sync("../escaped", record); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(dir, "..", "escaped")); !os.IsNotExist(err) {
+		t.Fatalf("expected the session file to be inside the directory")
+	}
+
+	if err = db.Destroy("sid"); err != nil {
+		t.Fatal(err)
+	}
+	expectRecord(t, db, "sid", sessions.Record{})
+}
+
+func TestDatabaseExpired(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sessiondb-file")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := file.New(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	active := sessions.Record{Values: map[string]interface{}{"name": "active"}, Expires: time.Now().Add(time.Hour)}
+	forever := sessions.Record{Values: map[string]interface{}{"name": "forever"}}
+	expired := sessions.Record{Values: map[string]interface{}{"name": "expired"}, Expires: time.Now().Add(-time.Second)}
+	for sid, record := range map[string]sessions.Record{"active": active, "forever": forever, "expired": expired} {
+		if err = db.Sync(sid, record); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ioutil.WriteFile(filepath.Join(dir, "corrupted"), []byte("corrupted"), 0600)
+
+	// a restart removes the expired and the corrupted sessions.
+	db, err = file.New(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectRecord(t, db, "active", active)
+	expectRecord(t, db, "forever", forever)
+	expectRecord(t, db, "expired", sessions.Record{})
+	if files, _ := ioutil.ReadDir(dir); len(files) != 2 {
+		t.Fatalf("expected the files of the 2 valid sessions but got %d", len(files))
+	}
+}
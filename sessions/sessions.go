@@ -0,0 +1,244 @@
+// Package sessions provides server-side sessions which are identified by a client cookie.
+//
+// Sessions are kept in memory, a `Database` can be registered
+// to make them persistent, see the `sessiondb/file` and `sessiondb/boltdb` packages.
+// See _examples/sessions
+package sessions
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/get-ion/ion/context"
+)
+
+// Sessions is the sessions manager, it starts, rotates and destroys
+// the session of a client based on the session cookie of the request.
+type Sessions struct {
+	config   Config
+	provider *provider
+}
+
+// New returns a new sessions manager based on the "cfg" configuration.
+func New(cfg Config) *Sessions {
+	c := cfg.Validate()
+	return &Sessions{
+		config:   c,
+		provider: newProvider(c.Logger),
+	}
+}
+
+// UseDatabase registers a session database to the manager's provider,
+// the sessions are loaded from and synced to that database.
+func (s *Sessions) UseDatabase(db Database) {
+	s.provider.RegisterDatabase(db)
+}
+
+// Start should start the session for the particular request.
+//
+// A new session is kept in memory, and in the database, after its first value is set,
+// the sessions without values, i.e of the bots, are not kept.
+func (s *Sessions) Start(ctx context.Context) *Session {
+	if sid := s.decodeCookieValue(GetCookie(ctx, s.config.Cookie)); sid != "" {
+		// the new session of the current request, it may not be stored yet.
+		if sess, ok := ctx.Values().Get(s.contextKey()).(*Session); ok && sess.ID() == sid {
+			return sess
+		}
+
+		if sess, found := s.provider.Read(sid); found {
+			return sess
+		}
+	}
+
+	// the client has no session or it has been expired or destroyed,
+	// always generate a new id, never accept a session id which the server didn't give.
+	sid := s.config.SessionIDGenerator()
+	sess := s.provider.Init(sid, s.config.Expires)
+	ctx.Values().Set(s.contextKey(), sess)
+	s.updateCookie(ctx, sid, s.config.Expires)
+	return sess
+}
+
+// contextKey returns the key of the context's values which keeps the new session of a request.
+func (s *Sessions) contextKey() string {
+	return "sessions." + s.config.Cookie
+}
+
+// ShiftExpiration move the expire date of a session to a new date
+// by using session default timeout configuration.
+func (s *Sessions) ShiftExpiration(ctx context.Context) {
+	s.UpdateExpiration(ctx, s.config.Expires)
+}
+
+// UpdateExpiration change expire date of a session to a new date
+// by using timeout value passed by `expires` receiver.
+func (s *Sessions) UpdateExpiration(ctx context.Context, expires time.Duration) {
+	sid := s.decodeCookieValue(GetCookie(ctx, s.config.Cookie))
+	if sid == "" {
+		return
+	}
+
+	if sess, found := s.provider.Read(sid); found {
+		s.provider.UpdateExpiration(sess, expires)
+		s.updateCookie(ctx, sid, expires)
+	}
+}
+
+// Rotate gives a new session id to the client's session, the values
+// and the expiration time are kept, the previous id is destroyed.
+// Rotate the session id every time that the privilege level of a client changes,
+// i.e after a successful login, to protect against session fixation.
+//
+// Returns the rotated session.
+func (s *Sessions) Rotate(ctx context.Context) *Session {
+	sess := s.Start(ctx)
+	sid := s.config.SessionIDGenerator()
+
+	rotated := s.provider.Rotate(sess, sid)
+	s.updateCookie(ctx, sid, s.config.Expires)
+	return rotated
+}
+
+// Destroy remove the session data and remove the associated cookie.
+func (s *Sessions) Destroy(ctx context.Context) {
+	cookieValue := GetCookie(ctx, s.config.Cookie)
+	// decode the client's cookie value in order to find the server's session id
+	// to destroy the session data.
+	cookieValue = s.decodeCookieValue(cookieValue)
+	if cookieValue == "" { // nothing to destroy
+		return
+	}
+	s.removeCookie(ctx)
+
+	s.provider.Destroy(cookieValue)
+}
+
+// DestroyByID removes the session entry
+// from the server-side memory (and database if registered).
+// Client's session cookie will still exist but it will be reseted on the next request.
+//
+// It's safe to use it even if you are not sure if a session with that id exists.
+//
+// Note: the sid should be the original one (i.e: fetched by a store )
+// it's not decoded.
+func (s *Sessions) DestroyByID(sid string) {
+	s.provider.Destroy(sid)
+}
+
+// DestroyAll removes all sessions
+// from the server-side memory (and database if registered).
+// Client's session cookie will still exist but it will be reseted on the next request.
+func (s *Sessions) DestroyAll() {
+	s.provider.DestroyAll()
+}
+
+// Len returns the number of the active sessions.
+func (s *Sessions) Len() int {
+	return s.provider.Len()
+}
+
+// updateCookie sets the session cookie to the client
+// and to the current request, so the next `Start` of the same request will find it.
+func (s *Sessions) updateCookie(ctx context.Context, sid string, expires time.Duration) {
+	cookie := s.newCookie(ctx, sid)
+
+	// MaxAge=0 means no 'Max-Age' attribute specified.
+	// MaxAge<0 means delete cookie now, equivalently 'Max-Age: 0'.
+	// MaxAge>0 means Max-Age attribute present and given in seconds
+	if expires >= 0 {
+		if expires == 0 { // unlimited life
+			cookie.Expires = CookieExpireUnlimited
+		} else { // > 0
+			cookie.Expires = time.Now().Add(expires)
+		}
+		cookie.MaxAge = int(cookie.Expires.Sub(time.Now()).Seconds())
+	}
+
+	// set the cookie to secure if this is a tls wrapped request
+	// and the configuration allows it.
+	if ctx.Request().TLS != nil && s.config.CookieSecureTLS {
+		cookie.Secure = true
+	}
+
+	// encode the session id cookie client value right before send it.
+	cookie.Value = s.encodeCookieValue(cookie.Value)
+	AddCookie(ctx, cookie)
+}
+
+func (s *Sessions) newCookie(ctx context.Context, value string) *http.Cookie {
+	cookie := &http.Cookie{
+		Name:     s.config.Cookie,
+		Value:    value,
+		Path:     "/",
+		HttpOnly: true,
+	}
+
+	if !s.config.DisableSubdomainPersistence {
+		cookie.Domain = cookieDomain(ctx.Host())
+	}
+
+	return cookie
+}
+
+// removeCookie expires the session cookie of the client.
+func (s *Sessions) removeCookie(ctx context.Context) {
+	cookie := s.newCookie(ctx, "")
+	cookie.Expires = CookieExpireDelete
+	cookie.MaxAge = -1
+	AddCookie(ctx, cookie)
+}
+
+// cookieDomain returns the domain that the session cookie should be valid for,
+// in order to be visible from the subdomains too, or empty if the host is an ip or a local host.
+func cookieDomain(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	if host == "localhost" || net.ParseIP(host) != nil {
+		return ""
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) < 2 {
+		return ""
+	}
+
+	// RFC2109, allow the first level of subdomains:
+	// mysubdomain.mydomain.com -> .mydomain.com
+	return "." + strings.Join(parts[len(parts)-2:], ".")
+}
+
+// decodeCookieValue returns the decoded value of the session cookie
+// or empty string if the cookie is missing or the decode failed.
+func (s *Sessions) decodeCookieValue(cookieValue string) string {
+	if cookieValue == "" {
+		return ""
+	}
+
+	if decode := s.config.Decode; decode != nil {
+		var cookieValueDecoded string
+		if err := decode(s.config.Cookie, cookieValue, &cookieValueDecoded); err != nil {
+			return ""
+		}
+		return cookieValueDecoded
+	}
+
+	return cookieValue
+}
+
+// encodeCookieValue returns the encoded value of the session id
+// if an encoder is configured.
+func (s *Sessions) encodeCookieValue(value string) string {
+	if encode := s.config.Encode; encode != nil {
+		newVal, err := encode(s.config.Cookie, value)
+		if err == nil {
+			return newVal
+		}
+		return ""
+	}
+
+	return value
+}
@@ -0,0 +1,117 @@
+package websocket
+
+import (
+	"github.com/get-ion/ion/context"
+)
+
+// ClientHandler is the handler which serves the javascript client-side
+// library, it's the client side of the event messages, i.e:
+// app.Any("/ion-ws.js", websocket.ClientHandler())
+//
+//	var socket = new Ws("ws://localhost:8080/my_endpoint");
+//	socket.OnConnect(function () { socket.Emit("chat", "Hello"); });
+//	socket.On("chat", function (msg) { console.log(msg); });
+func ClientHandler() context.Handler {
+	return func(ctx context.Context) {
+		ctx.ContentType("application/javascript")
+		ctx.Write(ClientSource)
+	}
+}
+
+// ClientSource the client-side javascript raw source code.
+var ClientSource = []byte(`var Ws = (function () {
+    function Ws(endpoint, protocols) {
+        var _this = this;
+        this.connectListeners = [];
+        this.disconnectListeners = [];
+        this.nativeMessageListeners = [];
+        this.messageListeners = {};
+        if (!window["WebSocket"]) {
+            return;
+        }
+        if (endpoint.indexOf("ws") == -1) {
+            endpoint = "ws://" + endpoint;
+        }
+        if (protocols != null && protocols.length > 0) {
+            this.conn = new WebSocket(endpoint, protocols);
+        } else {
+            this.conn = new WebSocket(endpoint);
+        }
+        this.conn.onopen = (function (evt) {
+            _this.fireConnect();
+            _this.isReady = true;
+            return null;
+        });
+        this.conn.onclose = (function (evt) {
+            _this.fireDisconnect();
+            return null;
+        });
+        this.conn.onmessage = (function (evt) {
+            _this.messageReceived(evt.data);
+        });
+    }
+    Ws.prototype.messageReceived = function (data) {
+        var msg;
+        try {
+            msg = JSON.parse(data);
+        } catch (e) {
+            msg = null;
+        }
+        if (msg === null || typeof msg !== "object" || !msg.event) {
+            for (var i = 0; i < this.nativeMessageListeners.length; i++) {
+                this.nativeMessageListeners[i](data);
+            }
+            return;
+        }
+        var listeners = this.messageListeners[msg.event];
+        if (listeners === undefined) {
+            return;
+        }
+        for (var j = 0; j < listeners.length; j++) {
+            listeners[j](msg.data);
+        }
+    };
+    Ws.prototype.OnConnect = function (fn) {
+        if (this.isReady) {
+            fn();
+        }
+        this.connectListeners.push(fn);
+    };
+    Ws.prototype.fireConnect = function () {
+        for (var i = 0; i < this.connectListeners.length; i++) {
+            this.connectListeners[i]();
+        }
+    };
+    Ws.prototype.OnDisconnect = function (fn) {
+        this.disconnectListeners.push(fn);
+    };
+    Ws.prototype.fireDisconnect = function () {
+        for (var i = 0; i < this.disconnectListeners.length; i++) {
+            this.disconnectListeners[i]();
+        }
+    };
+    Ws.prototype.OnMessage = function (cb) {
+        this.nativeMessageListeners.push(cb);
+    };
+    Ws.prototype.EmitMessage = function (websocketMessage) {
+        this.conn.send(websocketMessage);
+    };
+    Ws.prototype.On = function (event, cb) {
+        if (this.messageListeners[event] === undefined) {
+            this.messageListeners[event] = [];
+        }
+        this.messageListeners[event].push(cb);
+    };
+    Ws.prototype.Emit = function (event, data) {
+        var msg = { event: event };
+        if (data !== undefined) {
+            msg.data = data;
+        }
+        this.EmitMessage(JSON.stringify(msg));
+    };
+    Ws.prototype.Disconnect = function () {
+        this.conn.close();
+    };
+    return Ws;
+}());
+`)
@@ -0,0 +1,97 @@
+package websocket
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/get-ion/ion/context"
+	"github.com/satori/go.uuid"
+)
+
+const (
+	// DefaultWebsocketWriteTimeout 15 * time.Second
+	DefaultWebsocketWriteTimeout = 15 * time.Second
+	// DefaultWebsocketPongTimeout 60 * time.Second
+	DefaultWebsocketPongTimeout = 60 * time.Second
+	// DefaultWebsocketPingPeriod (DefaultPongTimeout * 9) / 10
+	DefaultWebsocketPingPeriod = (DefaultWebsocketPongTimeout * 9) / 10
+	// DefaultWebsocketMaxMessageSize 1024 * 1024
+	DefaultWebsocketMaxMessageSize = 1024 * 1024
+)
+
+// Config the websocket server configuration
+// all of these are optional.
+type Config struct {
+	// IDGenerator used to create (and later on, set)
+	// an ID for each incoming websocket connections (clients).
+	// The request is an argument which you can use to generate the ID (from headers for example).
+	// If empty then the ID is generated by a uuid.
+	IDGenerator func(ctx context.Context) string
+
+	// CheckOrigin a function that is called right before the handshake,
+	// if returns false then the client is not allowed to connect with the websocket server.
+	//
+	// Defaults to a function which allows the clients without an "Origin" header
+	// and the clients that their "Origin" host is the same as the request's host.
+	CheckOrigin func(ctx context.Context) bool
+
+	// WriteTimeout time allowed to write a message to the connection.
+	// Defaults to 15 seconds.
+	WriteTimeout time.Duration
+	// PongTimeout allowed to read the next pong message from the connection,
+	// actually it is the max time that the connection can stay without reading anything.
+	// Defaults to 60 seconds.
+	PongTimeout time.Duration
+	// PingPeriod send ping messages to the connection with this period. Must be less than PongTimeout.
+	// Defaults to (PongTimeout * 9) / 10.
+	PingPeriod time.Duration
+	// MaxMessageSize max message size allowed from connection.
+	// Defaults to 1024 * 1024 bytes.
+	MaxMessageSize int
+}
+
+// Validate validates the configuration
+func (c Config) Validate() Config {
+	if c.IDGenerator == nil {
+		c.IDGenerator = func(context.Context) string {
+			return uuid.NewV4().String()
+		}
+	}
+
+	if c.CheckOrigin == nil {
+		c.CheckOrigin = sameOrigin
+	}
+
+	if c.WriteTimeout <= 0 {
+		c.WriteTimeout = DefaultWebsocketWriteTimeout
+	}
+
+	if c.PongTimeout <= 0 {
+		c.PongTimeout = DefaultWebsocketPongTimeout
+	}
+
+	if c.PingPeriod <= 0 || c.PingPeriod >= c.PongTimeout {
+		c.PingPeriod = (c.PongTimeout * 9) / 10
+	}
+
+	if c.MaxMessageSize <= 0 {
+		c.MaxMessageSize = DefaultWebsocketMaxMessageSize
+	}
+
+	return c
+}
+
+// sameOrigin is the default `Config#CheckOrigin`.
+func sameOrigin(ctx context.Context) bool {
+	origin := ctx.GetHeader("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return u.Host == ctx.Host()
+}
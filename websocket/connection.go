@@ -0,0 +1,325 @@
+package websocket
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/get-ion/ion/context"
+
+	"golang.org/x/net/websocket"
+)
+
+type (
+	// Emitter is the message/or/event manager
+	Emitter interface {
+		// Emit sends a message on a particular event,
+		// the "data" are encoded as json.
+		Emit(event string, data interface{}) error
+	}
+
+	// Connection is the front-end API that you will use to communicate with the client side
+	Connection interface {
+		// Emitter implements EmitMessage & Emit
+		Emitter
+		// ID returns the connection's identifier
+		ID() string
+		// Server returns the websocket server instance
+		// which this connection is listening to.
+		//
+		// Its connection-relative operations are safe for use.
+		Server() *Server
+		// Context returns the (upgraded) context.Context of this connection
+		// avoid using it, you normally don't need it,
+		// websocket has everything you need to authenticate the user BUT if it's necessary
+		// then  you use it to receive user information, for example: from headers
+		Context() context.Context
+		// EmitMessage sends a native websocket message
+		EmitMessage(payload []byte) error
+		// To defines where server should send a message
+		// returns an emitter to send messages
+		To(roomName string) Emitter
+		// OnMessage registers a callback which fires when native websocket message received,
+		// the messages that are not event messages.
+		OnMessage(cb func(payload []byte))
+		// On registers a callback to a particular event which is fired when a message to this event is received
+		On(event string, cb MessageFunc)
+		// OnError registers a callback which fires when this connection occurs an error
+		OnError(cb func(err error))
+		// OnDisconnect registers a callback which fires when this connection is closed by an error or manual
+		OnDisconnect(cb func())
+		// OnLeave registers a callback which fires when this connection left from any joined room.
+		// This callback is called automatically on Disconnected client, because websocket server automatically
+		// deletes the disconnected connection from any joined rooms.
+		OnLeave(cb func(roomName string))
+		// Join joins this connection to a room, if it doesn't exist then it creates a new. One room can have one or more connections. One connection can be joined to many rooms. All connections are joined to a room specified by their `ID` automatically.
+		Join(roomName string)
+		// IsJoined returns true when this connection is joined to the room, otherwise false.
+		// It Takes the room name as its input parameter.
+		IsJoined(roomName string) bool
+		// Leave removes this connection entry from a room
+		// Returns true if the connection has actually left from the particular room.
+		Leave(roomName string) bool
+		// Disconnect disconnects the client, close the underline websocket conn and removes it from the conn list
+		// returns the error, if any, from the underline connection
+		Disconnect() error
+	}
+
+	connection struct {
+		id        string
+		underline *websocket.Conn
+		ctx       context.Context
+		server    *Server
+
+		writeMu sync.Mutex
+
+		mu               sync.RWMutex
+		onEvent          map[string][]eventCallback
+		onMessage        []func([]byte)
+		onError          []func(error)
+		onDisconnect     []func()
+		onLeave          []func(string)
+		disconnected     int32
+		disconnectedChan chan struct{}
+	}
+)
+
+var _ Connection = &connection{}
+
+func newConnection(s *Server, ctx context.Context, underline *websocket.Conn) *connection {
+	underline.MaxPayloadBytes = s.config.MaxMessageSize
+	// the event messages are sent as text frames.
+	underline.PayloadType = websocket.TextFrame
+
+	return &connection{
+		id:               s.config.IDGenerator(ctx),
+		underline:        underline,
+		ctx:              ctx,
+		server:           s,
+		onEvent:          make(map[string][]eventCallback),
+		disconnectedChan: make(chan struct{}),
+	}
+}
+
+func (c *connection) ID() string {
+	return c.id
+}
+
+func (c *connection) Server() *Server {
+	return c.server
+}
+
+func (c *connection) Context() context.Context {
+	return c.ctx
+}
+
+// pingCodec sends a websocket ping control frame,
+// the client responds with a pong which resets the read deadline of the connection.
+var pingCodec = websocket.Codec{
+	Marshal: func(v interface{}) ([]byte, byte, error) {
+		return nil, websocket.PingFrame, nil
+	},
+}
+
+func (c *connection) write(send func() error) error {
+	c.writeMu.Lock()
+	c.underline.SetWriteDeadline(time.Now().Add(c.server.config.WriteTimeout))
+	err := send()
+	c.writeMu.Unlock()
+
+	if err != nil {
+		c.fireOnError(err)
+		// the client is not reachable.
+		c.Disconnect()
+	}
+	return err
+}
+
+func (c *connection) EmitMessage(payload []byte) error {
+	return c.write(func() error {
+		_, err := c.underline.Write(payload)
+		return err
+	})
+}
+
+func (c *connection) Emit(event string, data interface{}) error {
+	payload, err := encodeMessage(event, data)
+	if err != nil {
+		return err
+	}
+	return c.EmitMessage(payload)
+}
+
+func (c *connection) To(roomName string) Emitter {
+	return &emitter{server: c.server, from: c.id, to: roomName}
+}
+
+func (c *connection) startPinger() {
+	ticker := time.NewTicker(c.server.config.PingPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-c.disconnectedChan:
+			return
+		case <-ticker.C:
+			err := c.write(func() error {
+				return pingCodec.Send(c.underline, nil)
+			})
+			if err != nil {
+				return
+			}
+		}
+	}
+}
+
+// startReader reads the messages of the client until the connection is closed.
+func (c *connection) startReader() {
+	for {
+		var payload []byte
+		if err := websocket.Message.Receive(c.underline, &payload); err != nil {
+			if !c.isDisconnected() {
+				// not an error of a closed connection.
+				c.fireOnError(err)
+			}
+			return
+		}
+
+		c.messageReceived(payload)
+	}
+}
+
+func (c *connection) messageReceived(payload []byte) {
+	msg, ok := decodeMessage(payload)
+	if !ok {
+		c.mu.RLock()
+		onMessage := c.onMessage
+		c.mu.RUnlock()
+
+		for _, cb := range onMessage {
+			cb(payload)
+		}
+		return
+	}
+
+	c.mu.RLock()
+	callbacks := c.onEvent[msg.Event]
+	c.mu.RUnlock()
+
+	for _, evt := range callbacks {
+		if err := evt.call(msg.Data); err != nil {
+			c.fireOnError(err)
+		}
+	}
+}
+
+func (c *connection) OnMessage(cb func(payload []byte)) {
+	c.mu.Lock()
+	c.onMessage = append(c.onMessage, cb)
+	c.mu.Unlock()
+}
+
+// On registers a callback to a particular event,
+// it panics if the "cb" is not a valid `MessageFunc`.
+func (c *connection) On(event string, cb MessageFunc) {
+	evt, err := newEventCallback(event, cb)
+	if err != nil {
+		panic(err)
+	}
+
+	c.mu.Lock()
+	c.onEvent[event] = append(c.onEvent[event], evt)
+	c.mu.Unlock()
+}
+
+func (c *connection) OnError(cb func(err error)) {
+	c.mu.Lock()
+	c.onError = append(c.onError, cb)
+	c.mu.Unlock()
+}
+
+func (c *connection) fireOnError(err error) {
+	c.mu.RLock()
+	onError := c.onError
+	c.mu.RUnlock()
+
+	for _, cb := range onError {
+		cb(err)
+	}
+}
+
+func (c *connection) OnDisconnect(cb func()) {
+	c.mu.Lock()
+	c.onDisconnect = append(c.onDisconnect, cb)
+	c.mu.Unlock()
+}
+
+func (c *connection) OnLeave(cb func(roomName string)) {
+	c.mu.Lock()
+	c.onLeave = append(c.onLeave, cb)
+	c.mu.Unlock()
+}
+
+func (c *connection) fireOnLeave(roomName string) {
+	c.mu.RLock()
+	onLeave := c.onLeave
+	c.mu.RUnlock()
+
+	for _, cb := range onLeave {
+		cb(roomName)
+	}
+}
+
+func (c *connection) Join(roomName string) {
+	c.server.Join(roomName, c.id)
+}
+
+func (c *connection) IsJoined(roomName string) bool {
+	return c.server.IsJoined(roomName, c.id)
+}
+
+func (c *connection) Leave(roomName string) bool {
+	return c.server.Leave(roomName, c.id)
+}
+
+func (c *connection) isDisconnected() bool {
+	return atomic.LoadInt32(&c.disconnected) > 0
+}
+
+func (c *connection) Disconnect() error {
+	if !atomic.CompareAndSwapInt32(&c.disconnected, 0, 1) {
+		return nil
+	}
+	close(c.disconnectedChan)
+
+	for _, roomName := range c.server.remove(c.id) {
+		c.fireOnLeave(roomName)
+	}
+
+	c.mu.RLock()
+	onDisconnect := c.onDisconnect
+	c.mu.RUnlock()
+
+	for _, cb := range onDisconnect {
+		cb()
+	}
+
+	c.writeMu.Lock()
+	c.underline.SetWriteDeadline(time.Now().Add(c.server.config.WriteTimeout))
+	err := c.underline.Close()
+	c.writeMu.Unlock()
+	return err
+}
+
+// emitter sends messages to a room of a server.
+type emitter struct {
+	server *Server
+	from   string
+	to     string
+}
+
+var _ Emitter = &emitter{}
+
+func (e *emitter) Emit(event string, data interface{}) error {
+	return e.server.emitTo(e.to, e.from, event, data)
+}
@@ -0,0 +1,87 @@
+package websocket
+
+import (
+	"encoding/json"
+	"reflect"
+
+	"github.com/get-ion/ion/core/errors"
+)
+
+// message is the form of the event messages that the server and the client (ion-ws.js) exchange,
+// i.e {"event": "chat", "data": "Hello"}.
+type message struct {
+	Event string          `json:"event"`
+	Data  json.RawMessage `json:"data,omitempty"`
+}
+
+func encodeMessage(event string, data interface{}) ([]byte, error) {
+	var raw json.RawMessage
+	if data != nil {
+		b, err := json.Marshal(data)
+		if err != nil {
+			return nil, err
+		}
+		raw = b
+	}
+
+	return json.Marshal(message{Event: event, Data: raw})
+}
+
+// decodeMessage returns the event message of the payload,
+// ok is false if the payload is not an event message.
+func decodeMessage(payload []byte) (msg message, ok bool) {
+	if len(payload) == 0 || payload[0] != '{' {
+		return
+	}
+
+	if err := json.Unmarshal(payload, &msg); err != nil || msg.Event == "" {
+		return
+	}
+
+	return msg, true
+}
+
+// MessageFunc is the second argument to the Connection's `On` method,
+// it's a function with zero or one input argument, i.e:
+// func(), func(string), func(int), func(myStruct), func(map[string]interface{}).
+//
+// The data of the event message are decoded (as json) to the type of the input argument.
+type MessageFunc interface{}
+
+var errInvalidMessageFunc = errors.New("websocket: the callback of the '%s' event should be a func with zero or one input argument, found: %T")
+
+// eventCallback is the validated form of a MessageFunc.
+type eventCallback struct {
+	fn     reflect.Value
+	inType reflect.Type // nil if the callback has not any input argument.
+}
+
+func newEventCallback(event string, cb MessageFunc) (eventCallback, error) {
+	fn := reflect.ValueOf(cb)
+	if fn.Kind() != reflect.Func || fn.Type().NumIn() > 1 {
+		return eventCallback{}, errInvalidMessageFunc.Format(event, cb)
+	}
+
+	evt := eventCallback{fn: fn}
+	if fn.Type().NumIn() == 1 {
+		evt.inType = fn.Type().In(0)
+	}
+	return evt, nil
+}
+
+func (evt eventCallback) call(data json.RawMessage) error {
+	if evt.inType == nil {
+		evt.fn.Call(nil)
+		return nil
+	}
+
+	in := reflect.New(evt.inType)
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, in.Interface()); err != nil {
+			return err
+		}
+	}
+
+	evt.fn.Call([]reflect.Value{in.Elem()})
+	return nil
+}
@@ -0,0 +1,388 @@
+// Package websocket provides a websocket server which is served by a route handler,
+// it's built on top of the golang.org/x/net/websocket package.
+//
+// The ion middleware of the route run before the websocket handshake, i.e basicauth.
+// See _examples/tutorial/online-visitors
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/get-ion/ion/context"
+	"github.com/get-ion/ion/core/host"
+
+	"golang.org/x/net/websocket"
+)
+
+const (
+	// All is the string which the Emitter use to send a message to all.
+	All = ""
+	// Broadcast is the string which the Emitter use to send a message to all except this connection.
+	Broadcast = ";ion;to;all;except;me;"
+)
+
+type (
+	// ConnectionFunc is the callback which fires when a client/connection is connected to the Server.
+	// Receives one parameter which is the Connection
+	ConnectionFunc func(Connection)
+
+	// Server is the websocket server,
+	// it keeps the registry of the connected clients and their rooms.
+	Server struct {
+		config Config
+
+		mu          sync.RWMutex
+		connections map[string]*connection
+		// rooms contains the ids of the connections that are joined to each room.
+		rooms        map[string]map[string]struct{}
+		onConnection []ConnectionFunc
+		closed       bool
+	}
+)
+
+// New returns a new websocket Server based on a configuration.
+// See `OnConnection` , to register a single event which will handle all incoming connections
+// and the `Handler` which builds the upgrader handler that you can register to a route based on an Endpoint.
+func New(cfg Config) *Server {
+	return &Server{
+		config:      cfg.Validate(),
+		connections: make(map[string]*connection),
+		rooms:       make(map[string]map[string]struct{}),
+	}
+}
+
+// OnConnection is the main event you, as developer, will work with each of the websocket connections.
+func (s *Server) OnConnection(cb ConnectionFunc) {
+	s.mu.Lock()
+	s.onConnection = append(s.onConnection, cb)
+	s.mu.Unlock()
+}
+
+// Handler builds the handler based on the configuration and returns it.
+// It should be called once per Server, its result should be passed
+// as a route handler, i.e app.Get("/ws", ws.Handler()).
+//
+// The handler blocks until the connection is closed,
+// the request's context is available through the `Connection#Context`.
+func (s *Server) Handler() context.Handler {
+	return func(ctx context.Context) {
+		if s.isClosed() {
+			ctx.StatusCode(http.StatusServiceUnavailable)
+			return
+		}
+
+		if !isWebsocketRequest(ctx.Request()) {
+			ctx.StatusCode(http.StatusBadRequest)
+			return
+		}
+
+		if !s.config.CheckOrigin(ctx) {
+			ctx.StatusCode(http.StatusForbidden)
+			return
+		}
+
+		wsServer := websocket.Server{
+			// the origin is checked already.
+			Handshake: func(*websocket.Config, *http.Request) error { return nil },
+			Handler: func(underline *websocket.Conn) {
+				s.handleConnection(ctx, underline)
+			},
+		}
+
+		w := &upgradeResponseWriter{
+			ResponseWriter: ctx.ResponseWriter(),
+			readTimeout:    s.config.PongTimeout,
+		}
+		wsServer.ServeHTTP(w, ctx.Request())
+	}
+}
+
+func isWebsocketRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		strings.EqualFold(r.Header.Get("Upgrade"), "websocket") &&
+		strings.Contains(strings.ToLower(r.Header.Get("Connection")), "upgrade")
+}
+
+func (s *Server) handleConnection(ctx context.Context, underline *websocket.Conn) {
+	c := newConnection(s, ctx, underline)
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		underline.Close()
+		return
+	}
+	s.connections[c.id] = c
+	s.joinRoom(c.id, c.id) // each connection is joined to its own room
+	onConnection := s.onConnection
+	s.mu.Unlock()
+
+	for _, cb := range onConnection {
+		cb(c)
+	}
+
+	go c.startPinger()
+	c.startReader() // blocks until the connection is closed.
+	c.Disconnect()
+}
+
+// upgradeResponseWriter is passed to the websocket package
+// in order to hijack the connection with a read deadline, see `timeoutConn`.
+type upgradeResponseWriter struct {
+	http.ResponseWriter
+	readTimeout time.Duration
+}
+
+func (w *upgradeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	c, rw, err := w.ResponseWriter.(http.Hijacker).Hijack()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tc := &timeoutConn{Conn: c, readTimeout: w.readTimeout}
+	var r io.Reader = tc
+	// keep the data that the server has already read from the connection.
+	if n := rw.Reader.Buffered(); n > 0 {
+		buffered, _ := rw.Reader.Peek(n)
+		r = io.MultiReader(bytes.NewReader(append([]byte(nil), buffered...)), tc)
+	}
+
+	return tc, bufio.NewReadWriter(bufio.NewReader(r), rw.Writer), nil
+}
+
+// timeoutConn extends the read deadline of the connection on each read,
+// the pong messages are reads too, so a connection that doesn't respond to
+// the server's pings is closed after the read timeout.
+type timeoutConn struct {
+	net.Conn
+	readTimeout time.Duration
+}
+
+func (c *timeoutConn) Read(b []byte) (int, error) {
+	c.Conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	return c.Conn.Read(b)
+}
+
+// joinRoom adds a connection to a room, the caller should lock.
+func (s *Server) joinRoom(roomName string, connID string) {
+	room, ok := s.rooms[roomName]
+	if !ok {
+		room = make(map[string]struct{})
+		s.rooms[roomName] = room
+	}
+	room[connID] = struct{}{}
+}
+
+// Join joins a websocket client to a room,
+// first parameter is the room name and the second the connection.ID()
+//
+// You can use connection.Join("room name") instead.
+func (s *Server) Join(roomName string, connID string) {
+	s.mu.Lock()
+	if _, ok := s.connections[connID]; ok {
+		s.joinRoom(roomName, connID)
+	}
+	s.mu.Unlock()
+}
+
+// IsJoined reports if a specific room has a specific connection into its values.
+// First parameter is the room name, second is the connection's id.
+//
+// You can use connection.IsJoined("room name") instead.
+func (s *Server) IsJoined(roomName string, connID string) bool {
+	s.mu.RLock()
+	_, ok := s.rooms[roomName][connID]
+	s.mu.RUnlock()
+	return ok
+}
+
+// Leave leaves a websocket client from a room,
+// first parameter is the room name and the second the connection.ID()
+//
+// You can use connection.Leave("room name") instead.
+// Returns true if the connection has actually left from the particular room.
+func (s *Server) Leave(roomName string, connID string) bool {
+	s.mu.Lock()
+	left := s.leave(roomName, connID)
+	c := s.connections[connID]
+	s.mu.Unlock()
+
+	if left && c != nil {
+		c.fireOnLeave(roomName)
+	}
+	return left
+}
+
+// leave removes a connection from a room, the caller should lock.
+func (s *Server) leave(roomName string, connID string) bool {
+	room, ok := s.rooms[roomName]
+	if !ok {
+		return false
+	}
+
+	if _, ok = room[connID]; !ok {
+		return false
+	}
+
+	delete(room, connID)
+	if len(room) == 0 {
+		delete(s.rooms, roomName)
+	}
+	return true
+}
+
+// GetConnection returns single connection or nil if not found.
+func (s *Server) GetConnection(connID string) Connection {
+	s.mu.RLock()
+	c, ok := s.connections[connID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+	return c
+}
+
+// GetConnections returns all connections.
+func (s *Server) GetConnections() []Connection {
+	s.mu.RLock()
+	conns := make([]Connection, 0, len(s.connections))
+	for _, c := range s.connections {
+		conns = append(conns, c)
+	}
+	s.mu.RUnlock()
+	return conns
+}
+
+// GetConnectionsByRoom returns a list of Connection
+// which are joined to this room.
+func (s *Server) GetConnectionsByRoom(roomName string) []Connection {
+	s.mu.RLock()
+	room := s.rooms[roomName]
+	conns := make([]Connection, 0, len(room))
+	for connID := range room {
+		if c, ok := s.connections[connID]; ok {
+			conns = append(conns, c)
+		}
+	}
+	s.mu.RUnlock()
+	return conns
+}
+
+// Len returns the length of the connected connections.
+func (s *Server) Len() int {
+	s.mu.RLock()
+	n := len(s.connections)
+	s.mu.RUnlock()
+	return n
+}
+
+// Broadcast sends an event message to all connections.
+func (s *Server) Broadcast(event string, data interface{}) error {
+	return s.emitTo(All, "", event, data)
+}
+
+// To returns an Emitter which sends messages
+// to the connections of the "roomName" room, or to all if "roomName" is `All`.
+func (s *Server) To(roomName string) Emitter {
+	return &emitter{server: s, to: roomName}
+}
+
+// emitTo sends an event message to the connections of a room,
+// the "from" connection is excluded when the room is the `Broadcast`.
+func (s *Server) emitTo(to string, from string, event string, data interface{}) error {
+	payload, err := encodeMessage(event, data)
+	if err != nil {
+		return err
+	}
+
+	var conns []Connection
+	switch to {
+	case All:
+		conns = s.GetConnections()
+	case Broadcast:
+		for _, c := range s.GetConnections() {
+			if c.ID() != from {
+				conns = append(conns, c)
+			}
+		}
+	default:
+		conns = s.GetConnectionsByRoom(to)
+	}
+
+	for _, c := range conns {
+		// a failed write closes that connection only,
+		// the rest of the connections should receive the message.
+		c.EmitMessage(payload)
+	}
+
+	return nil
+}
+
+// Disconnect force-disconnects a websocket connection based on its connection.ID()
+// What it does?
+// 1. remove the connection from the list
+// 2. leave from all joined rooms
+// 3. fire the disconnect callbacks, if any
+// 4. close the underline connection and return its error, if any.
+//
+// You can use the connection.Disconnect() instead.
+func (s *Server) Disconnect(connID string) error {
+	s.mu.RLock()
+	c, ok := s.connections[connID]
+	s.mu.RUnlock()
+
+	if !ok {
+		return nil
+	}
+	return c.Disconnect()
+}
+
+// remove removes a connection from the registry and from all of its rooms,
+// returns the rooms that the connection has left.
+func (s *Server) remove(connID string) (leftRooms []string) {
+	s.mu.Lock()
+	delete(s.connections, connID)
+	for roomName, room := range s.rooms {
+		if _, ok := room[connID]; ok {
+			s.leave(roomName, connID)
+			leftRooms = append(leftRooms, roomName)
+		}
+	}
+	s.mu.Unlock()
+	return
+}
+
+func (s *Server) isClosed() bool {
+	s.mu.RLock()
+	closed := s.closed
+	s.mu.RUnlock()
+	return closed
+}
+
+// Close disconnects all the connected clients and
+// rejects any new websocket connection.
+func (s *Server) Close() {
+	s.mu.Lock()
+	s.closed = true
+	s.mu.Unlock()
+
+	for _, c := range s.GetConnections() {
+		c.Disconnect()
+	}
+}
+
+// Run implements the `host.TaskRunner`, it closes the websocket server
+// when the host is shutdown, so the hijacked connections are closed cleanly.
+//
+// Usage: app.Scheduler.Schedule(ws)
+func (s *Server) Run(proc host.TaskProcess) {
+	proc.Host().RegisterOnShutdown(s.Close)
+}